@@ -185,6 +185,24 @@ func (v *verifiedCachedClient) SlotHeight(ctx context.Context) (uint64, error) {
 	return v.ReaderWriter.SlotHeight(ctx)
 }
 
+func (v *verifiedCachedClient) SlotHeightWithCommitment(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return 0, err
+	}
+
+	return v.ReaderWriter.SlotHeightWithCommitment(ctx, commitment)
+}
+
+func (v *verifiedCachedClient) BlockHeight(ctx context.Context) (uint64, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return 0, err
+	}
+
+	return v.ReaderWriter.BlockHeight(ctx)
+}
+
 func (v *verifiedCachedClient) LatestBlockhash(ctx context.Context) (*rpc.GetLatestBlockhashResult, error) {
 	verified, err := v.verifyChainID(ctx)
 	if !verified {
@@ -221,6 +239,51 @@ func (v *verifiedCachedClient) GetAccountInfoWithOpts(ctx context.Context, addr
 	return v.ReaderWriter.GetAccountInfoWithOpts(ctx, addr, opts)
 }
 
+func (v *verifiedCachedClient) GetRecentPrioritizationFees(ctx context.Context, accounts solanago.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return nil, err
+	}
+
+	return v.ReaderWriter.GetRecentPrioritizationFees(ctx, accounts)
+}
+
+func (v *verifiedCachedClient) SlotLeaders(ctx context.Context, start, limit uint64) ([]solanago.PublicKey, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return nil, err
+	}
+
+	return v.ReaderWriter.SlotLeaders(ctx, start, limit)
+}
+
+func (v *verifiedCachedClient) GetBlocks(ctx context.Context, start, end uint64) ([]uint64, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return nil, err
+	}
+
+	return v.ReaderWriter.GetBlocks(ctx, start, end)
+}
+
+func (v *verifiedCachedClient) GetBlock(ctx context.Context, slot uint64) (*rpc.GetBlockResult, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return nil, err
+	}
+
+	return v.ReaderWriter.GetBlock(ctx, slot)
+}
+
+func (v *verifiedCachedClient) TPUAddresses(ctx context.Context) (map[solanago.PublicKey]string, error) {
+	verified, err := v.verifyChainID(ctx)
+	if !verified {
+		return nil, err
+	}
+
+	return v.ReaderWriter.TPUAddresses(ctx)
+}
+
 func newChain(id string, cfg *config.TOMLConfig, ks loop.Keystore, lggr logger.Logger) (*chain, error) {
 	lggr = logger.With(lggr, "chainID", id, "chain", "solana")
 	var ch = chain{
@@ -268,18 +331,12 @@ func newChain(id string, cfg *config.TOMLConfig, ks loop.Keystore, lggr logger.L
 			mnCfg.DeathDeclarationDelay(),
 		)
 
-		// TODO: implement error classification; move logic to separate file if large
-		// TODO: might be useful to reference anza-xyz/agave@master/sdk/src/transaction/error.rs
-		classifySendError := func(tx *solanago.Transaction, err error) mn.SendTxReturnCode {
-			return 0 // TODO ClassifySendError(err, clientErrors, logger.Sugared(logger.Nop()), tx, common.Address{}, false)
-		}
-
 		txSender := mn.NewTransactionSender[*solanago.Transaction, mn.StringID, *client.MultiNodeClient](
 			lggr,
 			mn.StringID(id),
 			chainFamily,
 			multiNode,
-			classifySendError,
+			client.NewSendErrorClassifier(id),
 			0, // use the default value provided by the implementation
 		)
 
@@ -291,7 +348,7 @@ func newChain(id string, cfg *config.TOMLConfig, ks loop.Keystore, lggr logger.L
 	}
 
 	tc := func() (client.ReaderWriter, error) {
-		return ch.getClient()
+		return ch.getTxClient()
 	}
 	ch.txm = txm.NewTxm(ch.id, tc, cfg, ks, lggr)
 	bc := func() (monitor.BalanceClient, error) { return ch.getClient() }
@@ -395,6 +452,35 @@ func (c *chain) ChainID() string {
 	return c.id
 }
 
+// getTxClient returns the client used by the txm. If multinode is enabled, txs are broadcast to all nodes
+// through the transaction sender.
+func (c *chain) getTxClient() (client.ReaderWriter, error) {
+	rw, err := c.getClient()
+	if err != nil || !c.cfg.MultiNode.Enabled() {
+		return rw, err
+	}
+	return &txSenderClient{ReaderWriter: rw, txSender: c.txSender}, nil
+}
+
+// txSenderClient broadcasts txs to all primary and send-only nodes, other requests use the selected node
+type txSenderClient struct {
+	client.ReaderWriter
+	txSender *mn.TransactionSender[*solanago.Transaction, mn.StringID, *client.MultiNodeClient]
+}
+
+// SendTx returns the signature of the tx if it was accepted by a node, or a client.SendTxError with the aggregated
+// result of the nodes
+func (c *txSenderClient) SendTx(ctx context.Context, tx *solanago.Transaction) (solanago.Signature, error) {
+	if len(tx.Signatures) == 0 {
+		return solanago.Signature{}, errors.New("tx is not signed")
+	}
+	code, err := c.txSender.SendTransaction(ctx, tx)
+	if code != mn.Successful {
+		return solanago.Signature{}, &client.SendTxError{Code: code, Err: err}
+	}
+	return tx.Signatures[0], nil
+}
+
 // getClient returns a client, randomly selecting one from available and valid nodes
 // If multinode is enabled, it will return a client using the multinode selection instead.
 func (c *chain) getClient() (client.ReaderWriter, error) {
@@ -562,7 +648,7 @@ func (c *chain) sendTx(ctx context.Context, from, to string, amount *big.Int, ba
 	}
 
 	chainTxm := c.TxManager()
-	err = chainTxm.Enqueue(ctx, "", tx,
+	err = chainTxm.Enqueue(ctx, "", tx, nil,
 		txm.SetComputeUnitLimit(500), // reduce from default 200K limit - should only take 450 compute units
 		// no fee bumping and no additional fee - makes validating balance accurate
 		txm.SetComputeUnitPriceMax(0),
@@ -11,8 +11,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +27,7 @@ import (
 	"github.com/goplugin/plugin-common/pkg/utils/tests"
 
 	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	mn "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
 	solcfg "github.com/goplugin/plugin-solana/pkg/solana/config"
 	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 	"github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
@@ -181,6 +184,87 @@ func TestSolanaChain_MultiNode_GetClient(t *testing.T) {
 	assert.Equal(t, "devnet", id.String())
 }
 
+func TestSolanaChain_MultiNode_TxClient(t *testing.T) {
+	sig := solana.Signature{1}
+	var lock sync.Mutex
+	sends := map[string]int{}
+	rejectAll := false
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		out := fmt.Sprintf(TestSolanaGenesisHashTemplate, client.DevnetGenesisHash)
+		if strings.Contains(string(body), "sendTransaction") {
+			lock.Lock()
+			sends[r.URL.Path]++
+			out = fmt.Sprintf(`{"jsonrpc":"2.0","result":"%s","id":1}`, sig)
+			if rejectAll {
+				out = `{"jsonrpc":"2.0","error":{"code":-32003,"message":"Transaction signature verification failure"},"id":1}`
+			}
+			lock.Unlock()
+		}
+		_, err = w.Write([]byte(out))
+		require.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	ch := solcfg.Chain{}
+	ch.SetDefaults()
+	mnCfg := solcfg.MultiNodeConfig{
+		MultiNode: solcfg.MultiNode{
+			Enabled: ptr(true),
+		},
+	}
+	mnCfg.SetDefaults()
+
+	cfg := &solcfg.TOMLConfig{
+		ChainID:   ptr("devnet"),
+		Chain:     ch,
+		MultiNode: mnCfg,
+	}
+	cfg.Nodes = []*solcfg.Node{
+		{Name: ptr("primary-1"), URL: config.MustParseURL(mockServer.URL + "/1")},
+		{Name: ptr("primary-2"), URL: config.MustParseURL(mockServer.URL + "/2")},
+		{Name: ptr("sendonly"), URL: config.MustParseURL(mockServer.URL + "/3"), SendOnly: true},
+	}
+
+	testChain, err := newChain("devnet", cfg, nil, logger.Test(t))
+	require.NoError(t, err)
+	require.NoError(t, testChain.Start(tests.Context(t)))
+	defer func() {
+		require.NoError(t, testChain.Close())
+	}()
+
+	txClient, err := testChain.getTxClient()
+	require.NoError(t, err)
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, solana.PublicKey{}, solana.PublicKey{}).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(solana.PublicKey{}))
+	require.NoError(t, err)
+	_, err = txClient.SendTx(tests.Context(t), tx)
+	require.Error(t, err, "unsigned tx")
+
+	// tx is broadcast to all nodes
+	tx.Signatures = []solana.Signature{sig}
+	sent, err := txClient.SendTx(tests.Context(t), tx)
+	require.NoError(t, err)
+	assert.Equal(t, sig, sent)
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return sends["/1"] == 1 && sends["/2"] == 1 && sends["/3"] == 1
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+
+	// rejected by all nodes, the error is classified with the aggregated result
+	lock.Lock()
+	rejectAll = true
+	lock.Unlock()
+	_, err = txClient.SendTx(tests.Context(t), tx)
+	var sendErr *client.SendTxError
+	require.ErrorAs(t, err, &sendErr)
+	assert.Equal(t, mn.Fatal, sendErr.Code)
+}
+
 func TestSolanaChain_VerifiedClient(t *testing.T) {
 	ctx := tests.Context(t)
 	called := false
@@ -246,6 +330,12 @@ func TestSolanaChain_VerifiedClient(t *testing.T) {
 	// expect error from id mismatch (even if using a cached client) when performing RPC calls
 	assert.Error(t, err)
 	assert.Equal(t, fmt.Sprintf("client returned mismatched chain id (expected: %s, got: %s): %s", "incorrect", "devnet", node.URL), err.Error())
+	_, err = c.GetRecentPrioritizationFees(ctx, nil)
+	assert.ErrorContains(t, err, "mismatched chain id")
+	_, err = c.SlotLeaders(ctx, 0, 1)
+	assert.ErrorContains(t, err, "mismatched chain id")
+	_, err = c.TPUAddresses(ctx)
+	assert.ErrorContains(t, err, "mismatched chain id")
 }
 
 func TestSolanaChain_VerifiedClient_ParallelClients(t *testing.T) {
@@ -19,6 +19,7 @@ import (
 	"github.com/goplugin/plugin-common/pkg/types/query/primitives"
 	"github.com/goplugin/plugin-common/pkg/values"
 
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
 	"github.com/goplugin/plugin-solana/pkg/solana/codec"
 	"github.com/goplugin/plugin-solana/pkg/solana/config"
 )
@@ -332,19 +333,36 @@ func createRPCOpts(opts *config.RPCOpts) *rpc.GetAccountInfoOpts {
 }
 
 type accountDataReader struct {
-	client *rpc.Client
+	getReader func() (client.AccountReader, error)
 }
 
-func NewAccountDataReader(client *rpc.Client) *accountDataReader {
-	return &accountDataReader{client: client}
+// NewAccountDataReader returns a BinaryDataReader that reads account data with the chain client. The client
+// is requested for every read so that reads use the currently selected node of the chain.
+func NewAccountDataReader(getReader func() (client.AccountReader, error)) *accountDataReader {
+	return &accountDataReader{getReader: getReader}
 }
 
 func (r *accountDataReader) ReadAll(ctx context.Context, pk ag_solana.PublicKey, opts *rpc.GetAccountInfoOpts) ([]byte, error) {
-	result, err := r.client.GetAccountInfoWithOpts(ctx, pk, opts)
+	reader, err := r.getReader()
 	if err != nil {
 		return nil, err
 	}
 
+	// the chain client modifies the opts, copy them to avoid sharing between concurrent reads
+	var readOpts rpc.GetAccountInfoOpts
+	if opts != nil {
+		readOpts = *opts
+	}
+
+	result, err := reader.GetAccountInfoWithOpts(ctx, pk, &readOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil || result.Value == nil {
+		return nil, fmt.Errorf("%w: account %s", types.ErrNotFound, pk)
+	}
+
 	bts := result.Value.Data.GetBinary()
 
 	return bts, nil
@@ -0,0 +1,322 @@
+package chainwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-common/pkg/types"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+// instructionAccount is an account of the IDL instruction with the parsed lookup of its address
+type instructionAccount struct {
+	name     string
+	isMut    bool
+	isSigner bool
+	lookup   *accountLookup // nil for optional accounts that are not set
+}
+
+type accountLookup struct {
+	address *solana.PublicKey
+	param   string
+	pda     *pdaLookup
+	role    config.SignerRole
+}
+
+type pdaLookup struct {
+	programID *solana.PublicKey // nil for the program of the instruction
+	seeds     []seedLookup
+}
+
+type seedLookup struct {
+	value []byte // static seed
+	param string
+}
+
+// newInstructionAccounts flattens the (nested) IDL accounts of an instruction in order and parses the configured lookups
+func newInstructionAccounts(items codec.IdlAccountItemSlice, lookups map[string]config.ChainWriterAccount) ([]instructionAccount, error) {
+	var accounts []instructionAccount
+	var flatten func(items codec.IdlAccountItemSlice) error
+	flatten = func(items codec.IdlAccountItemSlice) error {
+		for _, item := range items {
+			if item.IdlAccounts != nil {
+				if err := flatten(item.IdlAccounts.Accounts); err != nil {
+					return err
+				}
+				continue
+			}
+			if item.IdlAccount == nil {
+				continue
+			}
+
+			account := instructionAccount{
+				name:     item.IdlAccount.Name,
+				isMut:    item.IdlAccount.IsMut,
+				isSigner: item.IdlAccount.IsSigner,
+			}
+			lookupCfg, ok := lookups[account.name]
+			if !ok && !item.IdlAccount.Optional {
+				return fmt.Errorf("%w: no lookup for account %s", types.ErrInvalidConfig, account.name)
+			}
+			if ok {
+				lookup, err := newAccountLookup(lookupCfg)
+				if err != nil {
+					return fmt.Errorf("account %s: %w", account.name, err)
+				}
+				// the txm only signs with the fee payer
+				if account.isSigner && lookup.role != config.SignerRoleFeePayer {
+					return fmt.Errorf("%w: signer account %s must use the %s role", types.ErrInvalidConfig, account.name, config.SignerRoleFeePayer)
+				}
+				account.lookup = &lookup
+			}
+			accounts = append(accounts, account)
+		}
+		return nil
+	}
+	if err := flatten(items); err != nil {
+		return nil, err
+	}
+
+	for name := range lookups {
+		if !hasAccount(accounts, name) {
+			return nil, fmt.Errorf("%w: account %s is not an account of the instruction", types.ErrInvalidConfig, name)
+		}
+	}
+	return accounts, nil
+}
+
+func hasAccount(accounts []instructionAccount, name string) bool {
+	for _, account := range accounts {
+		if account.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func newAccountLookup(cfg config.ChainWriterAccount) (accountLookup, error) {
+	var lookup accountLookup
+	set := 0
+	if cfg.Address != "" {
+		set++
+		address, err := solana.PublicKeyFromBase58(cfg.Address)
+		if err != nil {
+			return lookup, fmt.Errorf("%w: invalid address %s: %s", types.ErrInvalidConfig, cfg.Address, err)
+		}
+		lookup.address = &address
+	}
+	if cfg.Param != "" {
+		set++
+		lookup.param = cfg.Param
+	}
+	if cfg.PDA != nil {
+		set++
+		pda, err := newPDALookup(*cfg.PDA)
+		if err != nil {
+			return lookup, err
+		}
+		lookup.pda = &pda
+	}
+	if cfg.Role != "" {
+		set++
+		if cfg.Role != config.SignerRoleFeePayer {
+			return lookup, fmt.Errorf("%w: unknown signer role %s", types.ErrInvalidConfig, cfg.Role)
+		}
+		lookup.role = cfg.Role
+	}
+	if set != 1 {
+		return lookup, fmt.Errorf("%w: exactly one of address, param, pda or role must be set", types.ErrInvalidConfig)
+	}
+	return lookup, nil
+}
+
+func newPDALookup(cfg config.ChainWriterPDA) (pdaLookup, error) {
+	var pda pdaLookup
+	if cfg.ProgramID != "" {
+		programID, err := solana.PublicKeyFromBase58(cfg.ProgramID)
+		if err != nil {
+			return pda, fmt.Errorf("%w: invalid pda program %s: %s", types.ErrInvalidConfig, cfg.ProgramID, err)
+		}
+		pda.programID = &programID
+	}
+	if len(cfg.Seeds) == 0 || len(cfg.Seeds) > solana.MaxSeeds {
+		return pda, fmt.Errorf("%w: pda must have 1 to %d seeds", types.ErrInvalidConfig, solana.MaxSeeds)
+	}
+
+	for _, seedCfg := range cfg.Seeds {
+		var seed seedLookup
+		set := 0
+		if seedCfg.Value != "" {
+			set++
+			seed.value = []byte(seedCfg.Value)
+		}
+		if seedCfg.Address != "" {
+			set++
+			address, err := solana.PublicKeyFromBase58(seedCfg.Address)
+			if err != nil {
+				return pda, fmt.Errorf("%w: invalid seed address %s: %s", types.ErrInvalidConfig, seedCfg.Address, err)
+			}
+			seed.value = address.Bytes()
+		}
+		if seedCfg.Param != "" {
+			set++
+			seed.param = seedCfg.Param
+		}
+		if set != 1 {
+			return pda, fmt.Errorf("%w: exactly one of value, address or param must be set for a seed", types.ErrInvalidConfig)
+		}
+		pda.seeds = append(pda.seeds, seed)
+	}
+	return pda, nil
+}
+
+// resolveAccounts looks up the addresses of the instruction accounts for the args
+func resolveAccounts(accounts []instructionAccount, args any, programID, feePayer solana.PublicKey) (solana.AccountMetaSlice, error) {
+	metas := make(solana.AccountMetaSlice, 0, len(accounts))
+	for _, account := range accounts {
+		// anchor expects the program ID in place of optional accounts that are not set
+		address := programID
+		if account.lookup != nil {
+			var err error
+			if address, err = account.lookup.resolve(args, programID, feePayer); err != nil {
+				return nil, fmt.Errorf("failed to look up account %s: %w", account.name, err)
+			}
+		}
+		metas = append(metas, solana.NewAccountMeta(address, account.isMut && account.lookup != nil, account.isSigner && account.lookup != nil))
+	}
+	return metas, nil
+}
+
+func (l accountLookup) resolve(args any, programID, feePayer solana.PublicKey) (solana.PublicKey, error) {
+	switch {
+	case l.address != nil:
+		return *l.address, nil
+	case l.param != "":
+		value, err := getParam(args, l.param)
+		if err != nil {
+			return solana.PublicKey{}, err
+		}
+		return toPublicKey(value)
+	case l.pda != nil:
+		return l.pda.resolve(args, programID)
+	case l.role == config.SignerRoleFeePayer:
+		return feePayer, nil
+	default:
+		return solana.PublicKey{}, errors.New("empty account lookup")
+	}
+}
+
+func (p pdaLookup) resolve(args any, programID solana.PublicKey) (solana.PublicKey, error) {
+	if p.programID != nil {
+		programID = *p.programID
+	}
+
+	seeds := make([][]byte, len(p.seeds))
+	for idx, seed := range p.seeds {
+		if seed.param == "" {
+			seeds[idx] = seed.value
+			continue
+		}
+
+		value, err := getParam(args, seed.param)
+		if err != nil {
+			return solana.PublicKey{}, err
+		}
+		if seeds[idx], err = toSeed(value); err != nil {
+			return solana.PublicKey{}, fmt.Errorf("invalid seed %s: %w", seed.param, err)
+		}
+	}
+
+	address, _, err := solana.FindProgramAddress(seeds, programID)
+	return address, err
+}
+
+// getParam returns the value of a (nested) field or map key of the args. Names in the path are separated by '.'
+func getParam(args any, path string) (any, error) {
+	value := reflect.ValueOf(args)
+	for _, name := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, fmt.Errorf("%w: param %s is nil", types.ErrInvalidType, path)
+			}
+			value = value.Elem()
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			value = value.FieldByName(name)
+		case reflect.Map:
+			if value.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("%w: cannot look up param %s in a map with %s keys", types.ErrInvalidType, path, value.Type().Key())
+			}
+			value = value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		default:
+			return nil, fmt.Errorf("%w: cannot look up param %s in %s", types.ErrInvalidType, path, value.Type())
+		}
+
+		if !value.IsValid() {
+			return nil, fmt.Errorf("%w: param %s", types.ErrFieldNotFound, path)
+		}
+	}
+	return value.Interface(), nil
+}
+
+func toPublicKey(value any) (solana.PublicKey, error) {
+	switch v := value.(type) {
+	case solana.PublicKey:
+		return v, nil
+	case *solana.PublicKey:
+		if v == nil {
+			return solana.PublicKey{}, fmt.Errorf("%w: nil public key", types.ErrInvalidType)
+		}
+		return *v, nil
+	case string:
+		return solana.PublicKeyFromBase58(v)
+	}
+
+	bytes, ok := byteArray(reflect.ValueOf(value))
+	if !ok || len(bytes) != solana.PublicKeyLength {
+		return solana.PublicKey{}, fmt.Errorf("%w: %T is not a public key", types.ErrInvalidType, value)
+	}
+	return solana.PublicKeyFromBytes(bytes), nil
+}
+
+// toSeed returns the seed bytes of a param. strings are utf-8 bytes, integers little endian bytes (as in anchor)
+func toSeed(value any) ([]byte, error) {
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+
+	v := reflect.ValueOf(value)
+	if bytes, ok := byteArray(v); ok {
+		return bytes, nil
+	}
+
+	buf := make([]byte, 8)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.LittleEndian.PutUint64(buf, uint64(v.Int())) //nolint:gosec // two's complement bytes are expected
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		binary.LittleEndian.PutUint64(buf, v.Uint())
+	default:
+		return nil, fmt.Errorf("%w: unsupported seed type %T", types.ErrInvalidType, value)
+	}
+	return buf[:v.Type().Size()], nil
+}
+
+// byteArray returns the bytes of a byte slice or array (including named types like solana.PublicKey)
+func byteArray(v reflect.Value) ([]byte, bool) {
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	bytes := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(bytes), v)
+	return bytes, true
+}
@@ -0,0 +1,325 @@
+package chainwriter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/services"
+	"github.com/goplugin/plugin-common/pkg/types"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	"github.com/goplugin/plugin-solana/pkg/solana/txm"
+)
+
+const ServiceName = "SolanaChainWriter"
+
+// TxManager sends and tracks the transactions submitted by the chain writer.
+type TxManager interface {
+	Enqueue(ctx context.Context, accountID string, tx *solana.Transaction, txID *string, txCfgs ...txm.SetTxConfig) error
+	GetTransactionStatus(ctx context.Context, txID string) (txm.TxStatus, error)
+	FeeEstimator() fees.Estimator
+	RegisterProgramErrors(program solana.PublicKey, errs []codec.IdlErrorCode)
+}
+
+type SolanaChainWriterService struct {
+	// provided values
+	lggr   logger.Logger
+	reader func() (client.Reader, error)
+	txm    TxManager
+
+	// internal values
+	methods map[string]map[string]writeMethod // namespace -> method
+
+	// programs the IDL errors of a method are registered with the txm for
+	registeredLock sync.Mutex
+	registered     map[registeredErrors]struct{}
+
+	// service state management
+	wg sync.WaitGroup
+	services.StateMachine
+}
+
+// writeMethod holds everything needed to build the instruction of a configured method
+type writeMethod struct {
+	instruction string // IDL instruction name
+	codec       types.RemoteCodec
+	accounts    []instructionAccount
+	fromAddress solana.PublicKey
+	errors      []codec.IdlErrorCode    // IDL errors, decode the custom program errors of reverted txs
+	tables      *txm.LookupTableManager // lookup tables the accounts are loaded from (nil = legacy txs)
+}
+
+type registeredErrors struct {
+	namespace, method string
+	program           solana.PublicKey
+}
+
+var (
+	_ services.Service  = &SolanaChainWriterService{}
+	_ types.ChainWriter = &SolanaChainWriterService{}
+	_ TxManager         = &txm.Txm{}
+)
+
+// NewChainWriterService is a constructor for a new ChainWriterService for Solana. Returns a nil service on error.
+func NewChainWriterService(lggr logger.Logger, reader func() (client.Reader, error), txManager TxManager, cfg config.ChainWriter) (*SolanaChainWriterService, error) {
+	svc := &SolanaChainWriterService{
+		lggr:       logger.Named(lggr, ServiceName),
+		reader:     reader,
+		txm:        txManager,
+		methods:    map[string]map[string]writeMethod{},
+		registered: map[registeredErrors]struct{}{},
+	}
+
+	if err := svc.init(cfg.Namespaces); err != nil {
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+// Name implements the services.ServiceCtx interface and returns the logger service name.
+func (s *SolanaChainWriterService) Name() string {
+	return s.lggr.Name()
+}
+
+// Start implements the services.ServiceCtx interface. Subsequent calls to Start return an error.
+func (s *SolanaChainWriterService) Start(_ context.Context) error {
+	return s.StartOnce(ServiceName, func() error {
+		return nil
+	})
+}
+
+// Close implements the services.ServiceCtx interface and waits for in progress submissions. Subsequent
+// calls to Close return an error.
+func (s *SolanaChainWriterService) Close() error {
+	return s.StopOnce(ServiceName, func() error {
+		s.wg.Wait()
+
+		return nil
+	})
+}
+
+// Ready implements the services.ServiceCtx interface and returns an error if the service is not ready
+// to serve requests.
+func (s *SolanaChainWriterService) Ready() error {
+	return s.StateMachine.Ready()
+}
+
+// HealthReport implements the services.ServiceCtx interface and returns errors for any internal
+// function or service that may have failed.
+func (s *SolanaChainWriterService) HealthReport() map[string]error {
+	return map[string]error{s.Name(): s.Healthy()}
+}
+
+// SubmitTransaction implements the types.ChainWriter interface. The args are encoded as the configured
+// instruction of the program at toAddress, and the transaction is enqueued with the txm using the
+// transactionID as idempotency key. The meta gas limit is used as compute unit limit. Account params are
+// looked up in the args as passed, before the InputModifications are applied.
+func (s *SolanaChainWriterService) SubmitTransaction(ctx context.Context, contractName, method string, args any, transactionID string, toAddress string, meta *types.TxMeta, value *big.Int) error {
+	if err := s.Ready(); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	m, ok := s.methods[contractName][method]
+	if !ok {
+		return fmt.Errorf("%w: no method %s for contract %s", types.ErrInvalidType, method, contractName)
+	}
+
+	if value != nil && value.Sign() != 0 {
+		return fmt.Errorf("%w: transferring value is not supported", types.ErrInvalidType)
+	}
+
+	programID, err := solana.PublicKeyFromBase58(toAddress)
+	if err != nil {
+		return fmt.Errorf("%w: invalid program address %s: %s", types.ErrInvalidType, toAddress, err)
+	}
+	data, err := m.codec.Encode(ctx, args, m.instruction)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := resolveAccounts(m.accounts, args, programID, m.fromAddress)
+	if err != nil {
+		return err
+	}
+	s.registerProgramErrors(contractName, method, programID, m.errors)
+
+	var txCfgs []txm.SetTxConfig
+	if meta != nil && meta.GasLimit != nil {
+		if !meta.GasLimit.IsUint64() || meta.GasLimit.Uint64() > math.MaxUint32 {
+			return fmt.Errorf("%w: gas limit %s exceeds the max compute unit limit", types.ErrInvalidType, meta.GasLimit)
+		}
+		txCfgs = append(txCfgs, txm.SetComputeUnitLimit(uint32(meta.GasLimit.Uint64())), txm.SetEstimateComputeUnitLimit(false))
+	}
+
+	reader, err := s.reader()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+
+	blockhash, err := reader.LatestBlockhash(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	if blockhash == nil || blockhash.Value == nil {
+		return fmt.Errorf("failed to get latest blockhash: %w: empty result", types.ErrInternal)
+	}
+
+	tx, err := m.newTransaction(ctx, solana.NewInstruction(programID, accounts, data), blockhash.Value.Blockhash)
+	if err != nil {
+		return fmt.Errorf("failed to create tx: %w", err)
+	}
+
+	if err = s.txm.Enqueue(ctx, m.fromAddress.String(), tx, &transactionID, txCfgs...); err != nil {
+		return fmt.Errorf("failed to enqueue tx %s: %w", transactionID, err)
+	}
+
+	return nil
+}
+
+// newTransaction builds the tx of the instruction, a v0 tx if the method has lookup tables storing any of its accounts
+func (m writeMethod) newTransaction(ctx context.Context, instruction solana.Instruction, blockhash solana.Hash) (*solana.Transaction, error) {
+	if m.tables == nil {
+		return solana.NewTransaction([]solana.Instruction{instruction}, blockhash, solana.TransactionPayer(m.fromAddress))
+	}
+	if !m.tables.Loaded() {
+		if err := m.tables.Load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load lookup tables: %w", err)
+		}
+	}
+	return m.tables.NewTransaction([]solana.Instruction{instruction}, blockhash, m.fromAddress)
+}
+
+// registerProgramErrors registers the IDL errors of a method with the txm the first time the method is submitted to the program
+func (s *SolanaChainWriterService) registerProgramErrors(namespace, method string, programID solana.PublicKey, errs []codec.IdlErrorCode) {
+	key := registeredErrors{namespace: namespace, method: method, program: programID}
+
+	s.registeredLock.Lock()
+	defer s.registeredLock.Unlock()
+	if _, ok := s.registered[key]; ok {
+		return
+	}
+	s.txm.RegisterProgramErrors(programID, errs)
+	s.registered[key] = struct{}{}
+}
+
+// GetTransactionStatus implements the types.ChainWriter interface and maps the txm state of the transaction.
+func (s *SolanaChainWriterService) GetTransactionStatus(ctx context.Context, transactionID string) (types.TransactionStatus, error) {
+	status, err := s.txm.GetTransactionStatus(ctx, transactionID)
+	if err != nil {
+		return types.Unknown, err
+	}
+
+	switch status.State {
+	case txm.TxStatePending, txm.TxStateBroadcasted:
+		return types.Pending, nil
+	case txm.TxStateProcessed, txm.TxStateConfirmed:
+		return types.Unconfirmed, nil
+	case txm.TxStateFinalized:
+		return types.Finalized, nil
+	case txm.TxStateFailed, txm.TxStateCancelled:
+		return types.Failed, nil
+	case txm.TxStateFatal:
+		return types.Fatal, nil
+	default:
+		return types.Unknown, nil
+	}
+}
+
+// GetFeeComponents implements the types.ChainWriter interface. The execution fee is the compute unit
+// price (micro-lamports) used for new transactions, Solana has no data availability fee.
+func (s *SolanaChainWriterService) GetFeeComponents(_ context.Context) (*types.ChainFeeComponents, error) {
+	estimator := s.txm.FeeEstimator()
+	if estimator == nil {
+		return nil, fmt.Errorf("%w: fee estimator not available", types.ErrInternal)
+	}
+
+	return &types.ChainFeeComponents{
+		ExecutionFee:        new(big.Int).SetUint64(estimator.BaseComputeUnitPrice()),
+		DataAvailabilityFee: big.NewInt(0),
+	}, nil
+}
+
+func (s *SolanaChainWriterService) init(namespaces map[string]config.ChainWriterMethods) error {
+	for namespace, methods := range namespaces {
+		s.methods[namespace] = map[string]writeMethod{}
+
+		for methodName, method := range methods.Methods {
+			var idl codec.IDL
+			if err := json.Unmarshal([]byte(method.AnchorIDL), &idl); err != nil {
+				return err
+			}
+
+			var instruction *codec.IdlInstruction
+			for idx := range idl.Instructions {
+				if idl.Instructions[idx].Name == method.InstructionName {
+					instruction = &idl.Instructions[idx]
+				}
+			}
+
+			if instruction == nil {
+				return fmt.Errorf("%w: instruction %s not found in IDL for %s.%s", types.ErrInvalidConfig, method.InstructionName, namespace, methodName)
+			}
+
+			fromAddress, err := solana.PublicKeyFromBase58(method.FromAddress)
+			if err != nil {
+				return fmt.Errorf("%w: invalid from address for %s.%s: %s", types.ErrInvalidConfig, namespace, methodName, err)
+			}
+
+			idlCodec, err := codec.NewIDLInstructionsCodec(idl, config.BuilderForEncoding(method.Encoding))
+			if err != nil {
+				return err
+			}
+
+			mod, err := method.InputModifications.ToModifier(codec.DecoderHooks...)
+			if err != nil {
+				return err
+			}
+
+			codecWithModifiers, err := codec.NewNamedModifierCodec(idlCodec, instruction.Name, mod)
+			if err != nil {
+				return err
+			}
+
+			accounts, err := newInstructionAccounts(instruction.Accounts, method.Accounts)
+			if err != nil {
+				return fmt.Errorf("invalid accounts for %s.%s: %w", namespace, methodName, err)
+			}
+
+			var tables *txm.LookupTableManager
+			if len(method.LookupTables) > 0 {
+				addresses := make([]solana.PublicKey, len(method.LookupTables))
+				for i, table := range method.LookupTables {
+					if addresses[i], err = solana.PublicKeyFromBase58(table); err != nil {
+						return fmt.Errorf("%w: invalid lookup table %s for %s.%s: %s", types.ErrInvalidConfig, table, namespace, methodName, err)
+					}
+				}
+				tables = txm.NewLookupTableManager(s.lggr, s.reader, s.txm, fromAddress, addresses)
+			}
+
+			s.methods[namespace][methodName] = writeMethod{
+				instruction: instruction.Name,
+				codec:       codecWithModifiers,
+				accounts:    accounts,
+				fromAddress: fromAddress,
+				errors:      idl.Errors,
+				tables:      tables,
+			}
+		}
+	}
+
+	return nil
+}
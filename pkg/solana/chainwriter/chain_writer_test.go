@@ -0,0 +1,457 @@
+package chainwriter_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	commoncodec "github.com/goplugin/plugin-common/pkg/codec"
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/types"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/chainwriter"
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	feemocks "github.com/goplugin/plugin-solana/pkg/solana/fees/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/txm"
+)
+
+const (
+	Namespace = "Store"
+	Method    = "SetValue"
+
+	testIDL = `{
+  "version": "0.1.0",
+  "name": "store",
+  "instructions": [
+    {
+      "name": "setValue",
+      "accounts": [
+        {"name": "state", "isMut": true, "isSigner": false},
+        {"name": "authority", "isMut": false, "isSigner": true},
+        {"name": "accessController", "isMut": false, "isSigner": false},
+        {"name": "receiver", "isMut": true, "isSigner": false},
+        {"name": "hook", "isMut": false, "isSigner": false, "optional": true}
+      ],
+      "args": [
+        {"name": "value", "type": "u64"},
+        {"name": "label", "type": "string"}
+      ]
+    }
+  ],
+  "accounts": [],
+  "types": [],
+  "errors": [
+    {"code": 6000, "name": "StaleValue", "msg": "value is stale"}
+  ]
+}`
+)
+
+var (
+	feePayer         = solana.PublicKey{1}
+	accessController = solana.PublicKey{2}
+	programID        = solana.PublicKey{3}
+)
+
+type setValueArgs struct {
+	Value    uint64
+	Label    string
+	Receiver solana.PublicKey
+	Round    uint32
+}
+
+type testTxManager struct {
+	accountID string
+	tx        *solana.Transaction
+	txID      string
+	cfg       txm.TxConfig
+	status    txm.TxStatus
+	fee       fees.Estimator
+	errors    map[solana.PublicKey][]codec.IdlErrorCode
+	registers int
+}
+
+func (m *testTxManager) Enqueue(_ context.Context, accountID string, tx *solana.Transaction, txID *string, txCfgs ...txm.SetTxConfig) error {
+	m.accountID, m.tx, m.txID = accountID, tx, *txID
+	m.cfg = txm.TxConfig{}
+	for _, set := range txCfgs {
+		set(&m.cfg)
+	}
+	return nil
+}
+
+func (m *testTxManager) GetTransactionStatus(_ context.Context, txID string) (txm.TxStatus, error) {
+	if txID != m.txID {
+		return txm.TxStatus{}, txm.ErrTxNotFound
+	}
+	return m.status, nil
+}
+
+func (m *testTxManager) FeeEstimator() fees.Estimator {
+	return m.fee
+}
+
+func (m *testTxManager) RegisterProgramErrors(program solana.PublicKey, errs []codec.IdlErrorCode) {
+	if m.errors == nil {
+		m.errors = map[solana.PublicKey][]codec.IdlErrorCode{}
+	}
+	m.errors[program] = errs
+	m.registers++
+}
+
+func testConfig(accounts map[string]config.ChainWriterAccount) config.ChainWriter {
+	return config.ChainWriter{Namespaces: map[string]config.ChainWriterMethods{
+		Namespace: {Methods: map[string]config.ChainDataWriter{
+			Method: {
+				AnchorIDL:       testIDL,
+				Encoding:        config.EncodingTypeBorsh,
+				InstructionName: "setValue",
+				FromAddress:     feePayer.String(),
+				Accounts:        accounts,
+			},
+		}},
+	}}
+}
+
+func testAccounts() map[string]config.ChainWriterAccount {
+	return map[string]config.ChainWriterAccount{
+		"state": {PDA: &config.ChainWriterPDA{Seeds: []config.ChainWriterSeed{
+			{Value: "state"},
+			{Param: "Receiver"},
+			{Param: "Round"},
+		}}},
+		"authority":        {Role: config.SignerRoleFeePayer},
+		"accessController": {Address: accessController.String()},
+		"receiver":         {Param: "Receiver"},
+	}
+}
+
+func newTestChainWriter(t *testing.T, txManager chainwriter.TxManager) *chainwriter.SolanaChainWriterService {
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{4}}}, nil).Maybe()
+
+	cw, err := chainwriter.NewChainWriterService(logger.Test(t), func() (client.Reader, error) { return mc, nil }, txManager, testConfig(testAccounts()))
+	require.NoError(t, err)
+	require.NoError(t, cw.Start(tests.Context(t)))
+	t.Cleanup(func() { require.NoError(t, cw.Close()) })
+	return cw
+}
+
+func TestSolanaChainWriterService_ServiceCtx(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	svc, err := chainwriter.NewChainWriterService(logger.Test(t), nil, &testTxManager{}, config.ChainWriter{})
+	require.NoError(t, err)
+
+	require.Error(t, svc.Ready())
+	require.NoError(t, svc.Start(ctx))
+	require.NoError(t, svc.Ready())
+	require.Equal(t, map[string]error{chainwriter.ServiceName: nil}, svc.HealthReport())
+	require.Error(t, svc.Start(ctx))
+
+	require.NoError(t, svc.Close())
+	require.Error(t, svc.Ready())
+	require.Error(t, svc.SubmitTransaction(ctx, Namespace, Method, nil, "id", programID.String(), nil, nil))
+}
+
+func TestSolanaChainWriterService_SubmitTransaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	txManager := &testTxManager{}
+	cw := newTestChainWriter(t, txManager)
+
+	args := setValueArgs{Value: 42, Label: "label", Receiver: solana.PublicKey{5}, Round: 7}
+	require.NoError(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "tx-1", programID.String(), &types.TxMeta{GasLimit: big.NewInt(100_000)}, nil))
+
+	assert.Equal(t, feePayer.String(), txManager.accountID)
+	assert.Equal(t, "tx-1", txManager.txID)
+	assert.Equal(t, uint32(100_000), txManager.cfg.ComputeUnitLimit)
+	assert.False(t, txManager.cfg.EstimateComputeUnitLimit)
+	// IDL errors of the program are registered with the txm to decode reverts
+	assert.Equal(t, map[solana.PublicKey][]codec.IdlErrorCode{programID: {{Code: 6000, Name: "StaleValue", Msg: "value is stale"}}}, txManager.errors)
+
+	tx := txManager.tx
+	require.NotNil(t, tx)
+	assert.Equal(t, feePayer, tx.Message.AccountKeys[0])
+	assert.Equal(t, solana.Hash{4}, tx.Message.RecentBlockhash)
+	require.Len(t, tx.Message.Instructions, 1)
+	ix := tx.Message.Instructions[0]
+
+	// instruction data: discriminator + borsh encoded args
+	discriminator := sha256.Sum256([]byte("global:set_value"))
+	data := append([]byte{}, discriminator[:8]...)
+	data = binary.LittleEndian.AppendUint64(data, 42)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len("label")))
+	data = append(data, "label"...)
+	assert.Equal(t, data, []byte(ix.Data))
+
+	// accounts are looked up in the order of the IDL
+	state, _, err := solana.FindProgramAddress([][]byte{[]byte("state"), args.Receiver.Bytes(), {7, 0, 0, 0}}, programID)
+	require.NoError(t, err)
+	accounts, err := ix.ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Len(t, accounts, 5)
+	expected := []*solana.AccountMeta{
+		solana.NewAccountMeta(state, true, false),
+		solana.NewAccountMeta(feePayer, true, true), // fee payer is always writable
+		solana.NewAccountMeta(accessController, false, false),
+		solana.NewAccountMeta(args.Receiver, true, false),
+		solana.NewAccountMeta(programID, false, false), // optional accounts that are not set use the program ID
+	}
+	assert.Equal(t, expected, accounts)
+
+	// params can be looked up from maps
+	require.NoError(t, cw.SubmitTransaction(ctx, Namespace, Method, map[string]any{
+		"Value": uint64(1), "Label": "", "Receiver": args.Receiver[:], "Round": uint32(7),
+	}, "tx-2", programID.String(), nil, nil))
+	accounts, err = txManager.tx.Message.Instructions[0].ResolveInstructionAccounts(&txManager.tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, args.Receiver, accounts[3].PublicKey)
+	assert.Equal(t, txm.TxConfig{}, txManager.cfg)
+	// program errors are registered once per method and program
+	assert.Equal(t, 1, txManager.registers)
+
+	t.Run("invalid requests", func(t *testing.T) {
+		require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, "Unknown", args, "id", programID.String(), nil, nil), types.ErrInvalidType)
+		require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "id", "invalid", nil, nil), types.ErrInvalidType)
+		require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "id", programID.String(), nil, big.NewInt(1)), types.ErrInvalidType)
+		require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, Method, map[string]any{
+			"Value": uint64(1), "Label": "", "Round": uint32(7),
+		}, "id", programID.String(), nil, nil), types.ErrFieldNotFound)
+	})
+}
+
+func TestSolanaChainWriterService_SubmitTransaction_InputModifications(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{4}}}, nil).Maybe()
+
+	// the value arg is renamed to amount in the input, params use the input name
+	newWriter := func(t *testing.T, seed string) (*chainwriter.SolanaChainWriterService, *testTxManager) {
+		accounts := testAccounts()
+		accounts["state"] = config.ChainWriterAccount{PDA: &config.ChainWriterPDA{Seeds: []config.ChainWriterSeed{{Param: seed}}}}
+		cfg := testConfig(accounts)
+		method := cfg.Namespaces[Namespace].Methods[Method]
+		method.InputModifications = commoncodec.ModifiersConfig{&commoncodec.RenameModifierConfig{Fields: map[string]string{"Value": "Amount"}}}
+		cfg.Namespaces[Namespace].Methods[Method] = method
+
+		txManager := &testTxManager{}
+		cw, err := chainwriter.NewChainWriterService(logger.Test(t), func() (client.Reader, error) { return mc, nil }, txManager, cfg)
+		require.NoError(t, err)
+		require.NoError(t, cw.Start(ctx))
+		t.Cleanup(func() { require.NoError(t, cw.Close()) })
+		return cw, txManager
+	}
+
+	args := struct {
+		Amount   uint64
+		Label    string
+		Receiver solana.PublicKey
+	}{Amount: 42, Label: "label", Receiver: solana.PublicKey{5}}
+
+	cw, txManager := newWriter(t, "Amount")
+	require.NoError(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "tx-1", programID.String(), nil, nil))
+	state, _, err := solana.FindProgramAddress([][]byte{binary.LittleEndian.AppendUint64(nil, 42)}, programID)
+	require.NoError(t, err)
+	accounts, err := txManager.tx.Message.Instructions[0].ResolveInstructionAccounts(&txManager.tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, state, accounts[0].PublicKey)
+
+	// the modified (onchain) name is not in the input
+	cw, txManager = newWriter(t, "Value")
+	require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "tx-1", programID.String(), nil, nil), types.ErrFieldNotFound)
+	assert.Nil(t, txManager.tx)
+	assert.Zero(t, txManager.registers)
+}
+
+func TestSolanaChainWriterService_SubmitTransaction_EmptyBlockhash(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{}, nil)
+
+	txManager := &testTxManager{}
+	cw, err := chainwriter.NewChainWriterService(logger.Test(t), func() (client.Reader, error) { return mc, nil }, txManager, testConfig(testAccounts()))
+	require.NoError(t, err)
+	require.NoError(t, cw.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, cw.Close()) })
+
+	args := setValueArgs{Value: 42, Label: "label", Receiver: solana.PublicKey{5}, Round: 7}
+	require.ErrorIs(t, cw.SubmitTransaction(ctx, Namespace, Method, args, "tx-1", programID.String(), nil, nil), types.ErrInternal)
+	assert.Nil(t, txManager.tx)
+}
+
+func TestSolanaChainWriterService_GetTransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	txManager := &testTxManager{txID: "id"}
+	cw := newTestChainWriter(t, txManager)
+
+	for state, status := range map[txm.TxState]types.TransactionStatus{
+		txm.TxStatePending:     types.Pending,
+		txm.TxStateBroadcasted: types.Pending,
+		txm.TxStateProcessed:   types.Unconfirmed,
+		txm.TxStateConfirmed:   types.Unconfirmed,
+		txm.TxStateFinalized:   types.Finalized,
+		txm.TxStateFailed:      types.Failed,
+		txm.TxStateFatal:       types.Fatal,
+	} {
+		txManager.status = txm.TxStatus{State: state}
+		actual, err := cw.GetTransactionStatus(ctx, "id")
+		require.NoError(t, err)
+		assert.Equal(t, status, actual, state.String())
+	}
+
+	actual, err := cw.GetTransactionStatus(ctx, "unknown")
+	require.ErrorIs(t, err, txm.ErrTxNotFound)
+	assert.Equal(t, types.Unknown, actual)
+}
+
+func TestSolanaChainWriterService_GetFeeComponents(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	txManager := &testTxManager{}
+	cw := newTestChainWriter(t, txManager)
+
+	// txm not started
+	_, err := cw.GetFeeComponents(ctx)
+	require.Error(t, err)
+
+	estimator := feemocks.NewEstimator(t)
+	estimator.On("BaseComputeUnitPrice").Return(uint64(100))
+	txManager.fee = estimator
+
+	components, err := cw.GetFeeComponents(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), components.ExecutionFee)
+	assert.Equal(t, big.NewInt(0), components.DataAvailabilityFee)
+}
+
+func TestSolanaChainWriterService_SubmitTransaction_LookupTables(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	table, receiver := solana.PublicKey{6}, solana.PublicKey{5}
+	data, err := bin.MarshalBin(addresslookuptable.AddressLookupTableState{
+		TypeIndex:        1,
+		DeactivationSlot: math.MaxUint64,
+		Authority:        &feePayer,
+		Addresses:        solana.PublicKeySlice{accessController, receiver},
+	})
+	require.NoError(t, err)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{4}}}, nil)
+	mc.On("GetAccountInfoWithOpts", mock.Anything, table, mock.Anything).Return(&rpc.GetAccountInfoResult{Value: &rpc.Account{
+		Owner: txm.AddressLookupTableProgramID,
+		Data:  rpc.DataBytesOrJSONFromBytes(data),
+	}}, nil).Once() // tables are loaded once
+
+	cfg := testConfig(testAccounts())
+	method := cfg.Namespaces[Namespace].Methods[Method]
+	method.LookupTables = []string{table.String()}
+	cfg.Namespaces[Namespace].Methods[Method] = method
+	txManager := &testTxManager{}
+	cw, err := chainwriter.NewChainWriterService(logger.Test(t), func() (client.Reader, error) { return mc, nil }, txManager, cfg)
+	require.NoError(t, err)
+	require.NoError(t, cw.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, cw.Close()) })
+
+	// accounts stored in the lookup table are loaded from it by a v0 tx
+	args := setValueArgs{Value: 42, Label: "label", Receiver: receiver, Round: 7}
+	for _, id := range []string{"tx-1", "tx-2"} {
+		require.NoError(t, cw.SubmitTransaction(ctx, Namespace, Method, args, id, programID.String(), nil, nil))
+		require.True(t, txManager.tx.Message.IsVersioned())
+		require.Len(t, txManager.tx.Message.AddressTableLookups, 1)
+		assert.Equal(t, table, txManager.tx.Message.AddressTableLookups[0].AccountKey)
+		assert.NotContains(t, txManager.tx.Message.AccountKeys, receiver)
+		assert.NotContains(t, txManager.tx.Message.AccountKeys, accessController)
+	}
+}
+
+func TestNewChainWriterService_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	for name, update := range map[string]func(accounts map[string]config.ChainWriterAccount){
+		"missing account": func(accounts map[string]config.ChainWriterAccount) {
+			delete(accounts, "receiver")
+		},
+		"unknown account": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["unknown"] = config.ChainWriterAccount{Address: accessController.String()}
+		},
+		"signer without role": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["authority"] = config.ChainWriterAccount{Address: feePayer.String()}
+		},
+		"unknown role": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["authority"] = config.ChainWriterAccount{Role: "unknown"}
+		},
+		"multiple lookups": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["receiver"] = config.ChainWriterAccount{Param: "Receiver", Address: accessController.String()}
+		},
+		"invalid address": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["accessController"] = config.ChainWriterAccount{Address: "invalid"}
+		},
+		"pda without seeds": func(accounts map[string]config.ChainWriterAccount) {
+			accounts["state"] = config.ChainWriterAccount{PDA: &config.ChainWriterPDA{}}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			accounts := testAccounts()
+			update(accounts)
+			_, err := chainwriter.NewChainWriterService(logger.Test(t), nil, &testTxManager{}, testConfig(accounts))
+			require.ErrorIs(t, err, types.ErrInvalidConfig)
+		})
+	}
+
+	cfg := testConfig(testAccounts())
+	method := cfg.Namespaces[Namespace].Methods[Method]
+	method.InstructionName = "unknown"
+	cfg.Namespaces[Namespace].Methods[Method] = method
+	_, err := chainwriter.NewChainWriterService(logger.Test(t), nil, &testTxManager{}, cfg)
+	require.ErrorIs(t, err, types.ErrInvalidConfig)
+
+	cfg = testConfig(testAccounts())
+	method = cfg.Namespaces[Namespace].Methods[Method]
+	method.LookupTables = []string{"invalid"}
+	cfg.Namespaces[Namespace].Methods[Method] = method
+	_, err = chainwriter.NewChainWriterService(logger.Test(t), nil, &testTxManager{}, cfg)
+	require.ErrorIs(t, err, types.ErrInvalidConfig)
+}
+
+func TestChainWriterConfig_JSON(t *testing.T) {
+	t.Parallel()
+
+	raw, err := json.Marshal(testConfig(testAccounts()))
+	require.NoError(t, err)
+
+	var cfg config.ChainWriter
+	require.NoError(t, json.Unmarshal(raw, &cfg))
+	assert.Equal(t, testConfig(testAccounts()), cfg)
+
+	_, err = chainwriter.NewChainWriterService(logger.Test(t), nil, &testTxManager{}, cfg)
+	require.False(t, errors.Is(err, types.ErrInvalidConfig))
+	require.NoError(t, err)
+}
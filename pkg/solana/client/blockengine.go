@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	"github.com/goplugin/plugin-common/pkg/utils"
+)
+
+// MaxBundleStatuses is the max number of bundle ids per bundle status request
+const MaxBundleStatuses = 5
+
+// bundle states reported by getInflightBundleStatuses
+const (
+	BundleStatusInvalid = "Invalid" // bundle id unknown to the block engine (statuses are kept for 5 minutes)
+	BundleStatusPending = "Pending" // bundle not yet landed or failed
+	BundleStatusFailed  = "Failed"  // bundle did not land in any leader slot it was forwarded to
+	BundleStatusLanded  = "Landed"  // bundle landed on chain
+)
+
+// BundleStatus is the status of a bundle sent to the block engine
+type BundleStatus struct {
+	BundleID   string  `json:"bundle_id"`
+	Status     string  `json:"status"`
+	LandedSlot *uint64 `json:"landed_slot"`
+}
+
+// BlockEngine sends bundles of txs to a block engine (i.e. Jito) over its JSON-RPC bundle API.
+// the txs of a bundle are executed in order and land atomically in the slot of a leader running the block engine
+type BlockEngine struct {
+	rpc jsonrpc.RPCClient
+
+	lock        sync.RWMutex
+	tipAccounts []solana.PublicKey
+}
+
+// NewBlockEngine returns a client of the bundle API of the block engine, i.e. https://mainnet.block-engine.jito.wtf/api/v1/bundles
+func NewBlockEngine(url string) *BlockEngine {
+	return &BlockEngine{rpc: jsonrpc.NewClient(url)}
+}
+
+// SendBundle sends the signed txs as a bundle and returns the bundle id
+func (b *BlockEngine) SendBundle(ctx context.Context, txs []*solana.Transaction) (string, error) {
+	encoded := make([]string, len(txs))
+	for i, tx := range txs {
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal tx %d of bundle: %w", i, err)
+		}
+		encoded[i] = base64.StdEncoding.EncodeToString(data)
+	}
+	var id string
+	if err := b.rpc.CallForInto(ctx, &id, "sendBundle", []any{encoded, map[string]string{"encoding": "base64"}}); err != nil {
+		return "", fmt.Errorf("failed to send bundle: %w", err)
+	}
+	return id, nil
+}
+
+// TipAccounts returns the accounts bundles pay their tip to, fetched once from the block engine
+func (b *BlockEngine) TipAccounts(ctx context.Context) ([]solana.PublicKey, error) {
+	b.lock.RLock()
+	accounts := b.tipAccounts
+	b.lock.RUnlock()
+	if len(accounts) != 0 {
+		return accounts, nil
+	}
+
+	var res []string
+	if err := b.rpc.CallForInto(ctx, &res, "getTipAccounts", nil); err != nil {
+		return nil, fmt.Errorf("failed to get tip accounts: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("block engine returned no tip accounts")
+	}
+	accounts = make([]solana.PublicKey, len(res))
+	for i, a := range res {
+		key, err := solana.PublicKeyFromBase58(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tip account %s: %w", a, err)
+		}
+		accounts[i] = key
+	}
+
+	b.lock.Lock()
+	b.tipAccounts = accounts
+	b.lock.Unlock()
+	return accounts, nil
+}
+
+// InflightBundleStatuses returns the statuses of bundles sent within the last 5 minutes, in the order of the ids
+func (b *BlockEngine) InflightBundleStatuses(ctx context.Context, ids []string) ([]BundleStatus, error) {
+	batches, err := utils.BatchSplit(ids, MaxBundleStatuses)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]BundleStatus, 0, len(ids))
+	for _, batch := range batches {
+		var res struct {
+			Value []BundleStatus `json:"value"`
+		}
+		if err := b.rpc.CallForInto(ctx, &res, "getInflightBundleStatuses", []any{batch}); err != nil {
+			return nil, fmt.Errorf("failed to get inflight bundle statuses: %w", err)
+		}
+		if len(res.Value) != len(batch) {
+			return nil, fmt.Errorf("expected %d bundle statuses, got %d", len(batch), len(res.Value))
+		}
+		statuses = append(statuses, res.Value...)
+	}
+	return statuses, nil
+}
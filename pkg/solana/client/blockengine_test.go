@@ -0,0 +1,65 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	mn "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
+)
+
+func TestBlockEngine_SendBundle(t *testing.T) {
+	ctx := tests.Context(t)
+	engine := SetupTestBlockEngine(t, "")
+	be := NewBlockEngine(engine.URL)
+
+	accounts, err := be.TipAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, engine.TipAccounts, accounts)
+
+	payer := solana.NewWallet().PrivateKey
+	newTx := func(to solana.PublicKey, lamports uint64) *solana.Transaction {
+		tx, txErr := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(lamports, payer.PublicKey(), to).Build(),
+		}, solana.Hash{1}, solana.TransactionPayer(payer.PublicKey()))
+		require.NoError(t, txErr)
+		_, txErr = tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &payer })
+		require.NoError(t, txErr)
+		return tx
+	}
+
+	// bundles without a tip are rejected
+	_, err = be.SendBundle(ctx, []*solana.Transaction{newTx(solana.PublicKey{1}, engine.MinTip)})
+	require.ErrorContains(t, err, "tip account")
+	assert.Equal(t, mn.Fatal, ClassifySendError(nil, err))
+	_, err = be.SendBundle(ctx, []*solana.Transaction{newTx(accounts[0], engine.MinTip-1)})
+	require.Error(t, err)
+
+	var ids []string
+	for i := range MaxBundleStatuses + 1 {
+		id, sendErr := be.SendBundle(ctx, []*solana.Transaction{newTx(accounts[1], engine.MinTip+uint64(i))})
+		require.NoError(t, sendErr)
+		ids = append(ids, id)
+	}
+	require.Len(t, engine.Bundles(), len(ids))
+	engine.SetStatus(ids[1], BundleStatusLanded)
+
+	// statuses are requested in batches, unknown bundles are invalid
+	statuses, err := be.InflightBundleStatuses(ctx, append(ids, "unknown"))
+	require.NoError(t, err)
+	require.Len(t, statuses, len(ids)+1)
+	for i, status := range statuses[:len(ids)] {
+		assert.Equal(t, ids[i], status.BundleID)
+		if i == 1 {
+			assert.Equal(t, BundleStatusLanded, status.Status)
+			continue
+		}
+		assert.Equal(t, BundleStatusPending, status.Status)
+	}
+	assert.Equal(t, BundleStatusInvalid, statuses[len(ids)].Status)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
@@ -32,10 +33,17 @@ type Reader interface {
 	AccountReader
 	Balance(ctx context.Context, addr solana.PublicKey) (uint64, error)
 	SlotHeight(ctx context.Context) (uint64, error)
+	SlotHeightWithCommitment(ctx context.Context, commitment rpc.CommitmentType) (uint64, error)
+	BlockHeight(ctx context.Context) (uint64, error)
 	LatestBlockhash(ctx context.Context) (*rpc.GetLatestBlockhashResult, error)
 	ChainID(ctx context.Context) (mn.StringID, error)
 	GetFeeForMessage(ctx context.Context, msg string) (uint64, error)
 	GetLatestBlock(ctx context.Context) (*rpc.GetBlockResult, error)
+	GetBlocks(ctx context.Context, start, end uint64) ([]uint64, error)
+	GetBlock(ctx context.Context, slot uint64) (*rpc.GetBlockResult, error)
+	GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)
+	SlotLeaders(ctx context.Context, start, limit uint64) ([]solana.PublicKey, error)
+	TPUAddresses(ctx context.Context) (map[solana.PublicKey]string, error)
 }
 
 // AccountReader is an interface that allows users to pass either the solana rpc client or the relay client
@@ -113,12 +121,25 @@ func (c *Client) SlotHeightWithCommitment(ctx context.Context, commitment rpc.Co
 
 	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
 	defer cancel()
-	v, err, _ := c.requestGroup.Do("GetSlotHeight", func() (interface{}, error) {
+	v, err, _ := c.requestGroup.Do("GetSlotHeight"+string(commitment), func() (interface{}, error) {
 		return c.rpc.GetSlot(ctx, commitment)
 	})
 	return v.(uint64), err
 }
 
+// BlockHeight returns the current block height, used for determining blockhash expiry
+func (c *Client) BlockHeight(ctx context.Context) (uint64, error) {
+	done := c.latency("block_height")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
+	defer cancel()
+	v, err, _ := c.requestGroup.Do("GetBlockHeight", func() (interface{}, error) {
+		return c.rpc.GetBlockHeight(ctx, c.commitment)
+	})
+	return v.(uint64), err
+}
+
 func (c *Client) GetAccountInfoWithOpts(ctx context.Context, addr solana.PublicKey, opts *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
 	done := c.latency("account_info")
 	defer done()
@@ -190,6 +211,65 @@ func (c *Client) GetFeeForMessage(ctx context.Context, msg string) (uint64, erro
 	return *res.Value, nil
 }
 
+// https://solana.com/docs/rpc/http/getrecentprioritizationfees
+// accounts - (optional) fees are the max of the min fee of the block and the min fee of txs writing to any of the accounts
+func (c *Client) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	done := c.latency("recent_prioritization_fees")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
+	defer cancel()
+	res, err := c.rpc.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetRecentPrioritizationFees: %w", err)
+	}
+	return res, nil
+}
+
+// SlotLeaders returns the leaders of limit slots from the start slot
+func (c *Client) SlotLeaders(ctx context.Context, start, limit uint64) ([]solana.PublicKey, error) {
+	done := c.latency("slot_leaders")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
+	defer cancel()
+	res, err := c.rpc.GetSlotLeaders(ctx, start, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetSlotLeaders: %w", err)
+	}
+	return res, nil
+}
+
+// TPUAddresses returns the TPU QUIC address of the cluster nodes, nodes without a TPU QUIC address are omitted
+func (c *Client) TPUAddresses(ctx context.Context) (map[solana.PublicKey]string, error) {
+	done := c.latency("cluster_nodes")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
+	defer cancel()
+	v, err, _ := c.requestGroup.Do("GetClusterNodes", func() (interface{}, error) {
+		// rpc.GetClusterNodesResult does not include the tpuQuic address
+		var nodes []struct {
+			Pubkey  solana.PublicKey `json:"pubkey"`
+			TPUQUIC *string          `json:"tpuQuic,omitempty"`
+		}
+		if err := c.rpc.RPCCallForInto(ctx, &nodes, "getClusterNodes", nil); err != nil {
+			return nil, err
+		}
+		addrs := make(map[solana.PublicKey]string, len(nodes))
+		for _, n := range nodes {
+			if n.TPUQUIC != nil && *n.TPUQUIC != "" {
+				addrs[n.Pubkey] = *n.TPUQUIC
+			}
+		}
+		return addrs, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in GetClusterNodes: %w", err)
+	}
+	return v.(map[solana.PublicKey]string), nil
+}
+
 // https://docs.solana.com/developing/clients/jsonrpc-api#getsignaturestatuses
 func (c *Client) SignatureStatuses(ctx context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
 	done := c.latency("signature_statuses")
@@ -264,9 +344,34 @@ func (c *Client) GetLatestBlock(ctx context.Context) (*rpc.GetBlockResult, error
 	// get block based on slot
 	done := c.latency("latest_block")
 	defer done()
+	return c.getBlock(ctx, slot)
+}
+
+// GetBlocks returns the slots of the blocks produced from start to end (inclusive), skipped slots are omitted
+func (c *Client) GetBlocks(ctx context.Context, start, end uint64) ([]uint64, error) {
+	done := c.latency("blocks")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextDuration)
+	defer cancel()
+	res, err := c.rpc.GetBlocks(ctx, start, &end, c.commitment)
+	if err != nil {
+		return nil, fmt.Errorf("error in GetBlocks: %w", err)
+	}
+	return res, nil
+}
+
+// GetBlock returns the block produced in the slot
+func (c *Client) GetBlock(ctx context.Context, slot uint64) (*rpc.GetBlockResult, error) {
+	done := c.latency("block")
+	defer done()
+	return c.getBlock(ctx, slot)
+}
+
+func (c *Client) getBlock(ctx context.Context, slot uint64) (*rpc.GetBlockResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.txTimeout)
 	defer cancel()
-	v, err, _ := c.requestGroup.Do("GetBlockWithOpts", func() (interface{}, error) {
+	v, err, _ := c.requestGroup.Do("GetBlockWithOpts"+strconv.FormatUint(slot, 10), func() (interface{}, error) {
 		version := uint64(0) // pull all tx types (legacy + v0)
 		return c.rpc.GetBlockWithOpts(ctx, slot, &rpc.GetBlockOpts{
 			Commitment:                     c.commitment,
@@ -55,6 +55,11 @@ func TestClient_Reader_Integration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Greater(t, slot1, slot0)
 
+	// check BlockHeight
+	blockHeight, err := c.BlockHeight(ctx)
+	assert.NoError(t, err)
+	assert.Greater(t, blockHeight, uint64(0))
+
 	// fetch recent blockhash
 	hash, err := c.LatestBlockhash(ctx)
 	assert.NoError(t, err)
@@ -95,6 +100,31 @@ func TestClient_Reader_Integration(t *testing.T) {
 	assert.NotEqual(t, solana.Hash{}, block.Blockhash)
 	assert.NotEqual(t, uint64(0), block.ParentSlot)
 	assert.NotEqual(t, uint64(0), block.ParentSlot)
+
+	// blocks of recent slots
+	latest, err := c.SlotHeight(ctx)
+	require.NoError(t, err)
+	slots, err := c.GetBlocks(ctx, latest-4, latest)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	block, err = c.GetBlock(ctx, slots[len(slots)-1])
+	require.NoError(t, err)
+	assert.Less(t, block.ParentSlot, slots[len(slots)-1])
+
+	// get recent prioritization fees (per slot)
+	_, err = c.GetRecentPrioritizationFees(ctx, solana.PublicKeySlice{solana.SystemProgramID})
+	require.NoError(t, err)
+
+	// leaders + TPU addresses (the test validator is the only leader)
+	slot, err := c.SlotHeight(ctx)
+	require.NoError(t, err)
+	leaders, err := c.SlotLeaders(ctx, slot, 4)
+	require.NoError(t, err)
+	require.Len(t, leaders, 4)
+	addrs, err := c.TPUAddresses(ctx)
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	assert.NotEmpty(t, addrs[leaders[0]])
 }
 
 func TestClient_Reader_ChainID(t *testing.T) {
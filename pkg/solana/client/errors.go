@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	mn "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
+	"github.com/goplugin/plugin-solana/pkg/solana/monitor"
+)
+
+// JSON-RPC error codes returned by the Solana RPC
+// https://github.com/anza-xyz/agave/blob/master/rpc-client-api/src/custom_error.rs
+const (
+	rpcErrSendTransactionPreflightFailure   = -32002
+	rpcErrSignatureVerificationFailure      = -32003
+	rpcErrNodeUnhealthy                     = -32005
+	rpcErrTransactionPrecompileVerification = -32006
+	rpcErrSignatureLenMismatch              = -32013
+	rpcErrUnsupportedTransactionVersion     = -32015
+	rpcErrMinContextSlotNotReached          = -32016
+	rpcErrInvalidParams                     = -32602
+)
+
+// TransactionError variants, see anza-xyz/agave@master/sdk/src/transaction/error.rs
+var transactionErrorCodes = map[string]mn.SendTxReturnCode{
+	"AlreadyProcessed": mn.TransactionAlreadyKnown,
+
+	// blockhash is unknown to the node or expired, the tx can only be included with a new blockhash
+	"BlockhashNotFound": mn.TerminallyStuck,
+
+	"InsufficientFundsForFee":  mn.InsufficientFunds,
+	"InsufficientFundsForRent": mn.InsufficientFunds,
+
+	// temporary conditions of the node or the block being produced
+	"AccountInUse":                     mn.Retryable,
+	"ClusterMaintenance":               mn.Retryable,
+	"WouldExceedAccountDataBlockLimit": mn.Retryable,
+	"WouldExceedAccountDataTotalLimit": mn.Retryable,
+	"ResanitizationNeeded":             mn.Retryable,
+
+	// cost limits are reached by competing txs, a higher priority fee is needed to be scheduled first
+	"WouldExceedMaxBlockCostLimit":          mn.Underpriced,
+	"WouldExceedMaxAccountCostLimit":        mn.Underpriced,
+	"WouldExceedMaxVoteCostLimit":           mn.Underpriced,
+	"ProgramExecutionTemporarilyRestricted": mn.Retryable,
+
+	"UnsupportedVersion": mn.Unsupported,
+
+	// invalid txs that will never be included
+	"InstructionError":                           mn.Fatal,
+	"AccountLoadedTwice":                         mn.Fatal,
+	"AccountNotFound":                            mn.Fatal,
+	"ProgramAccountNotFound":                     mn.Fatal,
+	"InvalidAccountForFee":                       mn.Fatal,
+	"InvalidAccountIndex":                        mn.Fatal,
+	"SignatureFailure":                           mn.Fatal,
+	"InvalidProgramForExecution":                 mn.Fatal,
+	"SanitizeFailure":                            mn.Fatal,
+	"CallChainTooDeep":                           mn.Fatal,
+	"MissingSignatureForFee":                     mn.Fatal,
+	"InvalidAccountSize":                         mn.Fatal,
+	"InvalidWritableAccount":                     mn.Fatal,
+	"TooManyAccountLocks":                        mn.Fatal,
+	"AddressLookupTableNotFound":                 mn.Fatal,
+	"InvalidAddressLookupTableOwner":             mn.Fatal,
+	"InvalidAddressLookupTableData":              mn.Fatal,
+	"InvalidAddressLookupTableIndex":             mn.Fatal,
+	"InvalidRentPayingAccount":                   mn.Fatal,
+	"DuplicateInstruction":                       mn.Fatal,
+	"MaxLoadedAccountsDataSizeExceeded":          mn.Fatal,
+	"InvalidLoadedAccountsDataSizeLimit":         mn.Fatal,
+	"UnbalancedTransaction":                      mn.Fatal,
+	"InsufficientFundsForRentWithPendingAccount": mn.Fatal,
+}
+
+// SendTxError is the error of a tx broadcast to multiple RPCs, classified by the aggregated result of the RPCs
+type SendTxError struct {
+	Code mn.SendTxReturnCode
+	Err  error
+}
+
+func (e *SendTxError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *SendTxError) Unwrap() error {
+	return e.Err
+}
+
+// NewSendErrorClassifier returns a classifier of send errors that counts the classified errors of the chain
+func NewSendErrorClassifier(chainID string) mn.TxErrorClassifier[*solana.Transaction] {
+	return func(tx *solana.Transaction, err error) mn.SendTxReturnCode {
+		code := ClassifySendError(tx, err)
+		// errors of each RPC are counted when they are aggregated
+		var sendErr *SendTxError
+		if code != mn.Successful && !errors.As(err, &sendErr) {
+			monitor.IncSendTxError(chainID, code.String())
+		}
+		return code
+	}
+}
+
+// ClassifySendError maps the error returned by the RPC when sending a tx to a SendTxReturnCode.
+// txs that fail preflight simulation are classified by the TransactionError of the simulation.
+func ClassifySendError(_ *solana.Transaction, err error) mn.SendTxReturnCode {
+	if err == nil {
+		return mn.Successful
+	}
+
+	var sendErr *SendTxError
+	if errors.As(err, &sendErr) {
+		return sendErr.Code
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return mn.Retryable
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case rpcErrSendTransactionPreflightFailure:
+			if txErr, ok := TransactionErrorFromSendError(err); ok {
+				return ClassifyTransactionError(txErr)
+			}
+		case rpcErrNodeUnhealthy, rpcErrMinContextSlotNotReached:
+			return mn.Retryable
+		case rpcErrSignatureVerificationFailure, rpcErrTransactionPrecompileVerification, rpcErrSignatureLenMismatch, rpcErrInvalidParams:
+			return mn.Fatal
+		case rpcErrUnsupportedTransactionVersion:
+			return mn.Unsupported
+		}
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.Code == http.StatusTooManyRequests || httpErr.Code >= http.StatusInternalServerError) {
+		return mn.Retryable
+	}
+
+	return classifyErrorMessage(err.Error())
+}
+
+// classifyErrorMessage is the fallback for errors that are not returned as a structured RPC error
+func classifyErrorMessage(msg string) mn.SendTxReturnCode {
+	msg = strings.ToLower(msg)
+	switch {
+	case strings.Contains(msg, "already been processed"), strings.Contains(msg, "alreadyprocessed"):
+		return mn.TransactionAlreadyKnown
+	case strings.Contains(msg, "blockhash not found"), strings.Contains(msg, "blockhashnotfound"):
+		return mn.TerminallyStuck
+	case strings.Contains(msg, "insufficient funds"), strings.Contains(msg, "insufficientfundsforfee"):
+		return mn.InsufficientFunds
+	case strings.Contains(msg, "too many requests"), strings.Contains(msg, "rate limit"),
+		strings.Contains(msg, "node is unhealthy"), strings.Contains(msg, "node is behind"):
+		return mn.Retryable
+	default:
+		return mn.Unknown
+	}
+}
+
+// ClassifyTransactionError maps a TransactionError (the err field of rpc results i.e. simulation or signature statuses)
+// to a SendTxReturnCode
+func ClassifyTransactionError(txErr any) mn.SendTxReturnCode {
+	if txErr == nil {
+		return mn.Successful
+	}
+	if code, ok := transactionErrorCodes[TransactionErrorName(txErr)]; ok {
+		return code
+	}
+	return mn.Unknown
+}
+
+// TransactionErrorName returns the variant name of a TransactionError (i.e. "BlockhashNotFound", "InstructionError").
+// unit variants are encoded as strings, variants with fields as single key objects.
+func TransactionErrorName(txErr any) string {
+	switch v := normalizeTransactionError(txErr).(type) {
+	case string:
+		return v
+	case map[string]any:
+		for name := range v {
+			return name
+		}
+	}
+	return ""
+}
+
+// InstructionErrorCustomCode returns the index of the failed instruction and the program error code for an
+// InstructionError with a custom program error, i.e. {"InstructionError": [1, {"Custom": 6000}]}
+func InstructionErrorCustomCode(txErr any) (index int, code uint32, ok bool) {
+	v, isMap := normalizeTransactionError(txErr).(map[string]any)
+	if !isMap {
+		return 0, 0, false
+	}
+	ixErr, isSlice := v["InstructionError"].([]any)
+	if !isSlice || len(ixErr) != 2 {
+		return 0, 0, false
+	}
+	i, isIndex := toUint64(ixErr[0])
+	custom, isCustom := ixErr[1].(map[string]any)
+	if !isIndex || !isCustom {
+		return 0, 0, false
+	}
+	c, isCode := toUint64(custom["Custom"])
+	if !isCode || c > uint64(^uint32(0)) {
+		return 0, 0, false
+	}
+	return int(i), uint32(c), true //nolint:gosec // instruction index fits in a tx
+}
+
+// TransactionErrorFromSendError returns the TransactionError of a tx that failed preflight simulation when sent
+func TransactionErrorFromSendError(err error) (any, bool) {
+	var rpcErr *jsonrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpcErrSendTransactionPreflightFailure {
+		return nil, false
+	}
+	data, ok := rpcErr.Data.(map[string]any)
+	if !ok || data["err"] == nil {
+		return nil, false
+	}
+	return data["err"], true
+}
+
+// normalizeTransactionError returns the TransactionError as decoded from JSON into an any
+// (errors can be typed values, i.e. map[string][]any, when not decoded from an RPC response)
+func normalizeTransactionError(txErr any) any {
+	switch txErr.(type) {
+	case nil, string, map[string]any:
+		return txErr
+	}
+	b, err := json.Marshal(txErr)
+	if err != nil {
+		return txErr
+	}
+	var v any
+	if err = json.Unmarshal(b, &v); err != nil {
+		return txErr
+	}
+	return v
+}
+
+func toUint64(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return uint64(i), err == nil && i >= 0 //nolint:gosec // checked for negative values
+	case float64:
+		return uint64(n), n >= 0 && n == float64(uint64(n))
+	case int:
+		return uint64(n), n >= 0 //nolint:gosec // checked for negative values
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mn "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
+)
+
+func preflightErr(t *testing.T, txErr string) error {
+	var data map[string]any
+	require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{"err": %s, "logs": []}`, txErr)), &data))
+	return &jsonrpc.RPCError{Code: rpcErrSendTransactionPreflightFailure, Message: "Transaction simulation failed", Data: data}
+}
+
+func TestClassifySendError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		code mn.SendTxReturnCode
+	}{
+		{"nil", nil, mn.Successful},
+		{"context", fmt.Errorf("send: %w", context.DeadlineExceeded), mn.Retryable},
+		{"alreadyProcessed", preflightErr(t, `"AlreadyProcessed"`), mn.TransactionAlreadyKnown},
+		{"blockhashNotFound", preflightErr(t, `"BlockhashNotFound"`), mn.TerminallyStuck},
+		{"insufficientFundsForFee", preflightErr(t, `"InsufficientFundsForFee"`), mn.InsufficientFunds},
+		{"accountInUse", preflightErr(t, `"AccountInUse"`), mn.Retryable},
+		{"blockCostLimit", preflightErr(t, `"WouldExceedMaxBlockCostLimit"`), mn.Underpriced},
+		{"instructionError", preflightErr(t, `{"InstructionError": [0, {"Custom": 6000}]}`), mn.Fatal},
+		{"insufficientFundsForRent", preflightErr(t, `{"InsufficientFundsForRent": {"account_index": 1}}`), mn.InsufficientFunds},
+		{"unknownTxErr", preflightErr(t, `"SomethingNew"`), mn.Unknown},
+		{"nodeUnhealthy", &jsonrpc.RPCError{Code: rpcErrNodeUnhealthy, Message: "Node is unhealthy"}, mn.Retryable},
+		{"signatureFailure", &jsonrpc.RPCError{Code: rpcErrSignatureVerificationFailure}, mn.Fatal},
+		{"unsupportedVersion", &jsonrpc.RPCError{Code: rpcErrUnsupportedTransactionVersion}, mn.Unsupported},
+		{"rateLimited", fmt.Errorf("send: %w", jsonrpc.NewHTTPError(429, errors.New("429 Too Many Requests"))), mn.Retryable},
+		{"serverError", jsonrpc.NewHTTPError(503, errors.New("503 Service Unavailable")), mn.Retryable},
+		{"badRequest", jsonrpc.NewHTTPError(400, errors.New("400 Bad Request")), mn.Unknown},
+		{"message", errors.New("Transaction simulation failed: Blockhash not found"), mn.TerminallyStuck},
+		{"unknown", errors.New("FAIL"), mn.Unknown},
+		{"aggregated", fmt.Errorf("send: %w", &SendTxError{Code: mn.Retryable, Err: errors.New("FAIL")}), mn.Retryable},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.code, ClassifySendError(nil, test.err))
+		})
+	}
+}
+
+func TestInstructionErrorCustomCode(t *testing.T) {
+	var txErr any
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError": [2, {"Custom": 6001}]}`), &txErr))
+	index, code, ok := InstructionErrorCustomCode(txErr)
+	require.True(t, ok)
+	assert.Equal(t, 2, index)
+	assert.Equal(t, uint32(6001), code)
+	assert.Equal(t, "InstructionError", TransactionErrorName(txErr))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError": [0, "InvalidArgument"]}`), &txErr))
+	_, _, ok = InstructionErrorCustomCode(txErr)
+	assert.False(t, ok)
+
+	_, _, ok = InstructionErrorCustomCode("BlockhashNotFound")
+	assert.False(t, ok)
+}
+
+func TestClassifyTransactionError(t *testing.T) {
+	assert.Equal(t, mn.Successful, ClassifyTransactionError(nil))
+	assert.Equal(t, mn.TerminallyStuck, ClassifyTransactionError("BlockhashNotFound"))
+
+	// typed errors are classified the same as errors decoded from JSON
+	txErr := map[string][]any{"InstructionError": {1, map[string]int{"Custom": 6003}}}
+	assert.Equal(t, mn.Fatal, ClassifyTransactionError(txErr))
+	index, code, ok := InstructionErrorCustomCode(txErr)
+	require.True(t, ok)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, uint32(6003), code)
+}
@@ -5,10 +5,11 @@ package mocks
 import (
 	context "context"
 
-	rpc "github.com/gagliardetto/solana-go/rpc"
 	multinode "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
 	mock "github.com/stretchr/testify/mock"
 
+	rpc "github.com/gagliardetto/solana-go/rpc"
+
 	solana "github.com/gagliardetto/solana-go"
 )
 
@@ -45,6 +46,34 @@ func (_m *ReaderWriter) Balance(ctx context.Context, addr solana.PublicKey) (uin
 	return r0, r1
 }
 
+// BlockHeight provides a mock function with given fields: ctx
+func (_m *ReaderWriter) BlockHeight(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockHeight")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ChainID provides a mock function with given fields: ctx
 func (_m *ReaderWriter) ChainID(ctx context.Context) (multinode.StringID, error) {
 	ret := _m.Called(ctx)
@@ -103,6 +132,66 @@ func (_m *ReaderWriter) GetAccountInfoWithOpts(ctx context.Context, addr solana.
 	return r0, r1
 }
 
+// GetBlock provides a mock function with given fields: ctx, slot
+func (_m *ReaderWriter) GetBlock(ctx context.Context, slot uint64) (*rpc.GetBlockResult, error) {
+	ret := _m.Called(ctx, slot)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlock")
+	}
+
+	var r0 *rpc.GetBlockResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (*rpc.GetBlockResult, error)); ok {
+		return rf(ctx, slot)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *rpc.GetBlockResult); ok {
+		r0 = rf(ctx, slot)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*rpc.GetBlockResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, slot)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBlocks provides a mock function with given fields: ctx, start, end
+func (_m *ReaderWriter) GetBlocks(ctx context.Context, start uint64, end uint64) ([]uint64, error) {
+	ret := _m.Called(ctx, start, end)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlocks")
+	}
+
+	var r0 []uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]uint64, error)); ok {
+		return rf(ctx, start, end)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []uint64); ok {
+		r0 = rf(ctx, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFeeForMessage provides a mock function with given fields: ctx, msg
 func (_m *ReaderWriter) GetFeeForMessage(ctx context.Context, msg string) (uint64, error) {
 	ret := _m.Called(ctx, msg)
@@ -161,6 +250,36 @@ func (_m *ReaderWriter) GetLatestBlock(ctx context.Context) (*rpc.GetBlockResult
 	return r0, r1
 }
 
+// GetRecentPrioritizationFees provides a mock function with given fields: ctx, accounts
+func (_m *ReaderWriter) GetRecentPrioritizationFees(ctx context.Context, accounts solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error) {
+	ret := _m.Called(ctx, accounts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRecentPrioritizationFees")
+	}
+
+	var r0 []rpc.PriorizationFeeResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, solana.PublicKeySlice) ([]rpc.PriorizationFeeResult, error)); ok {
+		return rf(ctx, accounts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, solana.PublicKeySlice) []rpc.PriorizationFeeResult); ok {
+		r0 = rf(ctx, accounts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]rpc.PriorizationFeeResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, solana.PublicKeySlice) error); ok {
+		r1 = rf(ctx, accounts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LatestBlockhash provides a mock function with given fields: ctx
 func (_m *ReaderWriter) LatestBlockhash(ctx context.Context) (*rpc.GetLatestBlockhashResult, error) {
 	ret := _m.Called(ctx)
@@ -309,6 +428,94 @@ func (_m *ReaderWriter) SlotHeight(ctx context.Context) (uint64, error) {
 	return r0, r1
 }
 
+// SlotHeightWithCommitment provides a mock function with given fields: ctx, commitment
+func (_m *ReaderWriter) SlotHeightWithCommitment(ctx context.Context, commitment rpc.CommitmentType) (uint64, error) {
+	ret := _m.Called(ctx, commitment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SlotHeightWithCommitment")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, rpc.CommitmentType) (uint64, error)); ok {
+		return rf(ctx, commitment)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, rpc.CommitmentType) uint64); ok {
+		r0 = rf(ctx, commitment)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, rpc.CommitmentType) error); ok {
+		r1 = rf(ctx, commitment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SlotLeaders provides a mock function with given fields: ctx, start, limit
+func (_m *ReaderWriter) SlotLeaders(ctx context.Context, start uint64, limit uint64) ([]solana.PublicKey, error) {
+	ret := _m.Called(ctx, start, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SlotLeaders")
+	}
+
+	var r0 []solana.PublicKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]solana.PublicKey, error)); ok {
+		return rf(ctx, start, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []solana.PublicKey); ok {
+		r0 = rf(ctx, start, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]solana.PublicKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, start, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TPUAddresses provides a mock function with given fields: ctx
+func (_m *ReaderWriter) TPUAddresses(ctx context.Context) (map[solana.PublicKey]string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TPUAddresses")
+	}
+
+	var r0 map[solana.PublicKey]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[solana.PublicKey]string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[solana.PublicKey]string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[solana.PublicKey]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewReaderWriter creates a new instance of ReaderWriter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewReaderWriter(t interface {
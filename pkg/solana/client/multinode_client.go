@@ -301,7 +301,6 @@ func (m *MultiNodeClient) GetInterceptedChainInfo() (latest, highestUserObservat
 }
 
 func (m *MultiNodeClient) SendTransaction(ctx context.Context, tx *solana.Transaction) error {
-	// TODO: Use Transaction Sender
 	_, err := m.SendTx(ctx, tx)
 	return err
 }
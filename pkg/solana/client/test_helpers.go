@@ -2,12 +2,26 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os/exec"
+	"sync"
 	"testing"
 	"time"
 
+	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/quic-go/quic-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -70,3 +84,234 @@ func FundTestAccounts(t *testing.T, keys []solana.PublicKey, url string) {
 		require.NoError(t, err)
 	}
 }
+
+// TestBundle is a bundle received by a TestBlockEngine
+type TestBundle struct {
+	ID     string
+	Txs    []*solana.Transaction
+	Status string
+	Sends  int // number of times the bundle was sent
+}
+
+// TestBlockEngine is a local stand-in for the bundle API of a block engine. bundles must pay a tip of at least MinTip
+// lamports to one of the TipAccounts, accepted bundles are forwarded to the RPC node if set
+type TestBlockEngine struct {
+	URL         string
+	TipAccounts []solana.PublicKey
+	MinTip      uint64
+
+	rpc     *rpc.Client
+	lock    sync.Mutex
+	bundles map[string]*TestBundle
+	order   []string
+}
+
+// SetupTestBlockEngine starts a local block engine. bundles are forwarded to the RPC node at rpcURL and reported as
+// landed once accepted, if rpcURL is empty bundles are only recorded and stay pending until their status is set
+func SetupTestBlockEngine(t *testing.T, rpcURL string) *TestBlockEngine {
+	e := &TestBlockEngine{
+		TipAccounts: []solana.PublicKey{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()},
+		MinTip:      1_000,
+		bundles:     map[string]*TestBundle{},
+	}
+	if rpcURL != "" {
+		e.rpc = rpc.New(rpcURL)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(e.serveHTTP))
+	t.Cleanup(srv.Close)
+	e.URL = srv.URL
+	return e
+}
+
+// Bundles returns the received bundles in the order they were first sent
+func (e *TestBlockEngine) Bundles() []TestBundle {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	bundles := make([]TestBundle, len(e.order))
+	for i, id := range e.order {
+		bundles[i] = *e.bundles[id]
+	}
+	return bundles
+}
+
+// SetStatus sets the status reported for the bundle
+func (e *TestBlockEngine) SetStatus(id string, status string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if b, ok := e.bundles[id]; ok {
+		b.Status = status
+	}
+}
+
+func (e *TestBlockEngine) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int               `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	res := jsonrpc.RPCResponse{JSONRPC: "2.0"}
+	var result any
+	var err *jsonrpc.RPCError
+	if decodeErr := json.NewDecoder(r.Body).Decode(&req); decodeErr != nil {
+		err = &jsonrpc.RPCError{Code: -32700, Message: decodeErr.Error()}
+	} else {
+		res.ID = req.ID
+		result, err = e.handle(r.Context(), req.Method, req.Params)
+	}
+	if err != nil {
+		res.Error = err
+	} else {
+		res.Result, _ = json.Marshal(result)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (e *TestBlockEngine) handle(ctx context.Context, method string, params []json.RawMessage) (any, *jsonrpc.RPCError) {
+	invalid := func(msg string) *jsonrpc.RPCError { return &jsonrpc.RPCError{Code: -32602, Message: msg} }
+	switch method {
+	case "getTipAccounts":
+		accounts := make([]string, len(e.TipAccounts))
+		for i, a := range e.TipAccounts {
+			accounts[i] = a.String()
+		}
+		return accounts, nil
+	case "sendBundle":
+		var encoded []string
+		if len(params) == 0 || json.Unmarshal(params[0], &encoded) != nil || len(encoded) == 0 {
+			return nil, invalid("bundle must contain txs")
+		}
+		txs := make([]*solana.Transaction, len(encoded))
+		h := sha256.New()
+		for i, s := range encoded {
+			data, decodeErr := base64.StdEncoding.DecodeString(s)
+			if decodeErr != nil {
+				return nil, invalid("invalid tx encoding: " + decodeErr.Error())
+			}
+			if txs[i], decodeErr = solana.TransactionFromDecoder(bin.NewBinDecoder(data)); decodeErr != nil {
+				return nil, invalid("invalid tx: " + decodeErr.Error())
+			}
+			h.Write(txs[i].Signatures[0][:])
+		}
+		if !e.tipped(txs) {
+			return nil, invalid("bundle must write lock at least one tip account")
+		}
+		id := hex.EncodeToString(h.Sum(nil))
+
+		e.lock.Lock()
+		b, sent := e.bundles[id]
+		if !sent {
+			b = &TestBundle{ID: id, Txs: txs, Status: BundleStatusPending}
+			e.bundles[id] = b
+			e.order = append(e.order, id)
+		} else if b.Status == BundleStatusFailed {
+			b.Status = BundleStatusPending // resent bundles are forwarded to the next leader
+		}
+		b.Sends++
+		e.lock.Unlock()
+		if !sent && e.rpc != nil {
+			status := BundleStatusLanded
+			for _, tx := range txs {
+				if _, sendErr := e.rpc.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{SkipPreflight: true}); sendErr != nil {
+					status = BundleStatusFailed
+					break
+				}
+			}
+			e.SetStatus(id, status)
+		}
+		return id, nil
+	case "getInflightBundleStatuses":
+		var ids []string
+		if len(params) == 0 || json.Unmarshal(params[0], &ids) != nil || len(ids) > MaxBundleStatuses {
+			return nil, invalid("expected up to 5 bundle ids")
+		}
+		statuses := make([]BundleStatus, len(ids))
+		e.lock.Lock()
+		for i, id := range ids {
+			statuses[i] = BundleStatus{BundleID: id, Status: BundleStatusInvalid}
+			if b, ok := e.bundles[id]; ok {
+				statuses[i].Status = b.Status
+			}
+		}
+		e.lock.Unlock()
+		return map[string]any{"context": map[string]any{"slot": 0}, "value": statuses}, nil
+	default:
+		return nil, &jsonrpc.RPCError{Code: -32601, Message: "method not found"}
+	}
+}
+
+// tipped returns if a tx of the bundle transfers at least the min tip to a tip account
+func (e *TestBlockEngine) tipped(txs []*solana.Transaction) bool {
+	for _, tx := range txs {
+		for _, ix := range tx.Message.Instructions {
+			program, err := tx.Message.Program(ix.ProgramIDIndex)
+			// system transfer: u32 instruction 2, u64 lamports, accounts [from, to]
+			if err != nil || !program.Equals(solana.SystemProgramID) || len(ix.Data) != 12 || len(ix.Accounts) != 2 ||
+				binary.LittleEndian.Uint32(ix.Data) != 2 || binary.LittleEndian.Uint64(ix.Data[4:]) < e.MinTip {
+				continue
+			}
+			to, err := tx.Message.Account(ix.Accounts[1])
+			if err != nil {
+				continue
+			}
+			for _, a := range e.TipAccounts {
+				if a.Equals(to) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// TestTPU is a TPU QUIC server of a leader, recording the txs it receives
+type TestTPU struct {
+	Addr string
+	lock sync.Mutex
+	txs  [][]byte
+}
+
+// Received returns the txs received by the TPU
+func (tpu *TestTPU) Received() [][]byte {
+	tpu.lock.Lock()
+	defer tpu.lock.Unlock()
+	return append([][]byte{}, tpu.txs...)
+}
+
+// SetupTestTPU starts a TPU QUIC server on a random local port
+func SetupTestTPU(t *testing.T) *TestTPU {
+	cert, err := tpuCertificate()
+	require.NoError(t, err)
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{tpuALPN},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	tpu := &TestTPU{Addr: listener.Addr().String()}
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := conn.AcceptUniStream(context.Background())
+					if err != nil {
+						return
+					}
+					data, err := io.ReadAll(stream)
+					if err != nil {
+						continue
+					}
+					tpu.lock.Lock()
+					tpu.txs = append(tpu.txs, data)
+					tpu.lock.Unlock()
+				}
+			}()
+		}
+	}()
+	return tpu
+}
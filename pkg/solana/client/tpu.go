@@ -0,0 +1,287 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/quic-go/quic-go"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+)
+
+const (
+	// tpuALPN is the application protocol of TPU QUIC connections
+	tpuALPN = "solana-tpu"
+	// tpuSlotDuration is the target duration of a slot, used to estimate the current slot between leader refreshes
+	tpuSlotDuration = 400 * time.Millisecond
+	// tpuLeaderSlots is the number of slots the leaders are fetched for on each refresh
+	tpuLeaderSlots = 128
+	// tpuLeaderRefresh is how often the current slot and leaders are refreshed
+	tpuLeaderRefresh = 10 * time.Second
+	// tpuNodesRefresh is how often the TPU addresses of the cluster nodes are refreshed
+	tpuNodesRefresh = 5 * time.Minute
+	// tpuDialTimeout is the max duration of a QUIC handshake with a leader
+	tpuDialTimeout = 2 * time.Second
+)
+
+// LeaderReader reads the current slot, leader schedule and TPU addresses of the cluster
+type LeaderReader interface {
+	SlotHeight(ctx context.Context) (uint64, error)
+	SlotLeaders(ctx context.Context, start, limit uint64) ([]solana.PublicKey, error)
+	TPUAddresses(ctx context.Context) (map[solana.PublicKey]string, error)
+}
+
+// TPUSender sends signed txs directly to the TPU QUIC ports of the upcoming leaders.
+// RPC nodes only forward txs to the next leaders and drop txs once their queue is full, txs sent to the leaders
+// directly are sent in parallel with the RPC node
+type TPUSender struct {
+	reader func() (LeaderReader, error)
+	fanout uint64 // number of upcoming leaders each tx is sent to
+	lggr   logger.Logger
+	tls    *tls.Config
+
+	lock      sync.Mutex
+	slot      uint64             // slot of the first leader
+	leaders   []solana.PublicKey // leaders of the slots from slot
+	leadersAt time.Time
+	addrs     map[solana.PublicKey]string // leader -> TPU QUIC address
+	addrsAt   time.Time
+	conns     map[string]quic.Connection // TPU QUIC address -> connection, to the scheduled leaders only
+}
+
+// NewTPUSender returns a sender to the TPUs of the next fanout leaders. connections are authenticated with a
+// self-signed certificate of a new identity (unstaked)
+func NewTPUSender(reader func() (LeaderReader, error), fanout uint32, lggr logger.Logger) (*TPUSender, error) {
+	cert, err := tpuCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return &TPUSender{
+		reader: reader,
+		fanout: uint64(fanout),
+		lggr:   logger.Named(lggr, "TPUSender"),
+		tls: &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: true, //nolint:gosec // validators use self-signed certificates of their identity
+			NextProtos:         []string{tpuALPN},
+		},
+		addrs: map[solana.PublicKey]string{},
+		conns: map[string]quic.Connection{},
+	}, nil
+}
+
+// Send sends the tx to the TPUs of the upcoming leaders in parallel, an error is returned if it could not be sent to any
+func (s *TPUSender) Send(ctx context.Context, tx *solana.Transaction) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal tx: %w", err)
+	}
+	addrs, err := s.leaderAddrs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errors.New("no TPU address of the upcoming leaders")
+	}
+
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.sendTo(ctx, addr, data)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to send tx to TPUs %v: %w", addrs, errors.Join(errs...))
+}
+
+// Close closes the connections to the leaders
+func (s *TPUSender) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var err error
+	for addr, conn := range s.conns {
+		err = errors.Join(err, conn.CloseWithError(0, ""))
+		delete(s.conns, addr)
+	}
+	return err
+}
+
+// sendTo writes the tx to a new unidirectional stream of the connection to the address, each stream carries one tx
+func (s *TPUSender) sendTo(ctx context.Context, addr string, data []byte) error {
+	conn, err := s.conn(ctx, addr)
+	if err != nil {
+		return err
+	}
+	stream, err := conn.OpenUniStreamSync(ctx)
+	if err == nil {
+		if _, err = stream.Write(data); err == nil {
+			err = stream.Close()
+		}
+	}
+	if err != nil {
+		// the connection is redialed on the next send (i.e. closed by the leader)
+		s.lock.Lock()
+		if s.conns[addr] == conn {
+			delete(s.conns, addr)
+		}
+		s.lock.Unlock()
+		_ = conn.CloseWithError(0, "")
+		return fmt.Errorf("failed to send tx to TPU %s: %w", addr, err)
+	}
+	return nil
+}
+
+// conn returns the open connection to the address, dialing a new connection if there is none
+func (s *TPUSender) conn(ctx context.Context, addr string) (quic.Connection, error) {
+	s.lock.Lock()
+	conn, ok := s.conns[addr]
+	s.lock.Unlock()
+	if ok && conn.Context().Err() == nil {
+		return conn, nil
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, tpuDialTimeout)
+	defer cancel()
+	conn, err := quic.DialAddr(dialCtx, addr, s.tls, &quic.Config{KeepAlivePeriod: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial TPU %s: %w", addr, err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if existing, ok := s.conns[addr]; ok && existing.Context().Err() == nil {
+		// dialed concurrently, keep the first connection
+		_ = conn.CloseWithError(0, "")
+		return existing, nil
+	}
+	s.conns[addr] = conn
+	return conn, nil
+}
+
+// leaderAddrs returns the TPU QUIC addresses of the next fanout leaders, starting with the leader of the current slot.
+// the slot is estimated from the last refresh, leaders without a TPU QUIC address are skipped
+func (s *TPUSender) leaderAddrs(ctx context.Context) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if len(s.addrs) == 0 || now.Sub(s.addrsAt) > tpuNodesRefresh {
+		if err := s.refreshNodes(ctx); err != nil {
+			return nil, err
+		}
+	}
+	current := s.slot + uint64(now.Sub(s.leadersAt)/tpuSlotDuration) //nolint:gosec // elapsed time is positive
+	// refresh once the leaders of the fanout can no longer be covered
+	if len(s.leaders) == 0 || now.Sub(s.leadersAt) > tpuLeaderRefresh || current+s.fanout*4 >= s.slot+uint64(len(s.leaders)) {
+		if err := s.refreshLeaders(ctx); err != nil {
+			return nil, err
+		}
+		current = s.slot
+	}
+
+	var leaders []solana.PublicKey
+	for _, leader := range s.leaders[current-s.slot:] {
+		if uint64(len(leaders)) == s.fanout {
+			break
+		}
+		if !slices.Contains(leaders, leader) {
+			leaders = append(leaders, leader)
+		}
+	}
+	var addrs []string
+	for _, leader := range leaders {
+		if addr, ok := s.addrs[leader]; ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+func (s *TPUSender) refreshLeaders(ctx context.Context) error {
+	reader, err := s.reader()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	slot, err := reader.SlotHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get slot: %w", err)
+	}
+	leaders, err := reader.SlotLeaders(ctx, slot, tpuLeaderSlots)
+	if err != nil {
+		return fmt.Errorf("failed to get slot leaders: %w", err)
+	}
+	if len(leaders) == 0 {
+		return fmt.Errorf("no leaders from slot %d", slot)
+	}
+	s.slot, s.leaders, s.leadersAt = slot, leaders, time.Now()
+	s.evictConns()
+	return nil
+}
+
+// evictConns closes the connections to the TPUs of validators that are no longer scheduled as leaders,
+// connections are kept for the leaders of the refreshed slots only
+func (s *TPUSender) evictConns() {
+	scheduled := map[string]struct{}{}
+	for _, leader := range s.leaders {
+		if addr, ok := s.addrs[leader]; ok {
+			scheduled[addr] = struct{}{}
+		}
+	}
+	for addr, conn := range s.conns {
+		if _, ok := scheduled[addr]; !ok {
+			_ = conn.CloseWithError(0, "")
+			delete(s.conns, addr)
+		}
+	}
+}
+
+func (s *TPUSender) refreshNodes(ctx context.Context) error {
+	reader, err := s.reader()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	addrs, err := reader.TPUAddresses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get TPU addresses: %w", err)
+	}
+	s.addrs, s.addrsAt = addrs, time.Now()
+	s.lggr.Debugw("refreshed TPU addresses", "tpus", len(addrs))
+	return nil
+}
+
+// tpuCertificate returns a self-signed certificate of a new ed25519 identity, as expected by the TPU QUIC server
+func tpuCertificate() (tls.Certificate, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate TPU identity: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Solana node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create TPU certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, nil
+}
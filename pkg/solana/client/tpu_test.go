@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+)
+
+type testLeaderReader struct {
+	slot    uint64
+	leaders []solana.PublicKey
+	addrs   map[solana.PublicKey]string
+}
+
+func (r *testLeaderReader) SlotHeight(context.Context) (uint64, error) { return r.slot, nil }
+
+func (r *testLeaderReader) SlotLeaders(_ context.Context, start, limit uint64) ([]solana.PublicKey, error) {
+	leaders := r.leaders[start-r.slot:]
+	return leaders[:min(uint64(len(leaders)), limit)], nil
+}
+
+func (r *testLeaderReader) TPUAddresses(context.Context) (map[solana.PublicKey]string, error) {
+	return r.addrs, nil
+}
+
+func TestTPUSender_Send(t *testing.T) {
+	ctx := tests.Context(t)
+	tpus := []*TestTPU{SetupTestTPU(t), SetupTestTPU(t), SetupTestTPU(t)}
+	leaders := []solana.PublicKey{{1}, {2}, {3}, {4}}
+	reader := &testLeaderReader{slot: 10, addrs: map[solana.PublicKey]string{}}
+	for i, tpu := range tpus {
+		reader.addrs[leaders[i]] = tpu.Addr
+	}
+	// leaders[3] has no TPU QUIC address
+	// leaders are scheduled for 4 consecutive slots
+	for _, leader := range leaders {
+		for range 4 {
+			reader.leaders = append(reader.leaders, leader)
+		}
+	}
+
+	sender, err := NewTPUSender(func() (LeaderReader, error) { return reader, nil }, 2, logger.Test(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, sender.Close()) })
+
+	payer := solana.NewWallet().PrivateKey
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte{1}),
+	}, solana.Hash{1}, solana.TransactionPayer(payer.PublicKey()))
+	require.NoError(t, err)
+	_, err = tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &payer })
+	require.NoError(t, err)
+	data, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	// the tx is sent to the distinct next 2 leaders only
+	require.NoError(t, sender.Send(ctx, tx))
+	require.Eventually(t, func() bool {
+		return len(tpus[0].Received()) == 1 && len(tpus[1].Received()) == 1
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	assert.Equal(t, [][]byte{data}, tpus[0].Received())
+	assert.Equal(t, [][]byte{data}, tpus[1].Received())
+	assert.Empty(t, tpus[2].Received())
+
+	// connections are reused
+	require.NoError(t, sender.Send(ctx, tx))
+	require.Eventually(t, func() bool {
+		return len(tpus[0].Received()) == 2 && len(tpus[1].Received()) == 2
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	sender.lock.Lock()
+	assert.Len(t, sender.conns, 2)
+	sender.lock.Unlock()
+
+	// leaders without a TPU QUIC address are skipped, the send fails if no leader has an address
+	reader.slot, reader.leaders = 20, reader.leaders[10:]
+	sender.lock.Lock()
+	sender.leaders = nil
+	sender.lock.Unlock()
+	require.NoError(t, sender.Send(ctx, tx))
+	require.Eventually(t, func() bool {
+		return len(tpus[2].Received()) == 1
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	// connections to validators that are no longer scheduled are closed
+	sender.lock.Lock()
+	assert.Equal(t, []string{tpus[2].Addr}, maps.Keys(sender.conns))
+	sender.lock.Unlock()
+
+	reader.slot, reader.leaders = 30, reader.leaders[2:]
+	sender.lock.Lock()
+	sender.leaders = nil
+	sender.lock.Unlock()
+	require.ErrorContains(t, sender.Send(ctx, tx), "no TPU address")
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"reflect"
+	"strings"
+	"unicode"
 
 	"github.com/goplugin/plugin-common/pkg/codec/encodings"
 	"github.com/goplugin/plugin-common/pkg/types"
@@ -13,10 +15,35 @@ import (
 const discriminatorLength = 8
 
 func NewDiscriminator(name string) encodings.TypeCodec {
-	sum := sha256.Sum256([]byte("account:" + name))
+	return newDiscriminator("account:" + name)
+}
+
+// NewInstructionDiscriminator returns the discriminator prefixed to the data of an Anchor instruction.
+// Anchor hashes the snake case name of the instruction, IDL instruction names are camel case.
+func NewInstructionDiscriminator(name string) encodings.TypeCodec {
+	return newDiscriminator("global:" + toSnakeCase(name))
+}
+
+func newDiscriminator(preimage string) encodings.TypeCodec {
+	sum := sha256.Sum256([]byte(preimage))
 	return &discriminator{hashPrefix: sum[:discriminatorLength]}
 }
 
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 type discriminator struct {
 	hashPrefix []byte
 }
@@ -75,6 +75,15 @@ func TestDiscriminator(t *testing.T) {
 		require.Equal(t, 8, size)
 	})
 
+	t.Run("instruction discriminator hashes the snake case instruction name", func(t *testing.T) {
+		tmp := sha256.Sum256([]byte("global:set_config_v2"))
+		expected := tmp[:8]
+		c := codec.NewInstructionDiscriminator("setConfigV2")
+		encoded, err := c.Encode(nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, encoded)
+	})
+
 	t.Run("FixedSize returns the length of the discriminator", func(t *testing.T) {
 		c := codec.NewDiscriminator("Foo")
 		size, err := c.FixedSize()
@@ -62,15 +62,34 @@ func NewNamedModifierCodec(original types.RemoteCodec, itemType string, modifier
 
 // NewIDLAccountCodec is for Anchor custom types
 func NewIDLAccountCodec(idl IDL, builder encodings.Builder) (types.RemoteCodec, error) {
-	return newIDLCoded(idl, builder, idl.Accounts, true)
+	return newIDLCoded(idl, builder, idl.Accounts, NewDiscriminator)
 }
 
 func NewIDLDefinedTypesCodec(idl IDL, builder encodings.Builder) (types.RemoteCodec, error) {
-	return newIDLCoded(idl, builder, idl.Types, false)
+	return newIDLCoded(idl, builder, idl.Types, nil)
+}
+
+// NewIDLInstructionsCodec is for Anchor instruction data. Each instruction is encoded by name as a struct of the
+// instruction args prefixed by the instruction discriminator.
+func NewIDLInstructionsCodec(idl IDL, builder encodings.Builder) (types.RemoteCodec, error) {
+	instructions := make(IdlTypeDefSlice, len(idl.Instructions))
+	for idx, instruction := range idl.Instructions {
+		args := instruction.Args
+		if args == nil {
+			args = []IdlField{}
+		}
+
+		instructions[idx] = IdlTypeDef{
+			Name: instruction.Name,
+			Type: IdlTypeDefTy{Kind: IdlTypeDefTyKindStruct, Fields: &args},
+		}
+	}
+
+	return newIDLCoded(idl, builder, instructions, NewInstructionDiscriminator)
 }
 
 func newIDLCoded(
-	idl IDL, builder encodings.Builder, from IdlTypeDefSlice, includeDiscriminator bool) (types.RemoteCodec, error) {
+	idl IDL, builder encodings.Builder, from IdlTypeDefSlice, discriminator func(string) encodings.TypeCodec) (types.RemoteCodec, error) {
 	typeCodecs := make(encodings.LenientCodecFromTypeCodec)
 
 	refs := &codecRefs{
@@ -87,7 +106,7 @@ func newIDLCoded(
 			err      error
 		)
 
-		name, accCodec, err = createNamedCodec(def, refs, includeDiscriminator)
+		name, accCodec, err = createNamedCodec(def, refs, discriminator)
 		if err != nil {
 			return nil, err
 		}
@@ -108,14 +127,14 @@ type codecRefs struct {
 func createNamedCodec(
 	def IdlTypeDef,
 	refs *codecRefs,
-	includeDiscriminator bool,
+	discriminator func(string) encodings.TypeCodec, // nil for types without a discriminator
 ) (string, encodings.TypeCodec, error) {
 	caser := cases.Title(language.English)
 	name := def.Name
 
 	switch def.Type.Kind {
 	case IdlTypeDefTyKindStruct:
-		return asStruct(def, refs, name, caser, includeDiscriminator)
+		return asStruct(def, refs, name, caser, discriminator)
 	case IdlTypeDefTyKindEnum:
 		variants := def.Type.Variants
 		if !variants.IsAllUint8() {
@@ -133,16 +152,16 @@ func asStruct(
 	refs *codecRefs,
 	name string, // name is the struct name and can be used in dependency checks
 	caser cases.Caser,
-	includeDiscriminator bool,
+	discriminator func(string) encodings.TypeCodec,
 ) (string, encodings.TypeCodec, error) {
 	desLen := 0
-	if includeDiscriminator {
+	if discriminator != nil {
 		desLen = 1
 	}
 	named := make([]encodings.NamedTypeCodec, len(*def.Type.Fields)+desLen)
 
-	if includeDiscriminator {
-		named[0] = encodings.NamedTypeCodec{Name: "Discriminator" + name, Codec: NewDiscriminator(name)}
+	if discriminator != nil {
+		named[0] = encodings.NamedTypeCodec{Name: "Discriminator" + name, Codec: discriminator(name)}
 	}
 
 	for idx, field := range *def.Type.Fields {
@@ -207,7 +226,7 @@ func asDefined(parentTypeName string, definedName *IdlTypeDefined, refs *codecRe
 
 	saveDependency(refs, parentTypeName, definedName.Defined)
 
-	newTypeName, newTypeCodec, err := createNamedCodec(*nextDef, refs, false)
+	newTypeName, newTypeCodec, err := createNamedCodec(*nextDef, refs, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +129,40 @@ func TestNewIDLCodec_WithModifiers(t *testing.T) {
 	require.Equal(t, expected.EnumVal, unmodifiedDecoded.EnumVal)
 }
 
+func TestNewIDLInstructionsCodec(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	var idl codec.IDL
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"instructions": [{
+			"name": "setValue",
+			"accounts": [],
+			"args": [{"name": "value", "type": "u16"}, {"name": "flag", "type": "bool"}]
+		}]
+	}`), &idl))
+
+	entry, err := codec.NewIDLInstructionsCodec(idl, binary.LittleEndian())
+	require.NoError(t, err)
+
+	type setValue struct {
+		Value uint16
+		Flag  bool
+	}
+
+	bts, err := entry.Encode(ctx, setValue{Value: 258, Flag: true}, "setValue")
+	require.NoError(t, err)
+
+	// instruction discriminator + args
+	discriminator, err := codec.NewInstructionDiscriminator("setValue").Encode(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, append(discriminator, 2, 1, 1), bts)
+
+	var decoded setValue
+	require.NoError(t, entry.Decode(ctx, bts, &decoded, "setValue"))
+	require.Equal(t, setValue{Value: 258, Flag: true}, decoded)
+}
+
 func TestNewIDLCodec_CircularDependency(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"github.com/goplugin/plugin-common/pkg/codec"
+)
+
+type ChainWriter struct {
+	Namespaces map[string]ChainWriterMethods `json:"namespaces" toml:"namespaces"`
+}
+
+type ChainWriterMethods struct {
+	Methods map[string]ChainDataWriter `json:"methods" toml:"methods"`
+}
+
+type ChainDataWriter struct {
+	AnchorIDL string `json:"anchorIDL" toml:"anchorIDL"`
+	// Encoding defines the type of encoding used for the instruction args. Currently supported
+	// are 'borsh' and 'bincode'.
+	Encoding EncodingType `json:"encoding" toml:"encoding"`
+	// InstructionName refers to the instruction defined in the IDL.
+	InstructionName string `json:"instructionName" toml:"instructionName"`
+	// FromAddress is the fee payer of the transaction and the key used to sign it.
+	FromAddress string `json:"fromAddress" toml:"fromAddress"`
+	// Accounts maps the accounts of the IDL instruction by name to the rules used to look up their addresses.
+	// Optional IDL accounts without a lookup are set to the program ID.
+	Accounts map[string]ChainWriterAccount `json:"accounts" toml:"accounts"`
+	// InputModifications provides modifiers to convert custom input formats to the
+	// instruction args.
+	InputModifications codec.ModifiersConfig `json:"inputModifications,omitempty" toml:"inputModifications"`
+	// LookupTables are the addresses of address lookup tables the instruction accounts are loaded from. Transactions
+	// using accounts stored in the tables are sent as v0 transactions, so instructions with more accounts fit in a
+	// single transaction.
+	LookupTables []string `json:"lookupTables,omitempty" toml:"lookupTables"`
+}
+
+// ChainWriterAccount defines how the address of an instruction account is looked up. Exactly one of the
+// fields should be set.
+type ChainWriterAccount struct {
+	// Address is a static account address.
+	Address string `json:"address,omitempty" toml:"address"`
+	// Param is the name of the input param holding the account address. Nested params are separated by '.'.
+	// Params are looked up in the input before the InputModifications are applied, using the input field names.
+	Param string `json:"param,omitempty" toml:"param"`
+	// PDA derives the account address from seeds.
+	PDA *ChainWriterPDA `json:"pda,omitempty" toml:"pda"`
+	// Role resolves the account to a signer of the transaction.
+	Role SignerRole `json:"role,omitempty" toml:"role"`
+}
+
+type ChainWriterPDA struct {
+	// ProgramID is the program the address is derived for. Defaults to the program of the instruction.
+	ProgramID string            `json:"programID,omitempty" toml:"programID"`
+	Seeds     []ChainWriterSeed `json:"seeds" toml:"seeds"`
+}
+
+// ChainWriterSeed is a single PDA seed. Exactly one of the fields should be set.
+type ChainWriterSeed struct {
+	// Value is a static seed of the utf-8 bytes of the value.
+	Value string `json:"value,omitempty" toml:"value"`
+	// Address is a static seed of the public key bytes of the address.
+	Address string `json:"address,omitempty" toml:"address"`
+	// Param is the name of the input param used as seed. Strings are used as utf-8 bytes, integers as
+	// little endian bytes, and public keys or byte arrays as is.
+	Param string `json:"param,omitempty" toml:"param"`
+}
+
+type SignerRole string
+
+const (
+	// SignerRoleFeePayer resolves the account to the fee payer (FromAddress) of the transaction.
+	SignerRoleFeePayer SignerRole = "feePayer"
+)
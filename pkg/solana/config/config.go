@@ -1,12 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gagliardetto/solana-go/rpc"
 
 	"github.com/goplugin/plugin-common/pkg/config"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
 )
 
 // Global solana defaults.
@@ -21,16 +25,58 @@ var defaultConfigSet = Chain{
 	SkipPreflight:       ptr(true),                                      // to enable or disable preflight checks
 	Commitment:          ptr(string(rpc.CommitmentConfirmed)),
 	MaxRetries:          ptr(int64(0)), // max number of retries (default = 0). when config.MaxRetries < 0), interpreted as MaxRetries = nil and rpc node will do a reasonable number of retries
+	RefreshBlockhash:    ptr(false),    // set to true to re-sign txs with a fresh blockhash when the blockhash expires (requires TxRetryTimeout longer than the blockhash lifetime)
 
 	// fee estimator
-	FeeEstimatorMode:         ptr("fixed"),
-	ComputeUnitPriceMax:      ptr(uint64(1_000)),
-	ComputeUnitPriceMin:      ptr(uint64(0)),
-	ComputeUnitPriceDefault:  ptr(uint64(0)),
-	FeeBumpPeriod:            config.MustNewDuration(3 * time.Second), // set to 0 to disable fee bumping
-	BlockHistoryPollPeriod:   config.MustNewDuration(5 * time.Second),
-	ComputeUnitLimitDefault:  ptr(uint32(200_000)), // set to 0 to disable adding compute unit limit
-	EstimateComputeUnitLimit: ptr(false),           // set to false to disable compute unit limit estimation
+	FeeEstimatorMode:                 ptr("fixed"),
+	ComputeUnitPriceMax:              ptr(uint64(1_000)),
+	ComputeUnitPriceMin:              ptr(uint64(0)),
+	ComputeUnitPriceDefault:          ptr(uint64(0)),
+	FeeBumpPeriod:                    config.MustNewDuration(3 * time.Second), // set to 0 to disable fee bumping
+	BlockHistoryPollPeriod:           config.MustNewDuration(5 * time.Second),
+	RecentFeesPollPeriod:             config.MustNewDuration(5 * time.Second),
+	BlockHistorySize:                 ptr(uint64(1)),       // number of recent blocks the blockhistory estimator calculates the price from
+	FeeEstimatorPercentile:           ptr(uint32(50)),      // percentile of recent compute unit prices used by the blockhistory and recentfees estimators
+	BlockHistoryExcludeZeroPrices:    ptr(false),           // set to true to ignore txs without a compute unit price
+	BlockHistoryWeightByComputeUnits: ptr(false),           // set to true to weight the price of each tx by the compute units it consumed
+	ComputeUnitLimitDefault:          ptr(uint32(200_000)), // set to 0 to disable adding compute unit limit
+	EstimateComputeUnitLimit:         ptr(false),           // set to false to disable compute unit limit estimation
+	EstimateLoadedAccountsDataSize:   ptr(false),           // set to true to set the loaded accounts data size limit of txs estimated from simulation
+
+	// fee bumping
+	FeeBumpStrategy:  ptr("double"),     // "double", "linear", "percentage", "exponential" or "estimate"
+	FeeBumpIncrement: ptr(uint64(100)),  // micro-lamports added per bump (linear)
+	FeeBumpPercent:   ptr(uint32(20)),   // percent of the base price added per bump (percentage)
+	FeeBumpFactor:    ptr(float64(1.5)), // multiplier applied per bump (exponential)
+	TxFeeBudget:      ptr(uint64(0)),    // max lamports (signature + priority fees) a tx can pay, caps fee bumping (0 = no budget)
+
+	// transaction store
+	TxStorePath:        ptr(""),                                 // set to a file path to persist inflight txs across restarts (empty = in-memory only)
+	TxRetentionTimeout: config.MustNewDuration(5 * time.Minute), // duration a finished tx remains queryable by id
+	TxDeadLetterMaxLen: ptr(uint32(100)),                        // max number of failed txs kept in the dead-letter list after exhausting their retries, the oldest are purged first (0 = disabled)
+
+	// failed transaction retries
+	TxRetryDropped:  ptr(uint32(0)), // times a tx not included within the confirm timeout is rebuilt and resent
+	TxRetryExpired:  ptr(uint32(0)), // times a tx whose blockhash expired (or durable nonce advanced) is resent with a fresh blockhash
+	TxRetryReverted: ptr(uint32(0)), // times a tx that reverted onchain or in simulation is resent (reverts are usually deterministic)
+
+	// transaction queues
+	TxMaxQueueLen: ptr(uint32(1_000)), // max number of queued (not yet broadcasted) txs per fee payer
+	TxMaxInflight: ptr(uint32(0)),     // max number of broadcasted and unfinished txs per fee payer (0 = no limit)
+	TxBatching:    ptr(false),         // send compatible queued txs of a fee payer together in batch txs
+
+	// transaction submission
+	TxSubmitter:            ptr("rpc"),                              // "rpc" sends txs to the RPC node, "bundle" sends txs as bundles to the block engine at BlockEngineURL
+	BlockEngineURL:         ptr(""),                                 // bundle API of the block engine, i.e. https://mainnet.block-engine.jito.wtf/api/v1/bundles
+	BundleTipLamports:      ptr(uint64(10_000)),                     // lamports each tx sent as a bundle tips to a tip account of the block engine
+	BundleStatusPollPeriod: config.MustNewDuration(2 * time.Second), // polling for the status of inflight bundles, failed bundles are resent
+	TPUFanout:              ptr(uint32(0)),                          // number of upcoming leaders txs are also sent to directly over TPU QUIC, 0 sends to the RPC node only
+
+	// transaction finality
+	TxCompletionCommitment: ptr(string(rpc.CommitmentConfirmed)), // commitment at which a tx is complete ("confirmed" or "finalized"), txs are tracked until finalized regardless
+
+	// fee payer balance
+	TxBalanceCheck: ptr(false), // reject txs whose fee payer cannot pay the worst case fee of its unfinished txs
 }
 
 //go:generate mockery --name Config --output ./mocks/ --case=underscore --filename config.go
@@ -45,6 +91,7 @@ type Config interface {
 	SkipPreflight() bool
 	Commitment() rpc.CommitmentType
 	MaxRetries() *uint
+	RefreshBlockhash() bool
 
 	// fee estimator
 	FeeEstimatorMode() string
@@ -53,29 +100,106 @@ type Config interface {
 	ComputeUnitPriceDefault() uint64
 	FeeBumpPeriod() time.Duration
 	BlockHistoryPollPeriod() time.Duration
+	RecentFeesPollPeriod() time.Duration
+	BlockHistorySize() uint64
+	FeeEstimatorPercentile() uint32
+	BlockHistoryExcludeZeroPrices() bool
+	BlockHistoryWeightByComputeUnits() bool
 	ComputeUnitLimitDefault() uint32
 	EstimateComputeUnitLimit() bool
+	EstimateLoadedAccountsDataSize() bool
+
+	// fee bumping
+	FeeBumpStrategy() string
+	FeeBumpIncrement() uint64
+	FeeBumpPercent() uint32
+	FeeBumpFactor() float64
+	TxFeeBudget() uint64
+
+	// transaction store
+	TxStorePath() string
+	TxRetentionTimeout() time.Duration
+	TxDeadLetterMaxLen() uint32
+
+	// failed transaction retries
+	TxRetryDropped() uint32
+	TxRetryExpired() uint32
+	TxRetryReverted() uint32
+
+	// transaction queues
+	TxMaxQueueLen() uint32
+	TxMaxInflight() uint32
+	TxBatching() bool
+
+	// transaction submission
+	TxSubmitter() string
+	BlockEngineURL() string
+	BundleTipLamports() uint64
+	BundleStatusPollPeriod() time.Duration
+	TPUFanout() uint32
+
+	// transaction finality
+	TxCompletionCommitment() rpc.CommitmentType
+
+	// fee payer balance
+	TxBalanceCheck() bool
+	FeePayerPools() map[string][]string
+	ProgramConfigs() map[string]ProgramConfig
+
+	// durable nonce
+	NonceAccounts() map[string][]string
 }
 
 type Chain struct {
-	BalancePollPeriod        *config.Duration
-	ConfirmPollPeriod        *config.Duration
-	OCR2CachePollPeriod      *config.Duration
-	OCR2CacheTTL             *config.Duration
-	TxTimeout                *config.Duration
-	TxRetryTimeout           *config.Duration
-	TxConfirmTimeout         *config.Duration
-	SkipPreflight            *bool
-	Commitment               *string
-	MaxRetries               *int64
-	FeeEstimatorMode         *string
-	ComputeUnitPriceMax      *uint64
-	ComputeUnitPriceMin      *uint64
-	ComputeUnitPriceDefault  *uint64
-	FeeBumpPeriod            *config.Duration
-	BlockHistoryPollPeriod   *config.Duration
-	ComputeUnitLimitDefault  *uint32
-	EstimateComputeUnitLimit *bool
+	BalancePollPeriod                *config.Duration
+	ConfirmPollPeriod                *config.Duration
+	OCR2CachePollPeriod              *config.Duration
+	OCR2CacheTTL                     *config.Duration
+	TxTimeout                        *config.Duration
+	TxRetryTimeout                   *config.Duration
+	TxConfirmTimeout                 *config.Duration
+	TxCompletionCommitment           *string
+	TxBalanceCheck                   *bool
+	TxBatching                       *bool
+	TxSubmitter                      *string
+	BlockEngineURL                   *string
+	BundleTipLamports                *uint64
+	BundleStatusPollPeriod           *config.Duration
+	TPUFanout                        *uint32
+	SkipPreflight                    *bool
+	Commitment                       *string
+	MaxRetries                       *int64
+	RefreshBlockhash                 *bool
+	FeeEstimatorMode                 *string
+	ComputeUnitPriceMax              *uint64
+	ComputeUnitPriceMin              *uint64
+	ComputeUnitPriceDefault          *uint64
+	FeeBumpPeriod                    *config.Duration
+	BlockHistoryPollPeriod           *config.Duration
+	RecentFeesPollPeriod             *config.Duration
+	BlockHistorySize                 *uint64
+	FeeEstimatorPercentile           *uint32
+	BlockHistoryExcludeZeroPrices    *bool
+	BlockHistoryWeightByComputeUnits *bool
+	ComputeUnitLimitDefault          *uint32
+	EstimateComputeUnitLimit         *bool
+	EstimateLoadedAccountsDataSize   *bool
+	FeeBumpStrategy                  *string
+	FeeBumpIncrement                 *uint64
+	FeeBumpPercent                   *uint32
+	FeeBumpFactor                    *float64
+	TxFeeBudget                      *uint64
+	TxStorePath                      *string
+	TxRetentionTimeout               *config.Duration
+	TxDeadLetterMaxLen               *uint32
+	TxRetryDropped                   *uint32
+	TxRetryExpired                   *uint32
+	TxRetryReverted                  *uint32
+	TxMaxQueueLen                    *uint32
+	TxMaxInflight                    *uint32
+	NonceAccounts                    map[string][]string      // fee payer -> durable nonce accounts with the fee payer as nonce authority
+	FeePayerPools                    map[string][]string      // fee payer -> funded fee payers the txs of the fee payer are rotated across
+	ProgramConfigs                   map[string]ProgramConfig // program ID -> tx config of the txs invoking the program
 }
 
 func (c *Chain) SetDefaults() {
@@ -100,6 +224,30 @@ func (c *Chain) SetDefaults() {
 	if c.TxConfirmTimeout == nil {
 		c.TxConfirmTimeout = defaultConfigSet.TxConfirmTimeout
 	}
+	if c.TxCompletionCommitment == nil {
+		c.TxCompletionCommitment = defaultConfigSet.TxCompletionCommitment
+	}
+	if c.TxBalanceCheck == nil {
+		c.TxBalanceCheck = defaultConfigSet.TxBalanceCheck
+	}
+	if c.TxBatching == nil {
+		c.TxBatching = defaultConfigSet.TxBatching
+	}
+	if c.TxSubmitter == nil {
+		c.TxSubmitter = defaultConfigSet.TxSubmitter
+	}
+	if c.BlockEngineURL == nil {
+		c.BlockEngineURL = defaultConfigSet.BlockEngineURL
+	}
+	if c.BundleTipLamports == nil {
+		c.BundleTipLamports = defaultConfigSet.BundleTipLamports
+	}
+	if c.BundleStatusPollPeriod == nil {
+		c.BundleStatusPollPeriod = defaultConfigSet.BundleStatusPollPeriod
+	}
+	if c.TPUFanout == nil {
+		c.TPUFanout = defaultConfigSet.TPUFanout
+	}
 	if c.SkipPreflight == nil {
 		c.SkipPreflight = defaultConfigSet.SkipPreflight
 	}
@@ -109,6 +257,9 @@ func (c *Chain) SetDefaults() {
 	if c.MaxRetries == nil {
 		c.MaxRetries = defaultConfigSet.MaxRetries
 	}
+	if c.RefreshBlockhash == nil {
+		c.RefreshBlockhash = defaultConfigSet.RefreshBlockhash
+	}
 	if c.FeeEstimatorMode == nil {
 		c.FeeEstimatorMode = defaultConfigSet.FeeEstimatorMode
 	}
@@ -127,12 +278,69 @@ func (c *Chain) SetDefaults() {
 	if c.BlockHistoryPollPeriod == nil {
 		c.BlockHistoryPollPeriod = defaultConfigSet.BlockHistoryPollPeriod
 	}
+	if c.RecentFeesPollPeriod == nil {
+		c.RecentFeesPollPeriod = defaultConfigSet.RecentFeesPollPeriod
+	}
+	if c.BlockHistorySize == nil {
+		c.BlockHistorySize = defaultConfigSet.BlockHistorySize
+	}
+	if c.FeeEstimatorPercentile == nil {
+		c.FeeEstimatorPercentile = defaultConfigSet.FeeEstimatorPercentile
+	}
+	if c.BlockHistoryExcludeZeroPrices == nil {
+		c.BlockHistoryExcludeZeroPrices = defaultConfigSet.BlockHistoryExcludeZeroPrices
+	}
+	if c.BlockHistoryWeightByComputeUnits == nil {
+		c.BlockHistoryWeightByComputeUnits = defaultConfigSet.BlockHistoryWeightByComputeUnits
+	}
 	if c.ComputeUnitLimitDefault == nil {
 		c.ComputeUnitLimitDefault = defaultConfigSet.ComputeUnitLimitDefault
 	}
 	if c.EstimateComputeUnitLimit == nil {
 		c.EstimateComputeUnitLimit = defaultConfigSet.EstimateComputeUnitLimit
 	}
+	if c.EstimateLoadedAccountsDataSize == nil {
+		c.EstimateLoadedAccountsDataSize = defaultConfigSet.EstimateLoadedAccountsDataSize
+	}
+	if c.FeeBumpStrategy == nil {
+		c.FeeBumpStrategy = defaultConfigSet.FeeBumpStrategy
+	}
+	if c.FeeBumpIncrement == nil {
+		c.FeeBumpIncrement = defaultConfigSet.FeeBumpIncrement
+	}
+	if c.FeeBumpPercent == nil {
+		c.FeeBumpPercent = defaultConfigSet.FeeBumpPercent
+	}
+	if c.FeeBumpFactor == nil {
+		c.FeeBumpFactor = defaultConfigSet.FeeBumpFactor
+	}
+	if c.TxFeeBudget == nil {
+		c.TxFeeBudget = defaultConfigSet.TxFeeBudget
+	}
+	if c.TxStorePath == nil {
+		c.TxStorePath = defaultConfigSet.TxStorePath
+	}
+	if c.TxRetentionTimeout == nil {
+		c.TxRetentionTimeout = defaultConfigSet.TxRetentionTimeout
+	}
+	if c.TxDeadLetterMaxLen == nil {
+		c.TxDeadLetterMaxLen = defaultConfigSet.TxDeadLetterMaxLen
+	}
+	if c.TxRetryDropped == nil {
+		c.TxRetryDropped = defaultConfigSet.TxRetryDropped
+	}
+	if c.TxRetryExpired == nil {
+		c.TxRetryExpired = defaultConfigSet.TxRetryExpired
+	}
+	if c.TxRetryReverted == nil {
+		c.TxRetryReverted = defaultConfigSet.TxRetryReverted
+	}
+	if c.TxMaxQueueLen == nil {
+		c.TxMaxQueueLen = defaultConfigSet.TxMaxQueueLen
+	}
+	if c.TxMaxInflight == nil {
+		c.TxMaxInflight = defaultConfigSet.TxMaxInflight
+	}
 }
 
 type Node struct {
@@ -153,6 +361,63 @@ func (n *Node) ValidateConfig() (err error) {
 	return
 }
 
+// compute budget bounds (see fees.MinHeapFrame, fees.MaxHeapFrame and fees.MaxLoadedAccountsDataSizeLimit)
+const (
+	minHeapFrame                   = 32 * 1024
+	maxHeapFrame                   = 256 * 1024
+	heapFrameGranularity           = 1024
+	maxLoadedAccountsDataSizeLimit = 64 * 1024 * 1024
+)
+
+// ProgramConfig overrides the chain tx config for txs with an instruction of the program, unset fields use the chain config
+type ProgramConfig struct {
+	ComputeUnitLimit       *uint32
+	ComputeUnitPriceMin    *uint64
+	ComputeUnitPriceMax    *uint64
+	FeeBumpPeriod          *config.Duration
+	TxRetryTimeout         *config.Duration
+	TxCompletionCommitment *string
+
+	LoadedAccountsDataSizeLimit *uint32 // max bytes of account data the tx can load
+	HeapFrame                   *uint32 // heap bytes of each program invoked by the tx
+
+	AnchorIDL *string // Anchor IDL JSON of the program, its errors decode the custom program errors of reverted txs
+}
+
+func (p ProgramConfig) ValidateConfig() (err error) {
+	if p.ComputeUnitPriceMin != nil && p.ComputeUnitPriceMax != nil && *p.ComputeUnitPriceMin > *p.ComputeUnitPriceMax {
+		err = errors.Join(err, config.ErrInvalid{Name: "ComputeUnitPriceMin", Value: *p.ComputeUnitPriceMin, Msg: "must not be greater than ComputeUnitPriceMax"})
+	}
+	if commitment := p.TxCompletionCommitment; commitment != nil &&
+		*commitment != string(rpc.CommitmentConfirmed) && *commitment != string(rpc.CommitmentFinalized) {
+		err = errors.Join(err, config.ErrInvalid{Name: "TxCompletionCommitment", Value: *commitment, Msg: "must be confirmed or finalized"})
+	}
+	if limit := p.LoadedAccountsDataSizeLimit; limit != nil && (*limit == 0 || *limit > maxLoadedAccountsDataSizeLimit) {
+		err = errors.Join(err, config.ErrInvalid{Name: "LoadedAccountsDataSizeLimit", Value: *limit, Msg: fmt.Sprintf("must be between 1 and %d", maxLoadedAccountsDataSizeLimit)})
+	}
+	if heap := p.HeapFrame; heap != nil && (*heap < minHeapFrame || *heap > maxHeapFrame || *heap%heapFrameGranularity != 0) {
+		err = errors.Join(err, config.ErrInvalid{Name: "HeapFrame", Value: *heap, Msg: fmt.Sprintf("must be a multiple of %d between %d and %d", heapFrameGranularity, minHeapFrame, maxHeapFrame)})
+	}
+	if p.AnchorIDL != nil {
+		if _, idlErr := p.IDL(); idlErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "AnchorIDL", Value: "<idl>", Msg: idlErr.Error()})
+		}
+	}
+	return
+}
+
+// IDL returns the parsed AnchorIDL of the program, nil if not set
+func (p ProgramConfig) IDL() (*codec.IDL, error) {
+	if p.AnchorIDL == nil {
+		return nil, nil
+	}
+	var idl codec.IDL
+	if err := json.Unmarshal([]byte(*p.AnchorIDL), &idl); err != nil {
+		return nil, fmt.Errorf("invalid Anchor IDL: %w", err)
+	}
+	return &idl, nil
+}
+
 func ptr[T any](t T) *T {
 	return &t
 }
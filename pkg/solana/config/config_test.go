@@ -0,0 +1,208 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commoncfg "github.com/goplugin/plugin-common/pkg/config"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+func newValidConfig(t *testing.T) *config.TOMLConfig {
+	cfg := config.NewDefault()
+	cfg.ChainID = ptr("devnet")
+	cfg.Nodes = config.Nodes{{Name: ptr("primary"), URL: commoncfg.MustParseURL("http://localhost:8899")}}
+	require.NoError(t, commoncfg.Validate(cfg))
+	return cfg
+}
+
+func TestNewDefault(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.NewDefault()
+	// queue limits
+	assert.Equal(t, uint32(1_000), cfg.TxMaxQueueLen())
+	assert.Equal(t, uint32(0), cfg.TxMaxInflight())
+	// durable nonce
+	assert.Empty(t, cfg.NonceAccounts())
+	// completion
+	assert.Equal(t, rpc.CommitmentConfirmed, cfg.TxCompletionCommitment())
+	// fee estimation
+	assert.Equal(t, "fixed", cfg.FeeEstimatorMode())
+	assert.Equal(t, uint64(1), cfg.BlockHistorySize())
+	assert.Equal(t, 5*time.Second, cfg.RecentFeesPollPeriod())
+	assert.Equal(t, uint32(50), cfg.FeeEstimatorPercentile())
+	assert.False(t, cfg.BlockHistoryExcludeZeroPrices())
+	assert.False(t, cfg.BlockHistoryWeightByComputeUnits())
+	assert.False(t, cfg.EstimateComputeUnitLimit())
+	// fee bumping
+	assert.Equal(t, "double", cfg.FeeBumpStrategy())
+	assert.Equal(t, uint64(100), cfg.FeeBumpIncrement())
+	assert.Equal(t, uint32(20), cfg.FeeBumpPercent())
+	assert.InDelta(t, 1.5, cfg.FeeBumpFactor(), 0)
+	assert.Equal(t, uint64(0), cfg.TxFeeBudget())
+	// fee payer balance
+	assert.False(t, cfg.TxBalanceCheck())
+	assert.Empty(t, cfg.FeePayerPools())
+	// submission
+	assert.Equal(t, "rpc", cfg.TxSubmitter())
+	assert.Empty(t, cfg.BlockEngineURL())
+	assert.Equal(t, uint64(10_000), cfg.BundleTipLamports())
+	assert.Equal(t, 2*time.Second, cfg.BundleStatusPollPeriod())
+	assert.Equal(t, uint32(0), cfg.TPUFanout())
+	// program overrides
+	assert.Empty(t, cfg.ProgramConfigs())
+}
+
+func TestTOMLConfig_ValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	signer, account := solana.NewWallet().PublicKey().String(), solana.NewWallet().PublicKey().String()
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*config.TOMLConfig)
+		err    string
+	}{
+		{
+			name:   "invalid nonce signer",
+			modify: func(c *config.TOMLConfig) { c.Chain.NonceAccounts = map[string][]string{"signer": {account}} },
+			err:    "NonceAccounts: invalid value (signer): signer must be a valid public key",
+		},
+		{
+			name:   "invalid nonce account",
+			modify: func(c *config.TOMLConfig) { c.Chain.NonceAccounts = map[string][]string{signer: {"account"}} },
+			err:    "NonceAccounts." + signer + ": invalid value (account): nonce account must be a valid public key",
+		},
+		{
+			name: "duplicate nonce account",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.NonceAccounts = map[string][]string{signer: {account}, solana.NewWallet().PublicKey().String(): {account}}
+			},
+			err: "invalid value (" + account + "): duplicate - must be unique",
+		},
+		{
+			name:   "completion commitment",
+			modify: func(c *config.TOMLConfig) { c.Chain.TxCompletionCommitment = ptr(string(rpc.CommitmentProcessed)) },
+			err:    "TxCompletionCommitment: invalid value (processed): must be confirmed or finalized",
+		},
+		{
+			name:   "invalid pool fee payer",
+			modify: func(c *config.TOMLConfig) { c.Chain.FeePayerPools = map[string][]string{"payer": {account}} },
+			err:    "FeePayerPools: invalid value (payer): fee payer must be a valid public key",
+		},
+		{
+			name:   "invalid pooled fee payer",
+			modify: func(c *config.TOMLConfig) { c.Chain.FeePayerPools = map[string][]string{signer: {"pooled"}} },
+			err:    "FeePayerPools." + signer + ": invalid value (pooled): fee payer must be a valid public key",
+		},
+		{
+			name:   "zero block history size",
+			modify: func(c *config.TOMLConfig) { c.Chain.BlockHistorySize = ptr(uint64(0)) },
+			err:    "BlockHistorySize: invalid value (0): must be greater than 0",
+		},
+		{
+			name:   "zero recent fees poll period",
+			modify: func(c *config.TOMLConfig) { c.Chain.RecentFeesPollPeriod = commoncfg.MustNewDuration(0) },
+			err:    "RecentFeesPollPeriod: invalid value (0s): must be greater than 0",
+		},
+		{
+			name:   "estimator percentile out of range",
+			modify: func(c *config.TOMLConfig) { c.Chain.FeeEstimatorPercentile = ptr(uint32(101)) },
+			err:    "FeeEstimatorPercentile: invalid value (101): must be between 0 and 100",
+		},
+		{
+			name:   "unknown bump strategy",
+			modify: func(c *config.TOMLConfig) { c.Chain.FeeBumpStrategy = ptr("triple") },
+			err:    "FeeBumpStrategy: invalid value (triple): must be double, linear, percentage, exponential or estimate",
+		},
+		{
+			name: "zero linear bump increment",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.FeeBumpStrategy, c.Chain.FeeBumpIncrement = ptr("linear"), ptr(uint64(0))
+			},
+			err: "FeeBumpIncrement: invalid value (0): must be greater than 0 for the linear strategy",
+		},
+		{
+			name: "zero percentage bump percent",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.FeeBumpStrategy, c.Chain.FeeBumpPercent = ptr("percentage"), ptr(uint32(0))
+			},
+			err: "FeeBumpPercent: invalid value (0): must be greater than 0 for the percentage strategy",
+		},
+		{
+			name: "non-increasing exponential bump factor",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.FeeBumpStrategy, c.Chain.FeeBumpFactor = ptr("exponential"), ptr(float64(1))
+			},
+			err: "FeeBumpFactor: invalid value (1): must be greater than 1 for the exponential strategy",
+		},
+		{
+			name:   "invalid program",
+			modify: func(c *config.TOMLConfig) { c.Chain.ProgramConfigs = map[string]config.ProgramConfig{"program": {}} },
+			err:    "ProgramConfigs: invalid value (program): program must be a valid public key",
+		},
+		{
+			name: "program price range",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.ProgramConfigs = map[string]config.ProgramConfig{account: {ComputeUnitPriceMin: ptr(uint64(2)), ComputeUnitPriceMax: ptr(uint64(1))}}
+			},
+			err: "ProgramConfigs." + account + ".ComputeUnitPriceMin: invalid value (2): must not be greater than ComputeUnitPriceMax",
+		},
+		{
+			name: "program completion commitment",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.ProgramConfigs = map[string]config.ProgramConfig{account: {TxCompletionCommitment: ptr(string(rpc.CommitmentProcessed))}}
+			},
+			err: "ProgramConfigs." + account + ".TxCompletionCommitment: invalid value (processed): must be confirmed or finalized",
+		},
+		{
+			name:   "unknown submitter",
+			modify: func(c *config.TOMLConfig) { c.Chain.TxSubmitter = ptr("smtp") },
+			err:    "TxSubmitter: invalid value (smtp): must be rpc or bundle",
+		},
+		{
+			name:   "bundle submitter without block engine",
+			modify: func(c *config.TOMLConfig) { c.Chain.TxSubmitter = ptr("bundle") },
+			err:    "BlockEngineURL: missing: required for the bundle submitter",
+		},
+		{
+			name: "invalid block engine URL",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.TxSubmitter, c.Chain.BlockEngineURL = ptr("bundle"), ptr("block-engine")
+			},
+			err: "BlockEngineURL: invalid value (block-engine): must be a valid URL",
+		},
+		{
+			name: "bundle submitter with TPU fanout",
+			modify: func(c *config.TOMLConfig) {
+				c.Chain.TxSubmitter, c.Chain.BlockEngineURL = ptr("bundle"), ptr("https://block-engine.example/api/v1/bundles")
+				c.Chain.TPUFanout = ptr(uint32(2))
+			},
+			err: "TPUFanout: invalid value (2): only supported by the rpc submitter",
+		},
+		{
+			name:   "zero queue length",
+			modify: func(c *config.TOMLConfig) { c.Chain.TxMaxQueueLen = ptr(uint32(0)) },
+			err:    "TxMaxQueueLen: invalid value (0): must be greater than 0",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newValidConfig(t)
+			tc.modify(cfg)
+			assert.ErrorContains(t, commoncfg.Validate(cfg), tc.err)
+		})
+	}
+}
+
+func ptr[T any](t T) *T {
+	return &t
+}
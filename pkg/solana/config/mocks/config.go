@@ -3,9 +3,11 @@
 package mocks
 
 import (
-	rpc "github.com/gagliardetto/solana-go/rpc"
+	config "github.com/goplugin/plugin-solana/pkg/solana/config"
 	mock "github.com/stretchr/testify/mock"
 
+	rpc "github.com/gagliardetto/solana-go/rpc"
+
 	time "time"
 )
 
@@ -32,6 +34,42 @@ func (_m *Config) BalancePollPeriod() time.Duration {
 	return r0
 }
 
+// BlockEngineURL provides a mock function with given fields:
+func (_m *Config) BlockEngineURL() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockEngineURL")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// BlockHistoryExcludeZeroPrices provides a mock function with given fields:
+func (_m *Config) BlockHistoryExcludeZeroPrices() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockHistoryExcludeZeroPrices")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // BlockHistoryPollPeriod provides a mock function with given fields:
 func (_m *Config) BlockHistoryPollPeriod() time.Duration {
 	ret := _m.Called()
@@ -50,6 +88,78 @@ func (_m *Config) BlockHistoryPollPeriod() time.Duration {
 	return r0
 }
 
+// BlockHistorySize provides a mock function with given fields:
+func (_m *Config) BlockHistorySize() uint64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockHistorySize")
+	}
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
+// BlockHistoryWeightByComputeUnits provides a mock function with given fields:
+func (_m *Config) BlockHistoryWeightByComputeUnits() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockHistoryWeightByComputeUnits")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// BundleStatusPollPeriod provides a mock function with given fields:
+func (_m *Config) BundleStatusPollPeriod() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BundleStatusPollPeriod")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// BundleTipLamports provides a mock function with given fields:
+func (_m *Config) BundleTipLamports() uint64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BundleTipLamports")
+	}
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
 // Commitment provides a mock function with given fields:
 func (_m *Config) Commitment() rpc.CommitmentType {
 	ret := _m.Called()
@@ -176,6 +286,78 @@ func (_m *Config) EstimateComputeUnitLimit() bool {
 	return r0
 }
 
+// EstimateLoadedAccountsDataSize provides a mock function with given fields:
+func (_m *Config) EstimateLoadedAccountsDataSize() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for EstimateLoadedAccountsDataSize")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// FeeBumpFactor provides a mock function with given fields:
+func (_m *Config) FeeBumpFactor() float64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeBumpFactor")
+	}
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func() float64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	return r0
+}
+
+// FeeBumpIncrement provides a mock function with given fields:
+func (_m *Config) FeeBumpIncrement() uint64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeBumpIncrement")
+	}
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
+// FeeBumpPercent provides a mock function with given fields:
+func (_m *Config) FeeBumpPercent() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeBumpPercent")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
 // FeeBumpPeriod provides a mock function with given fields:
 func (_m *Config) FeeBumpPeriod() time.Duration {
 	ret := _m.Called()
@@ -194,6 +376,24 @@ func (_m *Config) FeeBumpPeriod() time.Duration {
 	return r0
 }
 
+// FeeBumpStrategy provides a mock function with given fields:
+func (_m *Config) FeeBumpStrategy() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeBumpStrategy")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // FeeEstimatorMode provides a mock function with given fields:
 func (_m *Config) FeeEstimatorMode() string {
 	ret := _m.Called()
@@ -212,6 +412,44 @@ func (_m *Config) FeeEstimatorMode() string {
 	return r0
 }
 
+// FeeEstimatorPercentile provides a mock function with given fields:
+func (_m *Config) FeeEstimatorPercentile() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeeEstimatorPercentile")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// FeePayerPools provides a mock function with given fields:
+func (_m *Config) FeePayerPools() map[string][]string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for FeePayerPools")
+	}
+
+	var r0 map[string][]string
+	if rf, ok := ret.Get(0).(func() map[string][]string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]string)
+		}
+	}
+
+	return r0
+}
+
 // MaxRetries provides a mock function with given fields:
 func (_m *Config) MaxRetries() *uint {
 	ret := _m.Called()
@@ -232,6 +470,26 @@ func (_m *Config) MaxRetries() *uint {
 	return r0
 }
 
+// NonceAccounts provides a mock function with given fields:
+func (_m *Config) NonceAccounts() map[string][]string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for NonceAccounts")
+	}
+
+	var r0 map[string][]string
+	if rf, ok := ret.Get(0).(func() map[string][]string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]string)
+		}
+	}
+
+	return r0
+}
+
 // OCR2CachePollPeriod provides a mock function with given fields:
 func (_m *Config) OCR2CachePollPeriod() time.Duration {
 	ret := _m.Called()
@@ -268,6 +526,62 @@ func (_m *Config) OCR2CacheTTL() time.Duration {
 	return r0
 }
 
+// ProgramConfigs provides a mock function with given fields:
+func (_m *Config) ProgramConfigs() map[string]config.ProgramConfig {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ProgramConfigs")
+	}
+
+	var r0 map[string]config.ProgramConfig
+	if rf, ok := ret.Get(0).(func() map[string]config.ProgramConfig); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]config.ProgramConfig)
+		}
+	}
+
+	return r0
+}
+
+// RecentFeesPollPeriod provides a mock function with given fields:
+func (_m *Config) RecentFeesPollPeriod() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecentFeesPollPeriod")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// RefreshBlockhash provides a mock function with given fields:
+func (_m *Config) RefreshBlockhash() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefreshBlockhash")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SkipPreflight provides a mock function with given fields:
 func (_m *Config) SkipPreflight() bool {
 	ret := _m.Called()
@@ -286,6 +600,78 @@ func (_m *Config) SkipPreflight() bool {
 	return r0
 }
 
+// TPUFanout provides a mock function with given fields:
+func (_m *Config) TPUFanout() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TPUFanout")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxBalanceCheck provides a mock function with given fields:
+func (_m *Config) TxBalanceCheck() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxBalanceCheck")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// TxBatching provides a mock function with given fields:
+func (_m *Config) TxBatching() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxBatching")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// TxCompletionCommitment provides a mock function with given fields:
+func (_m *Config) TxCompletionCommitment() rpc.CommitmentType {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxCompletionCommitment")
+	}
+
+	var r0 rpc.CommitmentType
+	if rf, ok := ret.Get(0).(func() rpc.CommitmentType); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(rpc.CommitmentType)
+	}
+
+	return r0
+}
+
 // TxConfirmTimeout provides a mock function with given fields:
 func (_m *Config) TxConfirmTimeout() time.Duration {
 	ret := _m.Called()
@@ -304,6 +690,150 @@ func (_m *Config) TxConfirmTimeout() time.Duration {
 	return r0
 }
 
+// TxDeadLetterMaxLen provides a mock function with given fields:
+func (_m *Config) TxDeadLetterMaxLen() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxDeadLetterMaxLen")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxFeeBudget provides a mock function with given fields:
+func (_m *Config) TxFeeBudget() uint64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxFeeBudget")
+	}
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	return r0
+}
+
+// TxMaxInflight provides a mock function with given fields:
+func (_m *Config) TxMaxInflight() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxMaxInflight")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxMaxQueueLen provides a mock function with given fields:
+func (_m *Config) TxMaxQueueLen() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxMaxQueueLen")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxRetentionTimeout provides a mock function with given fields:
+func (_m *Config) TxRetentionTimeout() time.Duration {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxRetentionTimeout")
+	}
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// TxRetryDropped provides a mock function with given fields:
+func (_m *Config) TxRetryDropped() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxRetryDropped")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxRetryExpired provides a mock function with given fields:
+func (_m *Config) TxRetryExpired() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxRetryExpired")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
+// TxRetryReverted provides a mock function with given fields:
+func (_m *Config) TxRetryReverted() uint32 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxRetryReverted")
+	}
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func() uint32); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	return r0
+}
+
 // TxRetryTimeout provides a mock function with given fields:
 func (_m *Config) TxRetryTimeout() time.Duration {
 	ret := _m.Called()
@@ -322,6 +852,42 @@ func (_m *Config) TxRetryTimeout() time.Duration {
 	return r0
 }
 
+// TxStorePath provides a mock function with given fields:
+func (_m *Config) TxStorePath() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxStorePath")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// TxSubmitter provides a mock function with given fields:
+func (_m *Config) TxSubmitter() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxSubmitter")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // TxTimeout provides a mock function with given fields:
 func (_m *Config) TxTimeout() time.Duration {
 	ret := _m.Called()
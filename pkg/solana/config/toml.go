@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/pelletier/go-toml/v2"
 	"golang.org/x/exp/slices"
@@ -155,6 +156,30 @@ func setFromChain(c, f *Chain) {
 	if f.TxConfirmTimeout != nil {
 		c.TxConfirmTimeout = f.TxConfirmTimeout
 	}
+	if f.TxCompletionCommitment != nil {
+		c.TxCompletionCommitment = f.TxCompletionCommitment
+	}
+	if f.TxBalanceCheck != nil {
+		c.TxBalanceCheck = f.TxBalanceCheck
+	}
+	if f.TxBatching != nil {
+		c.TxBatching = f.TxBatching
+	}
+	if f.TxSubmitter != nil {
+		c.TxSubmitter = f.TxSubmitter
+	}
+	if f.BlockEngineURL != nil {
+		c.BlockEngineURL = f.BlockEngineURL
+	}
+	if f.BundleTipLamports != nil {
+		c.BundleTipLamports = f.BundleTipLamports
+	}
+	if f.BundleStatusPollPeriod != nil {
+		c.BundleStatusPollPeriod = f.BundleStatusPollPeriod
+	}
+	if f.TPUFanout != nil {
+		c.TPUFanout = f.TPUFanout
+	}
 	if f.SkipPreflight != nil {
 		c.SkipPreflight = f.SkipPreflight
 	}
@@ -164,6 +189,9 @@ func setFromChain(c, f *Chain) {
 	if f.MaxRetries != nil {
 		c.MaxRetries = f.MaxRetries
 	}
+	if f.RefreshBlockhash != nil {
+		c.RefreshBlockhash = f.RefreshBlockhash
+	}
 	if f.FeeEstimatorMode != nil {
 		c.FeeEstimatorMode = f.FeeEstimatorMode
 	}
@@ -182,6 +210,72 @@ func setFromChain(c, f *Chain) {
 	if f.BlockHistoryPollPeriod != nil {
 		c.BlockHistoryPollPeriod = f.BlockHistoryPollPeriod
 	}
+	if f.RecentFeesPollPeriod != nil {
+		c.RecentFeesPollPeriod = f.RecentFeesPollPeriod
+	}
+	if f.BlockHistorySize != nil {
+		c.BlockHistorySize = f.BlockHistorySize
+	}
+	if f.FeeEstimatorPercentile != nil {
+		c.FeeEstimatorPercentile = f.FeeEstimatorPercentile
+	}
+	if f.BlockHistoryExcludeZeroPrices != nil {
+		c.BlockHistoryExcludeZeroPrices = f.BlockHistoryExcludeZeroPrices
+	}
+	if f.BlockHistoryWeightByComputeUnits != nil {
+		c.BlockHistoryWeightByComputeUnits = f.BlockHistoryWeightByComputeUnits
+	}
+	if f.EstimateLoadedAccountsDataSize != nil {
+		c.EstimateLoadedAccountsDataSize = f.EstimateLoadedAccountsDataSize
+	}
+	if f.FeeBumpStrategy != nil {
+		c.FeeBumpStrategy = f.FeeBumpStrategy
+	}
+	if f.FeeBumpIncrement != nil {
+		c.FeeBumpIncrement = f.FeeBumpIncrement
+	}
+	if f.FeeBumpPercent != nil {
+		c.FeeBumpPercent = f.FeeBumpPercent
+	}
+	if f.FeeBumpFactor != nil {
+		c.FeeBumpFactor = f.FeeBumpFactor
+	}
+	if f.TxFeeBudget != nil {
+		c.TxFeeBudget = f.TxFeeBudget
+	}
+	if f.TxStorePath != nil {
+		c.TxStorePath = f.TxStorePath
+	}
+	if f.TxRetentionTimeout != nil {
+		c.TxRetentionTimeout = f.TxRetentionTimeout
+	}
+	if f.TxDeadLetterMaxLen != nil {
+		c.TxDeadLetterMaxLen = f.TxDeadLetterMaxLen
+	}
+	if f.TxRetryDropped != nil {
+		c.TxRetryDropped = f.TxRetryDropped
+	}
+	if f.TxRetryExpired != nil {
+		c.TxRetryExpired = f.TxRetryExpired
+	}
+	if f.TxRetryReverted != nil {
+		c.TxRetryReverted = f.TxRetryReverted
+	}
+	if f.TxMaxQueueLen != nil {
+		c.TxMaxQueueLen = f.TxMaxQueueLen
+	}
+	if f.TxMaxInflight != nil {
+		c.TxMaxInflight = f.TxMaxInflight
+	}
+	if f.NonceAccounts != nil {
+		c.NonceAccounts = f.NonceAccounts
+	}
+	if f.FeePayerPools != nil {
+		c.FeePayerPools = f.FeePayerPools
+	}
+	if f.ProgramConfigs != nil {
+		c.ProgramConfigs = f.ProgramConfigs
+	}
 }
 
 func (c *TOMLConfig) ValidateConfig() (err error) {
@@ -194,6 +288,93 @@ func (c *TOMLConfig) ValidateConfig() (err error) {
 	if len(c.Nodes) == 0 {
 		err = errors.Join(err, config.ErrMissing{Name: "Nodes", Msg: "must have at least one node"})
 	}
+
+	nonceAccounts := map[string]struct{}{}
+	for signer, accounts := range c.Chain.NonceAccounts {
+		if _, parseErr := solana.PublicKeyFromBase58(signer); parseErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "NonceAccounts", Value: signer, Msg: "signer must be a valid public key"})
+		}
+		for _, a := range accounts {
+			if _, parseErr := solana.PublicKeyFromBase58(a); parseErr != nil {
+				err = errors.Join(err, config.ErrInvalid{Name: "NonceAccounts." + signer, Value: a, Msg: "nonce account must be a valid public key"})
+			}
+			if _, ok := nonceAccounts[a]; ok {
+				err = errors.Join(err, config.NewErrDuplicate("NonceAccounts."+signer, a))
+			}
+			nonceAccounts[a] = struct{}{}
+		}
+	}
+
+	for feePayer, pool := range c.Chain.FeePayerPools {
+		if _, parseErr := solana.PublicKeyFromBase58(feePayer); parseErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeePayerPools", Value: feePayer, Msg: "fee payer must be a valid public key"})
+		}
+		for _, k := range pool {
+			if _, parseErr := solana.PublicKeyFromBase58(k); parseErr != nil {
+				err = errors.Join(err, config.ErrInvalid{Name: "FeePayerPools." + feePayer, Value: k, Msg: "fee payer must be a valid public key"})
+			}
+		}
+	}
+
+	for program := range c.Chain.ProgramConfigs {
+		if _, parseErr := solana.PublicKeyFromBase58(program); parseErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "ProgramConfigs", Value: program, Msg: "program must be a valid public key"})
+		}
+	}
+
+	if size := c.Chain.BlockHistorySize; size != nil && *size == 0 {
+		err = errors.Join(err, config.ErrInvalid{Name: "BlockHistorySize", Value: *size, Msg: "must be greater than 0"})
+	}
+
+	if period := c.Chain.RecentFeesPollPeriod; period != nil && period.Duration() <= 0 {
+		err = errors.Join(err, config.ErrInvalid{Name: "RecentFeesPollPeriod", Value: period.Duration(), Msg: "must be greater than 0"})
+	}
+
+	if percentile := c.Chain.FeeEstimatorPercentile; percentile != nil && *percentile > 100 {
+		err = errors.Join(err, config.ErrInvalid{Name: "FeeEstimatorPercentile", Value: *percentile, Msg: "must be between 0 and 100"})
+	}
+
+	switch strategy := c.Chain.FeeBumpStrategy; {
+	case strategy == nil:
+	case *strategy == "linear":
+		if c.Chain.FeeBumpIncrement != nil && *c.Chain.FeeBumpIncrement == 0 {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeeBumpIncrement", Value: *c.Chain.FeeBumpIncrement, Msg: "must be greater than 0 for the linear strategy"})
+		}
+	case *strategy == "percentage":
+		if c.Chain.FeeBumpPercent != nil && *c.Chain.FeeBumpPercent == 0 {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeeBumpPercent", Value: *c.Chain.FeeBumpPercent, Msg: "must be greater than 0 for the percentage strategy"})
+		}
+	case *strategy == "exponential":
+		if c.Chain.FeeBumpFactor != nil && *c.Chain.FeeBumpFactor <= 1 {
+			err = errors.Join(err, config.ErrInvalid{Name: "FeeBumpFactor", Value: *c.Chain.FeeBumpFactor, Msg: "must be greater than 1 for the exponential strategy"})
+		}
+	case *strategy != "double" && *strategy != "estimate":
+		err = errors.Join(err, config.ErrInvalid{Name: "FeeBumpStrategy", Value: *strategy, Msg: "must be double, linear, percentage, exponential or estimate"})
+	}
+
+	switch submitter := c.Chain.TxSubmitter; {
+	case submitter == nil, *submitter == "rpc":
+	case *submitter == "bundle":
+		if c.Chain.BlockEngineURL == nil || *c.Chain.BlockEngineURL == "" {
+			err = errors.Join(err, config.ErrMissing{Name: "BlockEngineURL", Msg: "required for the bundle submitter"})
+		} else if _, parseErr := url.ParseRequestURI(*c.Chain.BlockEngineURL); parseErr != nil {
+			err = errors.Join(err, config.ErrInvalid{Name: "BlockEngineURL", Value: *c.Chain.BlockEngineURL, Msg: "must be a valid URL"})
+		}
+		if c.Chain.TPUFanout != nil && *c.Chain.TPUFanout > 0 {
+			err = errors.Join(err, config.ErrInvalid{Name: "TPUFanout", Value: *c.Chain.TPUFanout, Msg: "only supported by the rpc submitter"})
+		}
+	default:
+		err = errors.Join(err, config.ErrInvalid{Name: "TxSubmitter", Value: *submitter, Msg: "must be rpc or bundle"})
+	}
+
+	if commitment := c.Chain.TxCompletionCommitment; commitment != nil &&
+		*commitment != string(rpc.CommitmentConfirmed) && *commitment != string(rpc.CommitmentFinalized) {
+		err = errors.Join(err, config.ErrInvalid{Name: "TxCompletionCommitment", Value: *commitment, Msg: "must be confirmed or finalized"})
+	}
+
+	if queueLen := c.Chain.TxMaxQueueLen; queueLen != nil && *queueLen == 0 {
+		err = errors.Join(err, config.ErrInvalid{Name: "TxMaxQueueLen", Value: *queueLen, Msg: "must be greater than 0"})
+	}
 	return
 }
 
@@ -235,6 +416,38 @@ func (c *TOMLConfig) TxConfirmTimeout() time.Duration {
 	return c.Chain.TxConfirmTimeout.Duration()
 }
 
+func (c *TOMLConfig) TxCompletionCommitment() rpc.CommitmentType {
+	return rpc.CommitmentType(*c.Chain.TxCompletionCommitment)
+}
+
+func (c *TOMLConfig) TxBalanceCheck() bool {
+	return *c.Chain.TxBalanceCheck
+}
+
+func (c *TOMLConfig) TxBatching() bool {
+	return *c.Chain.TxBatching
+}
+
+func (c *TOMLConfig) TxSubmitter() string {
+	return *c.Chain.TxSubmitter
+}
+
+func (c *TOMLConfig) BlockEngineURL() string {
+	return *c.Chain.BlockEngineURL
+}
+
+func (c *TOMLConfig) BundleTipLamports() uint64 {
+	return *c.Chain.BundleTipLamports
+}
+
+func (c *TOMLConfig) BundleStatusPollPeriod() time.Duration {
+	return c.Chain.BundleStatusPollPeriod.Duration()
+}
+
+func (c *TOMLConfig) TPUFanout() uint32 {
+	return *c.Chain.TPUFanout
+}
+
 func (c *TOMLConfig) SkipPreflight() bool {
 	return *c.Chain.SkipPreflight
 }
@@ -254,6 +467,10 @@ func (c *TOMLConfig) MaxRetries() *uint {
 	return &mr
 }
 
+func (c *TOMLConfig) RefreshBlockhash() bool {
+	return *c.Chain.RefreshBlockhash
+}
+
 func (c *TOMLConfig) FeeEstimatorMode() string {
 	return *c.Chain.FeeEstimatorMode
 }
@@ -278,6 +495,26 @@ func (c *TOMLConfig) BlockHistoryPollPeriod() time.Duration {
 	return c.Chain.BlockHistoryPollPeriod.Duration()
 }
 
+func (c *TOMLConfig) RecentFeesPollPeriod() time.Duration {
+	return c.Chain.RecentFeesPollPeriod.Duration()
+}
+
+func (c *TOMLConfig) BlockHistorySize() uint64 {
+	return *c.Chain.BlockHistorySize
+}
+
+func (c *TOMLConfig) FeeEstimatorPercentile() uint32 {
+	return *c.Chain.FeeEstimatorPercentile
+}
+
+func (c *TOMLConfig) BlockHistoryExcludeZeroPrices() bool {
+	return *c.Chain.BlockHistoryExcludeZeroPrices
+}
+
+func (c *TOMLConfig) BlockHistoryWeightByComputeUnits() bool {
+	return *c.Chain.BlockHistoryWeightByComputeUnits
+}
+
 func (c *TOMLConfig) ComputeUnitLimitDefault() uint32 {
 	return *c.Chain.ComputeUnitLimitDefault
 }
@@ -286,6 +523,74 @@ func (c *TOMLConfig) EstimateComputeUnitLimit() bool {
 	return *c.Chain.EstimateComputeUnitLimit
 }
 
+func (c *TOMLConfig) EstimateLoadedAccountsDataSize() bool {
+	return *c.Chain.EstimateLoadedAccountsDataSize
+}
+
+func (c *TOMLConfig) FeeBumpStrategy() string {
+	return *c.Chain.FeeBumpStrategy
+}
+
+func (c *TOMLConfig) FeeBumpIncrement() uint64 {
+	return *c.Chain.FeeBumpIncrement
+}
+
+func (c *TOMLConfig) FeeBumpPercent() uint32 {
+	return *c.Chain.FeeBumpPercent
+}
+
+func (c *TOMLConfig) FeeBumpFactor() float64 {
+	return *c.Chain.FeeBumpFactor
+}
+
+func (c *TOMLConfig) TxFeeBudget() uint64 {
+	return *c.Chain.TxFeeBudget
+}
+
+func (c *TOMLConfig) TxStorePath() string {
+	return *c.Chain.TxStorePath
+}
+
+func (c *TOMLConfig) TxRetentionTimeout() time.Duration {
+	return c.Chain.TxRetentionTimeout.Duration()
+}
+
+func (c *TOMLConfig) TxDeadLetterMaxLen() uint32 {
+	return *c.Chain.TxDeadLetterMaxLen
+}
+
+func (c *TOMLConfig) TxRetryDropped() uint32 {
+	return *c.Chain.TxRetryDropped
+}
+
+func (c *TOMLConfig) TxRetryExpired() uint32 {
+	return *c.Chain.TxRetryExpired
+}
+
+func (c *TOMLConfig) TxRetryReverted() uint32 {
+	return *c.Chain.TxRetryReverted
+}
+
+func (c *TOMLConfig) TxMaxQueueLen() uint32 {
+	return *c.Chain.TxMaxQueueLen
+}
+
+func (c *TOMLConfig) TxMaxInflight() uint32 {
+	return *c.Chain.TxMaxInflight
+}
+
+func (c *TOMLConfig) NonceAccounts() map[string][]string {
+	return c.Chain.NonceAccounts
+}
+
+func (c *TOMLConfig) FeePayerPools() map[string][]string {
+	return c.Chain.FeePayerPools
+}
+
+func (c *TOMLConfig) ProgramConfigs() map[string]ProgramConfig {
+	return c.Chain.ProgramConfigs
+}
+
 func (c *TOMLConfig) ListNodes() Nodes {
 	return c.Nodes
 }
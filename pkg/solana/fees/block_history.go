@@ -3,12 +3,13 @@ package fees
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"sync"
 
 	"github.com/goplugin/plugin-common/pkg/logger"
 	"github.com/goplugin/plugin-common/pkg/services"
 	"github.com/goplugin/plugin-common/pkg/utils"
-	"github.com/goplugin/plugin-common/pkg/utils/mathutil"
 
 	"github.com/goplugin/plugin-solana/pkg/solana/client"
 	"github.com/goplugin/plugin-solana/pkg/solana/config"
@@ -16,29 +17,43 @@ import (
 
 var _ Estimator = &blockHistoryEstimator{}
 
+// blockHistorySkippedSlots is the number of slots searched beyond BlockHistorySize, so that the window is filled despite skipped slots
+const blockHistorySkippedSlots = 100
+
 type blockHistoryEstimator struct {
 	starter services.StateMachine
 	chStop  services.StopChan
 	done    sync.WaitGroup
 
-	client *utils.LazyLoad[client.ReaderWriter]
-	cfg    config.Config
-	lgr    logger.Logger
+	client  *utils.LazyLoad[client.ReaderWriter]
+	cfg     config.Config
+	lgr     logger.Logger
+	chainID string
+
+	blocks   []blockFees // rolling window of the latest BlockHistorySize blocks, oldest first (only accessed by run)
+	lastSlot uint64      // slot of the latest block in the window (only accessed by run)
 
 	price uint64
 	lock  sync.RWMutex
 }
 
-// NewBlockHistoryEstimator creates a new fee estimator that parses historical fees from a fetched block
+type blockFees struct {
+	slot uint64
+	BlockData
+}
+
+// NewBlockHistoryEstimator creates a new fee estimator that parses historical fees from a rolling window of fetched blocks
+// each poll fetches the blocks produced since the previous poll, so the window does not miss blocks between polls
 // Note: getRecentPrioritizationFees is not used because it provides the lowest prioritization fee for an included tx in the block
 // which is not effective enough for increasing the chances of block inclusion
-func NewBlockHistoryEstimator(c *utils.LazyLoad[client.ReaderWriter], cfg config.Config, lgr logger.Logger) (*blockHistoryEstimator, error) {
+func NewBlockHistoryEstimator(c *utils.LazyLoad[client.ReaderWriter], cfg config.Config, lgr logger.Logger, chainID string) (*blockHistoryEstimator, error) {
 	return &blockHistoryEstimator{
-		chStop: make(chan struct{}),
-		client: c,
-		cfg:    cfg,
-		lgr:    lgr,
-		price:  cfg.ComputeUnitPriceDefault(), // use default value
+		chStop:  make(chan struct{}),
+		client:  c,
+		cfg:     cfg,
+		lgr:     lgr,
+		chainID: chainID,
+		price:   cfg.ComputeUnitPriceDefault(), // use default value
 	}, nil
 }
 
@@ -105,33 +120,100 @@ func (bhe *blockHistoryEstimator) calculatePrice(ctx context.Context) error {
 		return fmt.Errorf("failed to get client in blockHistoryEstimator.getFee: %w", err)
 	}
 
-	// get latest block based on configured confirmation
-	block, err := c.GetLatestBlock(ctx)
+	// get latest slot based on configured confirmation
+	latest, err := c.SlotHeight(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get block in blockHistoryEstimator.getFee: %w", err)
+		return fmt.Errorf("failed to get slot in blockHistoryEstimator.getFee: %w", err)
 	}
 
-	// parse block for fee data
-	feeData, err := ParseBlock(block)
-	if err != nil {
-		return fmt.Errorf("failed to parse block in blockHistoryEstimator.getFee: %w", err)
+	// backfill the blocks produced since the last poll, blocks older than the window are not fetched
+	size := bhe.cfg.BlockHistorySize()
+	start := latest - min(latest, size-1+blockHistorySkippedSlots)
+	if bhe.lastSlot >= start {
+		start = bhe.lastSlot + 1
+	}
+	var slots []uint64
+	if start <= latest {
+		slots, err = c.GetBlocks(ctx, start, latest)
+		if err != nil {
+			return fmt.Errorf("failed to get blocks in blockHistoryEstimator.getFee: %w", err)
+		}
+		slots = slots[len(slots)-min(len(slots), int(size)):] //nolint:gosec // window size fits in an int
 	}
 
-	// take median of returned fee values
-	v, err := mathutil.Median(feeData.Prices...)
+	for _, slot := range slots {
+		// a block that can not be fetched or parsed is retried by the next poll while it is within the searched slots
+		block, err := c.GetBlock(ctx, slot)
+		if err != nil {
+			return fmt.Errorf("failed to get block in blockHistoryEstimator.getFee: %w", err)
+		}
+		feeData, err := ParseBlock(block)
+		if err != nil {
+			return fmt.Errorf("failed to parse block in blockHistoryEstimator.getFee: %w", err)
+		}
+		bhe.blocks = append(bhe.blocks, blockFees{slot: slot, BlockData: feeData})
+		bhe.lastSlot = slot
+		if uint64(len(bhe.blocks)) > size {
+			bhe.blocks = slices.Delete(bhe.blocks, 0, len(bhe.blocks)-int(size)) //nolint:gosec // size is less than len(blocks)
+		}
+	}
+
+	// take percentile of the fee values in the window
+	prices, weights := bhe.samples()
+	v, err := bhe.percentile(prices, weights, bhe.cfg.FeeEstimatorPercentile())
 	if err != nil {
-		return fmt.Errorf("failed to find median in blockHistoryEstimator.getFee: %w", err)
+		return fmt.Errorf("failed to find percentile in blockHistoryEstimator.getFee: %w", err)
 	}
 
 	// set data
 	bhe.lock.Lock()
-	bhe.price = uint64(v) // ComputeUnitPrice is uint64 underneath
+	bhe.price = v
 	bhe.lock.Unlock()
+	bhe.report(v, prices, weights)
 	bhe.lgr.Debugw("BlockHistoryEstimator: updated",
 		"computeUnitPrice", v,
-		"blockhash", block.Blockhash,
-		"slot", block.ParentSlot+1,
-		"count", len(feeData.Prices),
+		"slot", bhe.lastSlot,
+		"fetched", len(slots),
+		"samples", len(prices),
+		"blocks", len(bhe.blocks),
 	)
 	return nil
 }
+
+// samples returns the prices of the txs in the window, and their weights if weighted by compute units consumed
+func (bhe *blockHistoryEstimator) samples() (prices []uint64, weights []uint64) {
+	excludeZero, weighted := bhe.cfg.BlockHistoryExcludeZeroPrices(), bhe.cfg.BlockHistoryWeightByComputeUnits()
+	for _, b := range bhe.blocks {
+		for i, price := range b.Prices {
+			if excludeZero && price == 0 {
+				continue
+			}
+			prices = append(prices, uint64(price)) // ComputeUnitPrice is uint64 underneath
+			if weighted {
+				weights = append(weights, b.ComputeUnits[i])
+			}
+		}
+	}
+	return prices, weights
+}
+
+func (bhe *blockHistoryEstimator) percentile(prices, weights []uint64, p uint32) (uint64, error) {
+	if bhe.cfg.BlockHistoryWeightByComputeUnits() {
+		return weightedPercentile(prices, weights, p)
+	}
+	return percentile(prices, p)
+}
+
+// report exposes the calculated price and the distribution of prices in the window as metrics
+func (bhe *blockHistoryEstimator) report(price uint64, prices, weights []uint64) {
+	promBlockHistoryPrice.WithLabelValues(bhe.chainID).Set(float64(price))
+	promBlockHistorySamples.WithLabelValues(bhe.chainID).Set(float64(len(prices)))
+	promBlockHistoryBlocks.WithLabelValues(bhe.chainID).Set(float64(len(bhe.blocks)))
+	for _, p := range distributionPercentiles {
+		v, err := bhe.percentile(prices, weights, p)
+		if err != nil {
+			continue
+		}
+		promBlockHistoryDistribution.WithLabelValues(bhe.chainID, strconv.FormatUint(uint64(p), 10)).Set(float64(v))
+	}
+}
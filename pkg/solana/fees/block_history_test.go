@@ -7,7 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -35,6 +37,10 @@ func TestBlockHistoryEstimator(t *testing.T) {
 	cfg.On("ComputeUnitPriceMin").Return(min)
 	cfg.On("ComputeUnitPriceMax").Return(max)
 	cfg.On("BlockHistoryPollPeriod").Return(100 * time.Millisecond)
+	cfg.On("BlockHistorySize").Return(uint64(1))
+	cfg.On("FeeEstimatorPercentile").Return(uint32(50))
+	cfg.On("BlockHistoryExcludeZeroPrices").Return(false)
+	cfg.On("BlockHistoryWeightByComputeUnits").Return(false)
 	lgr, logs := logger.TestObserved(t, zapcore.DebugLevel)
 	ctx := tests.Context(t)
 
@@ -45,10 +51,13 @@ func TestBlockHistoryEstimator(t *testing.T) {
 	require.NoError(t, json.Unmarshal(testBlockData, blockRes))
 
 	// happy path
-	estimator, err := NewBlockHistoryEstimator(rwLoader, cfg, lgr)
+	estimator, err := NewBlockHistoryEstimator(rwLoader, cfg, lgr, "test")
 	require.NoError(t, err)
 
-	rw.On("GetLatestBlock", mock.Anything).Return(blockRes, nil).Once()
+	// the latest block is fetched on the first poll
+	rw.On("GetBlocks", mock.Anything, uint64(100), uint64(200)).Return([]uint64{150, 200}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(200)).Return(blockRes, nil).Once()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(200), nil).Once()
 	require.NoError(t, estimator.Start(ctx))
 	tests.AssertLogEventually(t, logs, "BlockHistoryEstimator: updated")
 	assert.Equal(t, uint64(55000), estimator.readRawPrice())
@@ -61,23 +70,31 @@ func TestBlockHistoryEstimator(t *testing.T) {
 	estimator.price = validPrice
 	assert.Equal(t, estimator.readRawPrice(), estimator.BaseComputeUnitPrice())
 
-	// failed to get latest block
-	rw.On("GetLatestBlock", mock.Anything).Return(nil, fmt.Errorf("fail rpc call")).Once()
+	// failed to get block
+	rw.On("GetBlocks", mock.Anything, uint64(201), uint64(201)).Return([]uint64{201}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(201)).Return(nil, fmt.Errorf("fail rpc call")).Once()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(201), nil).Once()
 	tests.AssertLogEventually(t, logs, "failed to get block")
 	assert.Equal(t, validPrice, estimator.BaseComputeUnitPrice(), "price should not change when getPrice fails")
 
-	// failed to parse block
-	rw.On("GetLatestBlock", mock.Anything).Return(nil, nil).Once()
+	// failed to parse block, the block that failed is retried
+	rw.On("GetBlocks", mock.Anything, uint64(201), uint64(202)).Return([]uint64{201, 202}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(202)).Return(nil, nil).Once()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(202), nil).Once()
 	tests.AssertLogEventually(t, logs, "failed to parse block")
 	assert.Equal(t, validPrice, estimator.BaseComputeUnitPrice(), "price should not change when getPrice fails")
 
-	// failed to calculate median
-	rw.On("GetLatestBlock", mock.Anything).Return(&rpc.GetBlockResult{}, nil).Once()
-	tests.AssertLogEventually(t, logs, "failed to find median")
+	// failed to calculate percentile (empty block replaces the block in the window)
+	rw.On("GetBlocks", mock.Anything, uint64(201), uint64(203)).Return([]uint64{203}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(203)).Return(&rpc.GetBlockResult{}, nil).Once()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(203), nil).Once()
+	tests.AssertLogEventually(t, logs, "failed to find percentile")
 	assert.Equal(t, validPrice, estimator.BaseComputeUnitPrice(), "price should not change when getPrice fails")
 
 	// back to happy path
-	rw.On("GetLatestBlock", mock.Anything).Return(blockRes, nil).Once()
+	rw.On("GetBlocks", mock.Anything, uint64(204), uint64(204)).Return([]uint64{204}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(204)).Return(blockRes, nil).Once()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(204), nil).Once()
 	tests.AssertEventually(t, func() bool {
 		return logs.FilterMessageSnippet("BlockHistoryEstimator: updated").Len() == 2
 	})
@@ -88,9 +105,145 @@ func TestBlockHistoryEstimator(t *testing.T) {
 	rwFail := utils.NewLazyLoad(func() (client.ReaderWriter, error) {
 		return nil, fmt.Errorf("fail client load")
 	})
-	estimator, err = NewBlockHistoryEstimator(rwFail, cfg, lgr)
+	estimator, err = NewBlockHistoryEstimator(rwFail, cfg, lgr, "test")
 	require.NoError(t, err)
 	require.NoError(t, estimator.Start(ctx))
 	tests.AssertLogEventually(t, logs, "failed to get client")
 	require.NoError(t, estimator.Close())
 }
+
+type testBlockTx struct {
+	price ComputeUnitPrice
+	units uint64
+}
+
+// testBlock returns a block of txs with a compute unit price that consumed compute units
+func testBlock(t *testing.T, blockhash solana.Hash, txs ...testBlockTx) *rpc.GetBlockResult {
+	block := &rpc.GetBlockResult{Blockhash: blockhash}
+	for _, btx := range txs {
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte{1}),
+		}, blockhash, solana.TransactionPayer(solana.PublicKey{1}))
+		require.NoError(t, err)
+		require.NoError(t, SetComputeUnitPrice(tx, btx.price))
+		data, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		program := solana.MemoProgramID.String()
+		block.Transactions = append(block.Transactions, rpc.TransactionWithMeta{
+			Transaction: rpc.DataBytesOrJSONFromBytes(data),
+			Meta: &rpc.TransactionMeta{LogMessages: []string{
+				"Program " + ComputeBudgetProgram.String() + " invoke [1]",
+				"Program " + ComputeBudgetProgram.String() + " success",
+				"Program " + program + " invoke [1]",
+				"Program " + program + " invoke [2]",
+				"Program " + program + " consumed 10 of 200000 compute units",
+				"Program " + program + " success",
+				fmt.Sprintf("Program %s consumed %d of 200000 compute units", program, btx.units),
+				"Program " + program + " success",
+			}},
+		})
+	}
+	return block
+}
+
+func TestBlockHistoryEstimator_Window(t *testing.T) {
+	ctx := tests.Context(t)
+	rw := clientmock.NewReaderWriter(t)
+	rwLoader := utils.NewLazyLoad(func() (client.ReaderWriter, error) {
+		return rw, nil
+	})
+	cfg := cfgmock.NewConfig(t)
+	cfg.On("ComputeUnitPriceDefault").Return(uint64(0))
+	cfg.On("BlockHistorySize").Return(uint64(2))
+	percentile := uint32(50)
+	cfg.On("FeeEstimatorPercentile").Return(func() uint32 { return percentile })
+	excludeZero, weighted := false, false
+	cfg.On("BlockHistoryExcludeZeroPrices").Return(func() bool { return excludeZero })
+	cfg.On("BlockHistoryWeightByComputeUnits").Return(func() bool { return weighted })
+
+	estimator, err := NewBlockHistoryEstimator(rwLoader, cfg, logger.Test(t), "test")
+	require.NoError(t, err)
+
+	blockA := testBlock(t, solana.Hash{1}, testBlockTx{0, 1_000}, testBlockTx{100, 1_000}, testBlockTx{200, 1_000})
+	blockB := testBlock(t, solana.Hash{2}, testBlockTx{0, 1_000}, testBlockTx{400, 1_000}, testBlockTx{1_000, 100_000})
+	blockC := testBlock(t, solana.Hash{3}, testBlockTx{300, 1_000})
+
+	rw.On("SlotHeight", mock.Anything).Return(uint64(10), nil).Twice()
+	rw.On("GetBlocks", mock.Anything, uint64(1), uint64(10)).Return([]uint64{10}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(10)).Return(blockA, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(100), estimator.readRawPrice())
+
+	// same block is not added twice, no blocks are fetched if no slot passed since the last poll
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Len(t, estimator.blocks, 1)
+
+	// window: 0, 0, 100, 200, 400, 1000
+	rw.On("SlotHeight", mock.Anything).Return(uint64(12), nil)
+	rw.On("GetBlocks", mock.Anything, uint64(11), uint64(12)).Return([]uint64{12}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(12)).Return(blockB, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(150), estimator.readRawPrice())
+
+	percentile = 90
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(700), estimator.readRawPrice())
+
+	// window: 100, 200, 400, 1000
+	percentile = 50
+	excludeZero = true
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(300), estimator.readRawPrice())
+
+	// weighted by compute units: 1000 consumed 100_000 of the 103_000 units in the window
+	weighted = true
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(1_000), estimator.readRawPrice())
+	percentile = 1
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(200), estimator.readRawPrice())
+
+	// oldest block is removed from the window: 0, 300, 400, 1000
+	weighted, excludeZero, percentile = false, false, 50
+	rw.On("SlotHeight", mock.Anything).Unset()
+	rw.On("SlotHeight", mock.Anything).Return(uint64(15), nil).Once()
+	rw.On("GetBlocks", mock.Anything, uint64(13), uint64(15)).Return([]uint64{14}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(14)).Return(blockC, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Len(t, estimator.blocks, 2)
+	assert.Equal(t, uint64(350), estimator.readRawPrice())
+
+	// distribution of the window
+	assert.Equal(t, float64(2), testutil.ToFloat64(promBlockHistoryBlocks.WithLabelValues("test")))
+	assert.Equal(t, float64(4), testutil.ToFloat64(promBlockHistorySamples.WithLabelValues("test")))
+	assert.Equal(t, float64(350), testutil.ToFloat64(promBlockHistoryPrice.WithLabelValues("test")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(promBlockHistoryDistribution.WithLabelValues("test", "0")))
+	assert.Equal(t, float64(1_000), testutil.ToFloat64(promBlockHistoryDistribution.WithLabelValues("test", "100")))
+
+	// blocks produced between polls are backfilled, only the blocks that fit the window are fetched: 0, 100, 200, 300
+	rw.On("SlotHeight", mock.Anything).Return(uint64(20), nil).Once()
+	rw.On("GetBlocks", mock.Anything, uint64(15), uint64(20)).Return([]uint64{16, 18, 20}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(18)).Return(blockA, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(20)).Return(blockC, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, []uint64{18, 20}, []uint64{estimator.blocks[0].slot, estimator.blocks[1].slot})
+	assert.Equal(t, uint64(150), estimator.readRawPrice())
+
+	// a block that can not be fetched is retried by the next poll
+	rw.On("SlotHeight", mock.Anything).Return(uint64(22), nil).Twice()
+	rw.On("GetBlocks", mock.Anything, uint64(21), uint64(22)).Return([]uint64{21, 22}, nil).Twice()
+	rw.On("GetBlock", mock.Anything, uint64(21)).Return(nil, fmt.Errorf("fail rpc call")).Once()
+	require.ErrorContains(t, estimator.calculatePrice(ctx), "failed to get block")
+	assert.Equal(t, uint64(20), estimator.lastSlot)
+	rw.On("GetBlock", mock.Anything, uint64(21)).Return(blockA, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(22)).Return(blockB, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, uint64(22), estimator.lastSlot)
+
+	// blocks older than the window are not fetched after a gap
+	rw.On("SlotHeight", mock.Anything).Return(uint64(1_000), nil).Once()
+	rw.On("GetBlocks", mock.Anything, uint64(1_000-1-blockHistorySkippedSlots), uint64(1_000)).Return([]uint64{1_000}, nil).Once()
+	rw.On("GetBlock", mock.Anything, uint64(1_000)).Return(blockC, nil).Once()
+	require.NoError(t, estimator.calculatePrice(ctx))
+	assert.Equal(t, []uint64{22, 1_000}, []uint64{estimator.blocks[0].slot, estimator.blocks[1].slot})
+}
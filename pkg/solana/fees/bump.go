@@ -0,0 +1,121 @@
+package fees
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+// BumpStrategy calculates the compute unit price of each fee bump of a tx.
+// prices returned by the strategy are not bounded, the txm applies the configured min, max and fee budget
+type BumpStrategy interface {
+	// Price returns the price after count bumps (count > 0), previous is the price after count-1 bumps
+	Price(base, previous uint64, count uint) uint64
+}
+
+var (
+	_ BumpStrategy = DoubleBump{}
+	_ BumpStrategy = LinearBump{}
+	_ BumpStrategy = PercentageBump{}
+	_ BumpStrategy = ExponentialBump{}
+	_ BumpStrategy = EstimateBump{}
+)
+
+// NewBumpStrategy returns the bump strategy selected by FeeBumpStrategy, the estimate strategy uses the estimator
+func NewBumpStrategy(cfg config.Config, estimator Estimator) (BumpStrategy, error) {
+	switch cfg.FeeBumpStrategy() {
+	case "double":
+		return DoubleBump{}, nil
+	case "linear":
+		return LinearBump{Increment: cfg.FeeBumpIncrement()}, nil
+	case "percentage":
+		return PercentageBump{Percent: cfg.FeeBumpPercent()}, nil
+	case "exponential":
+		return ExponentialBump{Factor: cfg.FeeBumpFactor()}, nil
+	case "estimate":
+		return EstimateBump{Estimator: estimator}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee bump strategy: %s", cfg.FeeBumpStrategy())
+	}
+}
+
+// DoubleBump doubles the price on each bump, starting at 1 when the base price is 0 (same as CalculateFee)
+type DoubleBump struct{}
+
+func (DoubleBump) Price(_, previous uint64, _ uint) uint64 {
+	if previous == 0 {
+		return 1
+	}
+	return saturatingAdd(previous, previous)
+}
+
+// LinearBump adds a fixed increment (micro-lamports) on each bump
+type LinearBump struct {
+	Increment uint64
+}
+
+func (s LinearBump) Price(_, previous uint64, _ uint) uint64 {
+	return saturatingAdd(previous, max(s.Increment, 1))
+}
+
+// PercentageBump adds a percentage of the base price on each bump (at least 1 micro-lamport)
+type PercentageBump struct {
+	Percent uint32
+}
+
+func (s PercentageBump) Price(base, previous uint64, count uint) uint64 {
+	// base * (100 + percent * count) / 100
+	price := new(big.Int).SetUint64(uint64(s.Percent))
+	price.Mul(price, new(big.Int).SetUint64(uint64(count)))
+	price.Add(price, big.NewInt(100))
+	price.Mul(price, new(big.Int).SetUint64(base))
+	price.Div(price, big.NewInt(100))
+	if !price.IsUint64() {
+		return math.MaxUint64
+	}
+	return max(price.Uint64(), saturatingAdd(previous, 1))
+}
+
+// ExponentialBump multiplies the price by a factor on each bump (at least 1 micro-lamport)
+type ExponentialBump struct {
+	Factor float64
+}
+
+func (s ExponentialBump) Price(_, previous uint64, _ uint) uint64 {
+	price := math.Ceil(float64(previous) * s.Factor)
+	if price >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return max(uint64(price), saturatingAdd(previous, 1))
+}
+
+// EstimateBump re-estimates the price from the fee estimator on each bump, the price never decreases
+type EstimateBump struct {
+	Estimator Estimator
+}
+
+func (s EstimateBump) Price(_, previous uint64, _ uint) uint64 {
+	return max(s.Estimator.BaseComputeUnitPrice(), previous)
+}
+
+// MaxComputeUnitPrice returns the max compute unit price (micro-lamports) for the priority fee to not exceed fee lamports
+func MaxComputeUnitPrice(fee uint64, limit ComputeUnitLimit) ComputeUnitPrice {
+	if limit == 0 {
+		return ComputeUnitPrice(math.MaxUint64)
+	}
+	price := new(big.Int).Mul(new(big.Int).SetUint64(fee), big.NewInt(1_000_000))
+	price.Div(price, new(big.Int).SetUint64(uint64(limit)))
+	if !price.IsUint64() {
+		return ComputeUnitPrice(math.MaxUint64)
+	}
+	return ComputeUnitPrice(price.Uint64())
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
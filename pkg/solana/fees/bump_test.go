@@ -0,0 +1,88 @@
+package fees
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees/mocks"
+)
+
+// bumps returns the prices of count bumps of the base price
+func bumps(s BumpStrategy, base uint64, count uint) []uint64 {
+	prices := []uint64{base}
+	for i := uint(1); i <= count; i++ {
+		prices = append(prices, s.Price(base, prices[i-1], i))
+	}
+	return prices
+}
+
+func TestBumpStrategies(t *testing.T) {
+	t.Run("double", func(t *testing.T) {
+		assert.Equal(t, []uint64{0, 1, 2, 4, 8}, bumps(DoubleBump{}, 0, 4))
+		assert.Equal(t, []uint64{3, 6, 12}, bumps(DoubleBump{}, 3, 2))
+		assert.Equal(t, uint64(math.MaxUint64), DoubleBump{}.Price(0, math.MaxUint64-1, 1)) // saturates
+		// matches CalculateFee without bounds
+		for i, price := range bumps(DoubleBump{}, 5, 10) {
+			assert.Equal(t, CalculateFee(5, math.MaxUint64, 0, uint(i)), price)
+		}
+	})
+
+	t.Run("linear", func(t *testing.T) {
+		assert.Equal(t, []uint64{0, 10, 20, 30}, bumps(LinearBump{Increment: 10}, 0, 3))
+		assert.Equal(t, []uint64{5, 6, 7}, bumps(LinearBump{}, 5, 2)) // at least 1
+	})
+
+	t.Run("percentage", func(t *testing.T) {
+		assert.Equal(t, []uint64{100, 120, 140, 160}, bumps(PercentageBump{Percent: 20}, 100, 3))
+		assert.Equal(t, []uint64{0, 1, 2}, bumps(PercentageBump{Percent: 20}, 0, 2)) // at least 1
+	})
+
+	t.Run("exponential", func(t *testing.T) {
+		assert.Equal(t, []uint64{100, 150, 225, 338}, bumps(ExponentialBump{Factor: 1.5}, 100, 3))
+		assert.Equal(t, []uint64{0, 1, 2}, bumps(ExponentialBump{Factor: 1.5}, 0, 2)) // at least 1
+		assert.Equal(t, uint64(math.MaxUint64), ExponentialBump{Factor: 2}.Price(0, math.MaxUint64/2+1, 1))
+	})
+
+	t.Run("estimate", func(t *testing.T) {
+		estimator := mocks.NewEstimator(t)
+		estimator.On("BaseComputeUnitPrice").Return(uint64(50)).Once()
+		estimator.On("BaseComputeUnitPrice").Return(uint64(20)).Once()
+		estimator.On("BaseComputeUnitPrice").Return(uint64(80)).Once()
+		// price follows the estimate but never decreases
+		assert.Equal(t, []uint64{10, 50, 50, 80}, bumps(EstimateBump{Estimator: estimator}, 10, 3))
+	})
+}
+
+func TestNewBumpStrategy(t *testing.T) {
+	cfg := config.NewDefault()
+	estimator := mocks.NewEstimator(t)
+	for name, expected := range map[string]BumpStrategy{
+		"double":      DoubleBump{},
+		"linear":      LinearBump{Increment: *cfg.Chain.FeeBumpIncrement},
+		"percentage":  PercentageBump{Percent: *cfg.Chain.FeeBumpPercent},
+		"exponential": ExponentialBump{Factor: *cfg.Chain.FeeBumpFactor},
+		"estimate":    EstimateBump{Estimator: estimator},
+	} {
+		strategy := name
+		cfg.Chain.FeeBumpStrategy = &strategy
+		s, err := NewBumpStrategy(cfg, estimator)
+		require.NoError(t, err)
+		assert.Equal(t, expected, s)
+	}
+
+	unknown := "unknown"
+	cfg.Chain.FeeBumpStrategy = &unknown
+	_, err := NewBumpStrategy(cfg, estimator)
+	require.Error(t, err)
+}
+
+func TestMaxComputeUnitPrice(t *testing.T) {
+	assert.Equal(t, ComputeUnitPrice(1_000), MaxComputeUnitPrice(200, 200_000))
+	assert.Equal(t, ComputeUnitPrice(333_333), MaxComputeUnitPrice(1, 3)) // rounds down
+	assert.Equal(t, uint64(200), CalculatePriorityFee(MaxComputeUnitPrice(200, 200_000), 200_000))
+	assert.Equal(t, ComputeUnitPrice(math.MaxUint64), MaxComputeUnitPrice(1, 0))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
 	"golang.org/x/exp/constraints"
 )
 
@@ -29,6 +30,20 @@ const (
 	// fee for higher transaction prioritization.
 	// note: uses ag_binary.Uint64
 	InstructionSetComputeUnitPrice
+
+	// Set a specific transaction-wide account data size limit, in bytes, is allowed to load.
+	// note: uses ag_binary.Uint32
+	InstructionSetLoadedAccountsDataSizeLimit
+)
+
+const (
+	MinHeapFrame         HeapFrame = 32 * 1024  // default heap frame size of each program
+	MaxHeapFrame         HeapFrame = 256 * 1024 // max heap frame size that can be requested
+	HeapFrameGranularity HeapFrame = 1024       // requested heap frame size must be a multiple of the granularity
+
+	MaxLoadedAccountsDataSizeLimit LoadedAccountsDataSizeLimit = 64 * 1024 * 1024 // default and max loaded accounts data size of a tx
+
+	MaxComputeUnitLimit ComputeUnitLimit = 1_400_000 // max compute unit limit of a tx
 )
 
 var (
@@ -48,6 +63,8 @@ func (ins computeBudgetInstruction) String() (out string) {
 		out = "SetComputeUnitLimit"
 	case InstructionSetComputeUnitPrice:
 		out = "SetComputeUnitPrice"
+	case InstructionSetLoadedAccountsDataSizeLimit:
+		out = "SetLoadedAccountsDataSizeLimit"
 	}
 	return out
 }
@@ -80,6 +97,28 @@ func (val ComputeUnitLimit) Selector() computeBudgetInstruction {
 	return InstructionSetComputeUnitLimit
 }
 
+// HeapFrame is the heap size (bytes) of each program executed by the tx, must be a multiple of HeapFrameGranularity
+type HeapFrame uint32
+
+func (val HeapFrame) Data() ([]byte, error) {
+	return encode(InstructionRequestHeapFrame, val)
+}
+
+func (val HeapFrame) Selector() computeBudgetInstruction {
+	return InstructionRequestHeapFrame
+}
+
+// LoadedAccountsDataSizeLimit is the max size (bytes) of the data of all accounts loaded by the tx
+type LoadedAccountsDataSizeLimit uint32
+
+func (val LoadedAccountsDataSizeLimit) Data() ([]byte, error) {
+	return encode(InstructionSetLoadedAccountsDataSizeLimit, val)
+}
+
+func (val LoadedAccountsDataSizeLimit) Selector() computeBudgetInstruction {
+	return InstructionSetLoadedAccountsDataSizeLimit
+}
+
 // encode combines the identifier and little encoded value into a byte array
 func encode[V constraints.Unsigned](identifier computeBudgetInstruction, val V) ([]byte, error) {
 	buf := new(bytes.Buffer)
@@ -107,6 +146,16 @@ func ParseComputeUnitLimit(data []byte) (ComputeUnitLimit, error) {
 	return ComputeUnitLimit(v), err
 }
 
+func ParseHeapFrame(data []byte) (HeapFrame, error) {
+	v, err := parse(InstructionRequestHeapFrame, data, binary.LittleEndian.Uint32)
+	return HeapFrame(v), err
+}
+
+func ParseLoadedAccountsDataSizeLimit(data []byte) (LoadedAccountsDataSizeLimit, error) {
+	v, err := parse(InstructionSetLoadedAccountsDataSizeLimit, data, binary.LittleEndian.Uint32)
+	return LoadedAccountsDataSizeLimit(v), err
+}
+
 // parse implements tx data parsing for the provided instruction type and specified decoder
 func parse[V constraints.Unsigned](ins computeBudgetInstruction, data []byte, decoder func([]byte) V) (V, error) {
 	if len(data) != (1 + binary.Size(V(0))) { // instruction byte + uintXXX length
@@ -131,6 +180,14 @@ func SetComputeUnitLimit(tx *solana.Transaction, value ComputeUnitLimit) error {
 	return set(tx, value, false) // appends instruction to the end
 }
 
+func SetHeapFrame(tx *solana.Transaction, value HeapFrame) error {
+	return set(tx, value, false) // appends instruction to the end
+}
+
+func SetLoadedAccountsDataSizeLimit(tx *solana.Transaction, value LoadedAccountsDataSizeLimit) error {
+	return set(tx, value, false) // appends instruction to the end
+}
+
 // set adds or modifies instructions for the compute budget program
 func set(tx *solana.Transaction, baseData instruction, appendToFront bool) error {
 	// find ComputeBudget program to accounts if it exists
@@ -146,6 +203,11 @@ func set(tx *solana.Transaction, baseData instruction, appendToFront bool) error
 	}
 	// if it doesn't exist, add to account keys
 	if !exists {
+		// v0 instructions index accounts loaded from lookup tables after the static account keys
+		// lookup indexes are shifted to make room for the new static account key
+		if tx.Message.IsVersioned() {
+			shiftLookupIndexes(&tx.Message)
+		}
 		tx.Message.AccountKeys = append(tx.Message.AccountKeys, ComputeBudgetProgram)
 		programIdx = len(tx.Message.AccountKeys) - 1 // last index of account keys
 
@@ -182,7 +244,12 @@ func set(tx *solana.Transaction, baseData instruction, appendToFront bool) error
 		tx.Message.Instructions[instructionIdx] = instruction
 	} else {
 		if appendToFront {
-			tx.Message.Instructions = append([]solana.CompiledInstruction{instruction}, tx.Message.Instructions...)
+			// durable nonce txs require AdvanceNonceAccount to remain the first instruction
+			front := 0
+			if IsAdvanceNonceInstruction(tx.Message, 0) {
+				front = 1
+			}
+			tx.Message.Instructions = append(tx.Message.Instructions[:front:front], append([]solana.CompiledInstruction{instruction}, tx.Message.Instructions[front:]...)...)
 		} else {
 			tx.Message.Instructions = append(tx.Message.Instructions, instruction)
 		}
@@ -190,3 +257,38 @@ func set(tx *solana.Transaction, baseData instruction, appendToFront bool) error
 
 	return nil
 }
+
+// IsAdvanceNonceInstruction returns if the instruction at index i is a system program AdvanceNonceAccount instruction
+func IsAdvanceNonceInstruction(msg solana.Message, i int) bool {
+	if i >= len(msg.Instructions) {
+		return false
+	}
+	ix := msg.Instructions[i]
+	if int(ix.ProgramIDIndex) >= len(msg.AccountKeys) || !msg.AccountKeys[ix.ProgramIDIndex].Equals(solana.SystemProgramID) {
+		return false
+	}
+	return len(ix.Data) == 4 && binary.LittleEndian.Uint32(ix.Data) == system.Instruction_AdvanceNonceAccount
+}
+
+// shiftLookupIndexes increments instruction account indexes that reference accounts loaded from address lookup tables
+// the message account keys must only contain the static account keys (lookups are not resolved)
+// instructions are copied to avoid modifying instructions shared with other copies of the tx
+func shiftLookupIndexes(msg *solana.Message) {
+	static := uint16(len(msg.AccountKeys)) //nolint:gosec // max value would exceed tx size
+	instructions := make([]solana.CompiledInstruction, len(msg.Instructions))
+	for i, ix := range msg.Instructions {
+		accounts := make([]uint16, len(ix.Accounts))
+		for j, a := range ix.Accounts {
+			accounts[j] = a
+			if a >= static {
+				accounts[j]++
+			}
+		}
+		instructions[i] = solana.CompiledInstruction{
+			ProgramIDIndex: ix.ProgramIDIndex, // programs cannot be loaded from lookup tables
+			Accounts:       accounts,
+			Data:           ix.Data,
+		}
+	}
+	msg.Instructions = instructions
+}
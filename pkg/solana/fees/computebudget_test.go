@@ -23,6 +23,78 @@ func TestSet(t *testing.T) {
 			return ComputeUnitLimit(v)
 		}, SetComputeUnitLimit, false)
 	})
+	t.Run("HeapFrame", func(t *testing.T) {
+		t.Parallel()
+		testSet(t, func(v uint) HeapFrame {
+			return HeapFrame(v)
+		}, SetHeapFrame, false)
+	})
+	t.Run("LoadedAccountsDataSizeLimit", func(t *testing.T) {
+		t.Parallel()
+		testSet(t, func(v uint) LoadedAccountsDataSizeLimit {
+			return LoadedAccountsDataSizeLimit(v)
+		}, SetLoadedAccountsDataSizeLimit, false)
+	})
+	t.Run("ComputeUnitPrice_durableNonce", func(t *testing.T) {
+		t.Parallel()
+		key, err := solana.NewRandomPrivateKey()
+		require.NoError(t, err)
+		nonceAccount, err := solana.NewRandomPrivateKey()
+		require.NoError(t, err)
+
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			system.NewAdvanceNonceAccountInstruction(nonceAccount.PublicKey(), solana.SysVarRecentBlockHashesPubkey, key.PublicKey()).Build(),
+			system.NewTransferInstruction(0, key.PublicKey(), key.PublicKey()).Build(),
+		}, solana.Hash{})
+		require.NoError(t, err)
+		require.True(t, IsAdvanceNonceInstruction(tx.Message, 0))
+		require.False(t, IsAdvanceNonceInstruction(tx.Message, 1))
+
+		// AdvanceNonceAccount remains the first instruction
+		require.NoError(t, SetComputeUnitPrice(tx, 1))
+		require.Len(t, tx.Message.Instructions, 3)
+		assert.True(t, IsAdvanceNonceInstruction(tx.Message, 0))
+		assert.Equal(t, ComputeBudgetProgram, tx.Message.AccountKeys[tx.Message.Instructions[1].ProgramIDIndex])
+	})
+	t.Run("v0_lookupTables", func(t *testing.T) {
+		t.Parallel()
+		payer := solana.PublicKey{1}
+		receiver := solana.PublicKey{2}
+		table := solana.PublicKey{3}
+
+		tx, err := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(0, payer, receiver).Build(),
+		}, solana.Hash{}, solana.TransactionPayer(payer), solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+			table: {receiver},
+		}))
+		require.NoError(t, err)
+		require.True(t, tx.Message.IsVersioned())
+		base := *tx
+
+		// resolve instruction accounts (receiver is loaded from the lookup table)
+		accounts := func(tx *solana.Transaction, i int) []solana.PublicKey {
+			keys, err := tx.Message.GetAllKeys()
+			require.NoError(t, err)
+			var out []solana.PublicKey
+			for _, a := range tx.Message.Instructions[i].Accounts {
+				out = append(out, keys[a])
+			}
+			return out
+		}
+		require.Equal(t, []solana.PublicKey{payer, receiver}, accounts(tx, 0))
+
+		require.NoError(t, SetComputeUnitPrice(tx, 1))
+		require.NoError(t, SetComputeUnitLimit(tx, 1))
+		require.Len(t, tx.Message.Instructions, 3)
+		assert.Equal(t, ComputeBudgetProgram, tx.Message.AccountKeys[tx.Message.Instructions[0].ProgramIDIndex])
+		assert.Equal(t, []solana.PublicKey{payer, receiver}, accounts(tx, 1))
+		assert.Equal(t, ComputeBudgetProgram, tx.Message.AccountKeys[tx.Message.Instructions[2].ProgramIDIndex])
+
+		// tx can be serialized + original instructions are not modified
+		_, err = tx.Message.MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, []solana.PublicKey{payer, receiver}, accounts(&base, 0))
+	})
 }
 
 func testSet[V instruction](t *testing.T, builder func(uint) V, setter func(*solana.Transaction, V) error, expectFirstInstruction bool) {
@@ -151,6 +223,18 @@ func TestParse(t *testing.T) {
 			return ComputeUnitLimit(v)
 		}, ParseComputeUnitLimit)
 	})
+	t.Run("HeapFrame", func(t *testing.T) {
+		t.Parallel()
+		testParse(t, func(v uint) HeapFrame {
+			return HeapFrame(v)
+		}, ParseHeapFrame)
+	})
+	t.Run("LoadedAccountsDataSizeLimit", func(t *testing.T) {
+		t.Parallel()
+		testParse(t, func(v uint) LoadedAccountsDataSizeLimit {
+			return LoadedAccountsDataSizeLimit(v)
+		}, ParseLoadedAccountsDataSizeLimit)
+	})
 }
 
 func testParse[V instruction](t *testing.T, builder func(uint) V, parser func([]byte) (V, error)) {
@@ -168,7 +252,7 @@ func testParse[V instruction](t *testing.T, builder func(uint) V, parser func([]
 	assert.ErrorContains(t, err, "invalid length")
 
 	invalidData := data
-	invalidData[0] = uint8(InstructionRequestHeapFrame)
+	invalidData[0] = uint8(InstructionRequestUnitsDeprecated)
 	_, err = parser(invalidData)
 	assert.ErrorContains(t, err, fmt.Sprintf("not %s identifier", builder(0).Selector()))
 }
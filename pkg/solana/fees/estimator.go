@@ -1,6 +1,10 @@
 package fees
 
-import "context"
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+)
 
 //go:generate mockery --name Estimator --output ./mocks/
 type Estimator interface {
@@ -8,3 +12,10 @@ type Estimator interface {
 	Close() error
 	BaseComputeUnitPrice() uint64
 }
+
+// TxEstimator is implemented by estimators that can estimate the compute unit price of a specific tx
+// (i.e. from the fees paid by recent txs writing to the same accounts)
+type TxEstimator interface {
+	Estimator
+	TxComputeUnitPrice(ctx context.Context, tx *solana.Transaction) (uint64, error)
+}
@@ -0,0 +1,29 @@
+package fees
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// block history estimator
+	promBlockHistoryPrice = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solana_fee_estimator_block_history_price",
+		Help: "Compute unit price (micro-lamports) calculated by the block history estimator, before min/max bounds",
+	}, []string{"chainID"})
+	promBlockHistoryDistribution = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solana_fee_estimator_block_history_distribution",
+		Help: "Compute unit price (micro-lamports) percentiles of the txs in the block history window",
+	}, []string{"chainID", "percentile"})
+	promBlockHistorySamples = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solana_fee_estimator_block_history_samples",
+		Help: "Number of txs in the block history window used to calculate the price",
+	}, []string{"chainID"})
+	promBlockHistoryBlocks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solana_fee_estimator_block_history_blocks",
+		Help: "Number of blocks in the block history window",
+	}, []string{"chainID"})
+)
+
+// distributionPercentiles are the percentiles of the block history window reported as metrics
+var distributionPercentiles = []uint32{0, 10, 25, 50, 75, 90, 100}
@@ -0,0 +1,136 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/services"
+	"github.com/goplugin/plugin-common/pkg/utils"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+var _ TxEstimator = &recentFeesEstimator{}
+
+type recentFeesEstimator struct {
+	starter services.StateMachine
+	chStop  services.StopChan
+	done    sync.WaitGroup
+
+	client *utils.LazyLoad[client.ReaderWriter]
+	cfg    config.Config
+	lgr    logger.Logger
+
+	price uint64
+	lock  sync.RWMutex
+}
+
+// NewRecentFeesEstimator creates a new fee estimator that uses a percentile of the prioritization fees of recent slots (getRecentPrioritizationFees).
+// txs are estimated from the fees paid to write to their writable accounts (signers excluded), BaseComputeUnitPrice is polled without accounts (global fees)
+func NewRecentFeesEstimator(c *utils.LazyLoad[client.ReaderWriter], cfg config.Config, lgr logger.Logger) (*recentFeesEstimator, error) {
+	return &recentFeesEstimator{
+		chStop: make(chan struct{}),
+		client: c,
+		cfg:    cfg,
+		lgr:    lgr,
+		price:  cfg.ComputeUnitPriceDefault(), // use default value
+	}, nil
+}
+
+func (rfe *recentFeesEstimator) Start(ctx context.Context) error {
+	return rfe.starter.StartOnce("solana_recentFeesEstimator", func() error {
+		rfe.done.Add(1)
+		go rfe.run()
+		rfe.lgr.Debugw("RecentFeesEstimator: started")
+		return nil
+	})
+}
+
+func (rfe *recentFeesEstimator) run() {
+	defer rfe.done.Done()
+	ctx, cancel := rfe.chStop.NewCtx()
+	defer cancel()
+
+	ticker := services.NewTicker(rfe.cfg.RecentFeesPollPeriod())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			price, err := rfe.calculatePrice(ctx, nil)
+			if err != nil {
+				rfe.lgr.Error(fmt.Errorf("RecentFeesEstimator failed to fetch price: %w", err))
+				continue
+			}
+			rfe.lock.Lock()
+			rfe.price = price
+			rfe.lock.Unlock()
+			rfe.lgr.Debugw("RecentFeesEstimator: updated", "computeUnitPrice", price)
+		}
+	}
+}
+
+func (rfe *recentFeesEstimator) Close() error {
+	close(rfe.chStop)
+	rfe.done.Wait()
+	rfe.lgr.Debugw("RecentFeesEstimator: stopped")
+	return nil
+}
+
+func (rfe *recentFeesEstimator) BaseComputeUnitPrice() uint64 {
+	return rfe.bound(rfe.readRawPrice())
+}
+
+// TxComputeUnitPrice returns the price to land a tx writing to the writable accounts of the tx, the global price if only signers are written
+func (rfe *recentFeesEstimator) TxComputeUnitPrice(ctx context.Context, tx *solana.Transaction) (uint64, error) {
+	accounts, err := writableAccounts(tx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get writable accounts in recentFeesEstimator.TxComputeUnitPrice: %w", err)
+	}
+	price, err := rfe.calculatePrice(ctx, accounts)
+	if err != nil {
+		return 0, err
+	}
+	return rfe.bound(price), nil
+}
+
+func (rfe *recentFeesEstimator) readRawPrice() uint64 {
+	rfe.lock.RLock()
+	defer rfe.lock.RUnlock()
+	return rfe.price
+}
+
+// bound returns the price within the configured min and max price
+func (rfe *recentFeesEstimator) bound(price uint64) uint64 {
+	return min(max(price, rfe.cfg.ComputeUnitPriceMin()), rfe.cfg.ComputeUnitPriceMax())
+}
+
+func (rfe *recentFeesEstimator) calculatePrice(ctx context.Context, accounts solana.PublicKeySlice) (uint64, error) {
+	// fetch client
+	c, err := rfe.client.Get()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client in recentFeesEstimator.calculatePrice: %w", err)
+	}
+
+	// fees of recent slots (one entry per slot)
+	res, err := c.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent fees in recentFeesEstimator.calculatePrice: %w", err)
+	}
+
+	prices := make([]uint64, len(res))
+	for i, r := range res {
+		prices[i] = r.PrioritizationFee
+	}
+	price, err := percentile(prices, rfe.cfg.FeeEstimatorPercentile())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find percentile in recentFeesEstimator.calculatePrice: %w", err)
+	}
+	return price, nil
+}
@@ -0,0 +1,98 @@
+package fees
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmock "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	cfgmock "github.com/goplugin/plugin-solana/pkg/solana/config/mocks"
+)
+
+func recentFees(prices ...uint64) []rpc.PriorizationFeeResult {
+	res := make([]rpc.PriorizationFeeResult, len(prices))
+	for i, p := range prices {
+		res[i] = rpc.PriorizationFeeResult{Slot: uint64(i), PrioritizationFee: p}
+	}
+	return res
+}
+
+func TestRecentFeesEstimator(t *testing.T) {
+	min := uint64(10)
+	max := uint64(1000)
+
+	rw := clientmock.NewReaderWriter(t)
+	rwLoader := utils.NewLazyLoad(func() (client.ReaderWriter, error) {
+		return rw, nil
+	})
+	cfg := cfgmock.NewConfig(t)
+	cfg.On("ComputeUnitPriceDefault").Return(uint64(100))
+	cfg.On("ComputeUnitPriceMin").Return(min)
+	cfg.On("ComputeUnitPriceMax").Return(max)
+	cfg.On("RecentFeesPollPeriod").Return(100 * time.Millisecond)
+	cfg.On("FeeEstimatorPercentile").Return(uint32(75))
+	lgr, logs := logger.TestObserved(t, zapcore.DebugLevel)
+	ctx := tests.Context(t)
+
+	estimator, err := NewRecentFeesEstimator(rwLoader, cfg, lgr)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), estimator.BaseComputeUnitPrice())
+
+	// global price is polled without accounts
+	rw.On("GetRecentPrioritizationFees", mock.Anything, solana.PublicKeySlice(nil)).Return(recentFees(0, 200, 400, 300), nil).Once()
+	require.NoError(t, estimator.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, estimator.Close()) })
+	tests.AssertLogEventually(t, logs, "RecentFeesEstimator: updated")
+	assert.Equal(t, uint64(325), estimator.BaseComputeUnitPrice())
+
+	// failed rpc call does not change the price
+	rw.On("GetRecentPrioritizationFees", mock.Anything, solana.PublicKeySlice(nil)).Return(nil, fmt.Errorf("fail rpc call")).Once()
+	tests.AssertLogEventually(t, logs, "failed to get recent fees")
+	assert.Equal(t, uint64(325), estimator.BaseComputeUnitPrice())
+
+	// no fees does not change the price
+	rw.On("GetRecentPrioritizationFees", mock.Anything, solana.PublicKeySlice(nil)).Return(recentFees(), nil)
+	tests.AssertLogEventually(t, logs, "failed to find percentile")
+	assert.Equal(t, uint64(325), estimator.BaseComputeUnitPrice())
+
+	// txs are estimated from the fees of their writable accounts, the fee payer is excluded
+	feePayer, writable, readonly := solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(writable).WRITE(), solana.Meta(readonly)}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	accounts := mock.MatchedBy(func(accounts solana.PublicKeySlice) bool {
+		return len(accounts) == 1 && accounts.Has(writable)
+	})
+
+	rw.On("GetRecentPrioritizationFees", mock.Anything, accounts).Return(recentFees(500, 700, 600, 0), nil).Once()
+	price, err := estimator.TxComputeUnitPrice(ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(625), price)
+
+	// min/max gates
+	rw.On("GetRecentPrioritizationFees", mock.Anything, accounts).Return(recentFees(5_000), nil).Once()
+	price, err = estimator.TxComputeUnitPrice(ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, max, price)
+	rw.On("GetRecentPrioritizationFees", mock.Anything, accounts).Return(recentFees(0), nil).Once()
+	price, err = estimator.TxComputeUnitPrice(ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, min, price)
+
+	rw.On("GetRecentPrioritizationFees", mock.Anything, accounts).Return(nil, fmt.Errorf("fail rpc call")).Once()
+	_, err = estimator.TxComputeUnitPrice(ctx, tx)
+	require.Error(t, err)
+}
@@ -1,12 +1,34 @@
 package fees
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// LamportsPerSignature is the base fee charged for each signature required by a transaction
+const LamportsPerSignature uint64 = 5_000
+
+// CalculatePriorityFee returns the priority fee in lamports for a compute unit price (micro-lamports) and compute unit limit
+func CalculatePriorityFee(price ComputeUnitPrice, limit ComputeUnitLimit) uint64 {
+	// micro-lamports -> lamports, rounded up
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(uint64(price)), new(big.Int).SetUint64(uint64(limit)))
+	fee.Add(fee, big.NewInt(999_999))
+	fee.Div(fee, big.NewInt(1_000_000))
+	if !fee.IsUint64() {
+		return math.MaxUint64
+	}
+	return fee.Uint64()
+}
+
 // returns new fee based on number of times bumped
 func CalculateFee(base, max, min uint64, count uint) uint64 {
 	amount := base
@@ -36,8 +58,11 @@ func CalculateFee(base, max, min uint64, count uint) uint64 {
 }
 
 type BlockData struct {
-	Fees   []uint64           // total fee
-	Prices []ComputeUnitPrice // price per unit
+	Fees                         []uint64                      // total fee
+	Prices                       []ComputeUnitPrice            // price per unit
+	ComputeUnits                 []uint64                      // compute units consumed (0 if not reported in the tx logs)
+	HeapFrames                   []HeapFrame                   // requested heap frame (0 if not requested)
+	LoadedAccountsDataSizeLimits []LoadedAccountsDataSizeLimit // loaded accounts data size limit (0 if not set)
 }
 
 // ParseBlock parses the fee calculations from all the transactions within a block
@@ -69,21 +94,120 @@ func ParseBlock(res *rpc.GetBlockResult) (out BlockData, err error) {
 		}
 
 		var price ComputeUnitPrice // default 0
+		var heapFrame HeapFrame
+		var dataSizeLimit LoadedAccountsDataSizeLimit
 		for _, instruction := range baseTx.Message.Instructions {
 			// find instructions for compute budget program
+			// only one instruction of each compute budget instruction type is allowed
+			// err returned if not the parsed instruction type
 			if baseTx.Message.AccountKeys[instruction.ProgramIDIndex] == ComputeBudgetProgram {
-				parsed, parseErr := ParseComputeUnitPrice(instruction.Data)
-				// if compute unit price found, break instruction loop
-				// only one compute unit price tx is allowed
-				// err returned if not SetComputeUnitPrice instruction
-				if parseErr == nil {
+				if parsed, parseErr := ParseComputeUnitPrice(instruction.Data); parseErr == nil {
 					price = parsed
-					break
+				} else if parsed, parseErr := ParseHeapFrame(instruction.Data); parseErr == nil {
+					heapFrame = parsed
+				} else if parsed, parseErr := ParseLoadedAccountsDataSizeLimit(instruction.Data); parseErr == nil {
+					dataSizeLimit = parsed
 				}
 			}
 		}
 		out.Prices = append(out.Prices, price)
 		out.Fees = append(out.Fees, tx.Meta.Fee)
+		out.ComputeUnits = append(out.ComputeUnits, consumedComputeUnits(tx.Meta.LogMessages))
+		out.HeapFrames = append(out.HeapFrames, heapFrame)
+		out.LoadedAccountsDataSizeLimits = append(out.LoadedAccountsDataSizeLimits, dataSizeLimit)
 	}
 	return out, nil
 }
+
+// consumedComputeUnits returns the compute units consumed by the top level instructions of a tx from its logs
+// builtin programs (i.e. system, compute budget) do not log consumed compute units
+func consumedComputeUnits(logs []string) (total uint64) {
+	depth := 0
+	for _, log := range logs {
+		fields := strings.Fields(log)
+		// skip logs of programs (Program log:, Program data:, Program return:)
+		if len(fields) < 3 || fields[0] != "Program" || strings.HasSuffix(fields[1], ":") {
+			continue
+		}
+		switch {
+		case fields[2] == "invoke":
+			depth++
+		case fields[2] == "success" || fields[2] == "failed:":
+			depth = max(depth-1, 0)
+		case fields[2] == "consumed" && depth == 1 && len(fields) > 3:
+			if units, err := strconv.ParseUint(fields[3], 10, 64); err == nil {
+				total += units
+			}
+		}
+	}
+	return total
+}
+
+// percentile returns the percentile (0-100) of the values, interpolated between the closest ranks (percentile 50 is the median)
+func percentile(values []uint64, p uint32) (uint64, error) {
+	if len(values) == 0 {
+		return 0, errors.New("no values to calculate percentile")
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	// rank = p/100 * (n-1), interpolated between floor(rank) and ceil(rank)
+	scaled := uint64(min(p, 100)) * uint64(len(sorted)-1)
+	lower := scaled / 100
+	if lower == uint64(len(sorted)-1) {
+		return sorted[lower], nil
+	}
+	diff := new(big.Int).SetUint64(sorted[lower+1] - sorted[lower])
+	diff.Mul(diff, new(big.Int).SetUint64(scaled%100))
+	diff.Div(diff, big.NewInt(100))
+	return sorted[lower] + diff.Uint64(), nil
+}
+
+// weightedPercentile returns the smallest value where the cumulative weight of the sorted values reaches the percentile (0-100) of the total weight.
+// values with a weight of 0 are ignored
+func weightedPercentile(values, weights []uint64, p uint32) (uint64, error) {
+	if len(values) != len(weights) {
+		return 0, fmt.Errorf("mismatched values (%d) and weights (%d)", len(values), len(weights))
+	}
+	indexes := make([]int, 0, len(values))
+	total := new(big.Int)
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		indexes = append(indexes, i)
+		total.Add(total, new(big.Int).SetUint64(w))
+	}
+	if len(indexes) == 0 {
+		return 0, errors.New("no weighted values to calculate percentile")
+	}
+	slices.SortFunc(indexes, func(a, b int) int { return cmp.Compare(values[a], values[b]) })
+
+	// target = ceil(total * p / 100), at least the first value
+	target := total.Mul(total, big.NewInt(int64(min(p, 100))))
+	target.Add(target, big.NewInt(99))
+	target.Div(target, big.NewInt(100))
+	cumulative := new(big.Int)
+	for _, i := range indexes {
+		cumulative.Add(cumulative, new(big.Int).SetUint64(weights[i]))
+		if cumulative.Cmp(target) >= 0 {
+			return values[i], nil
+		}
+	}
+	return values[indexes[len(indexes)-1]], nil
+}
+
+// writableAccounts returns the static accounts the tx locks as writable, excluding the signers.
+// the fee payer and other signers are written by every tx they sign, their fees include the bumped prices of our own txs
+func writableAccounts(tx *solana.Transaction) (solana.PublicKeySlice, error) {
+	var accounts solana.PublicKeySlice
+	for _, k := range tx.Message.AccountKeys[min(int(tx.Message.Header.NumRequiredSignatures), len(tx.Message.AccountKeys)):] {
+		writable, err := tx.Message.IsWritable(k)
+		if err != nil {
+			return nil, err
+		}
+		if writable {
+			accounts = append(accounts, k)
+		}
+	}
+	return accounts, nil
+}
@@ -4,13 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"testing"
 
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func TestCalculatePriorityFee(t *testing.T) {
+	assert.Equal(t, uint64(0), CalculatePriorityFee(0, 200_000))
+	assert.Equal(t, uint64(1), CalculatePriorityFee(1, 200_000))       // rounds up to the nearest lamport
+	assert.Equal(t, uint64(200), CalculatePriorityFee(1_000, 200_000)) // 1_000 micro-lamports * 200_000 units
+	assert.Equal(t, uint64(1_000_000), CalculatePriorityFee(1_000_000, 1_000_000))
+}
+
 func TestCalculateFee(t *testing.T) {
 	inputs := []struct {
 		base, max, min uint64
@@ -71,3 +80,97 @@ func TestParseBlock(t *testing.T) {
 	})
 	assert.Error(t, err)
 }
+
+func TestPercentile(t *testing.T) {
+	values := []uint64{50, 10, 40, 20, 30}
+	for p, expected := range map[uint32]uint64{0: 10, 10: 14, 25: 20, 50: 30, 90: 46, 100: 50, 200: 50} {
+		v, err := percentile(values, p)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v, "percentile %d", p)
+	}
+	assert.Equal(t, []uint64{50, 10, 40, 20, 30}, values, "values should not be sorted in place")
+
+	// median of an even number of values
+	v, err := percentile([]uint64{10, 20, 30, 41}, 50)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(25), v)
+
+	_, err = percentile(nil, 50)
+	require.Error(t, err)
+}
+
+func TestWeightedPercentile(t *testing.T) {
+	values := []uint64{30, 10, 20, 40}
+	weights := []uint64{1, 1, 0, 8}
+	for p, expected := range map[uint32]uint64{0: 10, 10: 10, 11: 30, 20: 30, 21: 40, 100: 40} {
+		v, err := weightedPercentile(values, weights, p)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v, "percentile %d", p)
+	}
+
+	_, err := weightedPercentile(values, []uint64{1}, 50)
+	require.Error(t, err)
+	_, err = weightedPercentile(values, []uint64{0, 0, 0, 0}, 50)
+	require.Error(t, err)
+}
+
+func TestConsumedComputeUnits(t *testing.T) {
+	assert.Equal(t, uint64(0), consumedComputeUnits(nil))
+	assert.Equal(t, uint64(2_150), consumedComputeUnits([]string{
+		"Program ComputeBudget111111111111111111111111111111 invoke [1]",
+		"Program ComputeBudget111111111111111111111111111111 success",
+		"Program A invoke [1]",
+		"Program B invoke [2]",
+		"Program B consumed 500 of 199000 compute units",
+		"Program B success",
+		"Program log: consumed 1 of 2",
+		"Program A consumed 2000 of 200000 compute units",
+		"Program A success",
+		"Program C invoke [1]",
+		"Program C consumed 150 of 198000 compute units",
+		"Program C failed: custom program error: 0x1",
+	}))
+}
+
+func TestWritableAccounts(t *testing.T) {
+	feePayer, signer, writable, readonly := solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}, solana.PublicKey{4}
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(signer).WRITE().SIGNER(), solana.Meta(writable).WRITE(), solana.Meta(readonly)}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+
+	// the fee payer and writable signers are excluded
+	accounts, err := writableAccounts(tx)
+	require.NoError(t, err)
+	assert.Equal(t, solana.PublicKeySlice{writable}, accounts)
+}
+
+func TestParseBlock_ComputeBudget(t *testing.T) {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(solana.PublicKey{1}))
+	require.NoError(t, err)
+	unset := *tx
+	unset.Message.Instructions = slices.Clone(tx.Message.Instructions)
+	require.NoError(t, SetComputeUnitPrice(tx, 100))
+	require.NoError(t, SetComputeUnitLimit(tx, 1_000))
+	require.NoError(t, SetHeapFrame(tx, 64*1024))
+	require.NoError(t, SetLoadedAccountsDataSizeLimit(tx, 10_000))
+
+	block := &rpc.GetBlockResult{}
+	for _, blockTx := range []*solana.Transaction{tx, &unset} {
+		data, err := blockTx.MarshalBinary()
+		require.NoError(t, err)
+		block.Transactions = append(block.Transactions, rpc.TransactionWithMeta{
+			Transaction: rpc.DataBytesOrJSONFromBytes(data),
+			Meta:        &rpc.TransactionMeta{Fee: 5_000},
+		})
+	}
+
+	out, err := ParseBlock(block)
+	require.NoError(t, err)
+	assert.Equal(t, []ComputeUnitPrice{100, 0}, out.Prices)
+	assert.Equal(t, []HeapFrame{64 * 1024, 0}, out.HeapFrames)
+	assert.Equal(t, []LoadedAccountsDataSizeLimit{10_000, 0}, out.LoadedAccountsDataSizeLimits)
+	assert.Equal(t, []uint64{5_000, 5_000}, out.Fees)
+}
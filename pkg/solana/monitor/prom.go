@@ -23,6 +23,10 @@ var (
 		prometheus.GaugeOpts{Name: "solana_client_latency_ms", Help: "Solana client request latency"},
 		[]string{"request", "url"},
 	)
+	promClientSendTxErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{Name: "solana_client_send_tx_errors", Help: "Solana RPC send tx errors by error class"},
+		[]string{"chainID", "class"},
+	)
 )
 
 func (b *balanceMonitor) updateProm(acc solana.PublicKey, lamports uint64) {
@@ -51,3 +55,10 @@ func GetClientLatency(request, url string) (prometheus.Gauge, error) {
 		"url":     url,
 	})
 }
+
+func IncSendTxError(chainID, class string) {
+	promClientSendTxErrors.With(prometheus.Labels{
+		"chainID": chainID,
+		"class":   class,
+	}).Inc()
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/gagliardetto/solana-go"
 
@@ -17,14 +18,22 @@ import (
 	relaytypes "github.com/goplugin/plugin-common/pkg/types"
 	"github.com/goplugin/plugin-common/pkg/types/core"
 
+	"github.com/goplugin/plugin-solana/pkg/solana/chainreader"
+	"github.com/goplugin/plugin-solana/pkg/solana/chainwriter"
 	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 	"github.com/goplugin/plugin-solana/pkg/solana/txm"
 )
 
 var _ TxManager = (*txm.Txm)(nil)
 
 type TxManager interface {
-	Enqueue(ctx context.Context, accountID string, msg *solana.Transaction, txCfgs ...txm.SetTxConfig) error
+	Enqueue(ctx context.Context, accountID string, msg *solana.Transaction, txID *string, txCfgs ...txm.SetTxConfig) error
+	GetTransactionStatus(ctx context.Context, txID string) (txm.TxStatus, error)
+	FeeEstimator() fees.Estimator
+	RegisterProgramErrors(program solana.PublicKey, errs []codec.IdlErrorCode)
 }
 
 var _ relaytypes.Relayer = &Relayer{} //nolint:staticcheck
@@ -34,14 +43,19 @@ type Relayer struct {
 	lggr   logger.Logger
 	chain  Chain
 	stopCh services.StopChan
+
+	// contract readers created by NewContractReader and not yet closed, closed with the relayer
+	readersLock sync.Mutex
+	readers     map[*chainreader.SolanaChainReaderService]struct{}
 }
 
 // Note: constructed in core
 func NewRelayer(lggr logger.Logger, chain Chain, _ core.CapabilitiesRegistry) *Relayer {
 	return &Relayer{
-		lggr:   logger.Named(lggr, "Relayer"),
-		chain:  chain,
-		stopCh: make(services.StopChan),
+		lggr:    logger.Named(lggr, "Relayer"),
+		chain:   chain,
+		stopCh:  make(services.StopChan),
+		readers: map[*chainreader.SolanaChainReaderService]struct{}{},
 	}
 }
 
@@ -64,7 +78,7 @@ func (r *Relayer) Start(ctx context.Context) error {
 func (r *Relayer) Close() error {
 	return r.StopOnce("SolanaRelayer", func() error {
 		close(r.stopCh)
-		return r.chain.Close()
+		return errors.Join(r.closeContractReaders(), r.chain.Close())
 	})
 }
 
@@ -121,12 +135,71 @@ func (r *Relayer) NewConfigProvider(ctx context.Context, args relaytypes.RelayAr
 	return configWatcher, err
 }
 
-func (r *Relayer) NewChainWriter(_ context.Context, _ []byte) (relaytypes.ChainWriter, error) {
-	return nil, errors.New("chain writer is not supported for solana")
+func (r *Relayer) NewChainWriter(_ context.Context, chainWriterConfig []byte) (relaytypes.ChainWriter, error) {
+	var cfg config.ChainWriter
+	if err := json.Unmarshal(chainWriterConfig, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain writer config: %w", err)
+	}
+
+	return chainwriter.NewChainWriterService(r.lggr, r.chain.Reader, r.chain.TxManager(), cfg)
+}
+
+// NewContractReader creates a contract reader of the config. The caller starts the reader, readers the
+// caller has not closed are closed with the relayer.
+func (r *Relayer) NewContractReader(_ context.Context, chainReaderConfig []byte) (relaytypes.ContractReader, error) {
+	var cfg config.ChainReader
+	if err := json.Unmarshal(chainReaderConfig, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain reader config: %w", err)
+	}
+
+	reader, err := r.newChainReaderService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.readersLock.Lock()
+	defer r.readersLock.Unlock()
+	r.readers[reader] = struct{}{}
+	return &contractReader{SolanaChainReaderService: reader, untrack: func() {
+		r.readersLock.Lock()
+		defer r.readersLock.Unlock()
+		delete(r.readers, reader)
+	}}, nil
+}
+
+// closeContractReaders closes the started contract readers the caller has not closed
+func (r *Relayer) closeContractReaders() (err error) {
+	r.readersLock.Lock()
+	readers := r.readers
+	r.readers = map[*chainreader.SolanaChainReaderService]struct{}{}
+	r.readersLock.Unlock()
+
+	for reader := range readers {
+		if reader.Ready() == nil {
+			err = errors.Join(err, reader.Close())
+		}
+	}
+	return err
+}
+
+// contractReader is a contract reader started by the caller and closed by the caller or with the relayer
+type contractReader struct {
+	*chainreader.SolanaChainReaderService
+	untrack func()
 }
 
-func (r *Relayer) NewContractReader(ctx context.Context, _ []byte) (relaytypes.ContractReader, error) {
-	return nil, errors.New("contract reader is not supported for solana")
+func (c *contractReader) Close() error {
+	defer c.untrack()
+	return c.SolanaChainReaderService.Close()
+}
+
+// newChainReaderService creates a chain reader that reads accounts with the (multinode) chain client
+func (r *Relayer) newChainReaderService(cfg config.ChainReader) (*chainreader.SolanaChainReaderService, error) {
+	dataReader := chainreader.NewAccountDataReader(func() (client.AccountReader, error) {
+		return r.chain.Reader()
+	})
+
+	return chainreader.NewChainReaderService(r.lggr, dataReader, cfg)
 }
 
 func (r *Relayer) NewMedianProvider(ctx context.Context, rargs relaytypes.RelayArgs, pargs relaytypes.PluginArgs) (relaytypes.MedianProvider, error) {
@@ -153,11 +226,20 @@ func (r *Relayer) NewMedianProvider(ctx context.Context, rargs relaytypes.RelayA
 		return nil, fmt.Errorf("error on 'solana.PublicKeyFromBase58' for 'spec.RelayConfig.TransmissionsID: %w", err)
 	}
 
+	// optional contract reader for the median plugin
+	var contractReader *chainreader.SolanaChainReaderService
+	if relayConfig.ChainReader != nil {
+		if contractReader, err = r.newChainReaderService(*relayConfig.ChainReader); err != nil {
+			return nil, fmt.Errorf("failed to create contract reader: %w", err)
+		}
+	}
+
 	cfg := configWatcher.chain.Config()
 	transmissionsCache := NewTransmissionsCache(transmissionsID, relayConfig.ChainID, cfg, configWatcher.reader, r.lggr)
 	return &medianProvider{
 		configProvider:     configWatcher,
 		transmissionsCache: transmissionsCache,
+		contractReader:     contractReader,
 		reportCodec:        ReportCodec{},
 		contract: &MedianContract{
 			stateCache:         configWatcher.stateCache,
@@ -276,25 +358,37 @@ type medianProvider struct {
 	reportCodec        median.ReportCodec
 	contract           median.MedianContract
 	transmitter        types.ContractTransmitter
+	contractReader     *chainreader.SolanaChainReaderService // nil if not configured
 }
 
 func (p *medianProvider) Name() string {
 	return p.stateCache.Name()
 }
 
-// start both cache services
+// start both cache services and the contract reader
 func (p *medianProvider) Start(ctx context.Context) error {
 	return p.StartOnce("SolanaMedianProvider", func() error {
 		if err := p.configProvider.stateCache.Start(ctx); err != nil {
 			return err
 		}
-		return p.transmissionsCache.Start(ctx)
+		if err := p.transmissionsCache.Start(ctx); err != nil {
+			return err
+		}
+		if p.contractReader != nil {
+			return p.contractReader.Start(ctx)
+		}
+		return nil
 	})
 }
 
-// close both cache services
+// close both cache services and the contract reader
 func (p *medianProvider) Close() error {
 	return p.StopOnce("SolanaMedianProvider", func() error {
+		if p.contractReader != nil {
+			if err := p.contractReader.Close(); err != nil {
+				return err
+			}
+		}
 		if err := p.configProvider.stateCache.Close(); err != nil {
 			return err
 		}
@@ -319,7 +413,11 @@ func (p *medianProvider) OnchainConfigCodec() median.OnchainConfigCodec {
 }
 
 func (p *medianProvider) ContractReader() relaytypes.ContractReader {
-	return nil
+	if p.contractReader == nil {
+		// avoid returning a non-nil interface holding a nil pointer
+		return nil
+	}
+	return p.contractReader
 }
 
 func (p *medianProvider) Codec() relaytypes.Codec {
@@ -0,0 +1,108 @@
+package solana
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	relaytypes "github.com/goplugin/plugin-common/pkg/types"
+	"github.com/goplugin/plugin-common/pkg/types/query/primitives"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+)
+
+// readerChain only implements the Reader of the Chain interface
+type readerChain struct {
+	Chain
+	reader client.Reader
+}
+
+func (c *readerChain) Reader() (client.Reader, error) {
+	return c.reader, nil
+}
+
+func (c *readerChain) Start(context.Context) error { return nil }
+
+func (c *readerChain) Close() error { return nil }
+
+func TestRelayer_NewContractReader(t *testing.T) {
+	ctx := tests.Context(t)
+	account := solana.PublicKey{1}
+
+	// account data: discriminator + u64 value
+	discriminator, err := codec.NewDiscriminator("DataAccount").Encode(nil, nil)
+	require.NoError(t, err)
+	data := binary.LittleEndian.AppendUint64(discriminator, 42)
+
+	rw := clientmocks.NewReaderWriter(t)
+	rw.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(&rpc.GetAccountInfoResult{
+		Value: &rpc.Account{Data: rpc.DataBytesOrJSONFromBytes(data)},
+	}, nil)
+	relayer := NewRelayer(logger.Test(t), &readerChain{reader: rw}, nil)
+
+	_, err = relayer.NewContractReader(ctx, []byte("invalid"))
+	require.Error(t, err)
+
+	idl := `{"accounts": [{"name": "DataAccount", "type": {"kind": "struct", "fields": [{"name": "value", "type": "u64"}]}}]}`
+	cfg := fmt.Sprintf(`{"namespaces": {"Contract": {"methods": {"GetValue": {
+		"anchorIDL": %q,
+		"encoding": "borsh",
+		"procedures": [{"idlAccount": "DataAccount"}]
+	}}}}}`, idl)
+	reader, err := relayer.NewContractReader(ctx, []byte(cfg))
+	require.NoError(t, err)
+	require.NoError(t, reader.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, reader.Close()) })
+
+	addresses, err := json.Marshal(map[string][]string{"GetValue": {account.String()}})
+	require.NoError(t, err)
+	contract := relaytypes.BoundContract{Name: "Contract", Address: base64.StdEncoding.EncodeToString(addresses)}
+	require.NoError(t, reader.Bind(ctx, []relaytypes.BoundContract{contract}))
+
+	var value struct{ Value uint64 }
+	require.NoError(t, reader.GetLatestValue(ctx, contract.ReadIdentifier("GetValue"), primitives.Unconfirmed, nil, &value))
+	assert.Equal(t, uint64(42), value.Value)
+}
+
+func TestRelayer_Close_ContractReaders(t *testing.T) {
+	ctx := tests.Context(t)
+	relayer := NewRelayer(logger.Test(t), &readerChain{reader: clientmocks.NewReaderWriter(t)}, nil)
+	require.NoError(t, relayer.Start(ctx))
+
+	cfg := []byte(`{"namespaces": {}}`)
+	running, err := relayer.NewContractReader(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, running.Start(ctx))
+	closed, err := relayer.NewContractReader(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, closed.Start(ctx))
+	require.NoError(t, closed.Close())
+	unstarted, err := relayer.NewContractReader(ctx, cfg)
+	require.NoError(t, err)
+
+	// only the running reader is closed with the relayer
+	require.NoError(t, relayer.Close())
+	require.Error(t, running.Ready())
+	require.Error(t, running.Close())
+	require.NoError(t, unstarted.Start(ctx))
+	require.NoError(t, unstarted.Close())
+}
+
+func TestMedianProvider_ContractReader(t *testing.T) {
+	// no contract reader configured
+	provider := &medianProvider{}
+	assert.Nil(t, provider.ContractReader())
+}
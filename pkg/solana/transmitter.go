@@ -13,10 +13,14 @@ import (
 	"github.com/goplugin/plugin-common/pkg/utils"
 
 	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	"github.com/goplugin/plugin-solana/pkg/solana/txm"
 )
 
 var _ types.ContractTransmitter = (*Transmitter)(nil)
 
+// maxTransmitAttempts is the number of txs sent for a report whose earlier transmits failed
+const maxTransmitAttempts = 5
+
 type Transmitter struct {
 	stateID, programID, storeProgramID, transmissionsID, transmissionSigner solana.PublicKey
 	reader                                                                  client.Reader
@@ -83,11 +87,43 @@ func (c *Transmitter) Transmit(
 	}
 
 	// pass transmit payload to tx manager queue
-	c.lggr.Debugf("Queuing transmit tx: state (%s) + transmissions (%s)", c.stateID.String(), c.transmissionsID.String())
-	if err = c.txManager.Enqueue(ctx, c.stateID.String(), tx); err != nil {
-		return fmt.Errorf("error on Transmit.txManager.Enqueue: %w", err)
+	// the tx id is derived from the report so the same report is only transmitted once, unless the earlier transmit failed
+	txID := transmitTxID(c.stateID, reportCtx)
+	for attempt := 0; attempt < maxTransmitAttempts; attempt++ {
+		id := txID
+		if attempt > 0 {
+			id = fmt.Sprintf("%s-%d", txID, attempt)
+		}
+		c.lggr.Debugw("Queuing transmit tx", "state", c.stateID.String(), "transmissions", c.transmissionsID.String(), "txID", id)
+		err = c.txManager.Enqueue(ctx, c.stateID.String(), tx, &id)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, txm.ErrTxAlreadyExists) {
+			return fmt.Errorf("error on Transmit.txManager.Enqueue: %w", err)
+		}
+
+		status, err := c.txManager.GetTransactionStatus(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error on Transmit.txManager.GetTransactionStatus: %w", err)
+		}
+		if !transmitFailed(status.State) {
+			c.lggr.Debugw("Transmit tx already queued for report", "txID", id, "state", status.State)
+			return nil
+		}
+		c.lggr.Infow("Transmit tx for report failed, transmitting again", "txID", id, "state", status.State, "error", status.Error)
 	}
-	return nil
+	return fmt.Errorf("error on Transmit: report failed to transmit %d times: %s", maxTransmitAttempts, txID)
+}
+
+// transmitTxID returns the txm idempotency key for transmitting a report to the state account
+func transmitTxID(stateID solana.PublicKey, reportCtx types.ReportContext) string {
+	return fmt.Sprintf("ocr2-transmit-%s-%s-%d-%d", stateID, reportCtx.ConfigDigest.Hex(), reportCtx.Epoch, reportCtx.Round)
+}
+
+// transmitFailed returns if the transmit tx ended without being included (failed, dropped, reverted or cancelled)
+func transmitFailed(state txm.TxState) bool {
+	return state == txm.TxStateFailed || state == txm.TxStateFatal || state == txm.TxStateCancelled
 }
 
 func (c *Transmitter) LatestConfigDigestAndEpoch(
@@ -2,6 +2,7 @@ package solana
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/gagliardetto/solana-go"
@@ -15,6 +16,7 @@ import (
 	"github.com/goplugin/plugin-common/pkg/utils/tests"
 
 	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
 	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 	"github.com/goplugin/plugin-solana/pkg/solana/txm"
 )
@@ -26,7 +28,7 @@ type verifyTxSize struct {
 	s *solana.PrivateKey
 }
 
-func (txm verifyTxSize) Enqueue(_ context.Context, _ string, tx *solana.Transaction, _ ...txm.SetTxConfig) error {
+func (txm verifyTxSize) Enqueue(_ context.Context, _ string, tx *solana.Transaction, _ *string, _ ...txm.SetTxConfig) error {
 	// additional components that transaction manager adds to the transaction
 	require.NoError(txm.t, fees.SetComputeUnitPrice(tx, 0))
 	require.NoError(txm.t, fees.SetComputeUnitLimit(tx, 0))
@@ -42,6 +44,16 @@ func (txm verifyTxSize) Enqueue(_ context.Context, _ string, tx *solana.Transact
 	return nil
 }
 
+func (verifyTxSize) GetTransactionStatus(context.Context, string) (txm.TxStatus, error) {
+	return txm.TxStatus{}, nil
+}
+
+func (verifyTxSize) FeeEstimator() fees.Estimator {
+	return nil
+}
+
+func (verifyTxSize) RegisterProgramErrors(solana.PublicKey, []codec.IdlErrorCode) {}
+
 func TestTransmitter_TxSize(t *testing.T) {
 	mustNewRandomPublicKey := func() solana.PublicKey {
 		k, err := solana.NewRandomPrivateKey()
@@ -83,3 +95,74 @@ func TestTransmitter_TxSize(t *testing.T) {
 	}
 	require.NoError(t, transmitter.Transmit(tests.Context(t), types.ReportContext{}, make([]byte, ReportLen), sigs))
 }
+
+// statusTxm tracks enqueued tx ids like the txm, with the state of each tx set by the test
+type statusTxm struct {
+	verifyTxSize
+	states map[string]txm.TxState
+	queued []string
+}
+
+func (m *statusTxm) Enqueue(_ context.Context, _ string, _ *solana.Transaction, txID *string, _ ...txm.SetTxConfig) error {
+	if _, exists := m.states[*txID]; exists {
+		return fmt.Errorf("%w: %s", txm.ErrTxAlreadyExists, *txID)
+	}
+	m.states[*txID] = txm.TxStatePending
+	m.queued = append(m.queued, *txID)
+	return nil
+}
+
+func (m *statusTxm) GetTransactionStatus(_ context.Context, txID string) (txm.TxStatus, error) {
+	return txm.TxStatus{State: m.states[txID]}, nil
+}
+
+func TestTransmitter_Retransmit(t *testing.T) {
+	rw := clientmocks.NewReaderWriter(t)
+	rw.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{},
+	}, nil)
+	mockTxm := &statusTxm{states: map[string]txm.TxState{}}
+	transmitter := Transmitter{
+		stateID:            solana.PublicKey{1},
+		programID:          solana.PublicKey{2},
+		storeProgramID:     solana.PublicKey{3},
+		transmissionsID:    solana.PublicKey{4},
+		transmissionSigner: solana.PublicKey{5},
+		reader:             rw,
+		stateCache:         &StateCache{},
+		lggr:               logger.Test(t),
+		txManager:          mockTxm,
+	}
+	ctx := tests.Context(t)
+	reportCtx := types.ReportContext{ReportTimestamp: types.ReportTimestamp{Epoch: 1, Round: 2}}
+	txID := transmitTxID(transmitter.stateID, reportCtx)
+	transmit := func() error {
+		return transmitter.Transmit(ctx, reportCtx, make([]byte, ReportLen), nil)
+	}
+
+	// pending, inflight and succeeded txs are not transmitted again
+	require.NoError(t, transmit())
+	for _, state := range []txm.TxState{txm.TxStatePending, txm.TxStateBroadcasted, txm.TxStateConfirmed, txm.TxStateFinalized} {
+		mockTxm.states[txID] = state
+		require.NoError(t, transmit())
+	}
+	assert.Equal(t, []string{txID}, mockTxm.queued)
+
+	// failed txs are transmitted again with a new id
+	mockTxm.states[txID] = txm.TxStateFailed
+	require.NoError(t, transmit())
+	assert.Equal(t, []string{txID, txID + "-1"}, mockTxm.queued)
+	require.NoError(t, transmit()) // retransmitted tx is pending
+	mockTxm.states[txID+"-1"] = txm.TxStateCancelled
+	require.NoError(t, transmit())
+	assert.Equal(t, []string{txID, txID + "-1", txID + "-2"}, mockTxm.queued)
+
+	// the report is not transmitted again once all attempts failed
+	for i := 2; i < maxTransmitAttempts-1; i++ {
+		mockTxm.states[fmt.Sprintf("%s-%d", txID, i)] = txm.TxStateFatal
+		require.NoError(t, transmit())
+	}
+	mockTxm.states[fmt.Sprintf("%s-%d", txID, maxTransmitAttempts-1)] = txm.TxStateFatal
+	require.ErrorContains(t, transmit(), "report failed to transmit")
+	assert.Len(t, mockTxm.queued, maxTransmitAttempts)
+}
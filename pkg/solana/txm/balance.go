@@ -0,0 +1,229 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	"github.com/goplugin/plugin-solana/pkg/solana/monitor"
+)
+
+const defaultInstructionComputeUnitLimit = 200_000 // compute units per instruction of a tx without a compute unit limit
+
+// ErrInsufficientBalance is returned by Enqueue when no fee payer of the tx can pay the worst case fee of the tx
+var ErrInsufficientBalance = errors.New("insufficient fee payer balance")
+
+// worstCaseFee returns the max lamports the tx can pay: the base fee of each signature plus the priority fee at the max compute unit price,
+// and the tip of the submitter
+func worstCaseFee(tx *solanaGo.Transaction, cfg TxConfig) uint64 {
+	return signatureFees(tx) + fees.CalculatePriorityFee(fees.ComputeUnitPrice(maxComputeUnitPrice(tx, cfg)), txComputeUnitLimit(tx, cfg)) + cfg.tip
+}
+
+// maxComputeUnitPrice returns the max compute unit price of the tx: ComputeUnitPriceMax capped by the fee budget of the tx.
+// the budget caps fee bumping, it does not lower the price of the initial attempt
+func maxComputeUnitPrice(tx *solanaGo.Transaction, cfg TxConfig) uint64 {
+	if cfg.FeeBudget == 0 {
+		return cfg.ComputeUnitPriceMax
+	}
+	var budgetPrice uint64
+	if sigFees := signatureFees(tx); cfg.FeeBudget > sigFees {
+		budgetPrice = uint64(fees.MaxComputeUnitPrice(cfg.FeeBudget-sigFees, txComputeUnitLimit(tx, cfg)))
+	}
+	initialPrice := min(max(cfg.BaseComputeUnitPrice, cfg.ComputeUnitPriceMin), cfg.ComputeUnitPriceMax)
+	return min(cfg.ComputeUnitPriceMax, max(budgetPrice, initialPrice))
+}
+
+func signatureFees(tx *solanaGo.Transaction) uint64 {
+	return uint64(max(tx.Message.Header.NumRequiredSignatures, 1)) * fees.LamportsPerSignature
+}
+
+// txComputeUnitLimit returns the compute units the tx can use: the compute unit limit, or the default limit of each instruction
+func txComputeUnitLimit(tx *solanaGo.Transaction, cfg TxConfig) fees.ComputeUnitLimit {
+	if cfg.ComputeUnitLimit != 0 {
+		return fees.ComputeUnitLimit(cfg.ComputeUnitLimit)
+	}
+	return fees.ComputeUnitLimit(min(len(tx.Message.Instructions)*defaultInstructionComputeUnitLimit, int(fees.MaxComputeUnitLimit))) //nolint:gosec // capped at fees.MaxComputeUnitLimit
+}
+
+// feePayerBalances tracks the lamport balance of fee payers and the worst case fees reserved by their unfinished txs.
+// txs of a fee payer with a pool are rotated across the fee payer and its pool, skipping fee payers that cannot pay
+type feePayerBalances struct {
+	ttl   time.Duration                               // balances older than ttl are fetched again
+	pools map[solanaGo.PublicKey][]solanaGo.PublicKey // fee payer -> fee payers the txs are rotated across
+
+	lock     sync.Mutex
+	balances map[solanaGo.PublicKey]cachedBalance
+	reserved map[solanaGo.PublicKey]map[string]uint64 // fee payer -> tx id -> worst case fee
+	next     map[solanaGo.PublicKey]int               // fee payer -> next rotation index
+}
+
+type cachedBalance struct {
+	lamports  uint64
+	fetchedAt time.Time
+}
+
+func newFeePayerBalances(ttl time.Duration, pools map[string][]string) (*feePayerBalances, error) {
+	b := &feePayerBalances{
+		ttl:      ttl,
+		pools:    map[solanaGo.PublicKey][]solanaGo.PublicKey{},
+		balances: map[solanaGo.PublicKey]cachedBalance{},
+		reserved: map[solanaGo.PublicKey]map[string]uint64{},
+		next:     map[solanaGo.PublicKey]int{},
+	}
+	for feePayer, pool := range pools {
+		feePayerKey, err := solanaGo.PublicKeyFromBase58(feePayer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee payer %s: %w", feePayer, err)
+		}
+		for _, k := range pool {
+			key, err := solanaGo.PublicKeyFromBase58(k)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pool fee payer %s: %w", k, err)
+			}
+			if !key.Equals(feePayerKey) {
+				b.pools[feePayerKey] = append(b.pools[feePayerKey], key)
+			}
+		}
+	}
+	return b, nil
+}
+
+// hasPool returns if txs of the fee payer are rotated across a pool of fee payers
+func (b *feePayerBalances) hasPool(feePayer solanaGo.PublicKey) bool {
+	return len(b.pools[feePayer]) > 0
+}
+
+// candidates returns the fee payer and its pool in rotation order, the rotation advances on each call
+func (b *feePayerBalances) candidates(feePayer solanaGo.PublicKey) []solanaGo.PublicKey {
+	all := append([]solanaGo.PublicKey{feePayer}, b.pools[feePayer]...)
+	b.lock.Lock()
+	start := b.next[feePayer] % len(all)
+	b.next[feePayer] = start + 1
+	b.lock.Unlock()
+
+	rotated := make([]solanaGo.PublicKey, 0, len(all))
+	rotated = append(rotated, all[start:]...)
+	return append(rotated, all[:start]...)
+}
+
+// available returns the balance of the fee payer that is not reserved by unfinished txs
+func (b *feePayerBalances) available(ctx context.Context, reader monitor.BalanceClient, feePayer solanaGo.PublicKey, finished func(id string) bool) (uint64, error) {
+	if err := b.refresh(ctx, reader, feePayer, finished); err != nil {
+		return 0, err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.unreserved(feePayer), nil
+}
+
+// reserve reserves fee lamports of the fee payer for the tracked tx id if the balance not reserved by unfinished txs covers it
+func (b *feePayerBalances) reserve(ctx context.Context, reader monitor.BalanceClient, feePayer solanaGo.PublicKey, id string, fee uint64, finished func(id string) bool) (available uint64, ok bool, err error) {
+	if err = b.refresh(ctx, reader, feePayer, finished); err != nil {
+		return 0, false, err
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	available = b.unreserved(feePayer)
+	if available < fee {
+		return available, false, nil
+	}
+	if b.reserved[feePayer] == nil {
+		b.reserved[feePayer] = map[string]uint64{}
+	}
+	b.reserved[feePayer][id] = fee
+	return available, true, nil
+}
+
+// refresh releases the reservations of finished txs and fetches the balance of the fee payer once stale
+func (b *feePayerBalances) refresh(ctx context.Context, reader monitor.BalanceClient, feePayer solanaGo.PublicKey, finished func(id string) bool) error {
+	if !b.stale(feePayer, finished) {
+		return nil
+	}
+	lamports, err := reader.Balance(ctx, feePayer)
+	if err != nil {
+		return fmt.Errorf("failed to fetch balance of %s: %w", feePayer, err)
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.balances[feePayer] = cachedBalance{lamports: lamports, fetchedAt: time.Now()}
+	return nil
+}
+
+func (b *feePayerBalances) unreserved(feePayer solanaGo.PublicKey) uint64 {
+	available := b.balances[feePayer].lamports
+	for _, r := range b.reserved[feePayer] {
+		available -= min(r, available)
+	}
+	return available
+}
+
+// stale releases the reservations of finished txs and returns if the balance of the fee payer must be fetched.
+// the balance is stale once a tx is finished because the fee paid by the tx is no longer reserved
+func (b *feePayerBalances) stale(feePayer solanaGo.PublicKey, finished func(id string) bool) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	cached, exists := b.balances[feePayer]
+	stale := !exists || time.Since(cached.fetchedAt) > b.ttl
+	for id := range b.reserved[feePayer] {
+		if finished(id) {
+			delete(b.reserved[feePayer], id)
+			stale = true
+		}
+	}
+	return stale
+}
+
+// release removes the reservation of the tx id (i.e. the tx was not queued)
+func (b *feePayerBalances) release(feePayer solanaGo.PublicKey, id string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.reserved[feePayer], id)
+}
+
+// checkFeePayer returns an error if the fee payer of the tx cannot be replaced with feePayer.
+// only possible when the current fee payer is not used by any instruction and the new fee payer is not in the tx
+func checkFeePayer(tx *solanaGo.Transaction, feePayer solanaGo.PublicKey) error {
+	for _, k := range tx.Message.AccountKeys {
+		if k.Equals(feePayer) {
+			return fmt.Errorf("fee payer %s is already used by the tx", feePayer)
+		}
+	}
+	for _, ix := range tx.Message.Instructions {
+		for _, a := range ix.Accounts {
+			if a == 0 {
+				return fmt.Errorf("fee payer %s is used by an instruction", tx.Message.AccountKeys[0])
+			}
+		}
+	}
+	return nil
+}
+
+// copyTx returns a copy of the tx whose signatures, account keys and instructions can be changed without changing tx
+func copyTx(tx *solanaGo.Transaction) *solanaGo.Transaction {
+	cp := *tx
+	cp.Signatures = slices.Clone(tx.Signatures)
+	cp.Message.AccountKeys = slices.Clone(tx.Message.AccountKeys)
+	cp.Message.Instructions = make([]solanaGo.CompiledInstruction, len(tx.Message.Instructions))
+	for i, ix := range tx.Message.Instructions {
+		ix.Accounts = slices.Clone(ix.Accounts)
+		ix.Data = slices.Clone(ix.Data)
+		cp.Message.Instructions[i] = ix
+	}
+	return &cp
+}
+
+// setFeePayer replaces the fee payer of the unsigned tx
+func setFeePayer(tx *solanaGo.Transaction, feePayer solanaGo.PublicKey) error {
+	if err := checkFeePayer(tx, feePayer); err != nil {
+		return err
+	}
+	tx.Message.AccountKeys[0] = feePayer
+	tx.Signatures = nil
+	return nil
+}
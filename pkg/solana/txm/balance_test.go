@@ -0,0 +1,198 @@
+package txm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+// payerTx returns a tx whose instruction does not use the fee payer, so the fee payer can be rotated
+func payerTx(t *testing.T, feePayer solana.PublicKey) *solana.Transaction {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(solana.PublicKey{9}).WRITE()}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	return tx
+}
+
+func TestWorstCaseFee(t *testing.T) {
+	tx := payerTx(t, solana.PublicKey{1})
+	assert.Equal(t, uint64(5_000+200), worstCaseFee(tx, TxConfig{ComputeUnitPriceMax: 1_000, ComputeUnitLimit: 200_000}))
+	// default limit per instruction without a compute unit limit
+	assert.Equal(t, uint64(5_000+200), worstCaseFee(tx, TxConfig{ComputeUnitPriceMax: 1_000}))
+	assert.Equal(t, uint64(5_000), worstCaseFee(tx, TxConfig{ComputeUnitLimit: 200_000}))
+	// fee budget caps the priority fee
+	assert.Equal(t, uint64(5_000+100), worstCaseFee(tx, TxConfig{ComputeUnitPriceMax: 1_000, ComputeUnitLimit: 200_000, FeeBudget: 5_100}))
+	// the tip of the submitter is paid by the fee payer
+	assert.Equal(t, uint64(5_000+200+10_000), worstCaseFee(tx, TxConfig{ComputeUnitPriceMax: 1_000, ComputeUnitLimit: 200_000, tip: 10_000}))
+}
+
+func TestMaxComputeUnitPrice(t *testing.T) {
+	tx := payerTx(t, solana.PublicKey{1})
+	cfg := TxConfig{ComputeUnitPriceMax: 1_000, ComputeUnitLimit: 200_000}
+	assert.Equal(t, uint64(1_000), maxComputeUnitPrice(tx, cfg))
+
+	cfg.FeeBudget = 5_100
+	assert.Equal(t, uint64(500), maxComputeUnitPrice(tx, cfg))
+	cfg.FeeBudget = 1_000_000
+	assert.Equal(t, uint64(1_000), maxComputeUnitPrice(tx, cfg))
+
+	// budget does not lower the initial price
+	cfg.FeeBudget = 1_000
+	cfg.BaseComputeUnitPrice = 100
+	assert.Equal(t, uint64(100), maxComputeUnitPrice(tx, cfg))
+	cfg.BaseComputeUnitPrice = 0
+	cfg.ComputeUnitPriceMin = 10
+	assert.Equal(t, uint64(10), maxComputeUnitPrice(tx, cfg))
+}
+
+func TestFeePayerBalances(t *testing.T) {
+	ctx := tests.Context(t)
+	keyA, keyB, keyC := solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}
+	b, err := newFeePayerBalances(time.Hour, map[string][]string{keyA.String(): {keyB.String(), keyC.String(), keyA.String()}})
+	require.NoError(t, err)
+
+	t.Run("rotation", func(t *testing.T) {
+		assert.True(t, b.hasPool(keyA))
+		assert.False(t, b.hasPool(keyB))
+		assert.Equal(t, []solana.PublicKey{keyA, keyB, keyC}, b.candidates(keyA))
+		assert.Equal(t, []solana.PublicKey{keyB, keyC, keyA}, b.candidates(keyA))
+		assert.Equal(t, []solana.PublicKey{keyC, keyA, keyB}, b.candidates(keyA))
+		assert.Equal(t, []solana.PublicKey{keyA, keyB, keyC}, b.candidates(keyA))
+		assert.Equal(t, []solana.PublicKey{keyB}, b.candidates(keyB))
+	})
+
+	t.Run("reservations", func(t *testing.T) {
+		reader := clientmocks.NewReaderWriter(t)
+		reader.On("Balance", mock.Anything, keyA).Return(uint64(12_000), nil).Once()
+		finished := map[string]bool{}
+		isFinished := func(id string) bool { return finished[id] }
+
+		available, ok, err := b.reserve(ctx, reader, keyA, "a", 5_000, isFinished)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(12_000), available)
+		_, ok, err = b.reserve(ctx, reader, keyA, "b", 5_000, isFinished)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		// balance is cached, reserved lamports are not available
+		available, ok, err = b.reserve(ctx, reader, keyA, "c", 5_000, isFinished)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, uint64(2_000), available)
+
+		// reservations of finished txs are released and the balance is fetched again
+		finished["a"] = true
+		reader.On("Balance", mock.Anything, keyA).Return(uint64(7_000), nil).Once()
+		available, err = b.available(ctx, reader, keyA, isFinished)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2_000), available)
+
+		b.release(keyA, "b")
+		available, err = b.available(ctx, reader, keyA, isFinished)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7_000), available)
+	})
+}
+
+func TestSetFeePayer(t *testing.T) {
+	feePayer, other := solana.PublicKey{1}, solana.PublicKey{2}
+	tx := payerTx(t, feePayer)
+	tx.Signatures = []solana.Signature{{1}}
+	require.Error(t, setFeePayer(tx, solana.PublicKey{9}), "already used by the tx")
+	require.NoError(t, setFeePayer(tx, other))
+	assert.Equal(t, other, tx.Message.AccountKeys[0])
+	assert.Empty(t, tx.Signatures)
+
+	// fee payer used by an instruction cannot be replaced
+	transfer, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, feePayer, solana.PublicKey{9}).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	require.Error(t, setFeePayer(transfer, other))
+
+	// changes to a copy do not change the tx
+	tx = payerTx(t, feePayer)
+	tx.Signatures = []solana.Signature{{1}}
+	cp := copyTx(tx)
+	require.NoError(t, setFeePayer(cp, other))
+	cp.Message.Instructions[0].Data[0] = 2
+	assert.Equal(t, feePayer, tx.Message.AccountKeys[0])
+	assert.Equal(t, []solana.Signature{{1}}, tx.Signatures)
+	assert.Equal(t, byte(1), tx.Message.Instructions[0].Data[0])
+}
+
+func TestTxm_BalanceCheck(t *testing.T) {
+	ctx := tests.Context(t)
+	funded, unfunded, pooled := solana.PublicKey{1}, solana.PublicKey{2}, solana.PublicKey{3}
+	cfg := config.NewDefault()
+	balanceCheck := true
+	cfg.Chain.TxBalanceCheck = &balanceCheck
+	cfg.Chain.FeePayerPools = map[string][]string{pooled.String(): {unfunded.String(), funded.String()}}
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sent := make(chan *solana.Transaction, 10)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("Balance", mock.Anything, funded).Return(uint64(solana.LAMPORTS_PER_SOL), nil)
+	mc.On("Balance", mock.Anything, unfunded).Return(uint64(0), nil)
+	mc.On("Balance", mock.Anything, pooled).Return(uint64(0), nil)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(solana.Signature{1}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{nil}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("balance_check", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// fee payer that cannot pay the worst case fee is rejected
+	id := uuid.NewString()
+	require.ErrorIs(t, txm.Enqueue(ctx, "test", payerTx(t, unfunded), &id), ErrInsufficientBalance)
+	_, err := txm.GetTransactionStatus(ctx, id)
+	require.ErrorIs(t, err, ErrTxNotFound)
+
+	// txs of a fee payer with a pool are paid by a funded fee payer of the pool
+	pooledID := uuid.NewString()
+	tx := payerTx(t, pooled)
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, &pooledID))
+	select {
+	case sentTx := <-sent:
+		assert.Equal(t, funded, sentTx.Message.AccountKeys[0])
+	case <-ctx.Done():
+		t.Fatal("tx not sent")
+	}
+	// the fee payer is rotated on a copy, the enqueued tx is unchanged
+	assert.Equal(t, pooled, tx.Message.AccountKeys[0])
+
+	// a rotated tx that can not be tracked keeps its fee payer
+	tx = payerTx(t, pooled)
+	require.ErrorIs(t, txm.Enqueue(ctx, "test", tx, &pooledID), ErrTxAlreadyExists)
+	assert.Equal(t, pooled, tx.Message.AccountKeys[0])
+}
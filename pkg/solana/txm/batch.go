@@ -0,0 +1,533 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+// ErrTxBatched is returned when cancelling a tx that was sent in a batch tx, the batch tx can be cancelled by its id
+var ErrTxBatched = errors.New("tx sent in batch tx")
+
+// batchable returns if the queued tx can be sent in a batch tx with other queued txs of its fee payer.
+// txs with supplied signatures, durable nonces or replacing a tx are bound to their own message and are sent on their own,
+// as are versioned txs (lookup table accounts are not merged)
+func batchable(msg pendingTx) bool {
+	return msg.cfg.Batch && msg.cfg.presigned == nil && msg.cfg.replaced == nil && !msg.cfg.UseDurableNonce &&
+		!msg.tx.Message.IsVersioned()
+}
+
+// popBatch dequeues the queued txs of the fee payer that are sent in a batch tx with the dequeued tx.
+// txs are added in queue order until a tx can not be batched, the batch tx would exceed the tx size or compute limits,
+// or the fee payer has no inflight capacity left: each member counts against TxMaxInflight, not the batch tx
+func (txm *Txm) popBatch(msg pendingTx) []pendingTx {
+	if !batchable(msg) {
+		return []pendingTx{msg}
+	}
+	batch := []pendingTx{msg}
+	popped := txm.queues.popWhile(msg.tx.Message.AccountKeys[0], func(next pendingTx) bool {
+		if !batchable(next) || next.cfg.CompletionCommitment != msg.cfg.CompletionCommitment {
+			return false
+		}
+		if !batchFits(append(slices.Clip(batch), next)) {
+			return false
+		}
+		batch = append(batch, next)
+		return true
+	})
+	return append([]pendingTx{msg}, popped...)
+}
+
+// sendBatch simulates the batch tx of the txs and broadcasts it. a batch tx that fails simulation is split in halves
+// until the txs that fail are sent on their own, so one failing tx does not revert the txs it is batched with
+func (txm *Txm) sendBatch(ctx context.Context, msgs []pendingTx) {
+	if len(msgs) == 1 {
+		txm.send(ctx, msgs[0])
+		return
+	}
+
+	batch, members, err := txm.newBatch(msgs)
+	if err == nil {
+		err = txm.simulateBatch(ctx, batch)
+	}
+	if err != nil {
+		txm.lggr.Debugw("splitting batch tx", "ids", batchIDs(msgs), "error", err)
+		promSolTxmBatchSplits.WithLabelValues(txm.chainID).Add(1)
+		mid := len(msgs) / 2
+		txm.sendBatch(ctx, msgs[:mid])
+		txm.sendBatch(ctx, msgs[mid:])
+		return
+	}
+
+	// track the batch tx before it is broadcasted, its state changes are mirrored to the members
+	msgBytes, err := batch.tx.Message.MarshalBinary()
+	if err == nil {
+		err = txm.txs.Pending(batch.id, msgBytes, batch.cfg.ComputeUnitLimit, batch.cfg.CompletionCommitment)
+	}
+	if err == nil {
+		err = txm.batches.add(batch.id, members)
+	}
+	if err != nil {
+		txm.lggr.Errorw("failed to track batch tx, sending txs on their own", "ids", batchIDs(msgs), "error", err)
+		txm.txs.Delete(batch.id)
+		for _, msg := range msgs {
+			txm.send(ctx, msg)
+		}
+		return
+	}
+	txm.lggr.Debugw("sending batch tx", "id", batch.id, "ids", batchIDs(msgs))
+	promSolTxmBatchedTxs.WithLabelValues(txm.chainID).Add(float64(len(msgs)))
+	txm.send(ctx, batch)
+}
+
+// newBatch returns the batch tx of the txs with a new id
+func (txm *Txm) newBatch(msgs []pendingTx) (pendingTx, []batchMember, error) {
+	tx, members, err := batchTx(msgs)
+	if err != nil {
+		return pendingTx{}, nil, err
+	}
+	return pendingTx{tx: &tx, cfg: batchConfig(msgs), id: "batch-" + uuid.NewString()}, members, nil
+}
+
+// simulateBatch returns an error if the batch tx fails simulation, the batch tx is sent if the simulation can not be run
+func (txm *Txm) simulateBatch(ctx context.Context, batch pendingTx) error {
+	client, err := txm.client.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	tx := *batch.tx
+	if err = withComputeBudget(&tx, batch.cfg); err != nil {
+		return err
+	}
+	tx.Signatures = make([]solanaGo.Signature, len(requiredSigners(tx.Message)))
+	res, err := client.SimulateTx(ctx, &tx, &rpc.SimulateTransactionOpts{
+		ReplaceRecentBlockhash: true, // the tx is not signed yet
+		Commitment:             txm.cfg.Commitment(),
+	})
+	if err != nil {
+		txm.lggr.Warnw("failed to simulate batch tx", "id", batch.id, "error", err)
+		return nil
+	}
+	if res.Err != nil {
+		return txm.simulationError(&tx, res)
+	}
+	return nil
+}
+
+// requeue adds the txs of a reverted batch tx that did not cause the revert back to their queues
+func (txm *Txm) requeue(msgs []pendingTx) {
+	for _, msg := range msgs {
+		if err := txm.queues.push(msg, nil); err != nil {
+			txm.txs.OnPendingError(msg.id, TxFailDrop, fmt.Errorf("failed to requeue tx of reverted batch tx: %w", err))
+		}
+	}
+}
+
+// batchTx merges the instructions of the txs into a tx of their fee payer. compute budget instructions of the txs are
+// dropped, the batch tx sets them from the batch config when it is signed
+func batchTx(msgs []pendingTx) (solanaGo.Transaction, []batchMember, error) {
+	var ixs []solanaGo.Instruction
+	members := make([]batchMember, len(msgs))
+	for i, msg := range msgs {
+		first := len(ixs)
+		for _, ci := range msg.tx.Message.Instructions {
+			program, err := msg.tx.Message.Program(ci.ProgramIDIndex)
+			if err != nil {
+				return solanaGo.Transaction{}, nil, fmt.Errorf("invalid program of tx %s: %w", msg.id, err)
+			}
+			if program.Equals(fees.ComputeBudgetProgram) {
+				continue
+			}
+			accounts, err := ci.ResolveInstructionAccounts(&msg.tx.Message)
+			if err != nil {
+				return solanaGo.Transaction{}, nil, fmt.Errorf("invalid accounts of tx %s: %w", msg.id, err)
+			}
+			ixs = append(ixs, solanaGo.NewInstruction(program, accounts, ci.Data))
+		}
+		member := msg
+		members[i] = batchMember{
+			id:  msg.id,
+			loc: TxBatchMember{Instruction: first, Instructions: len(ixs) - first},
+			msg: &member,
+		}
+	}
+	tx, err := solanaGo.NewTransaction(ixs, msgs[0].tx.Message.RecentBlockhash, solanaGo.TransactionPayer(msgs[0].tx.Message.AccountKeys[0]))
+	if err != nil {
+		return solanaGo.Transaction{}, nil, fmt.Errorf("failed to build batch tx: %w", err)
+	}
+	return *tx, members, nil
+}
+
+// batchConfig returns the config of the batch tx of the txs: the compute unit limits, data size limits and fee budgets
+// of the txs are added up, the highest prices and heap frame are used and the batch tx is signed with the latest blockhash
+func batchConfig(msgs []pendingTx) TxConfig {
+	cfg := msgs[0].cfg
+	cfg.OnEvent = nil // events of the members are published to their callbacks
+	cfg.RefreshBlockhash = true
+	cfg.ComputeUnitLimit, cfg.LoadedAccountsDataSizeLimit, cfg.FeeBudget = 0, 0, 0
+	cfg.Signers = nil
+	dataSizeLimit, feeBudget := true, true
+	for _, msg := range msgs {
+		c := msg.cfg
+		cfg.Timeout = min(cfg.Timeout, c.Timeout)
+		cfg.BaseComputeUnitPrice = max(cfg.BaseComputeUnitPrice, c.BaseComputeUnitPrice)
+		cfg.ComputeUnitPriceMin = max(cfg.ComputeUnitPriceMin, c.ComputeUnitPriceMin)
+		cfg.ComputeUnitPriceMax = max(cfg.ComputeUnitPriceMax, c.ComputeUnitPriceMax)
+		cfg.HeapFrame = max(cfg.HeapFrame, c.HeapFrame)
+		cfg.Signers = append(cfg.Signers, c.Signers...)
+
+		// txs without a compute unit limit use the runtime default of each instruction
+		limit := c.ComputeUnitLimit
+		if limit == 0 {
+			limit = uint32(min(len(msg.tx.Message.Instructions)*defaultInstructionComputeUnitLimit, int(fees.MaxComputeUnitLimit))) //nolint:gosec // capped at the max compute unit limit
+		}
+		cfg.ComputeUnitLimit += limit
+
+		// txs without a data size limit can load up to the max, as can the batch tx
+		dataSizeLimit = dataSizeLimit && c.LoadedAccountsDataSizeLimit != 0
+		cfg.LoadedAccountsDataSizeLimit += c.LoadedAccountsDataSizeLimit
+		feeBudget = feeBudget && c.FeeBudget != 0
+		cfg.FeeBudget += c.FeeBudget
+	}
+	if !dataSizeLimit {
+		cfg.LoadedAccountsDataSizeLimit = 0
+	}
+	cfg.LoadedAccountsDataSizeLimit = min(cfg.LoadedAccountsDataSizeLimit, uint32(fees.MaxLoadedAccountsDataSizeLimit))
+	if !feeBudget {
+		cfg.FeeBudget = 0
+	}
+	return cfg
+}
+
+// batchFits returns if the batch tx of the txs fits in a packet once signed and within the max compute unit limit
+func batchFits(msgs []pendingTx) bool {
+	cfg := batchConfig(msgs)
+	if cfg.ComputeUnitLimit > uint32(fees.MaxComputeUnitLimit) {
+		return false
+	}
+	tx, _, err := batchTx(msgs)
+	if err != nil {
+		return false
+	}
+	if err = withComputeBudget(&tx, cfg); err != nil {
+		return false
+	}
+	if err = fees.SetComputeUnitPrice(&tx, fees.ComputeUnitPrice(cfg.ComputeUnitPriceMax)); err != nil {
+		return false
+	}
+	tx.Signatures = make([]solanaGo.Signature, len(requiredSigners(tx.Message)))
+	data, err := tx.MarshalBinary()
+	return err == nil && len(data) <= MaxTxSize
+}
+
+// withComputeBudget adds the compute budget instructions of the config that are static for the tx.
+// instructions set to 0 are skipped (a compute unit limit of 0 would always fail, 0 sizes use the runtime defaults)
+func withComputeBudget(tx *solanaGo.Transaction, cfg TxConfig) error {
+	if cfg.ComputeUnitLimit != 0 {
+		if err := fees.SetComputeUnitLimit(tx, fees.ComputeUnitLimit(cfg.ComputeUnitLimit)); err != nil {
+			return fmt.Errorf("failed to add compute unit limit instruction: %w", err)
+		}
+	}
+	if cfg.HeapFrame != 0 {
+		if err := fees.SetHeapFrame(tx, fees.HeapFrame(cfg.HeapFrame)); err != nil {
+			return fmt.Errorf("failed to add heap frame instruction: %w", err)
+		}
+	}
+	if cfg.LoadedAccountsDataSizeLimit != 0 {
+		if err := fees.SetLoadedAccountsDataSizeLimit(tx, fees.LoadedAccountsDataSizeLimit(cfg.LoadedAccountsDataSizeLimit)); err != nil {
+			return fmt.Errorf("failed to add loaded accounts data size limit instruction: %w", err)
+		}
+	}
+	return nil
+}
+
+func batchIDs(msgs []pendingTx) []string {
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.id
+	}
+	return ids
+}
+
+// batchMember is a tx sent in a batch tx
+type batchMember struct {
+	id  string
+	loc TxBatchMember
+	msg *pendingTx // queued tx, requeued if another tx reverts the batch tx (nil for batch txs restored from the store)
+}
+
+// txBatches tracks the members of inflight batch txs, the membership is persisted with the member txs
+type txBatches struct {
+	store TxStore
+
+	lock    sync.RWMutex
+	members map[string][]batchMember // batch id -> members in instruction order
+	batchOf map[string]string        // member id -> batch id
+}
+
+func newTxBatches(store TxStore) *txBatches {
+	return &txBatches{
+		store:   store,
+		members: map[string][]batchMember{},
+		batchOf: map[string]string{},
+	}
+}
+
+// add records the members of the batch tx
+func (b *txBatches) add(batch string, members []batchMember) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i := range members {
+		members[i].loc.Batch = batch
+		if err := b.store.SetBatch(members[i].id, &members[i].loc); err != nil {
+			return fmt.Errorf("failed to store batch of tx %s: %w", members[i].id, err)
+		}
+	}
+	b.members[batch] = members
+	for _, m := range members {
+		b.batchOf[m.id] = batch
+	}
+	return nil
+}
+
+// restore resumes tracking a member of a batch tx loaded from the store
+func (b *txBatches) restore(rec TxRecord) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	members := append(b.members[rec.Batch.Batch], batchMember{id: rec.ID, loc: *rec.Batch})
+	slices.SortFunc(members, func(a, b batchMember) int { return a.loc.Instruction - b.loc.Instruction })
+	b.members[rec.Batch.Batch] = members
+	b.batchOf[rec.ID] = rec.Batch.Batch
+}
+
+func (b *txBatches) get(batch string) []batchMember {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.members[batch]
+}
+
+// batch returns the id of the inflight batch tx the tx was sent in
+func (b *txBatches) batch(id string) (string, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	batch, ok := b.batchOf[id]
+	return batch, ok
+}
+
+// remove stops tracking the members of a finished batch tx
+func (b *txBatches) remove(batch string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, m := range b.members[batch] {
+		delete(b.batchOf, m.id)
+	}
+	delete(b.members, batch)
+}
+
+// member returns the member of the batch tx with the instruction of an attempt, compute budget instructions
+// added to the attempt are not part of any member
+func member(members []batchMember, programs []solanaGo.PublicKey, instruction int) (batchMember, int, bool) {
+	if instruction < 0 || instruction >= len(programs) || programs[instruction].Equals(fees.ComputeBudgetProgram) {
+		return batchMember{}, 0, false
+	}
+	idx := 0
+	for _, program := range programs[:instruction] {
+		if !program.Equals(fees.ComputeBudgetProgram) {
+			idx++
+		}
+	}
+	for _, m := range members {
+		if idx >= m.loc.Instruction && idx < m.loc.Instruction+m.loc.Instructions {
+			return m, idx - m.loc.Instruction, true
+		}
+	}
+	return batchMember{}, 0, false
+}
+
+var _ PendingTxContext = &pendingTxContextWithBatches{}
+
+// pendingTxContextWithBatches mirrors the state changes of batch txs to the txs sent in them,
+// the members of a failed batch tx are retried and dead-lettered per the retry policy like txs sent on their own
+type pendingTxContextWithBatches struct {
+	PendingTxContext
+	batches *txBatches
+	events  *txEvents
+	policy  *txRetryPolicy
+	retry   func([]pendingTx) // requeues the members of a failed batch tx that did not cause the revert or are retried
+}
+
+func newPendingTxContextWithBatches(txs PendingTxContext, batches *txBatches, events *txEvents, policy *txRetryPolicy, retry func([]pendingTx)) *pendingTxContextWithBatches {
+	return &pendingTxContextWithBatches{
+		PendingTxContext: txs,
+		batches:          batches,
+		events:           events,
+		policy:           policy,
+		retry:            retry,
+	}
+}
+
+func (c *pendingTxContextWithBatches) New(id string, attempt TxAttempt, cancel context.CancelFunc) error {
+	if err := c.PendingTxContext.New(id, attempt, cancel); err != nil {
+		return err
+	}
+	c.mirror(id, TxEventBroadcast, attempt.ComputeUnitPrice)
+	return nil
+}
+
+func (c *pendingTxContextWithBatches) Add(id string, attempt TxAttempt) error {
+	if err := c.PendingTxContext.Add(id, attempt); err != nil {
+		return err
+	}
+	c.mirror(id, TxEventFeeBumped, attempt.ComputeUnitPrice)
+	return nil
+}
+
+// Cancel rejects cancelling a tx sent in a batch tx, the other txs of the batch tx would be cancelled with it
+func (c *pendingTxContextWithBatches) Cancel(id string, reason error) error {
+	if batch, ok := c.batches.batch(id); ok {
+		return fmt.Errorf("%w: tx %s is sent in batch tx %s and can not be cancelled on its own", ErrTxBatched, id, batch)
+	}
+	return c.PendingTxContext.Cancel(id, reason)
+}
+
+func (c *pendingTxContextWithBatches) OnProcessed(sig solanaGo.Signature) string {
+	id := c.PendingTxContext.OnProcessed(sig)
+	if id != "" {
+		c.mirror(id, TxEventProcessed, 0)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithBatches) OnSuccess(sig solanaGo.Signature) string {
+	id := c.PendingTxContext.OnSuccess(sig)
+	if id != "" {
+		c.mirror(id, TxEventConfirmed, 0)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithBatches) OnFinalized(sig solanaGo.Signature) string {
+	id := c.PendingTxContext.OnFinalized(sig)
+	if id != "" {
+		c.mirror(id, TxEventFinalized, 0)
+		c.batches.remove(id)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithBatches) OnRollback(sig solanaGo.Signature) string {
+	id := c.PendingTxContext.OnRollback(sig)
+	if id != "" {
+		c.mirror(id, TxEventRolledBack, 0)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithBatches) OnError(sig solanaGo.Signature, errType int, txErr error) string {
+	id := c.PendingTxContext.OnError(sig, errType, txErr)
+	if id != "" {
+		c.mirrorError(id, errType, txErr)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithBatches) OnPendingError(id string, errType int, txErr error) {
+	c.PendingTxContext.OnPendingError(id, errType, txErr)
+	c.mirrorError(id, errType, txErr)
+}
+
+// mirror sets the state of the batch tx on its members and publishes the event for them
+func (c *pendingTxContextWithBatches) mirror(batch string, evType TxEventType, price uint64) {
+	members := c.batches.get(batch)
+	if len(members) == 0 {
+		return
+	}
+	rec, err := c.Get(batch)
+	if err != nil {
+		return
+	}
+	for _, m := range members {
+		// processed batch txs are polled until confirmed, the event is published once
+		if evType == TxEventProcessed {
+			if memberRec, getErr := c.Get(m.id); getErr != nil || memberRec.State == TxStateProcessed {
+				continue
+			}
+		}
+		// best effort: the state of the members is only used for reporting
+		_ = c.batches.store.SetState(m.id, rec.State, rec.Signature, rec.Error)
+		c.events.publish(TxEvent{Type: evType, ID: m.id, State: rec.State, Signature: rec.Signature, ComputeUnitPrice: price})
+	}
+}
+
+// mirrorError fails the members of the failed batch tx. if an instruction of a member reverted the batch tx, only that
+// member fails with the decoded error and the other members were not executed and are requeued.
+// failed members are requeued while they have retries of the error class left, and dead-lettered once they fail
+func (c *pendingTxContextWithBatches) mirrorError(batch string, errType int, txErr error) {
+	members := c.batches.get(batch)
+	if len(members) == 0 {
+		return
+	}
+	defer c.batches.remove(batch)
+	rec, err := c.Get(batch)
+	if err != nil {
+		return
+	}
+
+	failed, failedInstruction, found := batchMember{}, 0, false
+	if rec.TxError != nil && (errType == TxFailRevert || errType == TxFailSimRevert) {
+		for _, attempt := range rec.Attempts {
+			if attempt.Signature == rec.Signature {
+				failed, failedInstruction, found = member(members, attempt.Programs, rec.TxError.InstructionIndex)
+				break
+			}
+		}
+	}
+
+	class, canRetry := retryClass(errType, txErr)
+	var retry []pendingTx
+	for _, m := range members {
+		if found && m.id != failed.id && m.msg != nil {
+			_ = c.batches.store.SetState(m.id, TxStatePending, solanaGo.Signature{}, "")
+			_ = c.batches.store.SetBatch(m.id, nil)
+			retry = append(retry, *m.msg)
+			continue
+		}
+		// members restored from the store have no queued tx to requeue
+		if canRetry && m.msg != nil && c.policy.allowed(m.id, class) {
+			_ = c.batches.store.SetBatch(m.id, nil)
+			c.policy.retried(m.id, rec.Signature, class, txErr)
+			retry = append(retry, *m.msg)
+			continue
+		}
+
+		var decodedErr *DecodedTxError
+		if rec.TxError != nil {
+			decoded := *rec.TxError
+			if found && m.id == failed.id {
+				decoded.InstructionIndex = failedInstruction // index of the failed instruction in the member tx
+			}
+			decodedErr = &decoded
+		}
+		_ = c.batches.store.SetState(m.id, rec.State, rec.Signature, rec.Error)
+		if decodedErr != nil {
+			_ = c.batches.store.SetTxError(m.id, decodedErr)
+		}
+		ev := TxEvent{Type: errorEventType(rec.State, errType), ID: m.id, State: rec.State, Signature: rec.Signature, Error: rec.Error}
+		if ev.Type == TxEventReverted {
+			ev.TxError = decodedErr
+		}
+		c.events.publish(ev)
+		c.policy.deadLetter(m.id)
+	}
+	if len(retry) > 0 {
+		c.retry(retry)
+	}
+}
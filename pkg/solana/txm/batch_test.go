@@ -0,0 +1,329 @@
+package txm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+const (
+	memoSimRevert   byte = 0xff // memo data of txs that fail simulation
+	memoChainRevert byte = 0xee // memo data of txs that pass simulation and revert onchain
+)
+
+// memoTx returns a tx of the fee payer with a memo instruction
+func memoTx(t *testing.T, feePayer solana.PublicKey, data ...byte) *solana.Transaction {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(solana.PublicKey{9}).WRITE()}, data),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	return tx
+}
+
+// memos returns the memo instruction data of the tx
+func memos(tx *solana.Transaction) (data [][]byte) {
+	for _, ix := range tx.Message.Instructions {
+		if program, err := tx.Message.Program(ix.ProgramIDIndex); err == nil && program.Equals(solana.MemoProgramID) {
+			data = append(data, ix.Data)
+		}
+	}
+	return data
+}
+
+// instructionError returns the InstructionError of the first instruction of the tx with the memo data
+func instructionError(t *testing.T, tx *solana.Transaction, data byte) any {
+	for i, ix := range tx.Message.Instructions {
+		if slices.Equal(ix.Data, []byte{data}) {
+			var txErr any
+			require.NoError(t, json.Unmarshal([]byte(`{"InstructionError": [`+strconv.Itoa(i)+`, {"Custom": 1}]}`), &txErr))
+			return txErr
+		}
+	}
+	return nil
+}
+
+func batchMsg(t *testing.T, id string, cfg TxConfig, data ...byte) pendingTx {
+	cfg.Batch = true
+	return pendingTx{tx: memoTx(t, solana.PublicKey{1}, data...), cfg: cfg, id: id}
+}
+
+func TestBatchTx(t *testing.T) {
+	a := batchMsg(t, "a", TxConfig{ComputeUnitLimit: 10_000, ComputeUnitPriceMax: 10, LoadedAccountsDataSizeLimit: 1_000, Timeout: time.Minute}, 1)
+	b := batchMsg(t, "b", TxConfig{ComputeUnitLimit: 20_000, ComputeUnitPriceMax: 20, LoadedAccountsDataSizeLimit: 2_000, Timeout: time.Second, HeapFrame: 64 * 1024}, 2)
+	// compute budget instructions of the txs are dropped
+	require.NoError(t, fees.SetComputeUnitPrice(b.tx, 5))
+
+	tx, members, err := batchTx([]pendingTx{a, b})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{{1}, {2}}, memos(&tx))
+	assert.Len(t, tx.Message.Instructions, 2)
+	assert.Equal(t, solana.PublicKey{1}, tx.Message.AccountKeys[0])
+	require.Len(t, members, 2)
+	assert.Equal(t, "a", members[0].id)
+	assert.Equal(t, TxBatchMember{Instruction: 0, Instructions: 1}, members[0].loc)
+	assert.Equal(t, "b", members[1].id)
+	assert.Equal(t, TxBatchMember{Instruction: 1, Instructions: 1}, members[1].loc)
+
+	cfg := batchConfig([]pendingTx{a, b})
+	assert.Equal(t, uint32(30_000), cfg.ComputeUnitLimit)
+	assert.Equal(t, uint32(3_000), cfg.LoadedAccountsDataSizeLimit)
+	assert.Equal(t, uint64(20), cfg.ComputeUnitPriceMax)
+	assert.Equal(t, uint32(64*1024), cfg.HeapFrame)
+	assert.Equal(t, time.Second, cfg.Timeout)
+	assert.True(t, cfg.RefreshBlockhash)
+
+	// txs without limits use the runtime defaults
+	c := batchMsg(t, "c", TxConfig{}, 3)
+	cfg = batchConfig([]pendingTx{a, c})
+	assert.Equal(t, uint32(10_000+defaultInstructionComputeUnitLimit), cfg.ComputeUnitLimit)
+	assert.Zero(t, cfg.LoadedAccountsDataSizeLimit)
+}
+
+func TestBatchFits(t *testing.T) {
+	var msgs []pendingTx
+	for i := 0; batchFits(append(slices.Clip(msgs), batchMsg(t, "", TxConfig{ComputeUnitLimit: 1_000}, make([]byte, 100)...))); i++ {
+		msgs = append(msgs, batchMsg(t, "", TxConfig{ComputeUnitLimit: 1_000}, make([]byte, 100)...))
+	}
+	// the size of the signed batch tx is limited
+	assert.Greater(t, len(msgs), 1)
+	tx, _, err := batchTx(msgs)
+	require.NoError(t, err)
+	data, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	assert.Less(t, len(data), MaxTxSize)
+
+	// the compute unit limit of the batch tx is limited
+	assert.True(t, batchFits([]pendingTx{batchMsg(t, "", TxConfig{ComputeUnitLimit: 1_000_000}), batchMsg(t, "", TxConfig{ComputeUnitLimit: 400_000})}))
+	assert.False(t, batchFits([]pendingTx{batchMsg(t, "", TxConfig{ComputeUnitLimit: 1_000_000}), batchMsg(t, "", TxConfig{ComputeUnitLimit: 400_001})}))
+}
+
+func TestBatchMember(t *testing.T) {
+	members := []batchMember{
+		{id: "a", loc: TxBatchMember{Instruction: 0, Instructions: 2}},
+		{id: "b", loc: TxBatchMember{Instruction: 2, Instructions: 1}},
+	}
+	// compute budget instructions are added to the attempts
+	programs := []solana.PublicKey{fees.ComputeBudgetProgram, solana.MemoProgramID, solana.MemoProgramID, fees.ComputeBudgetProgram, solana.MemoProgramID}
+
+	m, idx, ok := member(members, programs, 2)
+	require.True(t, ok)
+	assert.Equal(t, "a", m.id)
+	assert.Equal(t, 1, idx)
+	m, idx, ok = member(members, programs, 4)
+	require.True(t, ok)
+	assert.Equal(t, "b", m.id)
+	assert.Equal(t, 0, idx)
+	_, _, ok = member(members, programs, 3)
+	assert.False(t, ok)
+	_, _, ok = member(members, programs, 5)
+	assert.False(t, ok)
+}
+
+func TestPendingTxContextWithBatches_RetryPolicy(t *testing.T) {
+	store := newMemoryTxStore()
+	events := newTxEvents(logger.Test(t))
+	cfg := config.NewDefault()
+	retries := uint32(1)
+	cfg.Chain.TxRetryDropped = &retries
+	batches := newTxBatches(store)
+	var requeued []string
+	txs := newPendingTxContextWithBatches(newPendingTxContext(store), batches, events, newTxRetryPolicy("test", cfg, store, events), func(msgs []pendingTx) {
+		for _, msg := range msgs {
+			requeued = append(requeued, msg.id)
+		}
+	})
+
+	ids := []string{uuid.NewString(), uuid.NewString()}
+	for _, id := range ids {
+		require.NoError(t, txs.Pending(id, nil, 0, ""))
+	}
+	// sendBatch sends the txs in a batch tx that is dropped
+	sendBatch := func(sig solana.Signature) {
+		batch := "batch-" + uuid.NewString()
+		require.NoError(t, txs.Pending(batch, nil, 0, ""))
+		members := make([]batchMember, len(ids))
+		for i, id := range ids {
+			members[i] = batchMember{id: id, loc: TxBatchMember{Instruction: i, Instructions: 1}, msg: &pendingTx{id: id}}
+		}
+		require.NoError(t, batches.add(batch, members))
+		require.NoError(t, txs.New(batch, TxAttempt{Signature: sig}, func() {}))
+		require.NotEmpty(t, txs.OnError(sig, TxFailDrop, errors.New("tx dropped")))
+	}
+
+	// the members of the failed batch tx are requeued while they have retries left
+	sendBatch(solana.Signature{1})
+	assert.Equal(t, ids, requeued)
+	for _, id := range ids {
+		rec, err := store.Get(id)
+		require.NoError(t, err)
+		assert.Equal(t, TxStatePending, rec.State)
+		assert.Equal(t, 1, rec.Retries[TxRetryDropped])
+		assert.Nil(t, rec.Batch)
+	}
+
+	// members that exhausted their retries fail and are dead-lettered
+	sendBatch(solana.Signature{2})
+	assert.Len(t, requeued, len(ids))
+	deadLetters, err := store.ListDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, len(ids))
+	for _, rec := range deadLetters {
+		assert.Contains(t, ids, rec.ID)
+		assert.Equal(t, TxStateFailed, rec.State)
+	}
+}
+
+func TestTxm_Batching(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.NewWallet().PrivateKey
+	batching := true
+	cfg := config.NewDefault()
+	cfg.Chain.TxBatching = &batching
+
+	var lock sync.Mutex
+	var sentTxs []*solana.Transaction
+	txs := map[solana.Signature]*solana.Transaction{}
+	var sending sync.Mutex // held by the test to queue txs while a tx of the fee payer is being sent
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		tx := args.Get(1).(*solana.Transaction)
+		lock.Lock()
+		if _, ok := txs[tx.Signatures[0]]; !ok {
+			sentTxs = append(sentTxs, tx)
+			txs[tx.Signatures[0]] = tx
+		}
+		lock.Unlock()
+		sending.Lock()
+		defer sending.Unlock()
+	}).Return(func(_ context.Context, tx *solana.Transaction) solana.Signature {
+		return tx.Signatures[0]
+	}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction, _ *rpc.SimulateTransactionOpts) *rpc.SimulateTransactionResult {
+		return &rpc.SimulateTransactionResult{Err: instructionError(t, tx, memoSimRevert)}
+	}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) []*rpc.SignatureStatusesResult {
+		lock.Lock()
+		defer lock.Unlock()
+		res := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i, sig := range sigs {
+			tx, ok := txs[sig]
+			if !ok || instructionError(t, tx, memoSimRevert) != nil {
+				continue // not found, txs that fail simulation are not included
+			}
+			res[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed, Err: instructionError(t, tx, memoChainRevert)}
+		}
+		return res
+	}, nil).Maybe()
+
+	txm := NewTxm("batching", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	sentWith := func(data ...byte) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, tx := range sentTxs {
+			var sent []byte
+			for _, memo := range memos(tx) {
+				sent = append(sent, memo...)
+			}
+			if slices.Equal(sent, data) {
+				return true
+			}
+		}
+		return false
+	}
+	// enqueue queues the txs while the first tx is being sent
+	enqueue := func(data ...byte) []string {
+		sending.Lock()
+		ids := make([]string, len(data))
+		for i, d := range data {
+			ids[i] = uuid.NewString()
+			require.NoError(t, txm.Enqueue(ctx, "test", memoTx(t, payer.PublicKey(), d), &ids[i]))
+			if i == 0 {
+				require.Eventually(t, func() bool { return sentWith(d) }, tests.WaitTimeout(t), 10*time.Millisecond)
+			}
+		}
+		sending.Unlock()
+		return ids
+	}
+	waitState := func(id string, state TxState) TxStatus {
+		var status TxStatus
+		require.Eventually(t, func() bool {
+			var err error
+			status, err = txm.GetTransactionStatus(ctx, id)
+			return err == nil && status.State == state
+		}, tests.WaitTimeout(t), 10*time.Millisecond, "tx %s not %s", id, state)
+		return status
+	}
+
+	t.Run("batch tx", func(t *testing.T) {
+		ids := enqueue(1, 2, 3, 4)
+		for _, id := range ids {
+			waitState(id, TxStateConfirmed)
+		}
+		// the queued txs are sent in one batch tx, the state of the batch tx is set on its members
+		assert.True(t, sentWith(2, 3, 4))
+		status := waitState(ids[2], TxStateConfirmed)
+		assert.Equal(t, status.Signature, waitState(ids[1], TxStateConfirmed).Signature)
+		require.NotNil(t, status.Batch)
+		assert.Equal(t, 1, status.Batch.Instruction)
+		batch, err := txm.GetTransactionStatus(ctx, status.Batch.Batch)
+		require.NoError(t, err)
+		assert.Equal(t, status.Signature, batch.Signature)
+	})
+
+	t.Run("reverted instruction", func(t *testing.T) {
+		ids := enqueue(5, 6, memoChainRevert, 7)
+		// the tx of the reverted instruction fails, the other txs of the batch tx are requeued
+		status := waitState(ids[2], TxStateFailed)
+		require.NotNil(t, status.TxError)
+		assert.Equal(t, 0, status.TxError.InstructionIndex)
+		assert.Equal(t, solana.MemoProgramID, status.TxError.Program)
+		waitState(ids[1], TxStateConfirmed)
+		waitState(ids[3], TxStateConfirmed)
+		assert.True(t, sentWith(6, memoChainRevert, 7))
+		assert.True(t, sentWith(6, 7))
+
+		// the failed tx exhausted its retries and can be resubmitted from the dead-letter list
+		deadLetters, err := txm.DeadLetters(ctx)
+		require.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(deadLetters, func(rec TxRecord) bool { return rec.ID == ids[2] }))
+	})
+
+	t.Run("split on simulation error", func(t *testing.T) {
+		ids := enqueue(8, 9, memoSimRevert, 10)
+		// the batch tx is split until the tx that fails simulation is sent on its own
+		waitState(ids[1], TxStateConfirmed)
+		waitState(ids[3], TxStateConfirmed)
+		waitState(ids[2], TxStateFailed)
+		assert.True(t, sentWith(memoSimRevert))
+		assert.False(t, sentWith(9, memoSimRevert, 10))
+	})
+}
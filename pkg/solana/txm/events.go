@@ -0,0 +1,401 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+)
+
+const (
+	eventQueueLen      = 1000 // events waiting to be delivered, events are dropped if the dispatcher falls behind
+	eventSubscriberLen = 100  // buffered events per subscriber, events are dropped for subscribers that do not keep up
+)
+
+// TxEventType is a step in the lifecycle of a tx
+type TxEventType int
+
+const (
+	TxEventEnqueued   TxEventType = iota // accepted by Enqueue
+	TxEventBroadcast                     // initial attempt broadcasted
+	TxEventFeeBumped                     // re-signed with a bumped fee or refreshed blockhash and broadcasted
+	TxEventProcessed                     // included in a block
+	TxEventConfirmed                     // included in a block confirmed by the cluster
+	TxEventFinalized                     // included in a finalized block
+	TxEventReverted                      // included or simulated and failed with a TransactionError
+	TxEventDropped                       // not included before the blockhash expired, the nonce advanced or the confirm timeout
+	TxEventFailed                        // rejected by the RPC or failed before being broadcasted
+	TxEventCancelled                     // cancelled or replaced before being included
+	TxEventRolledBack                    // confirmed but no longer found, the block was rolled back
+	TxEventRetried                       // failed and queued to be rebuilt and resent per the retry policy of the error class
+)
+
+func (t TxEventType) String() string {
+	switch t {
+	case TxEventEnqueued:
+		return "Enqueued"
+	case TxEventBroadcast:
+		return "Broadcast"
+	case TxEventFeeBumped:
+		return "FeeBumped"
+	case TxEventProcessed:
+		return "Processed"
+	case TxEventConfirmed:
+		return "Confirmed"
+	case TxEventFinalized:
+		return "Finalized"
+	case TxEventReverted:
+		return "Reverted"
+	case TxEventDropped:
+		return "Dropped"
+	case TxEventFailed:
+		return "Failed"
+	case TxEventCancelled:
+		return "Cancelled"
+	case TxEventRolledBack:
+		return "RolledBack"
+	case TxEventRetried:
+		return "Retried"
+	default:
+		return fmt.Sprintf("TxEventType(%d)", int(t))
+	}
+}
+
+// TxEvent is published by the txm when a tx moves through its lifecycle
+type TxEvent struct {
+	Type             TxEventType
+	ID               string
+	State            TxState          // state of the tx after the event
+	Signature        solana.Signature // signature of the attempt that triggered the event
+	ComputeUnitPrice uint64           // price of the broadcasted attempt (Broadcast and FeeBumped)
+	Error            string           // reason for Reverted, Dropped, Failed, Cancelled and Retried
+	TxError          *DecodedTxError  // decoded TransactionError for Reverted (nil if unknown)
+	Timestamp        time.Time
+}
+
+// DecodedTxError is the TransactionError of a reverted tx
+type DecodedTxError struct {
+	Name             string           // TransactionError variant, i.e. InstructionError
+	InstructionIndex int              // failed instruction of an InstructionError with a custom program error
+	CustomCode       *uint32          // program error code of an InstructionError with a custom program error
+	Program          solana.PublicKey // program of the failed instruction (zero if unknown)
+	ErrorName        string           // custom program error name from the IDL of the program, i.e. StaleReport (empty if unknown)
+	ErrorMsg         string           // custom program error message from the IDL of the program
+	Logs             []string         // program logs of the simulation (nil if not simulated)
+	Raw              any              // TransactionError as returned by the RPC
+}
+
+// clone returns a copy of the error, Raw is shared as it is only read
+func (e *DecodedTxError) clone() *DecodedTxError {
+	if e == nil {
+		return nil
+	}
+	out := *e
+	if e.CustomCode != nil {
+		code := *e.CustomCode
+		out.CustomCode = &code
+	}
+	out.Logs = append([]string(nil), e.Logs...)
+	return &out
+}
+
+// ProgramError returns the decoded custom program error, empty if unknown
+func (e *DecodedTxError) ProgramError() string {
+	if e == nil || e.ErrorName == "" {
+		return ""
+	}
+	if e.ErrorMsg == "" {
+		return e.ErrorName
+	}
+	return fmt.Sprintf("%s: %s", e.ErrorName, e.ErrorMsg)
+}
+
+// TxError is the TransactionError returned by the RPC for a tx that was included or simulated
+type TxError struct {
+	Reason string // i.e. "tx reverted"
+	Err    any
+	Logs   []string // program logs of the simulation (nil if not simulated)
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+// decodeTxError returns the decoded TransactionError of a tx error or a send error that failed preflight simulation
+func decodeTxError(err error) *DecodedTxError {
+	var raw any
+	var logs []string
+	var txErr *TxError
+	if errors.As(err, &txErr) {
+		raw, logs = txErr.Err, txErr.Logs
+	} else if preflightErr, ok := client.TransactionErrorFromSendError(err); ok {
+		raw = preflightErr
+	}
+	if raw == nil {
+		return nil
+	}
+
+	decoded := &DecodedTxError{Name: client.TransactionErrorName(raw), Logs: logs, Raw: raw}
+	if index, code, ok := client.InstructionErrorCustomCode(raw); ok {
+		decoded.InstructionIndex = index
+		decoded.CustomCode = &code
+	}
+	return decoded
+}
+
+// txEvents delivers published events in order to subscribers and the callbacks registered per tx id
+type txEvents struct {
+	lggr logger.Logger
+	chIn chan TxEvent
+
+	lock      sync.Mutex
+	subs      map[int]chan TxEvent
+	nextSub   int
+	callbacks map[string]func(TxEvent)
+	closed    bool
+}
+
+func newTxEvents(lggr logger.Logger) *txEvents {
+	return &txEvents{
+		lggr:      lggr,
+		chIn:      make(chan TxEvent, eventQueueLen),
+		subs:      map[int]chan TxEvent{},
+		callbacks: map[string]func(TxEvent){},
+	}
+}
+
+// publish queues the event for delivery, never blocks tx processing
+func (e *txEvents) publish(ev TxEvent) {
+	ev.Timestamp = time.Now()
+	select {
+	case e.chIn <- ev:
+	default:
+		e.lggr.Warnw("event queue full, dropping tx event", "id", ev.ID, "event", ev.Type)
+	}
+}
+
+// subscribe returns a channel receiving all events and a func to unsubscribe
+func (e *txEvents) subscribe() (<-chan TxEvent, func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	ch := make(chan TxEvent, eventSubscriberLen)
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	id := e.nextSub
+	e.nextSub++
+	e.subs[id] = ch
+	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		if sub, ok := e.subs[id]; ok {
+			delete(e.subs, id)
+			close(sub)
+		}
+	}
+}
+
+// register sets the callback for events of the tx id, the callback is removed once the tx is finished
+func (e *txEvents) register(id string, fn func(TxEvent)) {
+	if fn == nil {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.callbacks[id] = fn
+}
+
+func (e *txEvents) unregister(id string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	delete(e.callbacks, id)
+}
+
+// run delivers events until stopped, subscriber channels are closed on exit
+func (e *txEvents) run(chStop <-chan struct{}) {
+	defer e.close()
+	for {
+		select {
+		case ev := <-e.chIn:
+			e.deliver(ev)
+		case <-chStop:
+			return
+		}
+	}
+}
+
+func (e *txEvents) deliver(ev TxEvent) {
+	e.lock.Lock()
+	for _, sub := range e.subs {
+		select {
+		case sub <- ev:
+		default:
+			e.lggr.Warnw("subscriber is not keeping up, dropping tx event", "id", ev.ID, "event", ev.Type)
+		}
+	}
+	fn := e.callbacks[ev.ID]
+	if ev.State.IsTerminal() {
+		delete(e.callbacks, ev.ID)
+	}
+	e.lock.Unlock()
+
+	// callbacks are called outside the lock so they can subscribe or enqueue txs
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+func (e *txEvents) close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.closed = true
+	for id, sub := range e.subs {
+		delete(e.subs, id)
+		close(sub)
+	}
+}
+
+var _ PendingTxContext = &pendingTxContextWithEvents{}
+
+// pendingTxContextWithEvents publishes the lifecycle events of the state changes of the wrapped PendingTxContext
+type pendingTxContextWithEvents struct {
+	PendingTxContext
+	events *txEvents
+
+	lock      sync.Mutex
+	processed map[string]struct{} // txs with a published Processed event, processed txs are polled until confirmed
+}
+
+func newPendingTxContextWithEvents(txs PendingTxContext, events *txEvents) *pendingTxContextWithEvents {
+	return &pendingTxContextWithEvents{
+		PendingTxContext: txs,
+		events:           events,
+		processed:        map[string]struct{}{},
+	}
+}
+
+func (c *pendingTxContextWithEvents) New(id string, attempt TxAttempt, cancel context.CancelFunc) error {
+	if err := c.PendingTxContext.New(id, attempt, cancel); err != nil {
+		return err
+	}
+	c.events.publish(TxEvent{Type: TxEventBroadcast, ID: id, State: TxStateBroadcasted, Signature: attempt.Signature, ComputeUnitPrice: attempt.ComputeUnitPrice})
+	return nil
+}
+
+func (c *pendingTxContextWithEvents) Add(id string, attempt TxAttempt) error {
+	if err := c.PendingTxContext.Add(id, attempt); err != nil {
+		return err
+	}
+	c.events.publish(TxEvent{Type: TxEventFeeBumped, ID: id, State: c.state(id), Signature: attempt.Signature, ComputeUnitPrice: attempt.ComputeUnitPrice})
+	return nil
+}
+
+func (c *pendingTxContextWithEvents) OnProcessed(sig solana.Signature) string {
+	id := c.PendingTxContext.OnProcessed(sig)
+	if id == "" {
+		return id
+	}
+	c.lock.Lock()
+	_, published := c.processed[id]
+	c.processed[id] = struct{}{}
+	c.lock.Unlock()
+	if !published {
+		c.events.publish(TxEvent{Type: TxEventProcessed, ID: id, State: TxStateProcessed, Signature: sig})
+	}
+	return id
+}
+
+func (c *pendingTxContextWithEvents) OnSuccess(sig solana.Signature) string {
+	id := c.PendingTxContext.OnSuccess(sig)
+	if id != "" {
+		c.finished(id)
+		c.events.publish(TxEvent{Type: TxEventConfirmed, ID: id, State: c.state(id), Signature: sig})
+	}
+	return id
+}
+
+func (c *pendingTxContextWithEvents) OnFinalized(sig solana.Signature) string {
+	id := c.PendingTxContext.OnFinalized(sig)
+	if id != "" {
+		c.events.publish(TxEvent{Type: TxEventFinalized, ID: id, State: c.state(id), Signature: sig})
+	}
+	return id
+}
+
+func (c *pendingTxContextWithEvents) OnRollback(sig solana.Signature) string {
+	id := c.PendingTxContext.OnRollback(sig)
+	if id != "" {
+		c.finished(id) // Processed is published again if the tx is included again
+		c.events.publish(TxEvent{Type: TxEventRolledBack, ID: id, State: c.state(id), Signature: sig})
+	}
+	return id
+}
+
+func (c *pendingTxContextWithEvents) OnError(sig solana.Signature, errType int, txErr error) string {
+	id := c.PendingTxContext.OnError(sig, errType, txErr)
+	if id != "" {
+		c.finished(id)
+		c.publishError(id, sig, errType, txErr)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithEvents) OnPendingError(id string, errType int, txErr error) {
+	c.PendingTxContext.OnPendingError(id, errType, txErr)
+	c.publishError(id, solana.Signature{}, errType, txErr)
+}
+
+func (c *pendingTxContextWithEvents) publishError(id string, sig solana.Signature, errType int, txErr error) {
+	ev := TxEvent{ID: id, Signature: sig, Error: errString(txErr)}
+	var decoded *DecodedTxError
+	rec, err := c.Get(id)
+	if err == nil {
+		ev.State = rec.State
+		ev.Error = rec.Error // cancelled txs are recorded with the cancellation reason
+		decoded = rec.TxError
+	}
+	ev.Type = errorEventType(ev.State, errType)
+	if ev.Type == TxEventReverted {
+		ev.TxError = decoded
+		if ev.TxError == nil {
+			ev.TxError = decodeTxError(txErr)
+		}
+	}
+	c.events.publish(ev)
+}
+
+// errorEventType returns the event of a tx that failed with the err type and moved to the state
+func errorEventType(state TxState, errType int) TxEventType {
+	switch {
+	case state == TxStateCancelled:
+		return TxEventCancelled
+	case errType == TxFailRevert || errType == TxFailSimRevert:
+		return TxEventReverted
+	case errType == TxFailDrop:
+		return TxEventDropped
+	default:
+		return TxEventFailed
+	}
+}
+
+func (c *pendingTxContextWithEvents) finished(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.processed, id)
+}
+
+func (c *pendingTxContextWithEvents) state(id string) TxState {
+	rec, err := c.Get(id)
+	if err != nil {
+		return TxStateNotFound
+	}
+	return rec.State
+}
@@ -0,0 +1,167 @@
+package txm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+func TestPendingTxContextWithEvents(t *testing.T) {
+	events := newTxEvents(logger.Test(t))
+	program := solana.PublicKey{9}
+	base := newPendingTxContext(newMemoryTxStore())
+	base.errs = newProgramErrors()
+	base.errs.register(program, []codec.IdlErrorCode{{Code: 6000, Name: "StaleReport", Msg: "stale report"}})
+	txs := newPendingTxContextWithEvents(base, events)
+	next := func() TxEvent {
+		select {
+		case ev := <-events.chIn:
+			return ev
+		default:
+			require.FailNow(t, "no event published")
+			return TxEvent{}
+		}
+	}
+
+	// broadcast, bump, processed once, confirmed
+	id := uuid.NewString()
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.NoError(t, txs.New(id, TxAttempt{Signature: solana.Signature{1}, ComputeUnitPrice: 1}, func() {}))
+	require.NoError(t, txs.Add(id, TxAttempt{Signature: solana.Signature{2}, ComputeUnitPrice: 2}))
+	txs.OnProcessed(solana.Signature{2})
+	txs.OnProcessed(solana.Signature{2})
+	txs.OnSuccess(solana.Signature{2})
+
+	ev := next()
+	assert.Equal(t, TxEventBroadcast, ev.Type)
+	assert.Equal(t, id, ev.ID)
+	assert.Equal(t, uint64(1), ev.ComputeUnitPrice)
+	ev = next()
+	assert.Equal(t, TxEventFeeBumped, ev.Type)
+	assert.Equal(t, solana.Signature{2}, ev.Signature)
+	assert.Equal(t, uint64(2), ev.ComputeUnitPrice)
+	assert.Equal(t, TxEventProcessed, next().Type)
+	ev = next()
+	assert.Equal(t, TxEventConfirmed, ev.Type)
+	assert.Equal(t, TxStateConfirmed, ev.State)
+	assert.Empty(t, events.chIn)
+
+	// rolled back, processed again, finalized
+	for range RollbackMisses {
+		txs.OnRollback(solana.Signature{2})
+	}
+	txs.OnProcessed(solana.Signature{2})
+	txs.OnSuccess(solana.Signature{2})
+	txs.OnFinalized(solana.Signature{2})
+	ev = next()
+	assert.Equal(t, TxEventRolledBack, ev.Type)
+	assert.Equal(t, TxStateBroadcasted, ev.State)
+	assert.Equal(t, TxEventProcessed, next().Type)
+	assert.Equal(t, TxEventConfirmed, next().Type)
+	ev = next()
+	assert.Equal(t, TxEventFinalized, ev.Type)
+	assert.Equal(t, TxStateFinalized, ev.State)
+	assert.Empty(t, events.chIn)
+
+	// reverted txs include the decoded error
+	var txErr any
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError": [1, {"Custom": 6000}]}`), &txErr))
+	revertID := uuid.NewString()
+	require.NoError(t, txs.Pending(revertID, nil, 0, ""))
+	require.NoError(t, txs.New(revertID, TxAttempt{Signature: solana.Signature{3}, Programs: []solana.PublicKey{fees.ComputeBudgetProgram, program}}, func() {}))
+	next()
+	txs.OnError(solana.Signature{3}, TxFailSimRevert, &TxError{Reason: "simulation reverted", Err: txErr, Logs: []string{"Program log: stale"}})
+	ev = next()
+	assert.Equal(t, TxEventReverted, ev.Type)
+	assert.Equal(t, TxStateFailed, ev.State)
+	require.NotNil(t, ev.TxError)
+	assert.Equal(t, "InstructionError", ev.TxError.Name)
+	assert.Equal(t, 1, ev.TxError.InstructionIndex)
+	require.NotNil(t, ev.TxError.CustomCode)
+	assert.Equal(t, uint32(6000), *ev.TxError.CustomCode)
+	// custom program errors are decoded from the IDL of the program of the failed instruction
+	assert.Equal(t, program, ev.TxError.Program)
+	assert.Equal(t, "StaleReport", ev.TxError.ErrorName)
+	assert.Equal(t, "stale report", ev.TxError.ErrorMsg)
+	assert.Equal(t, []string{"Program log: stale"}, ev.TxError.Logs)
+	rec, err := txs.Get(revertID)
+	require.NoError(t, err)
+	assert.Equal(t, ev.TxError, rec.TxError)
+	assert.Equal(t, "simulation reverted: map[InstructionError:[1 map[Custom:6000]]] (StaleReport: stale report)", rec.Error)
+
+	// dropped, cancelled and failed txs
+	dropID := uuid.NewString()
+	require.NoError(t, txs.Pending(dropID, nil, 0, ""))
+	require.NoError(t, txs.New(dropID, TxAttempt{Signature: solana.Signature{4}}, func() {}))
+	next()
+	txs.OnError(solana.Signature{4}, TxFailDrop, errors.New("tx blockhash expired"))
+	ev = next()
+	assert.Equal(t, TxEventDropped, ev.Type)
+	assert.Equal(t, "tx blockhash expired", ev.Error)
+
+	cancelID := uuid.NewString()
+	require.NoError(t, txs.Pending(cancelID, nil, 0, ""))
+	require.NoError(t, txs.New(cancelID, TxAttempt{Signature: solana.Signature{5}}, func() {}))
+	next()
+	require.NoError(t, txs.Cancel(cancelID, ErrTxCancelled))
+	txs.OnError(solana.Signature{5}, TxFailDrop, errors.New("tx blockhash expired"))
+	ev = next()
+	assert.Equal(t, TxEventCancelled, ev.Type)
+	assert.Equal(t, ErrTxCancelled.Error(), ev.Error)
+
+	rejectID := uuid.NewString()
+	require.NoError(t, txs.Pending(rejectID, nil, 0, ""))
+	txs.OnPendingError(rejectID, TxFailInvalid, &jsonrpc.RPCError{Code: -32003, Message: "signature verification failure"})
+	ev = next()
+	assert.Equal(t, TxEventFailed, ev.Type)
+	assert.Equal(t, TxStateFatal, ev.State)
+	assert.Nil(t, ev.TxError)
+}
+
+func TestTxEvents(t *testing.T) {
+	events := newTxEvents(logger.Test(t))
+	sub, unsubscribe := events.subscribe()
+	var received []TxEvent
+	events.register("a", func(ev TxEvent) { received = append(received, ev) })
+	events.register("b", nil)
+
+	// callbacks receive the events of their tx until it is finished
+	events.deliver(TxEvent{Type: TxEventBroadcast, ID: "a", State: TxStateBroadcasted})
+	events.deliver(TxEvent{Type: TxEventBroadcast, ID: "b", State: TxStateBroadcasted})
+	events.deliver(TxEvent{Type: TxEventConfirmed, ID: "a", State: TxStateConfirmed})
+	events.deliver(TxEvent{Type: TxEventFinalized, ID: "a", State: TxStateFinalized})
+	events.deliver(TxEvent{Type: TxEventFinalized, ID: "a", State: TxStateFinalized})
+	require.Len(t, received, 3)
+	assert.Equal(t, TxEventBroadcast, received[0].Type)
+	assert.Equal(t, TxEventConfirmed, received[1].Type)
+	assert.Equal(t, TxEventFinalized, received[2].Type)
+
+	// subscribers receive all events
+	for _, expected := range []string{"a", "b", "a", "a", "a"} {
+		assert.Equal(t, expected, (<-sub).ID)
+	}
+
+	// slow subscribers drop events instead of blocking
+	for i := 0; i < eventSubscriberLen+1; i++ {
+		events.deliver(TxEvent{ID: "c"})
+	}
+	assert.Len(t, sub, eventSubscriberLen)
+
+	unsubscribe()
+	unsubscribe() // no-op
+	events.close()
+	closedSub, _ := events.subscribe()
+	_, open := <-closedSub
+	assert.False(t, open)
+}
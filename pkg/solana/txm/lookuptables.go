@@ -0,0 +1,331 @@
+package txm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+)
+
+const (
+	// MaxLookupTableAddresses is the max number of addresses stored in a lookup table
+	MaxLookupTableAddresses = addresslookuptable.LOOKUP_TABLE_MAX_ADDRESSES
+	// MaxExtendLookupTableAddresses is the max number of addresses added by a single extend tx (limited by tx size)
+	MaxExtendLookupTableAddresses = 20
+
+	lookupTablePollPeriod = time.Second // polling rate for lookup table txs
+
+	// address lookup table program instructions
+	lookupTableInstructionCreate uint32 = 0
+	lookupTableInstructionExtend uint32 = 2
+)
+
+// AddressLookupTableProgramID is the native address lookup table program
+var AddressLookupTableProgramID = solanaGo.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
+
+// NewCreateLookupTableInstruction returns the instruction to create a lookup table and the derived lookup table address.
+// recentSlot must be a recent slot, it is used to derive the lookup table address
+func NewCreateLookupTableInstruction(authority, payer solanaGo.PublicKey, recentSlot uint64) (solanaGo.Instruction, solanaGo.PublicKey, error) {
+	slot := binary.LittleEndian.AppendUint64(nil, recentSlot)
+	table, bump, err := solanaGo.FindProgramAddress([][]byte{authority.Bytes(), slot}, AddressLookupTableProgramID)
+	if err != nil {
+		return nil, solanaGo.PublicKey{}, fmt.Errorf("failed to derive lookup table address: %w", err)
+	}
+
+	data := binary.LittleEndian.AppendUint32(nil, lookupTableInstructionCreate)
+	data = append(data, slot...)
+	data = append(data, bump)
+	return solanaGo.NewInstruction(AddressLookupTableProgramID, solanaGo.AccountMetaSlice{
+		solanaGo.Meta(table).WRITE(),
+		solanaGo.Meta(authority).SIGNER(),
+		solanaGo.Meta(payer).WRITE().SIGNER(),
+		solanaGo.Meta(solanaGo.SystemProgramID),
+	}, data), table, nil
+}
+
+// NewExtendLookupTableInstruction returns the instruction to add addresses to a lookup table.
+// added addresses can be used by txs starting from the next slot
+func NewExtendLookupTableInstruction(table, authority, payer solanaGo.PublicKey, addresses []solanaGo.PublicKey) solanaGo.Instruction {
+	data := binary.LittleEndian.AppendUint32(nil, lookupTableInstructionExtend)
+	data = binary.LittleEndian.AppendUint64(data, uint64(len(addresses)))
+	for _, a := range addresses {
+		data = append(data, a.Bytes()...)
+	}
+	return solanaGo.NewInstruction(AddressLookupTableProgramID, solanaGo.AccountMetaSlice{
+		solanaGo.Meta(table).WRITE(),
+		solanaGo.Meta(authority).SIGNER(),
+		solanaGo.Meta(payer).WRITE().SIGNER(),
+		solanaGo.Meta(solanaGo.SystemProgramID),
+	}, data)
+}
+
+// GetLookupTable fetches the addresses stored in an active lookup table
+func GetLookupTable(ctx context.Context, reader client.AccountReader, table solanaGo.PublicKey) (solanaGo.PublicKeySlice, error) {
+	res, err := reader.GetAccountInfoWithOpts(ctx, table, &rpc.GetAccountInfoOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lookup table %s: %w", table, err)
+	}
+	if res == nil || res.Value == nil {
+		return nil, fmt.Errorf("lookup table not found: %s", table)
+	}
+	state, err := addresslookuptable.DecodeAddressLookupTableState(res.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode lookup table %s: %w", table, err)
+	}
+	if !state.IsActive() {
+		return nil, fmt.Errorf("lookup table %s is deactivated", table)
+	}
+	return state.Addresses, nil
+}
+
+// LookupTableTxManager sends the lookup table management txs
+type LookupTableTxManager interface {
+	Enqueue(ctx context.Context, accountID string, tx *solanaGo.Transaction, txID *string, txCfgs ...SetTxConfig) error
+	GetTransactionStatus(ctx context.Context, txID string) (TxStatus, error)
+}
+
+var _ LookupTableTxManager = (*Txm)(nil)
+
+// LookupTableManager creates, extends and caches address lookup tables owned by the authority.
+// txs referencing frequently used accounts can be built as v0 txs using the managed tables so more accounts fit in a single tx
+type LookupTableManager struct {
+	lggr      logger.Logger
+	client    func() (client.Reader, error)
+	txm       LookupTableTxManager
+	authority solanaGo.PublicKey // pays for and signs lookup table txs (must be a keystore account)
+
+	lock   sync.RWMutex
+	tables map[solanaGo.PublicKey]solanaGo.PublicKeySlice // table -> addresses
+	order  []solanaGo.PublicKey                           // managed tables in creation order, new addresses are added to the last table
+	loaded bool                                           // addresses of the managed tables were fetched by Load
+
+	updateLock sync.Mutex    // serializes creating + extending tables
+	pollPeriod time.Duration // polling rate for lookup table txs
+}
+
+// NewLookupTableManager creates a manager for lookup tables owned by the authority.
+// tables are previously created lookup tables to keep using, call Load to populate the cache
+func NewLookupTableManager(lggr logger.Logger, tc func() (client.Reader, error), txm LookupTableTxManager, authority solanaGo.PublicKey, tables []solanaGo.PublicKey) *LookupTableManager {
+	m := &LookupTableManager{
+		lggr:       logger.Named(lggr, "LookupTableManager"),
+		client:     tc,
+		txm:        txm,
+		authority:  authority,
+		tables:     map[solanaGo.PublicKey]solanaGo.PublicKeySlice{},
+		pollPeriod: lookupTablePollPeriod,
+	}
+	for _, t := range tables {
+		if _, exists := m.tables[t]; !exists {
+			m.tables[t] = nil
+			m.order = append(m.order, t)
+		}
+	}
+	return m
+}
+
+// Load fetches the addresses of all managed tables into the cache
+func (m *LookupTableManager) Load(ctx context.Context) error {
+	c, err := m.client()
+	if err != nil {
+		return fmt.Errorf("failed to get client in LookupTableManager.Load: %w", err)
+	}
+
+	m.lock.RLock()
+	tables := append([]solanaGo.PublicKey{}, m.order...)
+	m.lock.RUnlock()
+
+	for _, t := range tables {
+		addresses, err := GetLookupTable(ctx, c, t)
+		if err != nil {
+			return err
+		}
+		m.lock.Lock()
+		m.tables[t] = addresses
+		m.lock.Unlock()
+	}
+
+	m.lock.Lock()
+	m.loaded = true
+	m.lock.Unlock()
+	return nil
+}
+
+// Loaded returns if the addresses of the managed tables were fetched by Load
+func (m *LookupTableManager) Loaded() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.loaded
+}
+
+// Tables returns the addresses of the managed tables (created tables can be persisted and passed to NewLookupTableManager)
+func (m *LookupTableManager) Tables() []solanaGo.PublicKey {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return append([]solanaGo.PublicKey{}, m.order...)
+}
+
+// AddressTables returns the cached tables that contain any of the accounts, for use with solana.TransactionAddressTables
+func (m *LookupTableManager) AddressTables(accounts []solanaGo.PublicKey) map[solanaGo.PublicKey]solanaGo.PublicKeySlice {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	out := map[solanaGo.PublicKey]solanaGo.PublicKeySlice{}
+	for _, t := range m.order {
+		for _, a := range accounts {
+			if m.tables[t].Contains(a) {
+				out[t] = append(solanaGo.PublicKeySlice{}, m.tables[t]...)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// NewTransaction builds a tx that loads accounts from the managed tables (a v0 tx if any account is found in the tables)
+func (m *LookupTableManager) NewTransaction(instructions []solanaGo.Instruction, blockhash solanaGo.Hash, payer solanaGo.PublicKey) (*solanaGo.Transaction, error) {
+	var accounts []solanaGo.PublicKey
+	for _, ix := range instructions {
+		for _, a := range ix.Accounts() {
+			accounts = append(accounts, a.PublicKey)
+		}
+	}
+	return solanaGo.NewTransaction(instructions, blockhash, solanaGo.TransactionPayer(payer), solanaGo.TransactionAddressTables(m.AddressTables(accounts)))
+}
+
+// EnsureAddresses adds the accounts missing from the managed tables.
+// the last managed table is extended, a new table is created if there are no tables or the last table is full
+func (m *LookupTableManager) EnsureAddresses(ctx context.Context, accounts []solanaGo.PublicKey) error {
+	m.updateLock.Lock()
+	defer m.updateLock.Unlock()
+
+	// find missing accounts (deduplicated)
+	var missing solanaGo.PublicKeySlice
+	m.lock.RLock()
+	for _, a := range accounts {
+		if missing.Contains(a) || m.contains(a) {
+			continue
+		}
+		missing = append(missing, a)
+	}
+	m.lock.RUnlock()
+
+	for len(missing) > 0 {
+		table, remaining, err := m.extendableTable(ctx)
+		if err != nil {
+			return err
+		}
+
+		batch := missing[:min(len(missing), remaining, MaxExtendLookupTableAddresses)]
+		if err := m.send(ctx, NewExtendLookupTableInstruction(table, m.authority, m.authority, batch)); err != nil {
+			return fmt.Errorf("failed to extend lookup table %s: %w", table, err)
+		}
+		m.lock.Lock()
+		m.tables[table] = append(m.tables[table], batch...)
+		m.lock.Unlock()
+		m.lggr.Infow("extended lookup table", "table", table, "addresses", batch)
+		missing = missing[len(batch):]
+	}
+	return nil
+}
+
+// contains returns if the account is in any managed table (requires lock)
+func (m *LookupTableManager) contains(account solanaGo.PublicKey) bool {
+	for _, addresses := range m.tables {
+		if addresses.Contains(account) {
+			return true
+		}
+	}
+	return false
+}
+
+// extendableTable returns the last managed table and the number of addresses it can still store, creating a table if needed
+func (m *LookupTableManager) extendableTable(ctx context.Context) (solanaGo.PublicKey, int, error) {
+	m.lock.RLock()
+	if len(m.order) > 0 {
+		last := m.order[len(m.order)-1]
+		if remaining := MaxLookupTableAddresses - len(m.tables[last]); remaining > 0 {
+			m.lock.RUnlock()
+			return last, remaining, nil
+		}
+	}
+	m.lock.RUnlock()
+
+	c, err := m.client()
+	if err != nil {
+		return solanaGo.PublicKey{}, 0, fmt.Errorf("failed to get client in LookupTableManager: %w", err)
+	}
+	// the table address is derived from a slot that must be in the SlotHashes of the validator creating the table,
+	// a processed slot can still be skipped
+	slot, err := c.SlotHeightWithCommitment(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solanaGo.PublicKey{}, 0, fmt.Errorf("failed to get recent slot for lookup table: %w", err)
+	}
+	ix, table, err := NewCreateLookupTableInstruction(m.authority, m.authority, slot)
+	if err != nil {
+		return solanaGo.PublicKey{}, 0, err
+	}
+	if err := m.send(ctx, ix); err != nil {
+		return solanaGo.PublicKey{}, 0, fmt.Errorf("failed to create lookup table: %w", err)
+	}
+
+	m.lock.Lock()
+	m.tables[table] = solanaGo.PublicKeySlice{}
+	m.order = append(m.order, table)
+	m.lock.Unlock()
+	m.lggr.Infow("created lookup table", "table", table, "authority", m.authority)
+	return table, MaxLookupTableAddresses, nil
+}
+
+// send enqueues a lookup table tx and waits until it is finished
+func (m *LookupTableManager) send(ctx context.Context, ix solanaGo.Instruction) error {
+	c, err := m.client()
+	if err != nil {
+		return fmt.Errorf("failed to get client in LookupTableManager: %w", err)
+	}
+	blockhash, err := c.LatestBlockhash(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+	if blockhash == nil || blockhash.Value == nil {
+		return errors.New("failed to get latest blockhash: empty result")
+	}
+	tx, err := solanaGo.NewTransaction([]solanaGo.Instruction{ix}, blockhash.Value.Blockhash, solanaGo.TransactionPayer(m.authority))
+	if err != nil {
+		return fmt.Errorf("failed to build lookup table tx: %w", err)
+	}
+
+	id := "lookup-table-" + uuid.NewString()
+	if err = m.txm.Enqueue(ctx, m.authority.String(), tx, &id); err != nil {
+		return err
+	}
+
+	tick := time.NewTicker(m.pollPeriod)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			status, err := m.txm.GetTransactionStatus(ctx, id)
+			if err != nil {
+				return err
+			}
+			switch status.State {
+			case TxStateConfirmed, TxStateFinalized:
+				return nil
+			case TxStateFailed, TxStateFatal, TxStateCancelled:
+				return fmt.Errorf("lookup table tx %s failed: %s", id, status.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package txm
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+// lookupTableTxManager confirms all enqueued txs
+type lookupTableTxManager struct {
+	lock sync.Mutex
+	txs  []*solana.Transaction
+}
+
+func (m *lookupTableTxManager) Enqueue(_ context.Context, _ string, tx *solana.Transaction, _ *string, _ ...SetTxConfig) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.txs = append(m.txs, tx)
+	return nil
+}
+
+func (m *lookupTableTxManager) GetTransactionStatus(context.Context, string) (TxStatus, error) {
+	return TxStatus{State: TxStateConfirmed}, nil
+}
+
+func TestLookupTableInstructions(t *testing.T) {
+	authority := solana.PublicKey{1}
+
+	ix, table, err := NewCreateLookupTableInstruction(authority, authority, 100)
+	require.NoError(t, err)
+	expected, bump, err := solana.FindProgramAddress([][]byte{authority.Bytes(), binary.LittleEndian.AppendUint64(nil, 100)}, AddressLookupTableProgramID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, table)
+	assert.Equal(t, AddressLookupTableProgramID, ix.ProgramID())
+	data, err := ix.Data()
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0, 0, 0, 0, 100, 0, 0, 0, 0, 0, 0, 0}, bump), data)
+	assert.Equal(t, table, ix.Accounts()[0].PublicKey)
+
+	ix = NewExtendLookupTableInstruction(table, authority, authority, []solana.PublicKey{{2}, {3}})
+	data, err = ix.Data()
+	require.NoError(t, err)
+	require.Len(t, data, 4+8+2*32)
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data[:4]))
+	assert.Equal(t, uint64(2), binary.LittleEndian.Uint64(data[4:12]))
+	assert.Equal(t, solana.PublicKey{3}.Bytes(), data[12+32:])
+}
+
+func TestLookupTableManager(t *testing.T) {
+	ctx := tests.Context(t)
+	authority := solana.PublicKey{1}
+	existing := solana.PublicKey{2}
+
+	mc := clientmocks.NewReaderWriter(t)
+	data, err := bin.MarshalBin(addresslookuptable.AddressLookupTableState{
+		TypeIndex:        1,
+		DeactivationSlot: math.MaxUint64,
+		Authority:        &authority,
+		Addresses:        solana.PublicKeySlice{{10}, {11}},
+	})
+	require.NoError(t, err)
+	mc.On("GetAccountInfoWithOpts", mock.Anything, existing, mock.Anything).Return(&rpc.GetAccountInfoResult{Value: &rpc.Account{
+		Owner: AddressLookupTableProgramID,
+		Data:  rpc.DataBytesOrJSONFromBytes(data),
+	}}, nil)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{}}, nil)
+	mc.On("SlotHeightWithCommitment", mock.Anything, rpc.CommitmentFinalized).Return(uint64(100), nil)
+
+	txm := &lookupTableTxManager{}
+	m := NewLookupTableManager(logger.Test(t), func() (client.Reader, error) { return mc, nil }, txm, authority, []solana.PublicKey{existing})
+	m.pollPeriod = time.Millisecond
+	assert.False(t, m.Loaded())
+	require.NoError(t, m.Load(ctx))
+	assert.True(t, m.Loaded())
+
+	// cached addresses are used for v0 txs
+	tx, err := m.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, authority, solana.PublicKey{10}).Build(),
+	}, solana.Hash{}, authority)
+	require.NoError(t, err)
+	assert.True(t, tx.Message.IsVersioned())
+	require.Len(t, tx.Message.AddressTableLookups, 1)
+	assert.Equal(t, existing, tx.Message.AddressTableLookups[0].AccountKey)
+
+	// txs without accounts in the tables are legacy txs
+	tx, err = m.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, authority, solana.PublicKey{20}).Build(),
+	}, solana.Hash{}, authority)
+	require.NoError(t, err)
+	assert.False(t, tx.Message.IsVersioned())
+
+	// existing table is filled before creating a new table
+	var accounts []solana.PublicKey
+	for i := 0; i < MaxLookupTableAddresses; i++ {
+		accounts = append(accounts, solana.PublicKey{0, byte(i), 1})
+	}
+	accounts = append(accounts, solana.PublicKey{10}) // already stored
+	require.NoError(t, m.EnsureAddresses(ctx, accounts))
+
+	tables := m.Tables()
+	require.Len(t, tables, 2)
+	assert.Equal(t, existing, tables[0])
+	all := m.AddressTables(accounts)
+	require.Len(t, all, 2)
+	assert.Len(t, all[existing], MaxLookupTableAddresses)
+	assert.Len(t, all[tables[1]], 2)
+
+	// txs: extend existing table (254 addresses), create table, extend new table
+	extends := (MaxLookupTableAddresses - 2 + MaxExtendLookupTableAddresses - 1) / MaxExtendLookupTableAddresses
+	require.Len(t, txm.txs, extends+2)
+	_, created, err := NewCreateLookupTableInstruction(authority, authority, 100)
+	require.NoError(t, err)
+	assert.Equal(t, created, tables[1])
+
+	// no txs if all addresses are stored
+	require.NoError(t, m.EnsureAddresses(ctx, accounts))
+	assert.Len(t, txm.txs, extends+2)
+
+	// empty blockhash results are not sent
+	emptyClient := clientmocks.NewReaderWriter(t)
+	emptyClient.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{}, nil)
+	empty := NewLookupTableManager(logger.Test(t), func() (client.Reader, error) { return emptyClient, nil }, txm, authority, []solana.PublicKey{existing})
+	err = empty.EnsureAddresses(ctx, []solana.PublicKey{{30}})
+	require.EqualError(t, err, "failed to extend lookup table "+existing.String()+": failed to get latest blockhash: empty result")
+}
+
+func TestTxm_VersionedTx(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.PublicKey{1}
+	receiver := solana.PublicKey{2}
+	table := solana.PublicKey{3}
+
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, payer.String(), mock.Anything).Return([]byte{1}, nil)
+
+	// v0 tx is sent with compute budget instructions + lookups preserved
+	sent := make(chan *solana.Transaction, 1)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100}}, nil)
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(solana.Signature{4}, nil)
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("versioned", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, config.NewDefault(), mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, payer, receiver).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(payer), solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+		table: {receiver},
+	}))
+	require.NoError(t, err)
+	id := "versioned"
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, &id))
+
+	var sentTx *solana.Transaction
+	select {
+	case sentTx = <-sent:
+	case <-ctx.Done():
+		t.Fatal("tx not sent")
+	}
+	require.True(t, sentTx.Message.IsVersioned())
+	require.Len(t, sentTx.Message.AddressTableLookups, 1)
+	require.Len(t, sentTx.Signatures, 1)
+	keys, err := sentTx.Message.GetAllKeys()
+	require.NoError(t, err)
+	var transferred bool
+	for _, ix := range sentTx.Message.Instructions {
+		if keys[ix.ProgramIDIndex].Equals(solana.SystemProgramID) {
+			assert.Equal(t, payer, keys[ix.Accounts[0]])
+			assert.Equal(t, receiver, keys[ix.Accounts[1]])
+			transferred = true
+		}
+	}
+	assert.True(t, transferred)
+
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateConfirmed
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,229 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	bin "github.com/gagliardetto/binary"
+	solanaGo "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+// NonceAccountSize is the size in bytes of a durable nonce account
+const NonceAccountSize = 80
+
+// DurableNonce is the nonce stored in a durable nonce account.
+// a tx using the nonce in place of a recent blockhash does not expire until the nonce is advanced
+type DurableNonce struct {
+	Account   solanaGo.PublicKey
+	Authority solanaGo.PublicKey
+	Value     solanaGo.Hash
+}
+
+// NewCreateNonceAccountInstructions returns the instructions to create and initialize a durable nonce account.
+// lamports must cover the rent exemption for NonceAccountSize bytes, and the nonce account must also sign the tx
+func NewCreateNonceAccountInstructions(payer, nonceAccount, authority solanaGo.PublicKey, lamports uint64) []solanaGo.Instruction {
+	return []solanaGo.Instruction{
+		system.NewCreateAccountInstruction(lamports, NonceAccountSize, solanaGo.SystemProgramID, payer, nonceAccount).Build(),
+		system.NewInitializeNonceAccountInstruction(authority, nonceAccount, solanaGo.SysVarRecentBlockHashesPubkey, solanaGo.SysVarRentPubkey).Build(),
+	}
+}
+
+// NewAdvanceNonceInstruction returns the instruction to advance a durable nonce account.
+// advancing the nonce invalidates any unconfirmed tx that uses the current nonce
+func NewAdvanceNonceInstruction(nonceAccount, authority solanaGo.PublicKey) solanaGo.Instruction {
+	return system.NewAdvanceNonceAccountInstruction(nonceAccount, solanaGo.SysVarRecentBlockHashesPubkey, authority).Build()
+}
+
+// GetDurableNonce fetches the current nonce of an initialized durable nonce account with the given authority
+func GetDurableNonce(ctx context.Context, reader client.AccountReader, account, authority solanaGo.PublicKey) (DurableNonce, error) {
+	res, err := reader.GetAccountInfoWithOpts(ctx, account, &rpc.GetAccountInfoOpts{})
+	if err != nil {
+		return DurableNonce{}, fmt.Errorf("failed to fetch nonce account %s: %w", account, err)
+	}
+	if res == nil || res.Value == nil {
+		return DurableNonce{}, fmt.Errorf("nonce account not found: %s", account)
+	}
+	if !res.Value.Owner.Equals(solanaGo.SystemProgramID) {
+		return DurableNonce{}, fmt.Errorf("nonce account %s is not owned by the system program", account)
+	}
+
+	var nonceAccount system.NonceAccount
+	if err = bin.NewBinDecoder(res.Value.Data.GetBinary()).Decode(&nonceAccount); err != nil {
+		return DurableNonce{}, fmt.Errorf("failed to decode nonce account %s: %w", account, err)
+	}
+	if nonceAccount.State != 1 { // 0 = uninitialized, 1 = initialized
+		return DurableNonce{}, fmt.Errorf("nonce account %s is not initialized", account)
+	}
+	if !nonceAccount.AuthorizedPubkey.Equals(authority) {
+		return DurableNonce{}, fmt.Errorf("nonce account %s authority %s does not match %s", account, nonceAccount.AuthorizedPubkey, authority)
+	}
+	return DurableNonce{Account: account, Authority: authority, Value: solanaGo.Hash(nonceAccount.Nonce)}, nil
+}
+
+// setDurableNonce replaces the recent blockhash of the tx with the durable nonce.
+// the AdvanceNonceAccount instruction is added as the first instruction, the nonce authority must be the fee payer
+func setDurableNonce(tx *solanaGo.Transaction, nonce DurableNonce) error {
+	if !tx.Message.AccountKeys[0].Equals(nonce.Authority) {
+		return fmt.Errorf("nonce authority %s is not the fee payer", nonce.Authority)
+	}
+	if fees.IsAdvanceNonceInstruction(tx.Message, 0) {
+		return errors.New("tx already advances a durable nonce")
+	}
+
+	// writable accounts must be added before readonly accounts to keep the readonly indexes stable
+	nonceIdx, err := addAccount(&tx.Message, nonce.Account, true)
+	if err != nil {
+		return err
+	}
+	sysvarIdx, err := addAccount(&tx.Message, solanaGo.SysVarRecentBlockHashesPubkey, false)
+	if err != nil {
+		return err
+	}
+	programIdx, err := addAccount(&tx.Message, solanaGo.SystemProgramID, false)
+	if err != nil {
+		return err
+	}
+
+	data, err := NewAdvanceNonceInstruction(nonce.Account, nonce.Authority).Data()
+	if err != nil {
+		return err
+	}
+	instruction := solanaGo.CompiledInstruction{
+		ProgramIDIndex: programIdx,
+		Accounts:       []uint16{nonceIdx, sysvarIdx, 0}, // fee payer (index 0) is the nonce authority
+		Data:           data,
+	}
+	tx.Message.Instructions = append([]solanaGo.CompiledInstruction{instruction}, tx.Message.Instructions...)
+	tx.Message.RecentBlockhash = nonce.Value
+	return nil
+}
+
+// addAccount returns the index of a non-signer static account in the message, adding it if it does not exist
+// writable accounts are inserted before the readonly unsigned accounts, readonly accounts after them.
+// instruction indexes are shifted to match (including v0 indexes of accounts loaded from lookup tables)
+func addAccount(msg *solanaGo.Message, key solanaGo.PublicKey, writable bool) (uint16, error) {
+	for i, k := range msg.AccountKeys {
+		if !k.Equals(key) {
+			continue
+		}
+		if writable && !isWritableAccount(*msg, i) {
+			return 0, fmt.Errorf("account %s is readonly in tx", key)
+		}
+		return uint16(i), nil //nolint:gosec // max value would exceed tx size
+	}
+
+	idx := uint16(len(msg.AccountKeys)) //nolint:gosec // max value would exceed tx size
+	if writable {
+		idx -= uint16(msg.Header.NumReadonlyUnsignedAccounts)
+	} else {
+		msg.Header.NumReadonlyUnsignedAccounts++
+	}
+
+	// copy account keys + instructions to avoid modifying slices shared with other copies of the tx
+	keys := make(solanaGo.PublicKeySlice, 0, len(msg.AccountKeys)+1)
+	keys = append(keys, msg.AccountKeys[:idx]...)
+	keys = append(keys, key)
+	msg.AccountKeys = append(keys, msg.AccountKeys[idx:]...)
+
+	shift := func(i uint16) uint16 {
+		if i >= idx {
+			return i + 1
+		}
+		return i
+	}
+	instructions := make([]solanaGo.CompiledInstruction, len(msg.Instructions))
+	for i, ix := range msg.Instructions {
+		accounts := make([]uint16, len(ix.Accounts))
+		for j, a := range ix.Accounts {
+			accounts[j] = shift(a)
+		}
+		instructions[i] = solanaGo.CompiledInstruction{
+			ProgramIDIndex: shift(ix.ProgramIDIndex),
+			Accounts:       accounts,
+			Data:           ix.Data,
+		}
+	}
+	msg.Instructions = instructions
+	return idx, nil
+}
+
+// isWritableAccount returns if the account key at index i is writable based on the message header
+func isWritableAccount(msg solanaGo.Message, i int) bool {
+	h := msg.Header
+	if i < int(h.NumRequiredSignatures) {
+		return i < int(h.NumRequiredSignatures-h.NumReadonlySignedAccounts)
+	}
+	return i < len(msg.AccountKeys)-int(h.NumReadonlyUnsignedAccounts)
+}
+
+// noncePool tracks which durable nonce accounts are used by inflight txs.
+// a nonce account can only be used by one tx at a time because the nonce is advanced when the tx is included
+type noncePool struct {
+	accounts map[solanaGo.PublicKey][]solanaGo.PublicKey // authority -> nonce accounts
+	inUse    map[solanaGo.PublicKey]string               // nonce account -> tx id
+	lock     sync.Mutex
+}
+
+func newNoncePool(accounts map[string][]string) (*noncePool, error) {
+	p := &noncePool{
+		accounts: map[solanaGo.PublicKey][]solanaGo.PublicKey{},
+		inUse:    map[solanaGo.PublicKey]string{},
+	}
+	for authority, nonceAccounts := range accounts {
+		authorityKey, err := solanaGo.PublicKeyFromBase58(authority)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nonce authority %s: %w", authority, err)
+		}
+		for _, a := range nonceAccounts {
+			key, err := solanaGo.PublicKeyFromBase58(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid nonce account %s: %w", a, err)
+			}
+			p.accounts[authorityKey] = append(p.accounts[authorityKey], key)
+		}
+	}
+	return p, nil
+}
+
+// has returns if any nonce accounts are configured for the authority
+func (p *noncePool) has(authority solanaGo.PublicKey) bool {
+	return len(p.accounts[authority]) > 0
+}
+
+// acquire reserves a nonce account of the authority for the tx id
+// accounts held by txs that are finished (released returns true) are reused
+func (p *noncePool) acquire(authority solanaGo.PublicKey, id string, released func(id string) bool) (solanaGo.PublicKey, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, account := range p.accounts[authority] {
+		if holder, ok := p.inUse[account]; ok && !released(holder) {
+			continue
+		}
+		p.inUse[account] = id
+		return account, nil
+	}
+	return solanaGo.PublicKey{}, fmt.Errorf("no durable nonce account available for %s", authority)
+}
+
+// reserve marks the nonce account as used by the tx id (used for restored txs)
+func (p *noncePool) reserve(account solanaGo.PublicKey, id string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inUse[account] = id
+}
+
+// release returns the nonce account to the pool if it is still held by the tx id
+func (p *noncePool) release(account solanaGo.PublicKey, id string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.inUse[account] == id {
+		delete(p.inUse, account)
+	}
+}
@@ -0,0 +1,358 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+// nonceAccountInfo returns the account info for an initialized nonce account
+func nonceAccountInfo(t *testing.T, authority solana.PublicKey, nonce solana.Hash) *rpc.GetAccountInfoResult {
+	data, err := bin.MarshalBin(system.NonceAccount{
+		State:            1,
+		AuthorizedPubkey: authority,
+		Nonce:            solana.PublicKey(nonce),
+	})
+	require.NoError(t, err)
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{
+		Owner: solana.SystemProgramID,
+		Data:  rpc.DataBytesOrJSONFromBytes(data),
+	}}
+}
+
+func TestGetDurableNonce(t *testing.T) {
+	ctx := tests.Context(t)
+	authority := solana.PublicKey{1}
+	account := solana.PublicKey{2}
+	mc := clientmocks.NewReaderWriter(t)
+
+	mc.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(nonceAccountInfo(t, authority, solana.Hash{3}), nil).Once()
+	nonce, err := GetDurableNonce(ctx, mc, account, authority)
+	require.NoError(t, err)
+	assert.Equal(t, DurableNonce{Account: account, Authority: authority, Value: solana.Hash{3}}, nonce)
+
+	mc.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(nonceAccountInfo(t, authority, solana.Hash{3}), nil).Once()
+	_, err = GetDurableNonce(ctx, mc, account, solana.PublicKey{4})
+	require.ErrorContains(t, err, "does not match")
+
+	uninitialized := nonceAccountInfo(t, authority, solana.Hash{})
+	uninitialized.Value.Data = rpc.DataBytesOrJSONFromBytes(make([]byte, NonceAccountSize))
+	mc.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(uninitialized, nil).Once()
+	_, err = GetDurableNonce(ctx, mc, account, authority)
+	require.ErrorContains(t, err, "not initialized")
+
+	wrongOwner := nonceAccountInfo(t, authority, solana.Hash{3})
+	wrongOwner.Value.Owner = solana.PublicKey{5}
+	mc.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(wrongOwner, nil).Once()
+	_, err = GetDurableNonce(ctx, mc, account, authority)
+	require.ErrorContains(t, err, "not owned by the system program")
+
+	mc.On("GetAccountInfoWithOpts", mock.Anything, account, mock.Anything).Return(nil, errors.New("rpc down")).Once()
+	_, err = GetDurableNonce(ctx, mc, account, authority)
+	require.ErrorContains(t, err, "rpc down")
+}
+
+func TestSetDurableNonce(t *testing.T) {
+	payer := solana.PublicKey{1}
+	receiver := solana.PublicKey{2}
+	nonce := DurableNonce{Account: solana.PublicKey{3}, Authority: payer, Value: solana.Hash{4}}
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, payer, receiver).Build(),
+	}, solana.Hash{5}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+	require.NoError(t, fees.SetComputeUnitLimit(tx, 1_000))
+	original := tx.Message.Instructions[0].Accounts[1] // receiver index before adding accounts
+
+	// resolve instruction accounts to keys
+	resolve := func(msg solana.Message, ix solana.CompiledInstruction) (solana.PublicKey, []solana.PublicKey) {
+		var accounts []solana.PublicKey
+		for _, a := range ix.Accounts {
+			accounts = append(accounts, msg.AccountKeys[a])
+		}
+		return msg.AccountKeys[ix.ProgramIDIndex], accounts
+	}
+
+	nonceTx := *tx
+	require.NoError(t, setDurableNonce(&nonceTx, nonce))
+	msg := nonceTx.Message
+	assert.Equal(t, nonce.Value, msg.RecentBlockhash)
+	require.Len(t, msg.Instructions, 3)
+	require.True(t, fees.IsAdvanceNonceInstruction(msg, 0))
+
+	program, accounts := resolve(msg, msg.Instructions[0])
+	assert.Equal(t, solana.SystemProgramID, program)
+	assert.Equal(t, []solana.PublicKey{nonce.Account, solana.SysVarRecentBlockHashesPubkey, payer}, accounts)
+
+	// existing instructions reference the same accounts after adding the nonce accounts
+	program, accounts = resolve(msg, msg.Instructions[1])
+	assert.Equal(t, solana.SystemProgramID, program)
+	assert.Equal(t, []solana.PublicKey{payer, receiver}, accounts)
+	program, _ = resolve(msg, msg.Instructions[2])
+	assert.Equal(t, fees.ComputeBudgetProgram, program)
+
+	// nonce account is writable, sysvar is readonly
+	for i, k := range msg.AccountKeys {
+		switch k {
+		case nonce.Account, payer, receiver:
+			assert.True(t, isWritableAccount(msg, i), k)
+		default:
+			assert.False(t, isWritableAccount(msg, i), k)
+		}
+	}
+
+	// original tx is not modified
+	assert.Equal(t, original, tx.Message.Instructions[0].Accounts[1])
+	assert.Equal(t, solana.Hash{5}, tx.Message.RecentBlockhash)
+
+	// price instruction is added after the nonce instruction
+	require.NoError(t, fees.SetComputeUnitPrice(&nonceTx, 1))
+	assert.True(t, fees.IsAdvanceNonceInstruction(nonceTx.Message, 0))
+
+	// nonce can only be set once
+	require.ErrorContains(t, setDurableNonce(&nonceTx, nonce), "already advances a durable nonce")
+
+	// nonce authority must be the fee payer
+	require.ErrorContains(t, setDurableNonce(tx, DurableNonce{Account: nonce.Account, Authority: receiver}), "not the fee payer")
+
+	t.Run("v0", func(t *testing.T) {
+		v0Tx, err := solana.NewTransaction([]solana.Instruction{
+			system.NewTransferInstruction(1, payer, receiver).Build(),
+		}, solana.Hash{5}, solana.TransactionPayer(payer), solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+			{6}: {receiver},
+		}))
+		require.NoError(t, err)
+		require.NoError(t, setDurableNonce(v0Tx, nonce))
+
+		// lookup table accounts are indexed after the added static accounts
+		keys, err := v0Tx.Message.GetAllKeys()
+		require.NoError(t, err)
+		resolveAll := func(ix solana.CompiledInstruction) []solana.PublicKey {
+			var accounts []solana.PublicKey
+			for _, a := range ix.Accounts {
+				accounts = append(accounts, keys[a])
+			}
+			return accounts
+		}
+		assert.Equal(t, []solana.PublicKey{nonce.Account, solana.SysVarRecentBlockHashesPubkey, payer}, resolveAll(v0Tx.Message.Instructions[0]))
+		assert.Equal(t, []solana.PublicKey{payer, receiver}, resolveAll(v0Tx.Message.Instructions[1]))
+		_, err = v0Tx.Message.MarshalBinary()
+		require.NoError(t, err)
+	})
+}
+
+func TestNoncePool(t *testing.T) {
+	authority := solana.PublicKey{1}
+	accounts := []solana.PublicKey{{2}, {3}}
+	pool, err := newNoncePool(map[string][]string{
+		authority.String(): {accounts[0].String(), accounts[1].String()},
+	})
+	require.NoError(t, err)
+	assert.True(t, pool.has(authority))
+	assert.False(t, pool.has(solana.PublicKey{4}))
+
+	_, err = newNoncePool(map[string][]string{"invalid": {}})
+	require.Error(t, err)
+
+	finished := map[string]bool{}
+	released := func(id string) bool { return finished[id] }
+
+	a, err := pool.acquire(authority, "a", released)
+	require.NoError(t, err)
+	b, err := pool.acquire(authority, "b", released)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, accounts, []solana.PublicKey{a, b})
+
+	// all accounts in use
+	_, err = pool.acquire(authority, "c", released)
+	require.ErrorContains(t, err, "no durable nonce account available")
+
+	// account of a finished tx is reused
+	finished["a"] = true
+	c, err := pool.acquire(authority, "c", released)
+	require.NoError(t, err)
+	assert.Equal(t, a, c)
+
+	// release is ignored if the account is held by another tx
+	pool.release(c, "a")
+	_, err = pool.acquire(authority, "d", released)
+	require.Error(t, err)
+	pool.release(c, "c")
+	_, err = pool.acquire(authority, "d", released)
+	require.NoError(t, err)
+
+	// reserved accounts are in use
+	pool.release(b, "b")
+	pool.reserve(b, "e")
+	_, err = pool.acquire(authority, "f", released)
+	require.Error(t, err)
+}
+
+func TestTxm_DurableNonce(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.PublicKey{1}
+	nonceAccount := solana.PublicKey{2}
+	initialNonce := solana.Hash{3}
+
+	cfg := config.NewDefault()
+	cfg.Chain.NonceAccounts = map[string][]string{payer.String(): {nonceAccount.String()}}
+
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, payer.String(), mock.Anything).Return([]byte{1}, nil)
+
+	// nonce is advanced once the tx has been broadcasted
+	var sent atomic.Bool
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("GetAccountInfoWithOpts", mock.Anything, nonceAccount, mock.Anything).Return(func(_ context.Context, _ solana.PublicKey, _ *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+		if sent.Load() {
+			return nonceAccountInfo(t, payer, solana.Hash{4}), nil
+		}
+		return nonceAccountInfo(t, payer, initialNonce), nil
+	})
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		if !fees.IsAdvanceNonceInstruction(tx.Message, 0) || tx.Message.RecentBlockhash != initialNonce {
+			return solana.Signature{}, errors.New("tx does not use durable nonce")
+		}
+		sent.Store(true)
+		return solana.Signature{5}, nil
+	}).Once()
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(solana.Signature{5}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		return make([]*rpc.SignatureStatusesResult, len(sigs)), nil
+	})
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("durable_nonce", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, payer, solana.PublicKey{6}).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+
+	// fee payer without nonce accounts is rejected
+	mkey.On("Sign", mock.Anything, solana.PublicKey{7}.String(), mock.Anything).Return([]byte{1}, nil)
+	otherTx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, solana.PublicKey{7}, solana.PublicKey{6}).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(solana.PublicKey{7}))
+	require.NoError(t, err)
+	require.ErrorContains(t, txm.Enqueue(ctx, "test", otherTx, nil, SetUseDurableNonce(true)), "no durable nonce accounts configured")
+
+	// tx is dropped once the nonce is advanced without the tx being included
+	id := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, &id, SetUseDurableNonce(true)))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateFailed
+	}, 5*time.Second, 100*time.Millisecond)
+	status, err := txm.GetTransactionStatus(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "tx durable nonce advanced", status.Error)
+}
+
+func TestTxm_DurableNonce_Replace(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.PublicKey{1}
+	nonceAccount := solana.PublicKey{2}
+	nonce := solana.Hash{3}
+	replacementKey := solana.PublicKey{8}
+	txSig, replacementSig := solana.Signature{5}, solana.Signature{9}
+
+	cfg := config.NewDefault()
+	cfg.Chain.NonceAccounts = map[string][]string{payer.String(): {nonceAccount.String()}}
+
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, payer.String(), mock.Anything).Return([]byte{1}, nil)
+
+	// nonce is advanced once the replacement is included
+	var replaced atomic.Bool
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("GetAccountInfoWithOpts", mock.Anything, nonceAccount, mock.Anything).Return(func(_ context.Context, _ solana.PublicKey, _ *rpc.GetAccountInfoOpts) (*rpc.GetAccountInfoResult, error) {
+		if replaced.Load() {
+			return nonceAccountInfo(t, payer, solana.Hash{4}), nil
+		}
+		return nonceAccountInfo(t, payer, nonce), nil
+	})
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		if !fees.IsAdvanceNonceInstruction(tx.Message, 0) || tx.Message.RecentBlockhash != nonce {
+			return solana.Signature{}, errors.New("tx does not use durable nonce")
+		}
+		for _, key := range tx.Message.AccountKeys {
+			if key == replacementKey {
+				replaced.Store(true)
+				return replacementSig, nil
+			}
+		}
+		return txSig, nil
+	})
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		out := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i := range sigs {
+			if sigs[i] == replacementSig {
+				out[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+			}
+		}
+		return out, nil
+	})
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("durable_nonce_replace", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, payer, solana.PublicKey{6}).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+	id := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, &id, SetUseDurableNonce(true), SetFeeBumpPeriod(0)))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateBroadcasted
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// the replacement takes over the only nonce account and uses the same nonce
+	replacement, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1, payer, replacementKey).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+	replacementID := uuid.NewString()
+	require.NoError(t, txm.Replace(ctx, id, replacement, &replacementID, SetFeeBumpPeriod(0)))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, replacementID)
+		return err == nil && status.State == TxStateConfirmed
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// replaced tx can no longer be included once the nonce is advanced
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateCancelled
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -3,50 +3,117 @@ package txm
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
-	"github.com/google/uuid"
+	"github.com/gagliardetto/solana-go/rpc"
 	"golang.org/x/exp/maps"
 )
 
 type PendingTxContext interface {
-	New(sig solana.Signature, cancel context.CancelFunc) (uuid.UUID, error)
-	Add(id uuid.UUID, sig solana.Signature) error
-	Remove(sig solana.Signature) uuid.UUID
+	// Pending registers a tx that is queued but not yet broadcasted, errors if the id is already known
+	Pending(id string, msg []byte, computeUnitLimit uint32, commitment rpc.CommitmentType) error
+	// New starts tracking the initial broadcast of a pending tx
+	New(id string, attempt TxAttempt, cancel context.CancelFunc) error
+	Add(id string, attempt TxAttempt) error
+	// Restore resumes tracking a tx loaded from the tx store (no retry context exists for restored txs)
+	Restore(rec TxRecord) error
+	Remove(sig solana.Signature) string
 	ListAll() []solana.Signature
 	Expired(sig solana.Signature, lifespan time.Duration) bool
+	// BlockhashExpired returns if all blockhashes used for the tx have expired at the block height
+	// always false while the blockhash is being refreshed by the tx retry
+	BlockhashExpired(sig solana.Signature, blockHeight uint64) bool
+	// SetRefreshBlockhash marks if the tx blockhash is refreshed by the tx retry when it expires
+	SetRefreshBlockhash(id string, refresh bool)
+	// DurableNonce returns the id and durable nonce of the tx for the signature, if the tx uses a durable nonce
+	DurableNonce(sig solana.Signature) (string, DurableNonce, bool)
+	// Cancel stops rebroadcasting the tx, broadcasted attempts are still confirmed.
+	// the tx is marked as cancelled with the reason if it is dropped, errors if the tx is unknown or finished
+	Cancel(id string, reason error) error
+	// Delete removes a pending tx that was never handed to the broadcaster
+	Delete(id string)
+	// Get returns the stored tx for the id
+	Get(id string) (TxRecord, error)
+	// PruneTerminal removes txs from the store that reached a terminal state longer than retention ago
+	PruneTerminal(retention time.Duration) int
 	// state change hooks
-	OnSuccess(sig solana.Signature) uuid.UUID
-	OnError(sig solana.Signature, errType int) uuid.UUID // match err type using enum
+	// OnProcessed returns an empty id for unknown or confirmed txs (confirmed txs are not moved back to processed)
+	OnProcessed(sig solana.Signature) string
+	// OnSuccess marks the tx as confirmed and stops retries, only the signature is polled until the tx is finalized.
+	// returns an empty id if the tx is unknown or already confirmed
+	OnSuccess(sig solana.Signature) string
+	// OnFinalized marks the tx as finalized and stops tracking it
+	OnFinalized(sig solana.Signature) string
+	// OnRollback moves a confirmed tx back to broadcasted once its signature is not found in RollbackMisses
+	// consecutive polls (the block was rolled back), the tx is dropped unless it is included again.
+	// returns an empty id if the tx is not confirmed or not rolled back yet
+	OnRollback(sig solana.Signature) string
+	// Rebroadcast sets the cancel func of a new retry of a broadcasted tx (i.e. rolled back or restored),
+	// errors if the tx is not tracked, confirmed or cancelled
+	Rebroadcast(id string, cancel context.CancelFunc) error
+	OnError(sig solana.Signature, errType int, txErr error) string // match err type using enum
+	// OnPendingError marks a tx that failed before it was broadcasted
+	OnPendingError(id string, errType int, txErr error)
 }
 
 var _ PendingTxContext = &pendingTxContext{}
 
 type pendingTxContext struct {
-	cancelBy  map[uuid.UUID]context.CancelFunc
-	timestamp map[uuid.UUID]time.Time
-	sigToID   map[solana.Signature]uuid.UUID
-	idToSigs  map[uuid.UUID][]solana.Signature
+	cancelBy  map[string]context.CancelFunc
+	timestamp map[string]time.Time
+	sigToID   map[solana.Signature]string
+	idToSigs  map[string][]solana.Signature
+	lastValid map[string]uint64 // highest last valid block height of the blockhashes used by the tx
+	refresh   map[string]bool
+	nonces    map[string]DurableNonce // durable nonce used by the tx in place of a recent blockhash
+	cancelled map[string]error        // reason the tx was cancelled, rebroadcasting is stopped for cancelled txs
+	confirmed map[string]struct{}     // confirmed txs waiting to be finalized
+	misses    map[string]int          // consecutive polls the included signature of a confirmed tx was not found
 	lock      sync.RWMutex
+
+	// store persists the txs, the maps above are the source of truth for inflight txs while the txm is running
+	store TxStore
+	// errs decodes the custom program errors of reverted txs (nil = custom program errors are not decoded)
+	errs *programErrors
 }
 
-func newPendingTxContext() *pendingTxContext {
+func newPendingTxContext(store TxStore) *pendingTxContext {
 	return &pendingTxContext{
-		cancelBy:  map[uuid.UUID]context.CancelFunc{},
-		timestamp: map[uuid.UUID]time.Time{},
-		sigToID:   map[solana.Signature]uuid.UUID{},
-		idToSigs:  map[uuid.UUID][]solana.Signature{},
+		cancelBy:  map[string]context.CancelFunc{},
+		timestamp: map[string]time.Time{},
+		sigToID:   map[solana.Signature]string{},
+		idToSigs:  map[string][]solana.Signature{},
+		lastValid: map[string]uint64{},
+		refresh:   map[string]bool{},
+		nonces:    map[string]DurableNonce{},
+		cancelled: map[string]error{},
+		confirmed: map[string]struct{}{},
+		misses:    map[string]int{},
+		store:     store,
 	}
 }
 
-func (c *pendingTxContext) New(sig solana.Signature, cancel context.CancelFunc) (uuid.UUID, error) {
+func (c *pendingTxContext) Pending(id string, msg []byte, computeUnitLimit uint32, commitment rpc.CommitmentType) error {
+	return c.store.Create(TxRecord{
+		ID:                   id,
+		Message:              msg,
+		ComputeUnitLimit:     computeUnitLimit,
+		CompletionCommitment: commitment,
+		State:                TxStatePending,
+	})
+}
+
+func (c *pendingTxContext) New(id string, attempt TxAttempt, cancel context.CancelFunc) error {
+	sig := attempt.Signature
+
 	// validate signature does not exist
 	c.lock.RLock()
 	if _, exists := c.sigToID[sig]; exists {
 		c.lock.RUnlock()
-		return uuid.UUID{}, errors.New("signature already exists")
+		return errors.New("signature already exists")
 	}
 	c.lock.RUnlock()
 
@@ -54,18 +121,39 @@ func (c *pendingTxContext) New(sig solana.Signature, cancel context.CancelFunc)
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if _, exists := c.sigToID[sig]; exists {
-		return uuid.UUID{}, errors.New("signature already exists")
+		return errors.New("signature already exists")
 	}
+	if _, exists := c.idToSigs[id]; exists {
+		return errors.New("id already exists")
+	}
+
+	// persist broadcast
+	if err := c.store.AddAttempt(id, attempt); err != nil {
+		return fmt.Errorf("failed to store tx signature: %w", err)
+	}
+	if err := c.store.SetState(id, TxStateBroadcasted, sig, ""); err != nil {
+		return fmt.Errorf("failed to store tx state: %w", err)
+	}
+
 	// save cancel func
-	id := uuid.New()
 	c.cancelBy[id] = cancel
 	c.timestamp[id] = time.Now()
 	c.sigToID[sig] = id
 	c.idToSigs[id] = []solana.Signature{sig}
-	return id, nil
+	c.lastValid[id] = attempt.LastValidBlockHeight
+	if attempt.DurableNonce != nil {
+		c.nonces[id] = *attempt.DurableNonce
+	}
+	// tx was cancelled while it was being broadcasted
+	if _, cancelled := c.cancelled[id]; cancelled {
+		cancel()
+	}
+	return nil
 }
 
-func (c *pendingTxContext) Add(id uuid.UUID, sig solana.Signature) error {
+func (c *pendingTxContext) Add(id string, attempt TxAttempt) error {
+	sig := attempt.Signature
+
 	// already exists
 	c.lock.RLock()
 	if _, exists := c.sigToID[sig]; exists {
@@ -87,14 +175,58 @@ func (c *pendingTxContext) Add(id uuid.UUID, sig solana.Signature) error {
 	if _, exists := c.idToSigs[id]; !exists {
 		return errors.New("id does not exist - tx likely confirmed by other signature")
 	}
+	if err := c.store.AddAttempt(id, attempt); err != nil {
+		return fmt.Errorf("failed to store tx signature: %w", err)
+	}
 	// save signature
 	c.sigToID[sig] = id
 	c.idToSigs[id] = append(c.idToSigs[id], sig)
+	c.lastValid[id] = max(c.lastValid[id], attempt.LastValidBlockHeight)
+	return nil
+}
+
+func (c *pendingTxContext) Restore(rec TxRecord) error {
+	sigs := rec.Signatures()
+	if len(sigs) == 0 {
+		return fmt.Errorf("stored tx has no signatures: %s", rec.ID)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exists := c.idToSigs[rec.ID]; exists {
+		return errors.New("id already exists")
+	}
+	for _, sig := range sigs {
+		if _, exists := c.sigToID[sig]; exists {
+			return errors.New("signature already exists")
+		}
+	}
+
+	// restored txs are only confirmed until they are rebroadcast
+	c.cancelBy[rec.ID] = func() {}
+	// the confirm timeout restarts, the signatures of txs stored before any downtime are polled before they expire
+	c.timestamp[rec.ID] = time.Now()
+	if rec.State == TxStateConfirmed {
+		// only the included signature is polled until finalized
+		sigs = []solana.Signature{rec.Signature}
+		c.confirmed[rec.ID] = struct{}{}
+	}
+	c.idToSigs[rec.ID] = sigs
+	for _, sig := range sigs {
+		c.sigToID[sig] = rec.ID
+	}
+	for _, attempt := range rec.Attempts {
+		c.lastValid[rec.ID] = max(c.lastValid[rec.ID], attempt.LastValidBlockHeight)
+		if attempt.DurableNonce != nil {
+			c.nonces[rec.ID] = *attempt.DurableNonce
+		}
+	}
 	return nil
 }
 
-// returns the id if removed (otherwise returns 0-id)
-func (c *pendingTxContext) Remove(sig solana.Signature) (id uuid.UUID) {
+// Remove stops tracking the tx and cancels any retries
+// returns the id if removed (otherwise returns empty id)
+func (c *pendingTxContext) Remove(sig solana.Signature) (id string) {
 	// check if already cancelled
 	c.lock.RLock()
 	id, sigExists := c.sigToID[sig]
@@ -125,6 +257,12 @@ func (c *pendingTxContext) Remove(sig solana.Signature) (id uuid.UUID) {
 	delete(c.cancelBy, id)
 	delete(c.timestamp, id)
 	delete(c.idToSigs, id)
+	delete(c.lastValid, id)
+	delete(c.refresh, id)
+	delete(c.nonces, id)
+	delete(c.cancelled, id)
+	delete(c.confirmed, id)
+	delete(c.misses, id)
 	for _, s := range sigs {
 		delete(c.sigToID, s)
 	}
@@ -154,12 +292,266 @@ func (c *pendingTxContext) Expired(sig solana.Signature, lifespan time.Duration)
 	return time.Since(timestamp) > lifespan
 }
 
-func (c *pendingTxContext) OnSuccess(sig solana.Signature) uuid.UUID {
-	return c.Remove(sig)
+func (c *pendingTxContext) BlockhashExpired(sig solana.Signature, blockHeight uint64) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	id, exists := c.sigToID[sig]
+	if !exists {
+		return false
+	}
+
+	// last valid block height is unknown or the tx will be re-signed with a new blockhash
+	lastValid := c.lastValid[id]
+	if lastValid == 0 || c.refresh[id] || blockHeight <= lastValid {
+		return false
+	}
+
+	// processed txs were included before the blockhash expired and may still be confirmed
+	rec, err := c.store.Get(id)
+	return err == nil && rec.State != TxStateProcessed
+}
+
+func (c *pendingTxContext) SetRefreshBlockhash(id string, refresh bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exists := c.idToSigs[id]; !exists {
+		return
+	}
+	if !refresh {
+		delete(c.refresh, id)
+		return
+	}
+	c.refresh[id] = true
+}
+
+func (c *pendingTxContext) DurableNonce(sig solana.Signature) (string, DurableNonce, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	id, exists := c.sigToID[sig]
+	if !exists {
+		return "", DurableNonce{}, false
+	}
+	nonce, exists := c.nonces[id]
+	return id, nonce, exists
+}
+
+func (c *pendingTxContext) Cancel(id string, reason error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rec, err := c.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if rec.State.IsTerminal() || rec.State == TxStateConfirmed {
+		return fmt.Errorf("tx %s is already %s", id, rec.State)
+	}
+	if _, cancelled := c.cancelled[id]; cancelled {
+		return fmt.Errorf("tx %s is already cancelled", id)
+	}
+
+	// pending txs are cancelled once they are tracked as broadcasted (see New)
+	c.cancelled[id] = reason
+	if cancel, exists := c.cancelBy[id]; exists {
+		cancel()
+	}
+	// no longer retried, the blockhash expiry of the broadcasted attempts decides when the tx is dropped
+	delete(c.refresh, id)
+	return nil
+}
+
+// cancelledError replaces the drop reason of a cancelled tx with its cancellation
+// txs that are included (even if they revert) keep their outcome
+func (c *pendingTxContext) cancelledError(sig solana.Signature, errType int, txErr error) (int, error) {
+	if errType != TxFailDrop {
+		return errType, txErr
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	reason, cancelled := c.cancelled[c.sigToID[sig]]
+	if !cancelled {
+		return errType, txErr
+	}
+	return TxFailCancel, reason
+}
+
+func (c *pendingTxContext) Delete(id string) {
+	_ = c.store.Delete(id)
+}
+
+func (c *pendingTxContext) Get(id string) (TxRecord, error) {
+	return c.store.Get(id)
+}
+
+func (c *pendingTxContext) PruneTerminal(retention time.Duration) int {
+	// best effort: records that fail to be pruned are retried on the next call
+	count, _ := c.store.PruneTerminal(time.Now().Add(-retention))
+	return count
+}
+
+func (c *pendingTxContext) OnProcessed(sig solana.Signature) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	id, exists := c.sigToID[sig]
+	if !exists {
+		return ""
+	}
+	if _, confirmed := c.confirmed[id]; confirmed {
+		// found by a node that is behind, not rolled back
+		delete(c.misses, id)
+		return ""
+	}
+	// best effort: the state is only used for reporting, confirmation is driven by the signatures
+	_ = c.store.SetState(id, TxStateProcessed, sig, "")
+	return id
+}
+
+func (c *pendingTxContext) OnSuccess(sig solana.Signature) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	id, exists := c.sigToID[sig]
+	if !exists {
+		return ""
+	}
+	if _, confirmed := c.confirmed[id]; confirmed {
+		delete(c.misses, id)
+		return ""
+	}
+
+	// stop retrying, the other signatures of the tx can no longer be included
+	c.cancelBy[id]()
+	for _, s := range c.idToSigs[id] {
+		if s != sig {
+			delete(c.sigToID, s)
+		}
+	}
+	c.idToSigs[id] = []solana.Signature{sig}
+	c.confirmed[id] = struct{}{}
+	// best effort: a record that fails to update is reconciled again on the next start
+	_ = c.store.SetState(id, TxStateConfirmed, sig, "")
+	return id
+}
+
+func (c *pendingTxContext) OnFinalized(sig solana.Signature) string {
+	id := c.Remove(sig)
+	if id != "" {
+		// best effort: a record that fails to update is reconciled again on the next start
+		_ = c.store.SetState(id, TxStateFinalized, sig, "")
+	}
+	return id
+}
+
+func (c *pendingTxContext) OnRollback(sig solana.Signature) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	id, exists := c.sigToID[sig]
+	if !exists {
+		return ""
+	}
+	if _, confirmed := c.confirmed[id]; !confirmed {
+		return ""
+	}
+	// a single node that lags behind (or is load balanced to) does not roll back the tx
+	c.misses[id]++
+	if c.misses[id] < RollbackMisses {
+		return ""
+	}
+	delete(c.misses, id)
+	delete(c.confirmed, id)
+	// the confirm timeout restarts, the tx is dropped if it is not included again
+	c.timestamp[id] = time.Now()
+	_ = c.store.SetState(id, TxStateBroadcasted, sig, "confirmed tx rolled back")
+	return id
+}
+
+func (c *pendingTxContext) Rebroadcast(id string, cancel context.CancelFunc) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exists := c.idToSigs[id]; !exists {
+		return fmt.Errorf("tx %s is not broadcasted", id)
+	}
+	if _, confirmed := c.confirmed[id]; confirmed {
+		return fmt.Errorf("tx %s is already confirmed", id)
+	}
+	if _, cancelled := c.cancelled[id]; cancelled {
+		return fmt.Errorf("tx %s is cancelled", id)
+	}
+	if retryCancel, exists := c.cancelBy[id]; exists {
+		retryCancel()
+	}
+	c.cancelBy[id] = cancel
+	return nil
+}
+
+func (c *pendingTxContext) OnError(sig solana.Signature, errType int, txErr error) string {
+	errType, txErr = c.cancelledError(sig, errType, txErr)
+	id := c.Remove(sig)
+	if id != "" {
+		decoded := c.decodeTxError(id, sig, txErr)
+		// best effort: a record that fails to update is reconciled again on the next start
+		_ = c.store.SetState(id, errTypeToState(errType), sig, txErrorString(txErr, decoded))
+		if decoded != nil {
+			_ = c.store.SetTxError(id, decoded)
+		}
+	}
+	return id
+}
+
+func (c *pendingTxContext) OnPendingError(id string, errType int, txErr error) {
+	c.lock.Lock()
+	delete(c.cancelled, id)
+	c.lock.Unlock()
+	decoded := decodeTxError(txErr)
+	// best effort: a record that fails to update is marked as failed on the next start
+	_ = c.store.SetState(id, errTypeToState(errType), solana.Signature{}, txErrorString(txErr, decoded))
+	if decoded != nil {
+		_ = c.store.SetTxError(id, decoded)
+	}
+}
+
+// decodeTxError decodes the TransactionError of the tx, the failed instruction is resolved from the programs of the attempt with the signature
+func (c *pendingTxContext) decodeTxError(id string, sig solana.Signature, txErr error) *DecodedTxError {
+	decoded := decodeTxError(txErr)
+	if decoded == nil || decoded.CustomCode == nil {
+		return decoded
+	}
+	rec, err := c.store.Get(id)
+	if err != nil {
+		return decoded
+	}
+	for _, attempt := range rec.Attempts {
+		if attempt.Signature == sig {
+			c.errs.decode(attempt.Programs, decoded)
+			break
+		}
+	}
+	return decoded
+}
+
+// errTypeToState maps the failure reason to the final state of a tx
+func errTypeToState(errType int) TxState {
+	// unrecognized simulation errors indicate the tx is invalid (i.e. insufficient funds, missing account)
+	if errType == TxFailSimOther || errType == TxFailInvalid {
+		return TxStateFatal
+	}
+	if errType == TxFailCancel {
+		return TxStateCancelled
+	}
+	return TxStateFailed
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
 }
 
-func (c *pendingTxContext) OnError(sig solana.Signature, _ int) uuid.UUID {
-	return c.Remove(sig)
+// txErrorString returns the error string of a tx error followed by its decoded custom program error
+func txErrorString(err error, decoded *DecodedTxError) string {
+	if programErr := decoded.ProgramError(); programErr != "" {
+		return fmt.Sprintf("%s (%s)", errString(err), programErr)
+	}
+	return errString(err)
 }
 
 var _ PendingTxContext = &pendingTxContextWithProm{}
@@ -175,24 +567,36 @@ const (
 	TxFailDrop
 	TxFailSimRevert
 	TxFailSimOther
+	TxFailInvalid // rejected by the RPC as a tx that will never be included (i.e. signature failure, missing account)
+	TxFailCancel  // cancelled or replaced by the caller and none of the broadcasted attempts were included
 )
 
-func newPendingTxContextWithProm(id string) *pendingTxContextWithProm {
+func newPendingTxContextWithProm(id string, store TxStore, errs *programErrors) *pendingTxContextWithProm {
+	pendingTx := newPendingTxContext(store)
+	pendingTx.errs = errs
 	return &pendingTxContextWithProm{
 		chainID:   id,
-		pendingTx: newPendingTxContext(),
+		pendingTx: pendingTx,
 	}
 }
 
-func (c *pendingTxContextWithProm) New(sig solana.Signature, cancel context.CancelFunc) (uuid.UUID, error) {
-	return c.pendingTx.New(sig, cancel)
+func (c *pendingTxContextWithProm) Pending(id string, msg []byte, computeUnitLimit uint32, commitment rpc.CommitmentType) error {
+	return c.pendingTx.Pending(id, msg, computeUnitLimit, commitment)
+}
+
+func (c *pendingTxContextWithProm) New(id string, attempt TxAttempt, cancel context.CancelFunc) error {
+	return c.pendingTx.New(id, attempt, cancel)
+}
+
+func (c *pendingTxContextWithProm) Add(id string, attempt TxAttempt) error {
+	return c.pendingTx.Add(id, attempt)
 }
 
-func (c *pendingTxContextWithProm) Add(id uuid.UUID, sig solana.Signature) error {
-	return c.pendingTx.Add(id, sig)
+func (c *pendingTxContextWithProm) Restore(rec TxRecord) error {
+	return c.pendingTx.Restore(rec)
 }
 
-func (c *pendingTxContextWithProm) Remove(sig solana.Signature) uuid.UUID {
+func (c *pendingTxContextWithProm) Remove(sig solana.Signature) string {
 	return c.pendingTx.Remove(sig)
 }
 
@@ -206,32 +610,88 @@ func (c *pendingTxContextWithProm) Expired(sig solana.Signature, lifespan time.D
 	return c.pendingTx.Expired(sig, lifespan)
 }
 
+func (c *pendingTxContextWithProm) BlockhashExpired(sig solana.Signature, blockHeight uint64) bool {
+	return c.pendingTx.BlockhashExpired(sig, blockHeight)
+}
+
+func (c *pendingTxContextWithProm) SetRefreshBlockhash(id string, refresh bool) {
+	c.pendingTx.SetRefreshBlockhash(id, refresh)
+}
+
+func (c *pendingTxContextWithProm) DurableNonce(sig solana.Signature) (string, DurableNonce, bool) {
+	return c.pendingTx.DurableNonce(sig)
+}
+
+func (c *pendingTxContextWithProm) Cancel(id string, reason error) error {
+	return c.pendingTx.Cancel(id, reason)
+}
+
+func (c *pendingTxContextWithProm) Rebroadcast(id string, cancel context.CancelFunc) error {
+	return c.pendingTx.Rebroadcast(id, cancel)
+}
+
+func (c *pendingTxContextWithProm) Delete(id string) {
+	c.pendingTx.Delete(id)
+}
+
+func (c *pendingTxContextWithProm) Get(id string) (TxRecord, error) {
+	return c.pendingTx.Get(id)
+}
+
+func (c *pendingTxContextWithProm) PruneTerminal(retention time.Duration) int {
+	return c.pendingTx.PruneTerminal(retention)
+}
+
+func (c *pendingTxContextWithProm) OnProcessed(sig solana.Signature) string {
+	return c.pendingTx.OnProcessed(sig)
+}
+
 // Success - tx included in block and confirmed
-func (c *pendingTxContextWithProm) OnSuccess(sig solana.Signature) uuid.UUID {
+func (c *pendingTxContextWithProm) OnSuccess(sig solana.Signature) string {
 	id := c.pendingTx.OnSuccess(sig) // empty ID indicates already previously removed
-	if id != uuid.Nil {              // increment if tx was not removed
+	if id != "" {                    // increment if tx was not removed
 		promSolTxmSuccessTxs.WithLabelValues(c.chainID).Add(1)
 	}
 	return id
 }
 
-func (c *pendingTxContextWithProm) OnError(sig solana.Signature, errType int) uuid.UUID {
-	// special RPC rejects transaction (signature will not be valid)
-	if errType == TxFailReject {
-		promSolTxmRejectTxs.WithLabelValues(c.chainID).Add(1)
-		promSolTxmErrorTxs.WithLabelValues(c.chainID).Add(1)
-		return uuid.Nil
+// Finalized - tx included in a finalized block
+func (c *pendingTxContextWithProm) OnFinalized(sig solana.Signature) string {
+	id := c.pendingTx.OnFinalized(sig)
+	if id != "" {
+		promSolTxmFinalizedTxs.WithLabelValues(c.chainID).Add(1)
+	}
+	return id
+}
+
+// Rollback - confirmed tx no longer found
+func (c *pendingTxContextWithProm) OnRollback(sig solana.Signature) string {
+	id := c.pendingTx.OnRollback(sig)
+	if id != "" {
+		promSolTxmRollbackTxs.WithLabelValues(c.chainID).Add(1)
 	}
+	return id
+}
 
-	id := c.pendingTx.OnError(sig, errType) // empty ID indicates already removed
-	if id != uuid.Nil {
+func (c *pendingTxContextWithProm) OnError(sig solana.Signature, errType int, txErr error) string {
+	errType, txErr = c.pendingTx.cancelledError(sig, errType, txErr)
+	id := c.pendingTx.OnError(sig, errType, txErr) // empty ID indicates already removed
+	if id != "" {
 		switch errType {
+		case TxFailCancel:
+			promSolTxmCancelTxs.WithLabelValues(c.chainID).Add(1)
 		case TxFailRevert:
 			promSolTxmRevertTxs.WithLabelValues(c.chainID).Add(1)
 			promSolTxmErrorTxs.WithLabelValues(c.chainID).Add(1)
 		case TxFailDrop:
 			promSolTxmDropTxs.WithLabelValues(c.chainID).Add(1)
 			promSolTxmErrorTxs.WithLabelValues(c.chainID).Add(1)
+		case TxFailInvalid:
+			promSolTxmRejectTxs.WithLabelValues(c.chainID).Add(1)
+			promSolTxmErrorTxs.WithLabelValues(c.chainID).Add(1)
+		}
+		if errType == TxFailRevert || errType == TxFailSimRevert {
+			c.observeTxError(id)
 		}
 	}
 
@@ -248,3 +708,36 @@ func (c *pendingTxContextWithProm) OnError(sig solana.Signature, errType int) uu
 
 	return id
 }
+
+// observeTxError counts the decoded TransactionError of the reverted tx per program and error name
+func (c *pendingTxContextWithProm) observeTxError(id string) {
+	rec, err := c.pendingTx.Get(id)
+	if err != nil || rec.TxError == nil {
+		return
+	}
+	name := rec.TxError.ErrorName
+	if name == "" {
+		name = rec.TxError.Name
+	}
+	program := ""
+	if !rec.TxError.Program.IsZero() {
+		program = rec.TxError.Program.String()
+	}
+	promSolTxmTxErrors.WithLabelValues(c.chainID, program, name).Add(1)
+}
+
+func (c *pendingTxContextWithProm) OnPendingError(id string, errType int, txErr error) {
+	c.pendingTx.OnPendingError(id, errType, txErr)
+
+	// cancelled txs are not errors
+	if errType == TxFailCancel {
+		promSolTxmCancelTxs.WithLabelValues(c.chainID).Add(1)
+		return
+	}
+
+	// special RPC rejects transaction (signature will not be valid)
+	if errType == TxFailReject || errType == TxFailInvalid {
+		promSolTxmRejectTxs.WithLabelValues(c.chainID).Add(1)
+	}
+	promSolTxmErrorTxs.WithLabelValues(c.chainID).Add(1)
+}
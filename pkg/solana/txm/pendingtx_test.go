@@ -3,6 +3,7 @@ package txm
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -36,18 +37,19 @@ func TestPendingTxContext(t *testing.T) {
 	}
 
 	// init inflight txs map + store some signatures and cancelFunc
-	txs := newPendingTxContext()
-	ids := map[solana.Signature]uuid.UUID{}
+	txs := newPendingTxContext(newMemoryTxStore())
+	ids := map[solana.Signature]string{}
 	n := 5
 	for i := 0; i < n; i++ {
 		sig, cancel := newProcess(i)
-		id, err := txs.New(sig, cancel)
-		assert.NoError(t, err)
+		id := uuid.NewString()
+		require.NoError(t, txs.Pending(id, nil, 0, ""))
+		assert.NoError(t, txs.New(id, TxAttempt{Signature: sig}, cancel))
 		ids[sig] = id
 	}
 
 	// cannot add signature for non existent ID
-	require.Error(t, txs.Add(uuid.New(), solana.Signature{}))
+	require.Error(t, txs.Add(uuid.NewString(), TxAttempt{Signature: solana.Signature{}}))
 
 	// return list of signatures
 	list := txs.ListAll()
@@ -60,7 +62,7 @@ func TestPendingTxContext(t *testing.T) {
 		assert.Equal(t, ids[list[i]], id)
 
 		// second remove should not return valid id - already removed
-		assert.Equal(t, uuid.Nil, txs.Remove(list[i]))
+		assert.Equal(t, "", txs.Remove(list[i]))
 	}
 	wg.Wait()
 }
@@ -68,31 +70,41 @@ func TestPendingTxContext(t *testing.T) {
 func TestPendingTxContext_expired(t *testing.T) {
 	_, cancel := context.WithCancel(tests.Context(t))
 	sig := solana.Signature{}
-	txs := newPendingTxContext()
+	txs := newPendingTxContext(newMemoryTxStore())
 
-	id, err := txs.New(sig, cancel)
-	assert.NoError(t, err)
+	id := uuid.NewString()
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	assert.NoError(t, txs.New(id, TxAttempt{Signature: sig}, cancel))
 
 	assert.True(t, txs.Expired(sig, 0*time.Second))   // expired for 0s lifetime
 	assert.False(t, txs.Expired(sig, 60*time.Second)) // not expired for 60s lifetime
 
 	assert.Equal(t, id, txs.Remove(sig))
 	assert.False(t, txs.Expired(sig, 60*time.Second)) // no longer exists, should return false
+
+	// restored txs expire from the time they are restored, not from when they were created
+	restoredID := uuid.NewString()
+	require.NoError(t, txs.Restore(TxRecord{ID: restoredID, Attempts: []TxAttempt{{Signature: sig}}, State: TxStateBroadcasted, CreatedAt: time.Now().Add(-time.Hour)}))
+	assert.False(t, txs.Expired(sig, 60*time.Second))
 }
 
 func TestPendingTxContext_race(t *testing.T) {
 	t.Run("new", func(t *testing.T) {
-		txCtx := newPendingTxContext()
+		txCtx := newPendingTxContext(newMemoryTxStore())
+		ids := [2]string{uuid.NewString(), uuid.NewString()}
+		for _, id := range ids {
+			require.NoError(t, txCtx.Pending(id, nil, 0, ""))
+		}
 		var wg sync.WaitGroup
 		wg.Add(2)
 		var err [2]error
 
 		go func() {
-			_, err[0] = txCtx.New(solana.Signature{}, func() {})
+			err[0] = txCtx.New(ids[0], TxAttempt{}, func() {})
 			wg.Done()
 		}()
 		go func() {
-			_, err[1] = txCtx.New(solana.Signature{}, func() {})
+			err[1] = txCtx.New(ids[1], TxAttempt{}, func() {})
 			wg.Done()
 		}()
 
@@ -101,19 +113,20 @@ func TestPendingTxContext_race(t *testing.T) {
 	})
 
 	t.Run("add", func(t *testing.T) {
-		txCtx := newPendingTxContext()
-		id, createErr := txCtx.New(solana.Signature{}, func() {})
-		require.NoError(t, createErr)
+		txCtx := newPendingTxContext(newMemoryTxStore())
+		id := uuid.NewString()
+		require.NoError(t, txCtx.Pending(id, nil, 0, ""))
+		require.NoError(t, txCtx.New(id, TxAttempt{}, func() {}))
 		var wg sync.WaitGroup
 		wg.Add(2)
 		var err [2]error
 
 		go func() {
-			err[0] = txCtx.Add(id, solana.Signature{1})
+			err[0] = txCtx.Add(id, TxAttempt{Signature: solana.Signature{1}})
 			wg.Done()
 		}()
 		go func() {
-			err[1] = txCtx.Add(id, solana.Signature{1})
+			err[1] = txCtx.Add(id, TxAttempt{Signature: solana.Signature{1}})
 			wg.Done()
 		}()
 
@@ -122,9 +135,10 @@ func TestPendingTxContext_race(t *testing.T) {
 	})
 
 	t.Run("remove", func(t *testing.T) {
-		txCtx := newPendingTxContext()
-		_, err := txCtx.New(solana.Signature{}, func() {})
-		require.NoError(t, err)
+		txCtx := newPendingTxContext(newMemoryTxStore())
+		id := uuid.NewString()
+		require.NoError(t, txCtx.Pending(id, nil, 0, ""))
+		require.NoError(t, txCtx.New(id, TxAttempt{}, func() {}))
 		var wg sync.WaitGroup
 		wg.Add(2)
 
@@ -140,3 +154,226 @@ func TestPendingTxContext_race(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestPendingTxContext_states(t *testing.T) {
+	txs := newPendingTxContext(newMemoryTxStore())
+
+	// duplicate ids are rejected
+	id := uuid.NewString()
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.ErrorIs(t, txs.Pending(id, nil, 0, ""), ErrTxAlreadyExists)
+
+	rec, err := txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStatePending, rec.State)
+
+	// broadcast + confirm + finalize
+	sig := solana.Signature{1}
+	require.NoError(t, txs.New(id, TxAttempt{Signature: sig}, func() {}))
+	assert.Equal(t, id, txs.OnProcessed(sig))
+	assert.Equal(t, id, txs.OnSuccess(sig))
+	rec, err = txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateConfirmed, rec.State)
+	assert.Equal(t, sig, rec.Signature)
+	assert.Equal(t, []solana.Signature{sig}, txs.ListAll()) // polled until finalized
+	assert.Equal(t, id, txs.OnFinalized(sig))
+	rec, err = txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, rec.State)
+	assert.Empty(t, txs.ListAll())
+
+	// simulation failures are fatal
+	fatalID := uuid.NewString()
+	fatalSig := solana.Signature{2}
+	require.NoError(t, txs.Pending(fatalID, nil, 0, ""))
+	require.NoError(t, txs.New(fatalID, TxAttempt{Signature: fatalSig}, func() {}))
+	assert.Equal(t, fatalID, txs.OnError(fatalSig, TxFailSimOther, errors.New("insufficient funds")))
+	rec, err = txs.Get(fatalID)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFatal, rec.State)
+	assert.Equal(t, "insufficient funds", rec.Error)
+
+	// failures before broadcast
+	rejectID := uuid.NewString()
+	require.NoError(t, txs.Pending(rejectID, nil, 0, ""))
+	txs.OnPendingError(rejectID, TxFailReject, errors.New("rejected"))
+	rec, err = txs.Get(rejectID)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFailed, rec.State)
+
+	// finished txs are pruned after retention
+	assert.Equal(t, 0, txs.PruneTerminal(time.Hour))
+	assert.Equal(t, 3, txs.PruneTerminal(0))
+	_, err = txs.Get(id)
+	require.ErrorIs(t, err, ErrTxNotFound)
+}
+
+func TestPendingTxContext_blockhashExpired(t *testing.T) {
+	txs := newPendingTxContext(newMemoryTxStore())
+	id := uuid.NewString()
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.NoError(t, txs.New(id, TxAttempt{Signature: solana.Signature{1}, LastValidBlockHeight: 10}, func() {}))
+
+	assert.False(t, txs.BlockhashExpired(solana.Signature{1}, 10))
+	assert.True(t, txs.BlockhashExpired(solana.Signature{1}, 11))
+	assert.False(t, txs.BlockhashExpired(solana.Signature{2}, 11)) // unknown signature
+
+	// not expired while blockhash is being refreshed
+	txs.SetRefreshBlockhash(id, true)
+	assert.False(t, txs.BlockhashExpired(solana.Signature{1}, 11))
+	txs.SetRefreshBlockhash(id, false)
+
+	// expiry uses the latest blockhash of the tx
+	require.NoError(t, txs.Add(id, TxAttempt{Signature: solana.Signature{2}, LastValidBlockHeight: 20}))
+	assert.False(t, txs.BlockhashExpired(solana.Signature{1}, 11))
+	assert.True(t, txs.BlockhashExpired(solana.Signature{1}, 21))
+
+	// processed txs are not expired
+	txs.OnProcessed(solana.Signature{2})
+	assert.False(t, txs.BlockhashExpired(solana.Signature{1}, 21))
+
+	// unknown last valid block height is never expired
+	unknownID := uuid.NewString()
+	require.NoError(t, txs.Pending(unknownID, nil, 0, ""))
+	require.NoError(t, txs.New(unknownID, TxAttempt{Signature: solana.Signature{3}}, func() {}))
+	assert.False(t, txs.BlockhashExpired(solana.Signature{3}, 100))
+}
+
+func TestPendingTxContext_durableNonce(t *testing.T) {
+	txs := newPendingTxContext(newMemoryTxStore())
+	nonce := DurableNonce{Account: solana.PublicKey{1}, Authority: solana.PublicKey{2}, Value: solana.Hash{3}}
+	id := uuid.NewString()
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.NoError(t, txs.New(id, TxAttempt{Signature: solana.Signature{1}, DurableNonce: &nonce}, func() {}))
+	require.NoError(t, txs.Add(id, TxAttempt{Signature: solana.Signature{2}, DurableNonce: &nonce}))
+
+	for _, sig := range []solana.Signature{{1}, {2}} {
+		nonceID, n, ok := txs.DurableNonce(sig)
+		require.True(t, ok)
+		assert.Equal(t, id, nonceID)
+		assert.Equal(t, nonce, n)
+	}
+
+	// blockhash txs do not use a durable nonce
+	blockhashID := uuid.NewString()
+	require.NoError(t, txs.Pending(blockhashID, nil, 0, ""))
+	require.NoError(t, txs.New(blockhashID, TxAttempt{Signature: solana.Signature{3}}, func() {}))
+	_, _, ok := txs.DurableNonce(solana.Signature{3})
+	assert.False(t, ok)
+
+	// removed txs are no longer tracked
+	txs.OnSuccess(solana.Signature{1})
+	_, _, ok = txs.DurableNonce(solana.Signature{2})
+	assert.False(t, ok)
+
+	// durable nonce is restored from the stored attempts
+	rec, err := txs.Get(id)
+	require.NoError(t, err)
+	restored := newPendingTxContext(newMemoryTxStore())
+	require.NoError(t, restored.Restore(rec))
+	_, n, ok := restored.DurableNonce(solana.Signature{1})
+	require.True(t, ok)
+	assert.Equal(t, nonce, n)
+}
+
+func TestPendingTxContext_finality(t *testing.T) {
+	txs := newPendingTxContext(newMemoryTxStore())
+	id := uuid.NewString()
+	ctx, cancel := context.WithCancel(tests.Context(t))
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.NoError(t, txs.New(id, TxAttempt{Signature: solana.Signature{1}}, cancel))
+	require.NoError(t, txs.Add(id, TxAttempt{Signature: solana.Signature{2}}))
+
+	// confirming stops the retry and only the included signature is polled
+	assert.Equal(t, "", txs.OnRollback(solana.Signature{2})) // not confirmed
+	assert.Equal(t, id, txs.OnSuccess(solana.Signature{2}))
+	assert.Equal(t, "", txs.OnSuccess(solana.Signature{2})) // already confirmed
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Equal(t, []solana.Signature{{2}}, txs.ListAll())
+	assert.Equal(t, "", txs.OnProcessed(solana.Signature{2})) // confirmed txs are not moved back to processed
+	require.Error(t, txs.Cancel(id, ErrTxCancelled))
+
+	require.Error(t, txs.Rebroadcast(id, func() {})) // confirmed txs are not rebroadcast
+
+	// a miss is reset once the signature is found again
+	for range RollbackMisses - 1 {
+		assert.Equal(t, "", txs.OnRollback(solana.Signature{2}))
+	}
+	assert.Equal(t, "", txs.OnSuccess(solana.Signature{2}))
+	for range RollbackMisses - 1 {
+		assert.Equal(t, "", txs.OnRollback(solana.Signature{2}))
+	}
+	rec, err := txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateConfirmed, rec.State)
+
+	// rolled back after consecutive misses, polled and rebroadcast again until included or dropped
+	assert.Equal(t, id, txs.OnRollback(solana.Signature{2}))
+	assert.Equal(t, "", txs.OnRollback(solana.Signature{2}))
+	rec, err = txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateBroadcasted, rec.State)
+	assert.Equal(t, "confirmed tx rolled back", rec.Error)
+	assert.False(t, txs.Expired(solana.Signature{2}, time.Minute)) // confirm timeout restarts
+	ctx, cancel = context.WithCancel(tests.Context(t))
+	require.NoError(t, txs.Rebroadcast(id, cancel))
+	require.Error(t, txs.Rebroadcast(uuid.NewString(), func() {}))
+
+	assert.Equal(t, id, txs.OnProcessed(solana.Signature{2}))
+	assert.Equal(t, id, txs.OnSuccess(solana.Signature{2}))
+	assert.ErrorIs(t, ctx.Err(), context.Canceled) // rebroadcast stops once confirmed again
+
+	// confirmed txs are restored with the included signature only
+	rec, err = txs.Get(id)
+	require.NoError(t, err)
+	restored := newPendingTxContext(newMemoryTxStore())
+	require.NoError(t, restored.Restore(rec))
+	assert.Equal(t, []solana.Signature{{2}}, restored.ListAll())
+	assert.Equal(t, "", restored.OnSuccess(solana.Signature{2}))
+
+	assert.Equal(t, id, txs.OnFinalized(solana.Signature{2}))
+	assert.Empty(t, txs.ListAll())
+	rec, err = txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, rec.State)
+}
+
+func TestPendingTxContext_cancel(t *testing.T) {
+	txs := newPendingTxContext(newMemoryTxStore())
+	require.Error(t, txs.Cancel(uuid.NewString(), ErrTxCancelled)) // unknown id
+
+	// cancelling stops the retry and dropped txs are cancelled
+	id := uuid.NewString()
+	ctx, cancel := context.WithCancel(tests.Context(t))
+	require.NoError(t, txs.Pending(id, nil, 0, ""))
+	require.NoError(t, txs.New(id, TxAttempt{Signature: solana.Signature{1}}, cancel))
+	require.NoError(t, txs.Cancel(id, ErrTxCancelled))
+	require.Error(t, txs.Cancel(id, ErrTxCancelled)) // already cancelled
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Len(t, txs.ListAll(), 1) // broadcasted attempts are still confirmed
+	assert.Equal(t, id, txs.OnError(solana.Signature{1}, TxFailDrop, errors.New("tx blockhash expired")))
+	rec, err := txs.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateCancelled, rec.State)
+	assert.Equal(t, ErrTxCancelled.Error(), rec.Error)
+	require.Error(t, txs.Cancel(id, ErrTxCancelled)) // finished
+
+	// cancelled txs that are included keep their outcome
+	includedID := uuid.NewString()
+	require.NoError(t, txs.Pending(includedID, nil, 0, ""))
+	require.NoError(t, txs.New(includedID, TxAttempt{Signature: solana.Signature{2}}, func() {}))
+	require.NoError(t, txs.Cancel(includedID, ErrTxCancelled))
+	assert.Equal(t, includedID, txs.OnSuccess(solana.Signature{2}))
+	rec, err = txs.Get(includedID)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateConfirmed, rec.State)
+
+	// txs cancelled while being broadcasted stop retrying once tracked
+	pendingID := uuid.NewString()
+	require.NoError(t, txs.Pending(pendingID, nil, 0, ""))
+	require.NoError(t, txs.Cancel(pendingID, ErrTxCancelled))
+	ctx, cancel = context.WithCancel(tests.Context(t))
+	require.NoError(t, txs.New(pendingID, TxAttempt{Signature: solana.Signature{3}}, cancel))
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,86 @@
+package txm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+// programErrors are the custom program errors of known programs, keyed by program ID and error code.
+// used to decode the InstructionErrors of reverted txs
+type programErrors struct {
+	lock sync.RWMutex
+	errs map[solana.PublicKey]map[uint32]codec.IdlErrorCode
+}
+
+func newProgramErrors() *programErrors {
+	return &programErrors{errs: map[solana.PublicKey]map[uint32]codec.IdlErrorCode{}}
+}
+
+// register adds the IDL errors of the program, errors with the same code are replaced
+func (p *programErrors) register(program solana.PublicKey, errs []codec.IdlErrorCode) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.errs[program] == nil {
+		p.errs[program] = map[uint32]codec.IdlErrorCode{}
+	}
+	for _, e := range errs {
+		if e.Code < 0 {
+			continue
+		}
+		p.errs[program][uint32(e.Code)] = e //nolint:gosec // negative codes are skipped
+	}
+}
+
+// registerConfigs registers the errors of the Anchor IDLs of the program configs
+func (p *programErrors) registerConfigs(cfgs map[string]config.ProgramConfig) error {
+	for program, cfg := range cfgs {
+		idl, err := cfg.IDL()
+		if err != nil {
+			return fmt.Errorf("invalid IDL of program %s: %w", program, err)
+		}
+		if idl == nil {
+			continue
+		}
+		key, err := solana.PublicKeyFromBase58(program)
+		if err != nil {
+			return fmt.Errorf("invalid program %s: %w", program, err)
+		}
+		p.register(key, idl.Errors)
+	}
+	return nil
+}
+
+// decode sets the program of the failed instruction and the name and message of its custom program error.
+// programs are the programs of the instructions of the tx that failed
+func (p *programErrors) decode(programs []solana.PublicKey, decoded *DecodedTxError) {
+	if p == nil || decoded == nil || decoded.CustomCode == nil {
+		return
+	}
+	if decoded.InstructionIndex < 0 || decoded.InstructionIndex >= len(programs) {
+		return
+	}
+	decoded.Program = programs[decoded.InstructionIndex]
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if e, ok := p.errs[decoded.Program][*decoded.CustomCode]; ok {
+		decoded.ErrorName = e.Name
+		decoded.ErrorMsg = e.Msg
+	}
+}
+
+// instructionPrograms returns the program of each instruction of the message (zero if the program index is invalid)
+func instructionPrograms(msg solana.Message) []solana.PublicKey {
+	programs := make([]solana.PublicKey, len(msg.Instructions))
+	for i, ix := range msg.Instructions {
+		if program, err := msg.Program(ix.ProgramIDIndex); err == nil {
+			programs[i] = program
+		}
+	}
+	return programs
+}
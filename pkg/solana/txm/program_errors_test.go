@@ -0,0 +1,110 @@
+package txm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+func TestProgramErrors(t *testing.T) {
+	programA, programB := solana.PublicKey{10}, solana.PublicKey{11}
+	p := newProgramErrors()
+	p.register(programA, []codec.IdlErrorCode{{Code: 6000, Name: "StaleReport", Msg: "stale report"}, {Code: -1, Name: "Invalid"}})
+	idl := `{"version": "0.1.0", "name": "b", "instructions": [], "errors": [{"code": 6000, "name": "Unauthorized"}]}`
+	require.NoError(t, p.registerConfigs(map[string]config.ProgramConfig{
+		programB.String():             {AnchorIDL: &idl},
+		solana.MemoProgramID.String(): {}, // no IDL
+	}))
+	invalid := "{"
+	require.Error(t, p.registerConfigs(map[string]config.ProgramConfig{programB.String(): {AnchorIDL: &invalid}}))
+
+	decode := func(programs []solana.PublicKey, index int, code uint32) *DecodedTxError {
+		decoded := &DecodedTxError{Name: "InstructionError", InstructionIndex: index, CustomCode: &code}
+		p.decode(programs, decoded)
+		return decoded
+	}
+	programs := []solana.PublicKey{programA, programB, solana.MemoProgramID}
+
+	decoded := decode(programs, 0, 6000)
+	assert.Equal(t, programA, decoded.Program)
+	assert.Equal(t, "StaleReport: stale report", decoded.ProgramError())
+
+	decoded = decode(programs, 1, 6000)
+	assert.Equal(t, programB, decoded.Program)
+	assert.Equal(t, "Unauthorized", decoded.ProgramError())
+
+	// unknown programs and codes only resolve the program
+	decoded = decode(programs, 2, 6000)
+	assert.Equal(t, solana.MemoProgramID, decoded.Program)
+	assert.Empty(t, decoded.ProgramError())
+	assert.Empty(t, decode(programs, 0, 6001).ProgramError())
+
+	// unknown instructions are not decoded
+	decoded = decode(programs, 3, 6000)
+	assert.True(t, decoded.Program.IsZero())
+	assert.Empty(t, decoded.ProgramError())
+
+	// errors without a custom code are not decoded
+	decoded = &DecodedTxError{Name: "InstructionError"}
+	p.decode(programs, decoded)
+	assert.True(t, decoded.Program.IsZero())
+}
+
+func TestInstructionPrograms(t *testing.T) {
+	program := solana.PublicKey{10}
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(program, solana.AccountMetaSlice{}, []byte{1}),
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{}, []byte{2}),
+	}, solana.Hash{}, solana.TransactionPayer(solana.PublicKey{1}))
+	require.NoError(t, err)
+	assert.Equal(t, []solana.PublicKey{program, solana.MemoProgramID}, instructionPrograms(tx.Message))
+}
+
+func TestTxm_ProgramErrors(t *testing.T) {
+	ctx := tests.Context(t)
+	idl := `{"version": "0.1.0", "name": "memo", "instructions": [], "errors": [{"code": 6000, "name": "StaleReport", "msg": "stale report"}]}`
+	estimate := true
+	cfg := config.NewDefault()
+	cfg.Chain.EstimateComputeUnitLimit = &estimate
+	cfg.Chain.ProgramConfigs = map[string]config.ProgramConfig{
+		solana.MemoProgramID.String(): {AnchorIDL: &idl},
+	}
+
+	var simErr any
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError": [0, {"Custom": 6000}]}`), &simErr))
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{
+		Err:  simErr,
+		Logs: []string{"Program log: AnchorError occurred. Error Code: StaleReport."},
+	}, nil)
+
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	txm := NewTxm("program_errors", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// simulation errors returned by Enqueue include the decoded program error and the logs
+	err := txm.Enqueue(ctx, "test", payerTx(t, solana.PublicKey{1}), nil)
+	require.ErrorContains(t, err, "(StaleReport: stale report)")
+	var txErr *TxError
+	require.ErrorAs(t, err, &txErr)
+	assert.Equal(t, []string{"Program log: AnchorError occurred. Error Code: StaleReport."}, txErr.Logs)
+}
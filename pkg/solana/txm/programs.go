@@ -0,0 +1,68 @@
+package txm
+
+import (
+	"fmt"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+// programConfigs are the tx config overrides of the txs invoking a program, keyed by program ID
+type programConfigs map[solanaGo.PublicKey]config.ProgramConfig
+
+func newProgramConfigs(cfgs map[string]config.ProgramConfig) (programConfigs, error) {
+	p := programConfigs{}
+	for program, cfg := range cfgs {
+		key, err := solanaGo.PublicKeyFromBase58(program)
+		if err != nil {
+			return nil, fmt.Errorf("invalid program %s: %w", program, err)
+		}
+		p[key] = cfg
+	}
+	return p, nil
+}
+
+// apply overrides the tx config with the config of the programs invoked by the instructions of the tx.
+// a field set by the config of multiple programs uses the config of the program of the first instruction
+func (p programConfigs) apply(tx *solanaGo.Transaction, cfg *TxConfig) {
+	if len(p) == 0 {
+		return
+	}
+	// apply in reverse order so the programs of earlier instructions take precedence
+	for i := len(tx.Message.Instructions) - 1; i >= 0; i-- {
+		index := int(tx.Message.Instructions[i].ProgramIDIndex)
+		if index >= len(tx.Message.AccountKeys) {
+			continue
+		}
+		programCfg, ok := p[tx.Message.AccountKeys[index]]
+		if !ok {
+			continue
+		}
+		if programCfg.ComputeUnitLimit != nil {
+			cfg.ComputeUnitLimit = *programCfg.ComputeUnitLimit
+		}
+		if programCfg.ComputeUnitPriceMin != nil {
+			cfg.ComputeUnitPriceMin = *programCfg.ComputeUnitPriceMin
+		}
+		if programCfg.ComputeUnitPriceMax != nil {
+			cfg.ComputeUnitPriceMax = *programCfg.ComputeUnitPriceMax
+		}
+		if programCfg.LoadedAccountsDataSizeLimit != nil {
+			cfg.LoadedAccountsDataSizeLimit = *programCfg.LoadedAccountsDataSizeLimit
+		}
+		if programCfg.HeapFrame != nil {
+			cfg.HeapFrame = *programCfg.HeapFrame
+		}
+		if programCfg.FeeBumpPeriod != nil {
+			cfg.FeeBumpPeriod = programCfg.FeeBumpPeriod.Duration()
+		}
+		if programCfg.TxRetryTimeout != nil {
+			cfg.Timeout = programCfg.TxRetryTimeout.Duration()
+		}
+		if programCfg.TxCompletionCommitment != nil {
+			cfg.CompletionCommitment = rpc.CommitmentType(*programCfg.TxCompletionCommitment)
+		}
+	}
+}
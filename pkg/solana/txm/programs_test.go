@@ -0,0 +1,118 @@
+package txm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	commoncfg "github.com/goplugin/plugin-common/pkg/config"
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+func TestProgramConfigs(t *testing.T) {
+	_, err := newProgramConfigs(map[string]config.ProgramConfig{"invalid": {}})
+	require.Error(t, err)
+
+	programA, programB := solana.PublicKey{10}, solana.PublicKey{11}
+	limitA, limitB, priceMax := uint32(1_000), uint32(2_000), uint64(5)
+	dataSizeLimit, heapFrame := uint32(10_240), uint32(64*1024)
+	bumpPeriod, confirmed := commoncfg.MustNewDuration(time.Minute), string(rpc.CommitmentConfirmed)
+	p, err := newProgramConfigs(map[string]config.ProgramConfig{
+		programA.String(): {ComputeUnitLimit: &limitA, FeeBumpPeriod: bumpPeriod, HeapFrame: &heapFrame},
+		programB.String(): {ComputeUnitLimit: &limitB, ComputeUnitPriceMax: &priceMax, TxCompletionCommitment: &confirmed, LoadedAccountsDataSizeLimit: &dataSizeLimit},
+	})
+	require.NoError(t, err)
+
+	newTx := func(programs ...solana.PublicKey) *solana.Transaction {
+		var ixs []solana.Instruction
+		for _, program := range programs {
+			ixs = append(ixs, solana.NewInstruction(program, solana.AccountMetaSlice{}, []byte{1}))
+		}
+		tx, txErr := solana.NewTransaction(ixs, solana.Hash{}, solana.TransactionPayer(solana.PublicKey{1}))
+		require.NoError(t, txErr)
+		return tx
+	}
+	base := TxConfig{ComputeUnitLimit: 200_000, ComputeUnitPriceMax: 1_000, FeeBumpPeriod: time.Second, Timeout: time.Hour}
+
+	// txs of programs without config are not changed
+	cfg := base
+	p.apply(newTx(solana.MemoProgramID), &cfg)
+	assert.Equal(t, base, cfg)
+
+	cfg = base
+	p.apply(newTx(solana.MemoProgramID, programB), &cfg)
+	assert.Equal(t, TxConfig{ComputeUnitLimit: 2_000, ComputeUnitPriceMax: 5, FeeBumpPeriod: time.Second, Timeout: time.Hour, CompletionCommitment: rpc.CommitmentConfirmed, LoadedAccountsDataSizeLimit: 10_240}, cfg)
+
+	// fields set by multiple programs use the program of the first instruction
+	cfg = base
+	p.apply(newTx(programA, programB), &cfg)
+	assert.Equal(t, TxConfig{ComputeUnitLimit: 1_000, ComputeUnitPriceMax: 5, FeeBumpPeriod: time.Minute, Timeout: time.Hour, CompletionCommitment: rpc.CommitmentConfirmed, LoadedAccountsDataSizeLimit: 10_240, HeapFrame: 64 * 1024}, cfg)
+}
+
+func TestTxm_ProgramConfigs(t *testing.T) {
+	ctx := tests.Context(t)
+	limit, confirmed, finalized := uint32(50_000), string(rpc.CommitmentConfirmed), string(rpc.CommitmentFinalized)
+	cfg := config.NewDefault()
+	cfg.Chain.TxCompletionCommitment = &finalized
+	cfg.Chain.ProgramConfigs = map[string]config.ProgramConfig{
+		solana.MemoProgramID.String(): {ComputeUnitLimit: &limit, TxCompletionCommitment: &confirmed},
+	}
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sent := make(chan *solana.Transaction, 10)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(solana.Signature{1}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("program_configs", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	id := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, "test", payerTx(t, solana.PublicKey{1}), &id))
+	select {
+	case tx := <-sent:
+		var limits []fees.ComputeUnitLimit
+		for _, ix := range tx.Message.Instructions {
+			if v, err := fees.ParseComputeUnitLimit(ix.Data); err == nil {
+				limits = append(limits, v)
+			}
+		}
+		assert.Equal(t, []fees.ComputeUnitLimit{50_000}, limits)
+	case <-ctx.Done():
+		t.Fatal("tx not sent")
+	}
+
+	// tx is finished once confirmed (program commitment) and keeps being tracked until finalized
+	require.Eventually(t, func() bool {
+		rec, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && rec.State == TxStateConfirmed
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.True(t, txm.txFinished(id))
+}
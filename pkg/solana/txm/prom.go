@@ -12,11 +12,68 @@ var (
 		Help: "Number of transactions that are included and successfully executed on chain",
 	}, []string{"chainID"})
 
+	promSolTxmFinalizedTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_finalized",
+		Help: "Number of transactions that are included in a finalized block",
+	}, []string{"chainID"})
+	promSolTxmRollbackTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_rollback",
+		Help: "Number of confirmed transactions that were no longer found (block rolled back)",
+	}, []string{"chainID"})
+
 	// inflight transactions
 	promSolTxmPendingTxs = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "solana_txm_tx_pending",
 		Help: "Number of transactions that are pending confirmation",
 	}, []string{"chainID"})
+	promSolTxmQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solana_txm_queue_depth",
+		Help: "Number of transactions waiting to be broadcasted per fee payer",
+	}, []string{"chainID", "signer"})
+	promSolTxmInsufficientBalance = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_insufficient_balance",
+		Help: "Number of transactions rejected because no fee payer could pay the worst case fee",
+	}, []string{"chainID", "signer"})
+
+	// batched transactions
+	promSolTxmBatchedTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_batched",
+		Help: "Number of transactions sent in batch txs with other transactions of their fee payer",
+	}, []string{"chainID"})
+	promSolTxmBatchSplits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_batch_split",
+		Help: "Number of batch txs split in halves after failing simulation",
+	}, []string{"chainID"})
+
+	// bundles
+	promSolTxmBundlesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_bundle_sent",
+		Help: "Number of bundles sent to the block engine (resent bundles included)",
+	}, []string{"chainID"})
+	promSolTxmBundleStatus = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_bundle_status",
+		Help: "Number of bundles reported by the block engine as landed, failed or invalid",
+	}, []string{"chainID", "status"})
+	promSolTxmTPUSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tpu_sent",
+		Help: "Number of txs sent directly to the TPUs of the upcoming leaders, by success (sent to at least one leader)",
+	}, []string{"chainID", "success"})
+
+	// cancelled transactions
+	promSolTxmCancelTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_cancel",
+		Help: "Number of transactions that were cancelled or replaced before being included",
+	}, []string{"chainID"})
+
+	// retried and dead-lettered transactions
+	promSolTxmRetryTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_retry",
+		Help: "Number of failed transactions that were rebuilt and resent, per error class",
+	}, []string{"chainID", "class"})
+	promSolTxmDeadLetterTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_dead_letter",
+		Help: "Number of failed transactions that exhausted their retries and were added to the dead-letter list",
+	}, []string{"chainID"})
 
 	// error cases
 	promSolTxmErrorTxs = promauto.NewCounterVec(prometheus.CounterOpts{
@@ -43,4 +100,8 @@ var (
 		Name: "solana_txm_tx_error_sim_other",
 		Help: "Number of transactions that failed simulation with an unrecognized error. Note: tx may still be included onchain",
 	}, []string{"chainID"})
+	promSolTxmTxErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solana_txm_tx_error_decoded",
+		Help: "Number of reverted transactions per failed program and error name (custom program error name from the program IDL, or the TransactionError variant)",
+	}, []string{"chainID", "program", "error"})
 )
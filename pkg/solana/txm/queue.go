@@ -0,0 +1,246 @@
+package txm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+)
+
+// ErrQueueFull is returned by Enqueue when the queue of the fee payer is full, the tx can be enqueued again later
+var ErrQueueFull = errors.New("tx queue is full")
+
+// signerQueues holds a FIFO queue of txs waiting to be broadcasted per fee payer.
+// txs are dequeued round robin across fee payers, a fee payer is skipped while its previous tx is being broadcasted
+// or while it has maxInflight unfinished txs
+type signerQueues struct {
+	chainID     string
+	maxLen      int
+	maxInflight int                  // 0 for no limit
+	finished    func(id string) bool // returns if a broadcasted tx is finished
+
+	lock     sync.Mutex
+	queues   map[solanaGo.PublicKey][]pendingTx
+	order    []solanaGo.PublicKey // round robin order of fee payers
+	next     int                  // index in order of the fee payer to dequeue from first
+	sending  map[solanaGo.PublicKey]bool
+	inflight map[solanaGo.PublicKey]map[string]struct{} // ids of broadcasted txs that may not be finished
+
+	notify chan struct{}
+}
+
+func newSignerQueues(chainID string, maxLen, maxInflight uint32, finished func(id string) bool) *signerQueues {
+	return &signerQueues{
+		chainID:     chainID,
+		maxLen:      int(maxLen),
+		maxInflight: int(maxInflight),
+		finished:    finished,
+		queues:      map[solanaGo.PublicKey][]pendingTx{},
+		sending:     map[solanaGo.PublicKey]bool{},
+		inflight:    map[solanaGo.PublicKey]map[string]struct{}{},
+		notify:      make(chan struct{}, 1),
+	}
+}
+
+// push adds the tx to the queue of its fee payer, queued is called before the tx can be dequeued (if not nil)
+func (q *signerQueues) push(msg pendingTx, queued func()) error {
+	key := msg.tx.Message.AccountKeys[0]
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	queue, ok := q.queues[key]
+	if len(queue) >= q.maxLen {
+		return fmt.Errorf("%w: %d txs queued for %s", ErrQueueFull, len(queue), key)
+	}
+	if !ok {
+		q.order = append(q.order, key)
+	}
+	q.queues[key] = append(queue, msg)
+	if queued != nil {
+		queued()
+	}
+	promSolTxmQueueDepth.WithLabelValues(q.chainID, key.String()).Set(float64(len(q.queues[key])))
+	q.signal()
+	return nil
+}
+
+// pop returns the next tx that can be broadcasted and marks its fee payer as sending until done is called
+func (q *signerQueues) pop() (pendingTx, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i := range q.order {
+		idx := (q.next + i) % len(q.order)
+		key := q.order[idx]
+		if q.sending[key] || !q.hasCapacity(key) {
+			continue
+		}
+
+		queue := q.queues[key]
+		msg := queue[0]
+		queue[0] = pendingTx{} // release the tx for garbage collection
+		queue = queue[1:]
+		if len(queue) == 0 {
+			// remove empty queues to not grow the round robin order with every fee payer ever seen
+			delete(q.queues, key)
+			q.order = append(q.order[:idx], q.order[idx+1:]...)
+			q.next = idx
+		} else {
+			q.queues[key] = queue
+			q.next = idx + 1
+		}
+		if len(q.order) > 0 {
+			q.next %= len(q.order)
+		}
+		promSolTxmQueueDepth.WithLabelValues(q.chainID, key.String()).Set(float64(len(queue)))
+
+		q.sending[key] = true
+		q.markInflight(key, msg.id)
+		return msg, true
+	}
+	return pendingTx{}, false
+}
+
+// popWhile dequeues the txs at the front of the queue of the fee payer while take returns true.
+// used to dequeue the txs sent together with a tx returned by pop, the fee payer is already marked as sending.
+// each dequeued tx counts against the maxInflight txs of the fee payer, txs are only dequeued while it has capacity
+func (q *signerQueues) popWhile(key solanaGo.PublicKey, take func(pendingTx) bool) []pendingTx {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	queue := q.queues[key]
+	n := 0
+	for n < len(queue) && q.hasCapacity(key) && take(queue[n]) {
+		q.markInflight(key, queue[n].id)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	msgs := slices.Clone(queue[:n])
+	clear(queue[:n]) // release the txs for garbage collection
+	queue = queue[n:]
+	if len(queue) == 0 {
+		delete(q.queues, key)
+		idx := slices.Index(q.order, key)
+		q.order = append(q.order[:idx], q.order[idx+1:]...)
+		if q.next > idx {
+			q.next--
+		}
+		if len(q.order) > 0 {
+			q.next %= len(q.order)
+		}
+	} else {
+		q.queues[key] = queue
+	}
+	promSolTxmQueueDepth.WithLabelValues(q.chainID, key.String()).Set(float64(len(queue)))
+	return msgs
+}
+
+// remove removes a queued tx, returns false if the tx is not queued (i.e. already dequeued for broadcasting)
+func (q *signerQueues) remove(id string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for idx, key := range q.order {
+		queue := q.queues[key]
+		i := slices.IndexFunc(queue, func(msg pendingTx) bool { return msg.id == id })
+		if i < 0 {
+			continue
+		}
+		queue = slices.Delete(queue, i, i+1)
+		if len(queue) == 0 {
+			delete(q.queues, key)
+			q.order = append(q.order[:idx], q.order[idx+1:]...)
+			if q.next > idx {
+				q.next--
+			}
+			if len(q.order) > 0 {
+				q.next %= len(q.order)
+			}
+		} else {
+			q.queues[key] = queue
+		}
+		promSolTxmQueueDepth.WithLabelValues(q.chainID, key.String()).Set(float64(len(queue)))
+		return true
+	}
+	return false
+}
+
+// release removes the tx from the inflight txs of its fee payer, cancelled txs no longer hold capacity
+func (q *signerQueues) release(id string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for key, ids := range q.inflight {
+		if _, ok := ids[id]; ok {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(q.inflight, key)
+			}
+			q.signal()
+			return
+		}
+	}
+}
+
+// done marks the broadcast of the fee payer tx as finished so the next tx of the fee payer can be dequeued
+func (q *signerQueues) done(key solanaGo.PublicKey) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.sending, key)
+	q.signal()
+}
+
+// markInflight adds the dequeued tx to the inflight txs of the fee payer
+func (q *signerQueues) markInflight(key solanaGo.PublicKey, id string) {
+	if q.maxInflight == 0 {
+		return
+	}
+	if q.inflight[key] == nil {
+		q.inflight[key] = map[string]struct{}{}
+	}
+	q.inflight[key][id] = struct{}{}
+}
+
+// hasCapacity returns if the fee payer has less than maxInflight unfinished txs, finished txs are removed from the count
+func (q *signerQueues) hasCapacity(key solanaGo.PublicKey) bool {
+	if q.maxInflight == 0 {
+		return true
+	}
+	ids := q.inflight[key]
+	if len(ids) < q.maxInflight {
+		return true
+	}
+	for id := range ids {
+		if q.finished(id) {
+			delete(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		delete(q.inflight, key)
+	}
+	return len(ids) < q.maxInflight
+}
+
+// len returns the number of queued txs across all fee payers
+func (q *signerQueues) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	n := 0
+	for _, queue := range q.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// ready is signaled when a tx is pushed or a fee payer is done sending
+func (q *signerQueues) ready() <-chan struct{} {
+	return q.notify
+}
+
+func (q *signerQueues) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
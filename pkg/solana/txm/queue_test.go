@@ -0,0 +1,172 @@
+package txm
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queuedTx(id string, feePayer solana.PublicKey) pendingTx {
+	tx := solana.Transaction{}
+	tx.Message.AccountKeys = append(tx.Message.AccountKeys, feePayer)
+	return pendingTx{tx: &tx, id: id}
+}
+
+func TestSignerQueues(t *testing.T) {
+	keyA, keyB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	t.Run("round robin", func(t *testing.T) {
+		q := newSignerQueues(t.Name(), 10, 0, func(string) bool { return false })
+		require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+		require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+		require.NoError(t, q.push(queuedTx("a3", keyA), nil))
+		require.NoError(t, q.push(queuedTx("b1", keyB), nil))
+		assert.Equal(t, 4, q.len())
+
+		var ids []string
+		for len(ids) < 4 {
+			msg, ok := q.pop()
+			require.True(t, ok)
+			ids = append(ids, msg.id)
+			q.done(msg.tx.Message.AccountKeys[0])
+		}
+		// fee payer B is not starved by the txs queued for fee payer A
+		assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, ids)
+		_, ok := q.pop()
+		assert.False(t, ok)
+		assert.Equal(t, 0, q.len())
+	})
+
+	t.Run("one send per fee payer", func(t *testing.T) {
+		q := newSignerQueues(t.Name(), 10, 0, func(string) bool { return false })
+		require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+		require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+
+		msg, ok := q.pop()
+		require.True(t, ok)
+		assert.Equal(t, "a1", msg.id)
+		_, ok = q.pop()
+		assert.False(t, ok) // a1 is still being broadcasted
+
+		q.done(keyA)
+		<-q.ready()
+		msg, ok = q.pop()
+		require.True(t, ok)
+		assert.Equal(t, "a2", msg.id)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		q := newSignerQueues(t.Name(), 2, 0, func(string) bool { return false })
+		require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+		require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+		assert.ErrorIs(t, q.push(queuedTx("a3", keyA), nil), ErrQueueFull)
+		// queues are limited per fee payer
+		require.NoError(t, q.push(queuedTx("b1", keyB), nil))
+	})
+
+	t.Run("inflight limit", func(t *testing.T) {
+		finished := map[string]bool{}
+		q := newSignerQueues(t.Name(), 10, 1, func(id string) bool { return finished[id] })
+		require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+		require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+
+		msg, ok := q.pop()
+		require.True(t, ok)
+		q.done(keyA)
+		_, ok = q.pop()
+		assert.False(t, ok) // a1 is broadcasted but not finished
+
+		finished[msg.id] = true
+		msg, ok = q.pop()
+		require.True(t, ok)
+		assert.Equal(t, "a2", msg.id)
+	})
+}
+
+func TestSignerQueues_remove(t *testing.T) {
+	keyA, keyB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	q := newSignerQueues(t.Name(), 10, 0, func(string) bool { return false })
+	require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+	require.NoError(t, q.push(queuedTx("b1", keyB), nil))
+	require.NoError(t, q.push(queuedTx("b2", keyB), nil))
+
+	assert.True(t, q.remove("a1"))
+	assert.False(t, q.remove("a1"))
+	assert.True(t, q.remove("b2"))
+	assert.Equal(t, 1, q.len())
+
+	msg, ok := q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "b1", msg.id)
+	assert.False(t, q.remove("b1")) // dequeued for broadcasting
+}
+
+func TestSignerQueues_release(t *testing.T) {
+	keyA := solana.NewWallet().PublicKey()
+	q := newSignerQueues(t.Name(), 10, 1, func(string) bool { return false })
+	require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+	require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+
+	_, ok := q.pop()
+	require.True(t, ok)
+	q.done(keyA)
+	_, ok = q.pop()
+	assert.False(t, ok)
+
+	// a cancelled tx no longer counts towards the inflight limit
+	q.release("a1")
+	msg, ok := q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "a2", msg.id)
+}
+
+func TestSignerQueues_popWhile(t *testing.T) {
+	keyA, keyB := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	q := newSignerQueues(t.Name(), 10, 0, func(string) bool { return false })
+	require.NoError(t, q.push(queuedTx("a1", keyA), nil))
+	require.NoError(t, q.push(queuedTx("a2", keyA), nil))
+	require.NoError(t, q.push(queuedTx("a3", keyA), nil))
+	require.NoError(t, q.push(queuedTx("a4", keyA), nil))
+	require.NoError(t, q.push(queuedTx("b1", keyB), nil))
+
+	msg, ok := q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "a1", msg.id)
+
+	// txs are dequeued in order until one is not taken
+	msgs := q.popWhile(keyA, func(msg pendingTx) bool { return msg.id != "a3" })
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "a2", msgs[0].id)
+	assert.Empty(t, q.popWhile(keyA, func(pendingTx) bool { return false }))
+
+	// the emptied queue is removed from the round robin order
+	assert.Len(t, q.popWhile(keyA, func(pendingTx) bool { return true }), 2)
+	assert.Equal(t, 1, q.len())
+	q.done(keyA)
+	msg, ok = q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "b1", msg.id)
+
+	// dequeued txs count against the inflight limit
+	finished := map[string]bool{}
+	q = newSignerQueues(t.Name(), 10, 3, func(id string) bool { return finished[id] })
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		require.NoError(t, q.push(queuedTx(id, keyA), nil))
+	}
+	_, ok = q.pop()
+	require.True(t, ok)
+	msgs = q.popWhile(keyA, func(pendingTx) bool { return true })
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "a3", msgs[1].id)
+	q.done(keyA)
+	_, ok = q.pop()
+	assert.False(t, ok)
+
+	finished["a2"] = true
+	msg, ok = q.pop()
+	require.True(t, ok)
+	assert.Equal(t, "a4", msg.id)
+	assert.Empty(t, q.popWhile(keyA, func(pendingTx) bool { return true }))
+}
@@ -0,0 +1,371 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	solanaGo "github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+// TxRetryClass groups the errors of failed txs that share a retry policy
+type TxRetryClass string
+
+const (
+	TxRetryDropped  TxRetryClass = "dropped"  // not found or not confirmed within the confirm timeout
+	TxRetryExpired  TxRetryClass = "expired"  // blockhash expired or durable nonce advanced before the tx was included
+	TxRetryReverted TxRetryClass = "reverted" // reverted onchain or in simulation
+)
+
+var (
+	errBlockhashExpired = errors.New("tx blockhash expired")
+	errNonceAdvanced    = errors.New("tx durable nonce advanced")
+)
+
+// retryClass returns the retry class of a failed tx, false if txs failing with the err type are never retried
+// (rejected, invalid and cancelled txs)
+func retryClass(errType int, txErr error) (TxRetryClass, bool) {
+	switch {
+	case errType == TxFailRevert || errType == TxFailSimRevert:
+		return TxRetryReverted, true
+	case errType != TxFailDrop:
+		return "", false
+	case errors.Is(txErr, errBlockhashExpired) || errors.Is(txErr, errNonceAdvanced):
+		return TxRetryExpired, true
+	default:
+		return TxRetryDropped, true
+	}
+}
+
+// retryLimit returns the number of times a tx failing with the error class is rebuilt and resent
+func retryLimit(cfg config.Config, class TxRetryClass) uint32 {
+	switch class {
+	case TxRetryDropped:
+		return cfg.TxRetryDropped()
+	case TxRetryExpired:
+		return cfg.TxRetryExpired()
+	case TxRetryReverted:
+		return cfg.TxRetryReverted()
+	default:
+		return 0
+	}
+}
+
+// retryable returns if the tx can be rebuilt from its queued message when it fails. txs with supplied signatures
+// are bound to their message and replacements to the window of the replaced tx
+func retryable(msg pendingTx) bool {
+	return msg.cfg.presigned == nil && msg.cfg.replaced == nil
+}
+
+// resend returns the durable nonce account of the failed attempt to the pool and queues the tx to be rebuilt
+// (fresh blockhash or nonce, compute budget and signatures) and broadcasted again under the same id
+func (txm *Txm) resend(msg pendingTx, nonce *DurableNonce) error {
+	if nonce != nil {
+		txm.nonces.release(nonce.Account, msg.id)
+	}
+	// the blockhash of the failed attempts may have expired, the resent tx is signed with the latest blockhash
+	msg.cfg.RefreshBlockhash = true
+	return txm.queues.push(msg, nil)
+}
+
+// DeadLetters returns the txs that failed after exhausting their retries, oldest first.
+// dead letters are kept (and persisted with the TxStorePath) until they are resubmitted or purged
+func (txm *Txm) DeadLetters(ctx context.Context) ([]TxRecord, error) {
+	recs, err := txm.store.ListDeadLetters()
+	if err != nil {
+		return nil, fmt.Errorf("error in soltxm.DeadLetters: %w", err)
+	}
+	slices.SortFunc(recs, func(a, b TxRecord) int { return deadLetteredAt(a).Compare(deadLetteredAt(b)) })
+	return recs, nil
+}
+
+// ResubmitDeadLetter enqueues the message of the dead-lettered tx for the txID again with the newTxID (random id if nil).
+// the tx is signed again with a fresh blockhash, signers that are not in the keystore must be supplied with the config.
+// the tx for the txID is removed from the dead-letter list and pruned after the TxRetentionTimeout
+func (txm *Txm) ResubmitDeadLetter(ctx context.Context, txID string, newTxID *string, txCfgs ...SetTxConfig) error {
+	if err := txm.Ready(); err != nil {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: %w", err)
+	}
+	rec, err := txm.store.Get(txID)
+	if err != nil {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: %w", err)
+	}
+	if !rec.DeadLetter {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: tx %s is not in the dead-letter list", txID)
+	}
+	tx := &solanaGo.Transaction{}
+	if err = tx.Message.UnmarshalWithDecoder(bin.NewBinDecoder(rec.Message)); err != nil {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: failed to decode message of tx %s: %w", txID, err)
+	}
+	if err = txm.validateTx(ctx, tx); err != nil {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: %w", err)
+	}
+	// removed from the list before it is enqueued so the message can not be resubmitted twice
+	if err = txm.store.SetDeadLetter(txID, false); err != nil {
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: %w", err)
+	}
+	if err = txm.enqueue(ctx, tx.Message.AccountKeys[0].String(), tx, newTxID, nil, txCfgs...); err != nil {
+		if restoreErr := txm.store.SetDeadLetter(txID, true); restoreErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to restore tx %s to the dead-letter list: %w", txID, restoreErr))
+		}
+		return fmt.Errorf("error in soltxm.ResubmitDeadLetter: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeadLetters deletes the dead-lettered txs for the txIDs (all dead letters if none are given).
+// returns the number of deleted txs, txIDs that are not in the dead-letter list are skipped
+func (txm *Txm) PurgeDeadLetters(ctx context.Context, txIDs ...string) (int, error) {
+	recs, err := txm.store.ListDeadLetters()
+	if err != nil {
+		return 0, fmt.Errorf("error in soltxm.PurgeDeadLetters: %w", err)
+	}
+	var count int
+	for _, rec := range recs {
+		if len(txIDs) > 0 && !slices.Contains(txIDs, rec.ID) {
+			continue
+		}
+		if err = txm.store.Delete(rec.ID); err != nil {
+			return count, fmt.Errorf("error in soltxm.PurgeDeadLetters: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// deadLetteredAt returns when the tx failed
+func deadLetteredAt(rec TxRecord) time.Time {
+	if len(rec.Transitions) == 0 {
+		return rec.CreatedAt
+	}
+	return rec.Transitions[len(rec.Transitions)-1].Timestamp
+}
+
+// txRetries holds the queued txs of the txs sent on their own until they are finished, failed txs are rebuilt from them
+type txRetries struct {
+	lock sync.Mutex
+	msgs map[string]pendingTx            // id -> tx as queued
+	ids  map[solanaGo.Signature]string   // signature of a broadcasted attempt -> id
+	sigs map[string][]solanaGo.Signature // id -> signatures of the broadcasted attempts
+}
+
+func newTxRetries() *txRetries {
+	return &txRetries{
+		msgs: map[string]pendingTx{},
+		ids:  map[solanaGo.Signature]string{},
+		sigs: map[string][]solanaGo.Signature{},
+	}
+}
+
+// track keeps the queued tx until it is finished, called before the tx is broadcasted
+func (r *txRetries) track(msg pendingTx) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.msgs[msg.id] = msg
+}
+
+// attempt maps the signature of a broadcasted attempt to its tracked tx
+func (r *txRetries) attempt(id string, sig solanaGo.Signature) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.msgs[id]; !ok {
+		return
+	}
+	r.ids[sig] = id
+	r.sigs[id] = append(r.sigs[id], sig)
+}
+
+// get returns the tracked tx of the signature
+func (r *txRetries) get(sig solanaGo.Signature) (pendingTx, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	msg, ok := r.msgs[r.ids[sig]]
+	return msg, ok
+}
+
+// reset forgets the attempts of the tx, the tx is tracked again once it is broadcasted
+func (r *txRetries) reset(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, sig := range r.sigs[id] {
+		delete(r.ids, sig)
+	}
+	delete(r.sigs, id)
+}
+
+// forget stops tracking the tx, returns false if the tx was not tracked
+func (r *txRetries) forget(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.msgs[id]
+	for _, sig := range r.sigs[id] {
+		delete(r.ids, sig)
+	}
+	delete(r.sigs, id)
+	delete(r.msgs, id)
+	return ok
+}
+
+// txRetryPolicy bounds the retries of failed txs per error class and adds the txs that exhausted them to the dead-letter list.
+// shared by the txs sent on their own and the members of failed batch txs
+type txRetryPolicy struct {
+	chainID string
+	cfg     config.Config
+	store   TxStore
+	events  *txEvents
+}
+
+func newTxRetryPolicy(chainID string, cfg config.Config, store TxStore, events *txEvents) *txRetryPolicy {
+	return &txRetryPolicy{
+		chainID: chainID,
+		cfg:     cfg,
+		store:   store,
+		events:  events,
+	}
+}
+
+// allowed returns if the failed tx has retries of the error class left
+func (p *txRetryPolicy) allowed(id string, class TxRetryClass) bool {
+	rec, err := p.store.Get(id)
+	return err == nil && rec.Retries[class] < int(retryLimit(p.cfg, class))
+}
+
+// retried moves the failed tx back to pending and counts the retry of the error class, the caller queues the tx
+func (p *txRetryPolicy) retried(id string, sig solanaGo.Signature, class TxRetryClass, txErr error) {
+	reason := fmt.Sprintf("retrying %s tx: %s", class, errString(txErr))
+	// best effort: the retries are only used for reporting and to bound the retries of the tx
+	_ = p.store.SetState(id, TxStatePending, sig, reason)
+	_ = p.store.AddRetry(id, class)
+	promSolTxmRetryTxs.WithLabelValues(p.chainID, string(class)).Add(1)
+	p.events.publish(TxEvent{Type: TxEventRetried, ID: id, State: TxStatePending, Signature: sig, Error: reason})
+}
+
+// deadLetter adds the failed tx to the dead-letter list, the oldest dead letters are purged once the list is full
+func (p *txRetryPolicy) deadLetter(id string) {
+	maxLen := int(p.cfg.TxDeadLetterMaxLen())
+	if maxLen == 0 {
+		return
+	}
+	rec, err := p.store.Get(id)
+	if err != nil || (rec.State != TxStateFailed && rec.State != TxStateFatal) {
+		return
+	}
+	if err = p.store.SetDeadLetter(id, true); err != nil {
+		return
+	}
+	promSolTxmDeadLetterTxs.WithLabelValues(p.chainID).Add(1)
+
+	recs, err := p.store.ListDeadLetters()
+	if err != nil || len(recs) <= maxLen {
+		return
+	}
+	slices.SortFunc(recs, func(a, b TxRecord) int { return deadLetteredAt(a).Compare(deadLetteredAt(b)) })
+	for _, old := range recs[:len(recs)-maxLen] {
+		_ = p.store.Delete(old.ID)
+	}
+}
+
+var _ PendingTxContext = &pendingTxContextWithRetries{}
+
+// pendingTxContextWithRetries resends failed txs per the retry policy of their error class and adds the txs that
+// exhausted their retries to the dead-letter list. only txs tracked in the retries are retried and dead-lettered
+type pendingTxContextWithRetries struct {
+	PendingTxContext
+	policy  *txRetryPolicy
+	retries *txRetries
+	resend  func(msg pendingTx, nonce *DurableNonce) error // queues the failed tx to be rebuilt and broadcasted again
+}
+
+func newPendingTxContextWithRetries(txs PendingTxContext, policy *txRetryPolicy, retries *txRetries, resend func(pendingTx, *DurableNonce) error) *pendingTxContextWithRetries {
+	return &pendingTxContextWithRetries{
+		PendingTxContext: txs,
+		policy:           policy,
+		retries:          retries,
+		resend:           resend,
+	}
+}
+
+func (c *pendingTxContextWithRetries) New(id string, attempt TxAttempt, cancel context.CancelFunc) error {
+	if err := c.PendingTxContext.New(id, attempt, cancel); err != nil {
+		return err
+	}
+	c.retries.attempt(id, attempt.Signature)
+	return nil
+}
+
+func (c *pendingTxContextWithRetries) Add(id string, attempt TxAttempt) error {
+	if err := c.PendingTxContext.Add(id, attempt); err != nil {
+		return err
+	}
+	c.retries.attempt(id, attempt.Signature)
+	return nil
+}
+
+// Cancel stops retrying the tx, cancelled txs are not resent or dead-lettered
+func (c *pendingTxContextWithRetries) Cancel(id string, reason error) error {
+	if err := c.PendingTxContext.Cancel(id, reason); err != nil {
+		return err
+	}
+	c.retries.forget(id)
+	return nil
+}
+
+func (c *pendingTxContextWithRetries) OnFinalized(sig solanaGo.Signature) string {
+	id := c.PendingTxContext.OnFinalized(sig)
+	if id != "" {
+		c.retries.forget(id)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithRetries) OnError(sig solanaGo.Signature, errType int, txErr error) string {
+	msg, tracked := c.retries.get(sig)
+	if !tracked {
+		return c.PendingTxContext.OnError(sig, errType, txErr)
+	}
+	if class, ok := retryClass(errType, txErr); ok && c.retry(msg, sig, class, txErr) {
+		return msg.id
+	}
+	id := c.PendingTxContext.OnError(sig, errType, txErr)
+	if id != "" {
+		c.retries.forget(id)
+		c.policy.deadLetter(id)
+	}
+	return id
+}
+
+func (c *pendingTxContextWithRetries) OnPendingError(id string, errType int, txErr error) {
+	c.PendingTxContext.OnPendingError(id, errType, txErr)
+	if c.retries.forget(id) {
+		c.policy.deadLetter(id)
+	}
+}
+
+// retry moves the failed tx back to pending and queues it to be resent, returns false if the retries of the class
+// are exhausted or the tx is no longer tracked
+func (c *pendingTxContextWithRetries) retry(msg pendingTx, sig solanaGo.Signature, class TxRetryClass, txErr error) bool {
+	if !c.policy.allowed(msg.id, class) {
+		return false
+	}
+	var nonce *DurableNonce
+	if _, n, ok := c.DurableNonce(sig); ok {
+		nonce = &n
+	}
+	// stops rebroadcasting the failed attempts
+	if c.Remove(sig) == "" {
+		return false
+	}
+	c.retries.reset(msg.id)
+	c.policy.retried(msg.id, sig, class, txErr)
+
+	if err := c.resend(msg, nonce); err != nil {
+		c.OnPendingError(msg.id, TxFailDrop, fmt.Errorf("failed to requeue tx for retry: %w", err))
+	}
+	return true
+}
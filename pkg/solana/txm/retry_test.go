@@ -0,0 +1,171 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/config"
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	solcfg "github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+func TestRetryClass(t *testing.T) {
+	for _, tc := range []struct {
+		errType int
+		err     error
+		class   TxRetryClass
+		ok      bool
+	}{
+		{TxFailDrop, errors.New("tx not found within confirm timeout"), TxRetryDropped, true},
+		{TxFailDrop, errBlockhashExpired, TxRetryExpired, true},
+		{TxFailDrop, errNonceAdvanced, TxRetryExpired, true},
+		{TxFailRevert, &TxError{Reason: "tx reverted"}, TxRetryReverted, true},
+		{TxFailSimRevert, &TxError{Reason: "simulation reverted"}, TxRetryReverted, true},
+		{TxFailReject, errors.New("rejected"), "", false},
+		{TxFailInvalid, errors.New("invalid"), "", false},
+		{TxFailSimOther, errors.New("simulation failed"), "", false},
+		{TxFailCancel, ErrTxCancelled, "", false},
+	} {
+		class, ok := retryClass(tc.errType, tc.err)
+		assert.Equal(t, tc.ok, ok, tc.err)
+		assert.Equal(t, tc.class, class, tc.err)
+	}
+}
+
+func TestTxm_RetriesAndDeadLetters(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.NewWallet().PrivateKey
+
+	cfg := solcfg.NewDefault()
+	retries := uint32(1)
+	cfg.Chain.TxRetryDropped = &retries
+	cfg.Chain.TxConfirmTimeout = config.MustNewDuration(500 * time.Millisecond)
+	cfg.Chain.ConfirmPollPeriod = config.MustNewDuration(50 * time.Millisecond)
+
+	// memo 1 is never found until confirm is set, memo 2 reverts
+	var confirm atomic.Bool
+	var blockhash atomic.Uint32
+	var lock sync.Mutex
+	sent := map[solana.Signature]*solana.Transaction{}
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(func(context.Context) (*rpc.GetLatestBlockhashResult, error) {
+		return &rpc.GetLatestBlockhashResult{
+			Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{byte(blockhash.Add(1))}, LastValidBlockHeight: 100},
+		}, nil
+	}).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		sent[tx.Signatures[0]] = tx
+		return tx.Signatures[0], nil
+	}).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) []*rpc.SignatureStatusesResult {
+		lock.Lock()
+		defer lock.Unlock()
+		res := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i, sig := range sigs {
+			tx, ok := sent[sig]
+			if !ok {
+				continue
+			}
+			switch memos(tx)[0][0] {
+			case 1:
+				if confirm.Load() {
+					res[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+				}
+			case 2:
+				res[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed, Err: "InvalidAccountData"}
+			}
+		}
+		return res
+	}, nil).Maybe()
+
+	txm := NewTxm("retries", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+	events, unsubscribe := txm.SubscribeEvents()
+	t.Cleanup(unsubscribe)
+
+	// the dropped tx is resent once with a fresh blockhash, then dead-lettered
+	dropped := "dropped"
+	require.NoError(t, txm.Enqueue(ctx, "test", memoTx(t, payer.PublicKey(), 1), &dropped))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, dropped)
+		return err == nil && status.State == TxStateFailed
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+
+	deadLetters, err := txm.DeadLetters(ctx)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, dropped, deadLetters[0].ID)
+	assert.Equal(t, map[TxRetryClass]int{TxRetryDropped: 1}, deadLetters[0].Retries)
+	require.Len(t, deadLetters[0].Attempts, 2)
+	assert.NotEqual(t, deadLetters[0].Attempts[0].Blockhash, deadLetters[0].Attempts[1].Blockhash)
+
+	var retried []TxEvent
+	for len(events) > 0 {
+		if ev := <-events; ev.Type == TxEventRetried {
+			retried = append(retried, ev)
+		}
+	}
+	require.Len(t, retried, 1)
+	assert.Equal(t, dropped, retried[0].ID)
+	assert.Equal(t, TxStatePending, retried[0].State)
+
+	// reverted txs are not retried by default
+	reverted := "reverted"
+	require.NoError(t, txm.Enqueue(ctx, "test", memoTx(t, payer.PublicKey(), 2), &reverted))
+	require.Eventually(t, func() bool {
+		deadLetters, err = txm.DeadLetters(ctx)
+		return err == nil && len(deadLetters) == 2
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	assert.Equal(t, reverted, deadLetters[1].ID)
+	assert.Empty(t, deadLetters[1].Retries)
+
+	// resubmitted txs are removed from the dead-letter list
+	confirm.Store(true)
+	resubmitted := "resubmitted"
+	require.ErrorContains(t, txm.ResubmitDeadLetter(ctx, "unknown", &resubmitted), "not found")
+	// a failed enqueue keeps the tx in the dead-letter list
+	require.ErrorIs(t, txm.ResubmitDeadLetter(ctx, dropped, &reverted), ErrTxAlreadyExists)
+	deadLetters, err = txm.DeadLetters(ctx)
+	require.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+	require.NoError(t, txm.ResubmitDeadLetter(ctx, dropped, &resubmitted))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, resubmitted)
+		return err == nil && status.State == TxStateConfirmed
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	require.ErrorContains(t, txm.ResubmitDeadLetter(ctx, dropped, nil), "not in the dead-letter list")
+	status, err := txm.GetTransactionStatus(ctx, dropped)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+
+	// purged txs are deleted
+	purged, err := txm.PurgeDeadLetters(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	purged, err = txm.PurgeDeadLetters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = txm.GetTransactionStatus(ctx, reverted)
+	require.ErrorIs(t, err, ErrTxNotFound)
+}
@@ -0,0 +1,115 @@
+package txm
+
+import (
+	"context"
+	"fmt"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+// requiredSigners returns the accounts that must sign the message, the first NumRequiredSignatures account keys.
+// the fee payer always signs, even if the header is not set
+func requiredSigners(msg solanaGo.Message) []solanaGo.PublicKey {
+	n := min(max(int(msg.Header.NumRequiredSignatures), 1), len(msg.AccountKeys))
+	return msg.AccountKeys[:n]
+}
+
+func signerKey(keys []solanaGo.PrivateKey, signer solanaGo.PublicKey) (solanaGo.PrivateKey, bool) {
+	for _, k := range keys {
+		if k.PublicKey().Equals(signer) {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// validateSigners checks that every required signer of the tx can be signed: with the keys of the tx config, the keystore,
+// or a valid signature supplied with the tx. returns the supplied signatures of the signers the txm cannot sign
+func (txm *Txm) validateSigners(ctx context.Context, tx *solanaGo.Transaction, keys []solanaGo.PrivateKey) (map[solanaGo.PublicKey]solanaGo.Signature, error) {
+	msg, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tx message: %w", err)
+	}
+	presigned := map[solanaGo.PublicKey]solanaGo.Signature{}
+	for i, signer := range requiredSigners(tx.Message) {
+		if _, ok := signerKey(keys, signer); ok {
+			continue
+		}
+		_, ksErr := txm.ks.Sign(ctx, signer.String(), nil)
+		if ksErr == nil {
+			continue
+		}
+		if i < len(tx.Signatures) && !tx.Signatures[i].IsZero() && tx.Signatures[i].Verify(signer, msg) {
+			presigned[signer] = tx.Signatures[i]
+			continue
+		}
+		return nil, fmt.Errorf("no key or valid signature for signer %s: %w", signer, ksErr)
+	}
+	if len(presigned) == 0 {
+		return nil, nil
+	}
+	return presigned, nil
+}
+
+// signTx replaces the signatures of the tx with the signatures of all required signers over the current message.
+// signers are signed with the keys of the tx config, the signatures supplied with the tx, or the keystore
+func (txm *Txm) signTx(ctx context.Context, tx *solanaGo.Transaction, keys []solanaGo.PrivateKey, presigned map[solanaGo.PublicKey]solanaGo.Signature) error {
+	msg, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal tx message: %w", err)
+	}
+	signers := requiredSigners(tx.Message)
+	sigs := make([]solanaGo.Signature, len(signers))
+	for i, signer := range signers {
+		if key, ok := signerKey(keys, signer); ok {
+			if sigs[i], err = key.Sign(msg); err != nil {
+				return fmt.Errorf("failed to sign with key of %s: %w", signer, err)
+			}
+			continue
+		}
+		if sig, ok := presigned[signer]; ok {
+			// supplied signatures can not be re-signed, only valid while the message is unchanged
+			if !sig.Verify(signer, msg) {
+				return fmt.Errorf("signature supplied for %s is not valid for the tx message", signer)
+			}
+			sigs[i] = sig
+			continue
+		}
+		sigBytes, signErr := txm.ks.Sign(ctx, signer.String(), msg)
+		if signErr != nil {
+			return fmt.Errorf("failed to sign with keystore key of %s: %w", signer, signErr)
+		}
+		copy(sigs[i][:], sigBytes)
+	}
+	tx.Signatures = sigs
+	return nil
+}
+
+// sealPresigned sets the config of a tx with supplied signatures so that its message is sent unchanged: compute budget
+// instructions are not added, fees are not bumped and the blockhash is not refreshed. the compute unit limit and price
+// set by the tx instructions are used to track the fee of the tx
+func sealPresigned(tx *solanaGo.Transaction, cfg *TxConfig) {
+	var limit, price uint64
+	for _, ix := range tx.Message.Instructions {
+		program, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil || !program.Equals(fees.ComputeBudgetProgram) {
+			continue
+		}
+		if v, err := fees.ParseComputeUnitLimit(ix.Data); err == nil {
+			limit = uint64(v)
+		}
+		if v, err := fees.ParseComputeUnitPrice(ix.Data); err == nil {
+			price = uint64(v)
+		}
+	}
+	cfg.ComputeUnitLimit = uint32(limit) //nolint:gosec // parsed from a uint32
+	cfg.BaseComputeUnitPrice, cfg.ComputeUnitPriceMin, cfg.ComputeUnitPriceMax = price, price, price
+	cfg.EstimateComputeUnitLimit = false
+	cfg.EstimateLoadedAccountsDataSize = false
+	cfg.LoadedAccountsDataSizeLimit = 0
+	cfg.HeapFrame = 0
+	cfg.FeeBumpPeriod = 0
+	cfg.RefreshBlockhash = false
+}
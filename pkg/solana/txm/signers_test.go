@@ -0,0 +1,219 @@
+package txm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+// keystoreWith returns a keystore mock that only holds the key
+func keystoreWith(t *testing.T, key solana.PrivateKey) *keyMocks.SimpleKeystore {
+	ks := keyMocks.NewSimpleKeystore(t)
+	ks.On("Sign", mock.Anything, key.PublicKey().String(), mock.Anything).Return(func(_ context.Context, _ string, data []byte) ([]byte, error) {
+		sig, err := key.Sign(data)
+		return sig[:], err
+	}).Maybe()
+	ks.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("key not found")).Maybe()
+	return ks
+}
+
+// multiSignerTx returns a tx paid by payer with an instruction that requires the signature of signer
+func multiSignerTx(t *testing.T, payer, signer solana.PublicKey) *solana.Transaction {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(signer).SIGNER().WRITE()}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+	return tx
+}
+
+func verifySignatures(t *testing.T, tx *solana.Transaction) {
+	msg, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+	signers := requiredSigners(tx.Message)
+	require.Len(t, tx.Signatures, len(signers))
+	for i, signer := range signers {
+		assert.True(t, tx.Signatures[i].Verify(signer, msg), "invalid signature of %s", signer)
+	}
+}
+
+// presign sets the signature of the signer key supplied with the tx
+func presign(t *testing.T, tx *solana.Transaction, key solana.PrivateKey) {
+	msg, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+	signers := requiredSigners(tx.Message)
+	if len(tx.Signatures) != len(signers) {
+		tx.Signatures = make([]solana.Signature, len(signers))
+	}
+	for i, signer := range signers {
+		if signer.Equals(key.PublicKey()) {
+			tx.Signatures[i], err = key.Sign(msg)
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestRequiredSigners(t *testing.T) {
+	payer, signer := solana.PublicKey{1}, solana.PublicKey{2}
+	tx := multiSignerTx(t, payer, signer)
+	assert.Equal(t, []solana.PublicKey{payer, signer}, requiredSigners(tx.Message))
+
+	// fee payer always signs
+	assert.Equal(t, []solana.PublicKey{{}}, requiredSigners(NewTestTx().Message))
+	assert.Empty(t, requiredSigners(solana.Message{}))
+}
+
+func TestTxm_SignTx(t *testing.T) {
+	ctx := tests.Context(t)
+	payer, signer := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+	txm := &Txm{ks: keystoreWith(t, payer)}
+
+	t.Run("keystore and tx keys", func(t *testing.T) {
+		tx := multiSignerTx(t, payer.PublicKey(), signer.PublicKey())
+		presigned, err := txm.validateSigners(ctx, tx, []solana.PrivateKey{signer})
+		require.NoError(t, err)
+		assert.Nil(t, presigned)
+
+		require.NoError(t, txm.signTx(ctx, tx, []solana.PrivateKey{signer}, presigned))
+		verifySignatures(t, tx)
+
+		// re-signed after the message changes
+		require.NoError(t, fees.SetComputeUnitPrice(tx, 1))
+		require.NoError(t, txm.signTx(ctx, tx, []solana.PrivateKey{signer}, presigned))
+		verifySignatures(t, tx)
+	})
+
+	t.Run("supplied signatures", func(t *testing.T) {
+		tx := multiSignerTx(t, payer.PublicKey(), signer.PublicKey())
+		presign(t, tx, signer)
+
+		presigned, err := txm.validateSigners(ctx, tx, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[solana.PublicKey]solana.Signature{signer.PublicKey(): tx.Signatures[1]}, presigned)
+		require.NoError(t, txm.signTx(ctx, tx, nil, presigned))
+		verifySignatures(t, tx)
+
+		// supplied signatures are invalid once the message changes
+		require.NoError(t, fees.SetComputeUnitPrice(tx, 1))
+		require.ErrorContains(t, txm.signTx(ctx, tx, nil, presigned), "not valid for the tx message")
+	})
+
+	t.Run("missing signer", func(t *testing.T) {
+		tx := multiSignerTx(t, payer.PublicKey(), signer.PublicKey())
+		_, err := txm.validateSigners(ctx, tx, nil)
+		require.ErrorContains(t, err, "no key or valid signature for signer "+signer.PublicKey().String())
+
+		// signatures over another message are rejected
+		tx.Signatures = []solana.Signature{{}, {1}}
+		_, err = txm.validateSigners(ctx, tx, nil)
+		require.ErrorContains(t, err, "no key or valid signature for signer "+signer.PublicKey().String())
+	})
+}
+
+func TestSealPresigned(t *testing.T) {
+	tx := payerTx(t, solana.PublicKey{1})
+	require.NoError(t, fees.SetComputeUnitPrice(tx, 10))
+	require.NoError(t, fees.SetComputeUnitLimit(tx, 50_000))
+
+	cfg := TxConfig{
+		BaseComputeUnitPrice:           1,
+		ComputeUnitPriceMax:            1_000,
+		ComputeUnitLimit:               200_000,
+		EstimateComputeUnitLimit:       true,
+		EstimateLoadedAccountsDataSize: true,
+		HeapFrame:                      64 * 1024,
+		FeeBumpPeriod:                  time.Second,
+		RefreshBlockhash:               true,
+	}
+	sealPresigned(tx, &cfg)
+	assert.Equal(t, TxConfig{
+		BaseComputeUnitPrice: 10,
+		ComputeUnitPriceMin:  10,
+		ComputeUnitPriceMax:  10,
+		ComputeUnitLimit:     50_000,
+	}, cfg)
+}
+
+func TestTxm_MultiSigner(t *testing.T) {
+	ctx := tests.Context(t)
+	payer, signer := solana.NewWallet().PrivateKey, solana.NewWallet().PrivateKey
+
+	sent := make(chan *solana.Transaction, 10)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(func(_ context.Context, tx *solana.Transaction) solana.Signature {
+		return tx.Signatures[0]
+	}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{nil}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := NewTxm("multi_signer", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, config.NewDefault(), keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// waitSent returns the first sent tx that matches
+	waitSent := func(match func(*solana.Transaction) bool) (tx *solana.Transaction) {
+		require.Eventually(t, func() bool {
+			for {
+				select {
+				case tx = <-sent:
+					if match(tx) {
+						return true
+					}
+				default:
+					return false
+				}
+			}
+		}, tests.WaitTimeout(t), 10*time.Millisecond)
+		return tx
+	}
+
+	// signers without a key are rejected
+	require.ErrorContains(t, txm.Enqueue(ctx, "test", multiSignerTx(t, payer.PublicKey(), signer.PublicKey()), nil), "no key or valid signature")
+
+	// every attempt is signed by all signers, the fee bump changes the message
+	require.NoError(t, txm.Enqueue(ctx, "test", multiSignerTx(t, payer.PublicKey(), signer.PublicKey()), nil,
+		SetSigners(signer), SetFeeBumpPeriod(100*time.Millisecond), SetBaseComputeUnitPrice(1)))
+	first := waitSent(func(*solana.Transaction) bool { return true })
+	verifySignatures(t, first)
+	bumped := waitSent(func(tx *solana.Transaction) bool { return tx.Signatures[0] != first.Signatures[0] })
+	verifySignatures(t, bumped)
+
+	// txs with supplied signatures are sent unchanged
+	tx := multiSignerTx(t, payer.PublicKey(), signer.PublicKey())
+	presign(t, tx, signer)
+	msg, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+	signature := tx.Signatures[1]
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, nil))
+	presigned := waitSent(func(tx *solana.Transaction) bool { return len(tx.Signatures) == 2 && tx.Signatures[1] == signature })
+	verifySignatures(t, presigned)
+	sentMsg, err := presigned.Message.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, msg, sentMsg)
+}
@@ -0,0 +1,311 @@
+package txm
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	solanaGo "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/services"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+)
+
+const (
+	// tipComputeUnits is the compute units of the tip transfer added to txs sent as bundles
+	tipComputeUnits = 150
+	// bundleStatusWindow is how long the block engine reports the status of a bundle
+	bundleStatusWindow = 5 * time.Minute
+	// tpuSendTimeout is the max duration of sending a tx to the TPUs of the upcoming leaders
+	tpuSendTimeout = 5 * time.Second
+)
+
+// Submitter broadcasts the signed txs of the txm, selected per chain with TxSubmitter
+type Submitter interface {
+	Start(context.Context) error
+	Close() error
+	// Prepare adds the instructions the submitter requires to the unsigned tx (i.e. tips), called once per tx
+	// before compute budget instructions are added. the message of txs with supplied signatures is not prepared
+	Prepare(ctx context.Context, tx *solanaGo.Transaction, cfg *TxConfig) error
+	// Tip returns the lamports the fee payer pays with each prepared tx (0 = none), included in the worst case fee of the tx
+	Tip() uint64
+	// Submit broadcasts the signed tx, called for the initial broadcast and every rebroadcast of the tx.
+	// errors are classified as RPC send errors
+	Submit(ctx context.Context, client client.ReaderWriter, tx *solanaGo.Transaction) (solanaGo.Signature, error)
+}
+
+var _ Submitter = rpcSubmitter{}
+
+// rpcSubmitter sends txs to the RPC node with sendTransaction
+type rpcSubmitter struct{}
+
+func (rpcSubmitter) Start(context.Context) error { return nil }
+
+func (rpcSubmitter) Close() error { return nil }
+
+func (rpcSubmitter) Prepare(context.Context, *solanaGo.Transaction, *TxConfig) error { return nil }
+
+func (rpcSubmitter) Tip() uint64 { return 0 }
+
+func (rpcSubmitter) Submit(ctx context.Context, client client.ReaderWriter, tx *solanaGo.Transaction) (solanaGo.Signature, error) {
+	return client.SendTx(ctx, tx)
+}
+
+var _ Submitter = &tpuSubmitter{}
+
+// tpuSubmitter sends txs to the RPC node and, in parallel, directly to the TPUs of the upcoming leaders.
+// the result of the RPC send is returned, failed TPU sends only fall back to the RPC node
+type tpuSubmitter struct {
+	rpcSubmitter
+	starter services.StateMachine
+	chStop  services.StopChan
+	done    sync.WaitGroup
+
+	chainID string
+	lggr    logger.Logger
+	tpu     *client.TPUSender
+}
+
+func newTPUSubmitter(chainID string, tpu *client.TPUSender, lggr logger.Logger) *tpuSubmitter {
+	return &tpuSubmitter{
+		chStop:  make(chan struct{}),
+		chainID: chainID,
+		lggr:    logger.Named(lggr, "TPUSubmitter"),
+		tpu:     tpu,
+	}
+}
+
+func (s *tpuSubmitter) Start(context.Context) error {
+	return s.starter.StartOnce("solana_tpuSubmitter", func() error { return nil })
+}
+
+func (s *tpuSubmitter) Close() error {
+	return s.starter.StopOnce("solana_tpuSubmitter", func() error {
+		close(s.chStop)
+		s.done.Wait()
+		return s.tpu.Close()
+	})
+}
+
+// Submit sends the tx to the TPUs without delaying the RPC send, the TPU send outlives the context of the broadcast
+func (s *tpuSubmitter) Submit(ctx context.Context, c client.ReaderWriter, tx *solanaGo.Transaction) (solanaGo.Signature, error) {
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		tpuCtx, cancel := s.chStop.CtxWithTimeout(tpuSendTimeout)
+		defer cancel()
+		err := s.tpu.Send(tpuCtx, tx)
+		promSolTxmTPUSent.WithLabelValues(s.chainID, strconv.FormatBool(err == nil)).Inc()
+		if err != nil {
+			s.lggr.Debugw("failed to send tx to TPUs, relying on the RPC node", "signature", tx.Signatures[0], "error", err)
+		}
+	}()
+	return s.rpcSubmitter.Submit(ctx, c, tx)
+}
+
+var _ Submitter = &bundleSubmitter{}
+
+// sentBundle is the latest bundle a signed tx was sent in
+type sentBundle struct {
+	id     string
+	status string
+	sent   time.Time
+}
+
+// bundleSubmitter sends each tx as a bundle to a block engine. txs tip a tip account of the block engine, bundles are
+// only resent once the block engine reports them as failed or unknown, the landed txs are confirmed by the RPC node
+type bundleSubmitter struct {
+	starter services.StateMachine
+	chStop  services.StopChan
+	done    sync.WaitGroup
+
+	chainID    string
+	lggr       logger.Logger
+	engine     *client.BlockEngine
+	tip        uint64
+	pollPeriod time.Duration
+
+	lock    sync.Mutex
+	bundles map[solanaGo.Signature]*sentBundle // signature of the tx -> latest bundle
+}
+
+func newBundleSubmitter(chainID string, engine *client.BlockEngine, tip uint64, pollPeriod time.Duration, lggr logger.Logger) *bundleSubmitter {
+	return &bundleSubmitter{
+		chStop:     make(chan struct{}),
+		chainID:    chainID,
+		lggr:       logger.Named(lggr, "BundleSubmitter"),
+		engine:     engine,
+		tip:        tip,
+		pollPeriod: pollPeriod,
+		bundles:    map[solanaGo.Signature]*sentBundle{},
+	}
+}
+
+func (s *bundleSubmitter) Start(context.Context) error {
+	return s.starter.StartOnce("solana_bundleSubmitter", func() error {
+		s.done.Add(1)
+		go s.run()
+		return nil
+	})
+}
+
+func (s *bundleSubmitter) Close() error {
+	return s.starter.StopOnce("solana_bundleSubmitter", func() error {
+		close(s.chStop)
+		s.done.Wait()
+		return nil
+	})
+}
+
+// Prepare adds a transfer of the tip from the fee payer to a random tip account of the block engine
+func (s *bundleSubmitter) Prepare(ctx context.Context, tx *solanaGo.Transaction, cfg *TxConfig) error {
+	accounts, err := s.engine.TipAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	if err = withTip(tx, accounts[rand.IntN(len(accounts))], s.tip); err != nil { //nolint:gosec // tip accounts are picked at random to spread write locks
+		return err
+	}
+	if cfg.ComputeUnitLimit != 0 {
+		cfg.ComputeUnitLimit += tipComputeUnits
+	}
+	return nil
+}
+
+func (s *bundleSubmitter) Tip() uint64 { return s.tip }
+
+// Submit sends the tx as a bundle unless the bundle the tx was last sent in is pending or landed.
+// txs without a tip (txs with supplied signatures) can not land in a bundle and are sent to the RPC node
+func (s *bundleSubmitter) Submit(ctx context.Context, c client.ReaderWriter, tx *solanaGo.Transaction) (solanaGo.Signature, error) {
+	accounts, err := s.engine.TipAccounts(ctx)
+	if err != nil {
+		return solanaGo.Signature{}, err
+	}
+	if !hasTip(tx, accounts) {
+		return c.SendTx(ctx, tx)
+	}
+
+	sig := tx.Signatures[0]
+	s.lock.Lock()
+	b, ok := s.bundles[sig]
+	resend := !ok || b.status == client.BundleStatusFailed || b.status == client.BundleStatusInvalid
+	s.lock.Unlock()
+	if !resend {
+		return sig, nil
+	}
+
+	id, err := s.engine.SendBundle(ctx, []*solanaGo.Transaction{tx})
+	if err != nil {
+		return solanaGo.Signature{}, err
+	}
+	promSolTxmBundlesSent.WithLabelValues(s.chainID).Inc()
+	s.lock.Lock()
+	s.bundles[sig] = &sentBundle{id: id, status: client.BundleStatusPending, sent: time.Now()}
+	s.lock.Unlock()
+	s.lggr.Debugw("tx sent in bundle", "signature", sig, "bundle", id, "resent", ok)
+	return sig, nil
+}
+
+func (s *bundleSubmitter) run() {
+	defer s.done.Done()
+	ctx, cancel := s.chStop.NewCtx()
+	defer cancel()
+
+	tick := services.NewTicker(s.pollPeriod)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+// poll updates the status of pending bundles. bundles are tracked while the block engine reports their status
+func (s *bundleSubmitter) poll(ctx context.Context) {
+	s.lock.Lock()
+	var ids []string
+	for sig, b := range s.bundles {
+		if time.Since(b.sent) > bundleStatusWindow {
+			delete(s.bundles, sig)
+			continue
+		}
+		if b.status == client.BundleStatusPending {
+			ids = append(ids, b.id)
+		}
+	}
+	s.lock.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	res, err := s.engine.InflightBundleStatuses(ctx, ids)
+	if err != nil {
+		s.lggr.Warnw("failed to get bundle statuses", "error", err)
+		return
+	}
+	statuses := make(map[string]string, len(res))
+	for _, status := range res {
+		statuses[status.BundleID] = status.Status
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for sig, b := range s.bundles {
+		status, ok := statuses[b.id]
+		if !ok || b.status != client.BundleStatusPending || status == b.status {
+			continue
+		}
+		b.status = status
+		promSolTxmBundleStatus.WithLabelValues(s.chainID, status).Inc()
+		if status != client.BundleStatusLanded {
+			s.lggr.Debugw("bundle not landed, resending on the next rebroadcast", "signature", sig, "bundle", b.id, "status", status)
+		}
+	}
+}
+
+// withTip appends a transfer of lamports from the fee payer to the tip account
+func withTip(tx *solanaGo.Transaction, tipAccount solanaGo.PublicKey, lamports uint64) error {
+	// writable accounts must be added before readonly accounts to keep the readonly indexes stable
+	tipIdx, err := addAccount(&tx.Message, tipAccount, true)
+	if err != nil {
+		return err
+	}
+	programIdx, err := addAccount(&tx.Message, solanaGo.SystemProgramID, false)
+	if err != nil {
+		return err
+	}
+	data, err := system.NewTransferInstruction(lamports, tx.Message.AccountKeys[0], tipAccount).Build().Data()
+	if err != nil {
+		return fmt.Errorf("failed to encode tip instruction: %w", err)
+	}
+	tx.Message.Instructions = append(slices.Clip(tx.Message.Instructions), solanaGo.CompiledInstruction{
+		ProgramIDIndex: programIdx,
+		Accounts:       []uint16{0, tipIdx}, // fee payer (index 0) pays the tip
+		Data:           data,
+	})
+	return nil
+}
+
+// hasTip returns if the tx transfers lamports to one of the tip accounts
+func hasTip(tx *solanaGo.Transaction, tipAccounts []solanaGo.PublicKey) bool {
+	for _, ix := range tx.Message.Instructions {
+		program, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil || !program.Equals(solanaGo.SystemProgramID) || len(ix.Accounts) != 2 {
+			continue
+		}
+		if to, err := tx.Message.Account(ix.Accounts[1]); err == nil && slices.ContainsFunc(tipAccounts, to.Equals) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package txm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/config"
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	solcfg "github.com/goplugin/plugin-solana/pkg/solana/config"
+)
+
+func TestWithTip(t *testing.T) {
+	payer, tipAccount := solana.PublicKey{1}, solana.PublicKey{2}
+	tx := memoTx(t, payer, 1)
+	original := *tx
+
+	require.NoError(t, withTip(tx, tipAccount, 1_000))
+	require.Len(t, tx.Message.Instructions, 2)
+	program, err := tx.Message.Program(tx.Message.Instructions[1].ProgramIDIndex)
+	require.NoError(t, err)
+	assert.Equal(t, solana.SystemProgramID, program)
+	accounts, err := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, []*solana.AccountMeta{solana.Meta(payer).SIGNER().WRITE(), solana.Meta(tipAccount).WRITE()}, accounts)
+
+	// existing instructions reference the same accounts
+	assert.Equal(t, [][]byte{{1}}, memos(tx))
+	accounts, err = tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, []*solana.AccountMeta{solana.Meta(solana.PublicKey{9}).WRITE()}, accounts)
+	assert.Len(t, original.Message.Instructions, 1)
+
+	assert.True(t, hasTip(tx, []solana.PublicKey{{3}, tipAccount}))
+	assert.False(t, hasTip(tx, []solana.PublicKey{{3}}))
+	assert.False(t, hasTip(&original, []solana.PublicKey{tipAccount}))
+}
+
+func TestTxm_BundleSubmitter(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.NewWallet().PrivateKey
+	engine := client.SetupTestBlockEngine(t, "")
+
+	cfg := solcfg.NewDefault()
+	submitter := "bundle"
+	cfg.Chain.TxSubmitter = &submitter
+	cfg.Chain.BlockEngineURL = &engine.URL
+	cfg.Chain.BundleStatusPollPeriod = config.MustNewDuration(50 * time.Millisecond)
+	cfg.Chain.FeeBumpPeriod = config.MustNewDuration(0) // rebroadcasts resend the same tx
+
+	// txs are only sent to the block engine, landed bundles are confirmed by the RPC node
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) []*rpc.SignatureStatusesResult {
+		res := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for _, b := range engine.Bundles() {
+			for i, sig := range sigs {
+				if b.Status == client.BundleStatusLanded && b.Txs[0].Signatures[0] == sig {
+					res[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+				}
+			}
+		}
+		return res
+	}, nil).Maybe()
+
+	txm := NewTxm("bundle", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// the tx is sent as a bundle that tips a tip account (bundles without tip are rejected by the block engine)
+	id := "bundled"
+	require.NoError(t, txm.Enqueue(ctx, "test", memoTx(t, payer.PublicKey(), 1), &id))
+	var bundle client.TestBundle
+	require.Eventually(t, func() bool {
+		bundles := engine.Bundles()
+		if len(bundles) == 0 {
+			return false
+		}
+		bundle = bundles[0]
+		return true
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	require.Len(t, bundle.Txs, 1)
+	assert.True(t, hasTip(bundle.Txs[0], engine.TipAccounts))
+	verifySignatures(t, bundle.Txs[0])
+
+	// pending bundles are not resent on rebroadcast, failed bundles are
+	time.Sleep(500 * time.Millisecond)
+	require.Len(t, engine.Bundles(), 1)
+	assert.Equal(t, 1, engine.Bundles()[0].Sends)
+	engine.SetStatus(bundle.ID, client.BundleStatusFailed)
+	require.Eventually(t, func() bool {
+		return engine.Bundles()[0].Sends > 1
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+
+	// the tx of the landed bundle is confirmed
+	engine.SetStatus(bundle.ID, client.BundleStatusLanded)
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateConfirmed
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	assert.Len(t, engine.Bundles(), 1)
+}
+
+func TestTxm_BundleSubmitter_BalanceCheck(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.NewWallet().PrivateKey
+	engine := client.SetupTestBlockEngine(t, "")
+
+	cfg := solcfg.NewDefault()
+	submitter := "bundle"
+	cfg.Chain.TxSubmitter = &submitter
+	cfg.Chain.BlockEngineURL = &engine.URL
+	balanceCheck := true
+	cfg.Chain.TxBalanceCheck = &balanceCheck
+
+	// the balance covers the signature and priority fees, but not the tip
+	tx := memoTx(t, payer.PublicKey(), 1)
+	fee := worstCaseFee(tx, TxConfig{ComputeUnitPriceMax: cfg.ComputeUnitPriceMax(), ComputeUnitLimit: cfg.ComputeUnitLimitDefault()})
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("Balance", mock.Anything, payer.PublicKey()).Return(fee+cfg.BundleTipLamports()-1, nil)
+
+	txm := NewTxm("bundle", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// the tip added when the tx is sent is included in the worst case fee
+	require.ErrorIs(t, txm.Enqueue(ctx, "test", tx, nil), ErrInsufficientBalance)
+}
+
+func TestTxm_TPUSubmitter(t *testing.T) {
+	ctx := tests.Context(t)
+	payer := solana.NewWallet().PrivateKey
+	tpu := client.SetupTestTPU(t)
+	leader := solana.PublicKey{1}
+
+	cfg := solcfg.NewDefault()
+	fanout := uint32(2)
+	cfg.Chain.TPUFanout = &fanout
+
+	// txs are sent to the TPU of the leader and the RPC node, the RPC node confirms the txs it received
+	var lock sync.Mutex
+	sent := map[solana.Signature]*solana.Transaction{}
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SlotHeight", mock.Anything).Return(uint64(10), nil).Maybe()
+	mc.On("SlotLeaders", mock.Anything, uint64(10), mock.Anything).Return([]solana.PublicKey{leader, leader, leader, leader}, nil).Maybe()
+	mc.On("TPUAddresses", mock.Anything).Return(map[solana.PublicKey]string{leader: tpu.Addr}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		sent[tx.Signatures[0]] = tx
+		return tx.Signatures[0], nil
+	}).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) []*rpc.SignatureStatusesResult {
+		lock.Lock()
+		defer lock.Unlock()
+		res := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i, sig := range sigs {
+			if _, ok := sent[sig]; ok {
+				res[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+			}
+		}
+		return res
+	}, nil).Maybe()
+
+	txm := NewTxm("tpu", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keystoreWith(t, payer), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	id := "tpu"
+	require.NoError(t, txm.Enqueue(ctx, "test", memoTx(t, payer.PublicKey(), 1), &id))
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, id)
+		return err == nil && status.State == TxStateConfirmed
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return len(tpu.Received()) > 0
+	}, tests.WaitTimeout(t), 10*time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	for _, data := range tpu.Received() {
+		tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+		require.NoError(t, err)
+		assert.Contains(t, sent, tx.Signatures[0])
+		verifySignatures(t, tx)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	bin "github.com/gagliardetto/binary"
 	solanaGo "github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/google/uuid"
@@ -21,19 +24,31 @@ import (
 	bigmath "github.com/goplugin/plugin-common/pkg/utils/big_math"
 
 	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	mn "github.com/goplugin/plugin-solana/pkg/solana/client/multinode"
+	"github.com/goplugin/plugin-solana/pkg/solana/codec"
 	"github.com/goplugin/plugin-solana/pkg/solana/config"
 	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 )
 
 const (
-	MaxQueueLen                    = 1000
-	MaxRetryTimeMs                 = 250 // max tx retry time (exponential retry will taper to retry every 0.25s)
-	MaxSigsToConfirm               = 256 // max number of signatures in GetSignatureStatus call
-	EstimateComputeUnitLimitBuffer = 10  // percent buffer added on top of estimated compute unit limits to account for any variance
+	MaxQueueLen                          = 1000 // max len of the simulation queue
+	MaxRetryTimeMs                       = 250  // max tx retry time (exponential retry will taper to retry every 0.25s)
+	MaxSigsToConfirm                     = 256  // max number of signatures in GetSignatureStatus call
+	RollbackMisses                       = 3    // consecutive polls the signature of a confirmed tx must be missing before the tx is rolled back
+	EstimateComputeUnitLimitBuffer       = 10   // percent buffer added on top of estimated compute unit limits to account for any variance
+	EstimateLoadedAccountsDataSizeBuffer = 10   // percent buffer added on top of estimated loaded accounts data sizes to account for accounts growing
+	loadedAccountBaseSize                = 64   // bytes the runtime charges per loaded account on top of the account data
+	MaxTxSize                            = 1232 // max size of a serialized signed tx (packet data size)
+
+	// how often the send queues are checked for fee payers with capacity again, finished txs do not signal the queues
+	queuePollPeriod = 100 * time.Millisecond
 )
 
 var _ services.Service = (*Txm)(nil)
 
+// ErrTxCancelled is the error of txs that were cancelled or replaced before being included
+var ErrTxCancelled = errors.New("tx cancelled")
+
 //go:generate mockery --name SimpleKeystore --output ./mocks/ --case=underscore --filename simple_keystore.go
 type SimpleKeystore interface {
 	Sign(ctx context.Context, account string, data []byte) (signature []byte, err error)
@@ -43,53 +58,129 @@ type SimpleKeystore interface {
 var _ loop.Keystore = (SimpleKeystore)(nil)
 
 // Txm manages transactions for the solana blockchain.
-// simple implementation with no persistently stored txs
+// inflight txs are persisted to the configured TxStore and resumed on restart
 type Txm struct {
 	services.StateMachine
-	lggr   logger.Logger
-	chSend chan pendingTx
-	chSim  chan pendingTx
-	chStop services.StopChan
-	done   sync.WaitGroup
-	cfg    config.Config
-	txs    PendingTxContext
-	ks     SimpleKeystore
-	client *utils.LazyLoad[client.ReaderWriter]
-	fee    fees.Estimator
+	chainID  string
+	lggr     logger.Logger
+	queues   *signerQueues // per fee payer send queues
+	chSim    chan pendingTx
+	chStop   services.StopChan
+	done     sync.WaitGroup
+	cfg      config.Config
+	txs      PendingTxContext
+	store    TxStore
+	ks       SimpleKeystore
+	client   *utils.LazyLoad[client.ReaderWriter]
+	fee      fees.Estimator
+	feeLock  sync.RWMutex // guards fee for FeeEstimator callers, txm routines read fee after it is set on start
+	bump     fees.BumpStrategy
+	submit   Submitter // broadcasts signed txs to the RPC node (and leader TPUs) or block engine
+	nonces   *noncePool
+	balances *feePayerBalances
+	programs programConfigs
+	errs     *programErrors // custom program errors of known programs, decodes reverted txs
+	events   *txEvents
+	batches  *txBatches // members of inflight batch txs
+	retries  *txRetries // queued txs of inflight txs, failed txs are rebuilt from them
+
+	// ids of the txs being replaced, a tx is replaced by one caller at a time
+	replacingLock sync.Mutex
+	replacing     map[string]struct{}
+
+	// classifies send errors to decide whether to retry, bump, rebuild or fail a tx
+	classifySendError mn.TxErrorClassifier[*solanaGo.Transaction]
+
+	// latest block height observed by the confirmer, used to detect expired blockhashes
+	blockHeight atomic.Uint64
 }
 
 type TxConfig struct {
 	Timeout time.Duration // transaction broadcast timeout
 
 	// compute unit price config
-	FeeBumpPeriod        time.Duration // how often to bump fee
-	BaseComputeUnitPrice uint64        // starting price
-	ComputeUnitPriceMin  uint64        // min price
-	ComputeUnitPriceMax  uint64        // max price
+	FeeBumpPeriod        time.Duration     // how often to bump fee
+	BaseComputeUnitPrice uint64            // starting price
+	ComputeUnitPriceMin  uint64            // min price
+	ComputeUnitPriceMax  uint64            // max price
+	FeeBumpStrategy      fees.BumpStrategy // price of each fee bump (nil = double the price)
+	FeeBudget            uint64            // max lamports the tx can pay (signature + priority fees), caps fee bumping (0 = no budget)
 
 	EstimateComputeUnitLimit bool   // enable compute limit estimations using simulation
 	ComputeUnitLimit         uint32 // compute unit limit
+
+	EstimateLoadedAccountsDataSize bool   // enable loaded accounts data size estimations using simulation
+	LoadedAccountsDataSizeLimit    uint32 // loaded accounts data size limit in bytes (0 = runtime default)
+	HeapFrame                      uint32 // heap frame size in bytes (0 = runtime default)
+
+	RefreshBlockhash     bool               // re-sign with a fresh blockhash when the blockhash expires during retries
+	CompletionCommitment rpc.CommitmentType // commitment at which the tx is finished (empty = TxCompletionCommitment)
+	UseDurableNonce      bool               // use a durable nonce account of the fee payer in place of a recent blockhash
+
+	Signers []solanaGo.PrivateKey // keys of required signers that are not in the keystore (i.e. new account keypairs), re-signed on every attempt
+
+	Batch bool // send the tx in a batch tx with other queued txs of the fee payer, the tx state follows the batch tx
+
+	OnEvent func(TxEvent) // called with each lifecycle event of the tx, must not block
+
+	replaced *TxAttempt // latest attempt of the tx being replaced, its durable nonce or blockhash is reused
+	// signatures supplied with the tx for signers the txm cannot sign, the tx message is sent unchanged (nil = none)
+	presigned map[solanaGo.PublicKey]solanaGo.Signature
+	// the worst case fee of the tx is reserved with its fee payer (balance checks or fee payer pool)
+	balanceCheck bool
+	// lamports the fee payer tips the submitter (bundle tips), 0 for txs that are not prepared by the submitter
+	tip uint64
 }
 
 type pendingTx struct {
 	tx        *solanaGo.Transaction
 	cfg       TxConfig
 	signature solanaGo.Signature
-	id        uuid.UUID
+	id        string
+}
+
+// TxStatus describes the current state of a tx enqueued with the txm
+type TxStatus struct {
+	State     TxState
+	Signature solanaGo.Signature // signature that triggered the latest state (included signature once confirmed)
+	Fee       uint64             // lamports paid (or to be paid) by the signature
+	Error     string             // reason for Failed and Fatal states
+	TxError   *DecodedTxError    // decoded TransactionError of reverted txs (nil if unknown)
+	Batch     *TxBatchMember     // batch tx the tx was sent in (nil if sent on its own)
 }
 
 // NewTxm creates a txm. Uses simulation so should only be used to send txes to trusted contracts i.e. OCR.
 func NewTxm(chainID string, tc func() (client.ReaderWriter, error), cfg config.Config, ks SimpleKeystore, lggr logger.Logger) *Txm {
-	return &Txm{
-		lggr:   logger.Named(lggr, "Txm"),
-		chSend: make(chan pendingTx, MaxQueueLen), // queue can support 1000 pending txs
-		chSim:  make(chan pendingTx, MaxQueueLen), // queue can support 1000 pending txs
-		chStop: make(chan struct{}),
-		cfg:    cfg,
-		txs:    newPendingTxContextWithProm(chainID),
-		ks:     ks,
-		client: utils.NewLazyLoad(tc),
+	lggr = logger.Named(lggr, "Txm")
+	txm := &Txm{
+		chainID: chainID,
+		lggr:    lggr,
+		chSim:   make(chan pendingTx, MaxQueueLen), // queue can support 1000 pending txs
+		chStop:  make(chan struct{}),
+		cfg:     cfg,
+		errs:    newProgramErrors(),
+		events:  newTxEvents(lggr),
+		ks:      ks,
+		client:  utils.NewLazyLoad(tc),
+		submit:  rpcSubmitter{},
+
+		replacing: map[string]struct{}{},
+
+		classifySendError: client.NewSendErrorClassifier(chainID),
 	}
+	txm.txs = txm.newPendingTxContext(NewMemoryTxStore())
+	return txm
+}
+
+// newPendingTxContext returns the context tracking the txs persisted in the store
+func (txm *Txm) newPendingTxContext(store TxStore) PendingTxContext {
+	txm.store = store
+	txm.batches = newTxBatches(store)
+	txm.retries = newTxRetries()
+	var txs PendingTxContext = newPendingTxContextWithEvents(newPendingTxContextWithProm(txm.chainID, store, txm.errs), txm.events)
+	policy := newTxRetryPolicy(txm.chainID, txm.cfg, store, txm.events)
+	txs = newPendingTxContextWithBatches(txs, txm.batches, txm.events, policy, txm.requeue)
+	return newPendingTxContextWithRetries(txs, policy, txm.retries, txm.resend)
 }
 
 // Start subscribes to queuing channel and processes them.
@@ -102,89 +193,285 @@ func (txm *Txm) Start(ctx context.Context) error {
 		case "fixed":
 			estimator, err = fees.NewFixedPriceEstimator(txm.cfg)
 		case "blockhistory":
-			estimator, err = fees.NewBlockHistoryEstimator(txm.client, txm.cfg, txm.lggr)
+			estimator, err = fees.NewBlockHistoryEstimator(txm.client, txm.cfg, txm.lggr, txm.chainID)
+		case "recentfees":
+			estimator, err = fees.NewRecentFeesEstimator(txm.client, txm.cfg, txm.lggr)
 		default:
 			err = fmt.Errorf("unknown solana fee estimator type: %s", txm.cfg.FeeEstimatorMode())
 		}
 		if err != nil {
 			return err
 		}
+		txm.feeLock.Lock()
 		txm.fee = estimator
+		txm.feeLock.Unlock()
+		if txm.bump, err = fees.NewBumpStrategy(txm.cfg, txm.fee); err != nil {
+			return err
+		}
 		if err := txm.fee.Start(ctx); err != nil {
 			return err
 		}
 
-		txm.done.Add(3) // waitgroup: tx retry, confirmer, simulator
+		nonces, err := newNoncePool(txm.cfg.NonceAccounts())
+		if err != nil {
+			return errors.Join(err, txm.fee.Close())
+		}
+		txm.nonces = nonces
+		balances, err := newFeePayerBalances(txm.cfg.BalancePollPeriod(), txm.cfg.FeePayerPools())
+		if err != nil {
+			return errors.Join(err, txm.fee.Close())
+		}
+		txm.balances = balances
+		programs, err := newProgramConfigs(txm.cfg.ProgramConfigs())
+		if err != nil {
+			return errors.Join(err, txm.fee.Close())
+		}
+		txm.programs = programs
+		if err = txm.errs.registerConfigs(txm.cfg.ProgramConfigs()); err != nil {
+			return errors.Join(err, txm.fee.Close())
+		}
+		txm.queues = newSignerQueues(txm.chainID, txm.cfg.TxMaxQueueLen(), txm.cfg.TxMaxInflight(), txm.txFinished)
+
+		// determine submitter type
+		switch strings.ToLower(txm.cfg.TxSubmitter()) {
+		case "rpc":
+			txm.submit = rpcSubmitter{}
+			if fanout := txm.cfg.TPUFanout(); fanout > 0 {
+				tpu, tpuErr := client.NewTPUSender(func() (client.LeaderReader, error) { return txm.client.Get() }, fanout, txm.lggr)
+				if tpuErr != nil {
+					return errors.Join(tpuErr, txm.fee.Close())
+				}
+				txm.submit = newTPUSubmitter(txm.chainID, tpu, txm.lggr)
+			}
+		case "bundle":
+			engine := client.NewBlockEngine(txm.cfg.BlockEngineURL())
+			txm.submit = newBundleSubmitter(txm.chainID, engine, txm.cfg.BundleTipLamports(), txm.cfg.BundleStatusPollPeriod(), txm.lggr)
+		default:
+			return errors.Join(fmt.Errorf("unknown solana tx submitter type: %s", txm.cfg.TxSubmitter()), txm.fee.Close())
+		}
+		if err = txm.submit.Start(ctx); err != nil {
+			return errors.Join(err, txm.fee.Close())
+		}
+
+		// open persistent tx store if configured and resume confirming any stored inflight txs
+		if path := txm.cfg.TxStorePath(); path != "" {
+			store, err := NewFileTxStore(path)
+			if err != nil {
+				return errors.Join(err, txm.fee.Close(), txm.submit.Close())
+			}
+			txm.txs = txm.newPendingTxContext(store)
+			txm.reconcile()
+		}
+
+		txm.done.Add(4) // waitgroup: tx retry, confirmer, simulator, events
 		go txm.run()
 		go txm.confirm()
 		go txm.simulate()
+		go func() {
+			defer txm.done.Done()
+			txm.events.run(txm.chStop)
+		}()
 
 		return nil
 	})
 }
 
+// reconcile loads txs from the store that had not reached a terminal state when the txm stopped
+// and resumes polling for their confirmation. broadcasted txs are resent while their blockhash or nonce is still valid
+func (txm *Txm) reconcile() {
+	records, err := txm.store.ListInflight()
+	if err != nil {
+		txm.lggr.Errorw("failed to load inflight txs from store", "error", err)
+		return
+	}
+
+	var broadcasted []TxRecord
+	for _, rec := range records {
+		// txs that were never broadcasted cannot be confirmed
+		if rec.State == TxStatePending {
+			txm.txs.OnPendingError(rec.ID, TxFailDrop, errors.New("txm stopped before tx was broadcasted"))
+			txm.lggr.Infow("dropping stored tx that was never broadcasted", "id", rec.ID)
+			continue
+		}
+		// txs sent in a batch tx follow the state of the restored batch tx
+		if rec.Batch != nil {
+			txm.batches.restore(rec)
+			continue
+		}
+		if err := txm.txs.Restore(rec); err != nil {
+			txm.lggr.Errorw("failed to restore tx from store", "id", rec.ID, "error", err)
+			continue
+		}
+		// nonce accounts of restored txs cannot be reused until the tx is finished
+		for _, attempt := range rec.Attempts {
+			if attempt.DurableNonce != nil {
+				txm.nonces.reserve(attempt.DurableNonce.Account, rec.ID)
+			}
+		}
+		// the members of a batch tx are resent with the batch tx
+		if rec.State == TxStateBroadcasted {
+			broadcasted = append(broadcasted, rec)
+		}
+		txm.lggr.Infow("resuming confirmation of stored tx", "id", rec.ID, "state", rec.State, "signatures", rec.Signatures())
+	}
+
+	if len(broadcasted) > 0 {
+		txm.done.Add(1)
+		go txm.resendRestored(broadcasted)
+	}
+}
+
+// resendRestored rebroadcasts the latest signed attempt of restored broadcasted txs that can still be included,
+// a tx that had not reached the leader before the txm stopped would otherwise always expire
+func (txm *Txm) resendRestored(records []TxRecord) {
+	defer txm.done.Done()
+	ctx, cancel := txm.chStop.NewCtx()
+	defer cancel()
+
+	client, err := txm.client.Get()
+	if err != nil {
+		txm.lggr.Warnw("failed to get client to resend restored txs", "error", err)
+		return
+	}
+	// restored txs using a blockhash are not resent if the block height is unknown
+	height, err := client.BlockHeight(ctx)
+	if err != nil {
+		txm.lggr.Warnw("failed to get block height to resend restored txs", "error", err)
+	}
+
+	for _, rec := range records {
+		i := len(rec.Attempts) - 1
+		for i >= 0 && len(rec.Attempts[i].Tx) == 0 {
+			i--
+		}
+		if i < 0 {
+			continue
+		}
+		attempt := rec.Attempts[i]
+
+		var valid bool
+		if attempt.DurableNonce != nil {
+			current, nonceErr := GetDurableNonce(ctx, client, attempt.DurableNonce.Account, attempt.DurableNonce.Authority)
+			valid = nonceErr == nil && current.Value == attempt.DurableNonce.Value
+		} else {
+			valid = height != 0 && height <= attempt.LastValidBlockHeight
+		}
+		if !valid {
+			txm.lggr.Debugw("not resending restored tx, blockhash expired or nonce advanced", "id", rec.ID, "signature", attempt.Signature)
+			continue
+		}
+		txm.rebroadcast(rec.ID, attempt.Signature)
+	}
+}
+
 func (txm *Txm) run() {
 	defer txm.done.Done()
 	ctx, cancel := txm.chStop.NewCtx()
 	defer cancel()
 
+	tick := time.NewTicker(queuePollPeriod)
+	defer tick.Stop()
 	for {
-		select {
-		case msg := <-txm.chSend:
-			// process tx (pass tx copy)
-			tx, id, sig, err := txm.sendWithRetry(ctx, *msg.tx, msg.cfg)
-			if err != nil {
-				txm.lggr.Errorw("failed to send transaction", "error", err)
-				txm.client.Reset() // clear client if tx fails immediately (potentially bad RPC)
-				continue           // skip remainining
-			}
-
-			// send tx + signature to simulation queue
-			msg.tx = &tx
-			msg.signature = sig
-			msg.id = id
-			select {
-			case txm.chSim <- msg:
-			default:
-				txm.lggr.Warnw("failed to enqeue tx for simulation", "queueFull", len(txm.chSend) == MaxQueueLen, "tx", msg)
+		// broadcast the next tx of every fee payer that is not sending and has capacity
+		for {
+			msg, ok := txm.queues.pop()
+			if !ok {
+				break
 			}
+			msgs := txm.popBatch(msg)
+			txm.done.Add(1)
+			go func() {
+				defer txm.done.Done()
+				defer txm.queues.done(msg.tx.Message.AccountKeys[0])
+				if len(msgs) > 1 {
+					txm.sendBatch(ctx, msgs)
+					return
+				}
+				txm.send(ctx, msg)
+			}()
+		}
 
-			txm.lggr.Debugw("transaction sent", "signature", sig.String(), "id", id)
+		select {
+		case <-txm.queues.ready():
+		case <-tick.C:
 		case <-txm.chStop:
 			return
 		}
 	}
 }
 
-func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction, txcfg TxConfig) (solanaGo.Transaction, uuid.UUID, solanaGo.Signature, error) {
+// send broadcasts the tx and queues it for simulation
+func (txm *Txm) send(ctx context.Context, msg pendingTx) {
+	// batch txs are not retried, their members are requeued or fail with them
+	if retryable(msg) && len(txm.batches.get(msg.id)) == 0 {
+		txm.retries.track(msg)
+	}
+
+	// process tx (pass tx copy)
+	tx, sig, err := txm.sendWithRetry(ctx, msg.id, *msg.tx, msg.cfg)
+	if err != nil {
+		txm.lggr.Errorw("failed to send transaction", "id", msg.id, "error", err)
+		txm.client.Reset() // clear client if tx fails immediately (potentially bad RPC)
+		return
+	}
+
+	// send tx + signature to simulation queue
+	msg.tx = &tx
+	msg.signature = sig
+	select {
+	case txm.chSim <- msg:
+	default:
+		txm.lggr.Warnw("failed to enqeue tx for simulation", "queueFull", len(txm.chSim) == MaxQueueLen, "tx", msg)
+	}
+
+	txm.lggr.Debugw("transaction sent", "signature", sig.String(), "id", msg.id)
+}
+
+// sendWithRetry broadcasts the pending tx for the id and rebroadcasts it until confirmed or timed out
+// the pending tx is marked as failed if it cannot be broadcasted
+func (txm *Txm) sendWithRetry(ctx context.Context, id string, baseTx solanaGo.Transaction, txcfg TxConfig) (tx solanaGo.Transaction, sig solanaGo.Signature, err error) {
+	failType := TxFailReject
+	defer func() {
+		// errors are only returned before the broadcasted tx is tracked
+		if err != nil {
+			txm.txs.OnPendingError(id, failType, err)
+		}
+	}()
+
 	// fetch client
 	client, clientErr := txm.client.Get()
 	if clientErr != nil {
-		return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, fmt.Errorf("failed to get client in soltxm.sendWithRetry: %w", clientErr)
+		return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to get client in soltxm.sendWithRetry: %w", clientErr)
 	}
 
-	// get key
-	// fee payer account is index 0 account
-	// https://github.com/gagliardetto/solana-go/blob/main/transaction.go#L252
-	key := baseTx.Message.AccountKeys[0].String()
+	// add the instructions required by the submitter (i.e. bundle tips), before the fee of the tx is bounded
+	if txcfg.presigned == nil {
+		if prepareErr := txm.submit.Prepare(ctx, &baseTx, &txcfg); prepareErr != nil {
+			return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to prepare tx for submission: %w", prepareErr)
+		}
+	}
 
 	// base compute unit price should only be calculated once
 	// prevent underlying base changing when bumping (could occur with RPC based estimation)
+	// the price of each bump is calculated once from the price of the previous bump, bounded by the min, max and fee budget
+	bump := txcfg.FeeBumpStrategy
+	if bump == nil {
+		bump = fees.DoubleBump{}
+	}
+	maxPrice := maxComputeUnitPrice(&baseTx, txcfg)
+	prices := []uint64{txcfg.BaseComputeUnitPrice}
 	getFee := func(count int) fees.ComputeUnitPrice {
-		fee := fees.CalculateFee(
-			txcfg.BaseComputeUnitPrice,
-			txcfg.ComputeUnitPriceMax,
-			txcfg.ComputeUnitPriceMin,
-			uint(count), //nolint:gosec // reasonable number of bumps should never cause overflow
-		)
-		return fees.ComputeUnitPrice(fee)
+		for len(prices) <= count {
+			prices = append(prices, bump.Price(txcfg.BaseComputeUnitPrice, prices[len(prices)-1], uint(len(prices)))) //nolint:gosec // reasonable number of bumps should never cause overflow
+		}
+		return fees.ComputeUnitPrice(min(max(prices[count], txcfg.ComputeUnitPriceMin), maxPrice))
 	}
 
-	// add compute unit limit instruction - static for the transaction
-	// skip if compute unit limit = 0 (otherwise would always fail)
-	if txcfg.ComputeUnitLimit != 0 {
-		if computeUnitLimitErr := fees.SetComputeUnitLimit(&baseTx, fees.ComputeUnitLimit(txcfg.ComputeUnitLimit)); computeUnitLimitErr != nil {
-			return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, fmt.Errorf("failed to add compute unit limit instruction: %w", computeUnitLimitErr)
+	// add compute unit limit, heap frame and loaded accounts data size limit instructions - static for the transaction
+	// skip if the message of a presigned tx can not change
+	if txcfg.presigned == nil {
+		if computeBudgetErr := withComputeBudget(&baseTx, txcfg); computeBudgetErr != nil {
+			return solanaGo.Transaction{}, solanaGo.Signature{}, computeBudgetErr
 		}
 	}
 
@@ -193,54 +480,101 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 
 		// set fee
 		// fee bumping can be enabled by moving the setting & signing logic to the broadcaster
-		if computeUnitErr := fees.SetComputeUnitPrice(&newTx, getFee(retryCount)); computeUnitErr != nil {
-			return solanaGo.Transaction{}, computeUnitErr
+		if txcfg.presigned == nil {
+			if computeUnitErr := fees.SetComputeUnitPrice(&newTx, getFee(retryCount)); computeUnitErr != nil {
+				return solanaGo.Transaction{}, computeUnitErr
+			}
 		}
 
-		// sign tx
-		txMsg, marshalErr := newTx.Message.MarshalBinary()
-		if marshalErr != nil {
-			return solanaGo.Transaction{}, fmt.Errorf("error in soltxm.SendWithRetry.MarshalBinary: %w", marshalErr)
-		}
-		sigBytes, signErr := txm.ks.Sign(ctx, key, txMsg)
-		if signErr != nil {
+		// sign tx with all required signers (fee payer is index 0 account)
+		// https://github.com/gagliardetto/solana-go/blob/main/transaction.go#L252
+		if signErr := txm.signTx(ctx, &newTx, txcfg.Signers, txcfg.presigned); signErr != nil {
 			return solanaGo.Transaction{}, fmt.Errorf("error in soltxm.SendWithRetry.Sign: %w", signErr)
 		}
-		var finalSig [64]byte
-		copy(finalSig[:], sigBytes)
-		newTx.Signatures = append(newTx.Signatures, finalSig)
 
 		return newTx, nil
 	}
 
+	// use a durable nonce in place of the recent blockhash
+	// the tx does not expire by block height so the blockhash is never refreshed
+	var nonce *DurableNonce
+	if txcfg.UseDurableNonce {
+		n, nonceErr := txm.useDurableNonce(ctx, client, id, &baseTx, txcfg.replaced)
+		if nonceErr != nil {
+			return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to set durable nonce: %w", nonceErr)
+		}
+		nonce = &n
+		txcfg.RefreshBlockhash = false
+	}
+
+	// track blockhash expiry (refreshing uses the latest blockhash for the initial broadcast)
+	var lastValid uint64
+	switch {
+	case nonce != nil:
+	case txcfg.replaced != nil:
+		// a replacement is only valid within the blockhash window of the replaced tx
+		baseTx.Message.RecentBlockhash = txcfg.replaced.Blockhash
+		lastValid = txcfg.replaced.LastValidBlockHeight
+	default:
+		lastValid = txm.getLastValidBlockHeight(ctx, client, &baseTx, txcfg.RefreshBlockhash)
+	}
+
 	initTx, initBuildErr := buildTx(ctx, baseTx, 0)
 	if initBuildErr != nil {
-		return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, initBuildErr
+		return solanaGo.Transaction{}, solanaGo.Signature{}, initBuildErr
 	}
 
 	// create timeout context
 	ctx, cancel := context.WithTimeout(ctx, txcfg.Timeout)
 
-	// send initial tx (do not retry and exit early if fails)
-	sig, initSendErr := client.SendTx(ctx, &initTx)
-	if initSendErr != nil {
-		cancel()                           // cancel context when exiting early
-		txm.txs.OnError(sig, TxFailReject) // increment failed metric
-		return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, fmt.Errorf("tx failed initial transmit: %w", initSendErr)
+	// send initial tx (exit early if the tx is rejected, temporary errors are retried by the rebroadcast loop)
+	sig, initSendErr := txm.submit.Submit(ctx, client, &initTx)
+	code := txm.classifySendError(&initTx, initSendErr)
+	// the blockhash is unknown to the node, nothing was accepted so the tx is rebuilt once with the latest blockhash
+	if code == mn.TerminallyStuck && nonce == nil && txcfg.replaced == nil && txcfg.presigned == nil {
+		txm.lggr.Debugw("blockhash not found on initial transmit, rebuilding tx with latest blockhash", "id", id, "error", initSendErr)
+		lastValid = txm.getLastValidBlockHeight(ctx, client, &baseTx, true)
+		if initTx, initBuildErr = buildTx(ctx, baseTx, 0); initBuildErr != nil {
+			cancel() // cancel context when exiting early
+			return solanaGo.Transaction{}, solanaGo.Signature{}, initBuildErr
+		}
+		sig, initSendErr = txm.submit.Submit(ctx, client, &initTx)
+		code = txm.classifySendError(&initTx, initSendErr)
 	}
-
-	// store tx signature + cancel function
-	id, initStoreErr := txm.txs.New(sig, cancel)
-	if initStoreErr != nil {
+	var bumpNow atomic.Bool // set when the RPC reports the tx as underpriced
+	switch code {
+	case mn.Successful:
+	case mn.TransactionAlreadyKnown, mn.Retryable, mn.Underpriced:
+		// the signature is deterministic, track the tx and let the retry loop rebroadcast it
+		txm.lggr.Debugw("tx initial transmit not accepted, retrying", "id", id, "class", code, "error", initSendErr)
+		sig = initTx.Signatures[0]
+		bumpNow.Store(code == mn.Underpriced)
+	default:
 		cancel() // cancel context when exiting early
-		return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, fmt.Errorf("failed to save tx signature (%s) to inflight txs: %w", sig, initStoreErr)
+		if isInvalidSendError(code) {
+			failType = sendErrorFailType(initSendErr)
+		}
+		return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("tx failed initial transmit (%s): %w", code, initSendErr)
 	}
 
 	// used for tracking rebroadcasting only in SendWithRetry
 	var sigs signatureList
 	sigs.Allocate()
 	if initSetErr := sigs.Set(0, sig); initSetErr != nil {
-		return solanaGo.Transaction{}, uuid.Nil, solanaGo.Signature{}, fmt.Errorf("failed to save initial signature in signature list: %w", initSetErr)
+		cancel() // cancel context when exiting early
+		return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to save initial signature in signature list: %w", initSetErr)
+	}
+
+	// store tx signature + cancel function
+	signed, marshalErr := initTx.MarshalBinary()
+	if marshalErr != nil {
+		cancel() // cancel context when exiting early
+		return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to marshal signed tx: %w", marshalErr)
+	}
+	initAttempt := TxAttempt{Signature: sig, ComputeUnitPrice: uint64(getFee(0)), Blockhash: initTx.Message.RecentBlockhash, LastValidBlockHeight: lastValid, DurableNonce: nonce, Programs: instructionPrograms(initTx.Message), Tx: signed}
+	if initStoreErr := txm.txs.New(id, initAttempt, cancel); initStoreErr != nil {
+		cancel() // cancel context when exiting early
+		return solanaGo.Transaction{}, solanaGo.Signature{}, fmt.Errorf("failed to save tx signature (%s) to inflight txs: %w", sig, initStoreErr)
 	}
 
 	txm.lggr.Debugw("tx initial broadcast", "id", id, "signature", sig)
@@ -251,10 +585,16 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 	// pass in copy of baseTx (used to build new tx with bumped fee) and broadcasted tx == initTx (used to retry tx without bumping)
 	go func(ctx context.Context, baseTx, currentTx solanaGo.Transaction) {
 		defer txm.done.Done()
+		if txcfg.RefreshBlockhash {
+			// expired blockhashes are dropped by the confirmer once the tx is no longer retried
+			txm.txs.SetRefreshBlockhash(id, true)
+			defer txm.txs.SetRefreshBlockhash(id, false)
+		}
 		deltaT := 1 // ms
 		tick := time.After(0)
 		bumpCount := 0
 		bumpTime := time.Now()
+		sigCount := 0
 		var wg sync.WaitGroup
 
 		for {
@@ -266,47 +606,79 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 				return
 			case <-tick:
 				var shouldBump bool
-				// bump if period > 0 and past time
-				if txcfg.FeeBumpPeriod != 0 && time.Since(bumpTime) > txcfg.FeeBumpPeriod {
+				// bump if period > 0 and past time, or right away if the tx was reported as underpriced
+				if txcfg.FeeBumpPeriod != 0 && (time.Since(bumpTime) > txcfg.FeeBumpPeriod || bumpNow.Swap(false)) {
 					bumpCount++
 					bumpTime = time.Now()
 					shouldBump = true
 				}
 
-				// if fee should be bumped, build new tx and replace currentTx
-				if shouldBump {
+				// refresh blockhash once the current blockhash has expired
+				// previous signatures can no longer be included so only the refreshed tx can land
+				var shouldRefresh bool
+				if txcfg.RefreshBlockhash && txm.shouldRefreshBlockhash(id, lastValid) {
+					latest, latestErr := client.LatestBlockhash(ctx)
+					if latestErr != nil || latest == nil || latest.Value == nil {
+						txm.lggr.Warnw("failed to get latest blockhash for refreshing tx", "error", latestErr, "id", id)
+					} else {
+						baseTx.Message.RecentBlockhash = latest.Value.Blockhash
+						lastValid = latest.Value.LastValidBlockHeight
+						shouldRefresh = true
+					}
+				}
+
+				// if fee should be bumped or blockhash refreshed, build new tx and replace currentTx
+				if shouldBump || shouldRefresh {
 					var retryBuildErr error
 					currentTx, retryBuildErr = buildTx(ctx, baseTx, bumpCount)
 					if retryBuildErr != nil {
-						txm.lggr.Errorw("failed to build bumped retry tx", "error", retryBuildErr, "id", id)
+						txm.lggr.Errorw("failed to build retry tx", "error", retryBuildErr, "id", id, "bumped", shouldBump, "refreshed", shouldRefresh)
 						return // exit func if cannot build tx for retrying
 					}
+					sigCount++
 					ind := sigs.Allocate()
-					if ind != bumpCount {
-						txm.lggr.Errorw("INVARIANT VIOLATION: index (%d) != bumpCount (%d)", ind, bumpCount)
+					if ind != sigCount {
+						txm.lggr.Errorw("INVARIANT VIOLATION: index (%d) != sigCount (%d)", ind, sigCount)
 						return
 					}
 				}
 
-				// take currentTx and broadcast, if bumped fee -> save signature to list
+				// take currentTx and broadcast, if rebuilt -> save signature to list
 				wg.Add(1)
-				go func(bump bool, count int, retryTx solanaGo.Transaction) {
+				go func(rebuilt bool, count int, attempt TxAttempt, retryTx solanaGo.Transaction) {
 					defer wg.Done()
 
-					retrySig, retrySendErr := client.SendTx(ctx, &retryTx)
-					// this could occur if endpoint goes down or if ctx cancelled
-					if retrySendErr != nil {
-						if strings.Contains(retrySendErr.Error(), "context canceled") || strings.Contains(retrySendErr.Error(), "context deadline exceeded") {
-							txm.lggr.Debugw("ctx error on send retry transaction", "error", retrySendErr, "signatures", sigs.List(), "id", id)
-						} else {
-							txm.lggr.Warnw("failed to send retry transaction", "error", retrySendErr, "signatures", sigs.List(), "id", id)
+					retrySig, retrySendErr := txm.submit.Submit(ctx, client, &retryTx)
+					switch code := txm.classifySendError(&retryTx, retrySendErr); {
+					case code == mn.Successful:
+					case code == mn.TransactionAlreadyKnown:
+						retrySig = retryTx.Signatures[0]
+					case ctx.Err() != nil:
+						txm.lggr.Debugw("ctx error on send retry transaction", "error", retrySendErr, "signatures", sigs.List(), "id", id)
+						return
+					case isInvalidSendError(code) && !rebuilt:
+						// the tracked tx will never be included, stop retrying and fail it
+						txm.lggr.Warnw("retry transaction rejected", "class", code, "error", retrySendErr, "signatures", sigs.List(), "id", id)
+						txm.txs.OnError(retryTx.Signatures[0], sendErrorFailType(retrySendErr), fmt.Errorf("tx rejected on retry (%s): %w", code, retrySendErr))
+						return
+					default:
+						// temporary errors are retried on the next tick, previous attempts can still be included
+						// if a rebuilt tx is rejected (i.e. the bumped fee can not be paid)
+						if code == mn.Underpriced {
+							bumpNow.Store(true)
 						}
+						txm.lggr.Warnw("failed to send retry transaction", "class", code, "error", retrySendErr, "signatures", sigs.List(), "id", id)
 						return
 					}
 
-					// save new signature if fee bumped
-					if bump {
-						if retryStoreErr := txm.txs.Add(id, retrySig); retryStoreErr != nil {
+					// save new signature if rebuilt
+					if rebuilt {
+						attempt.Signature = retrySig
+						if attempt.Tx, retrySendErr = retryTx.MarshalBinary(); retrySendErr != nil {
+							txm.lggr.Errorw("failed to marshal retry transaction", "error", retrySendErr, "id", id)
+							return
+						}
+						if retryStoreErr := txm.txs.Add(id, attempt); retryStoreErr != nil {
 							txm.lggr.Warnw("error in adding retry transaction", "error", retryStoreErr, "id", id)
 							return
 						}
@@ -314,7 +686,7 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 							// this should never happen
 							txm.lggr.Errorw("INVARIANT VIOLATION", "error", setErr)
 						}
-						txm.lggr.Debugw("tx rebroadcast with bumped fee", "id", id, "fee", getFee(count), "signatures", sigs.List())
+						txm.lggr.Debugw("tx rebroadcast with new signature", "id", id, "fee", attempt.ComputeUnitPrice, "lastValidBlockHeight", attempt.LastValidBlockHeight, "signatures", sigs.List())
 					}
 
 					// prevent locking on waitgroup when ctx is closed
@@ -333,7 +705,7 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 					if fetchedSig, fetchErr := sigs.Get(count); fetchErr != nil || retrySig != fetchedSig {
 						txm.lggr.Errorw("original signature does not match retry signature", "expectedSignatures", sigs.List(), "receivedSignature", retrySig, "error", fetchErr)
 					}
-				}(shouldBump, bumpCount, currentTx)
+				}(shouldBump || shouldRefresh, sigCount, TxAttempt{ComputeUnitPrice: uint64(getFee(bumpCount)), Blockhash: currentTx.Message.RecentBlockhash, LastValidBlockHeight: lastValid, DurableNonce: nonce, Programs: instructionPrograms(currentTx.Message)}, currentTx)
 			}
 
 			// exponential increase in wait time, capped at 250ms
@@ -345,8 +717,163 @@ func (txm *Txm) sendWithRetry(ctx context.Context, baseTx solanaGo.Transaction,
 		}
 	}(ctx, baseTx, initTx)
 
-	// return signed tx, id, signature for use in simulation
-	return initTx, id, sig, nil
+	// return signed tx, signature for use in simulation
+	return initTx, sig, nil
+}
+
+// rebroadcast resends the signed tx of the attempt with the signature (i.e. rolled back or restored) until it is confirmed,
+// finished or the TxRetryTimeout passes. the attempt is resent unchanged, rebuilding it could include the tx twice
+func (txm *Txm) rebroadcast(id string, sig solanaGo.Signature) {
+	rec, err := txm.txs.Get(id)
+	if err != nil {
+		txm.lggr.Warnw("failed to get tx to rebroadcast", "id", id, "error", err)
+		return
+	}
+	i := slices.IndexFunc(rec.Attempts, func(attempt TxAttempt) bool { return attempt.Signature == sig })
+	if i < 0 || len(rec.Attempts[i].Tx) == 0 {
+		txm.lggr.Warnw("no signed tx stored to rebroadcast", "id", id, "signature", sig)
+		return
+	}
+	tx, err := solanaGo.TransactionFromDecoder(bin.NewBinDecoder(rec.Attempts[i].Tx))
+	if err != nil {
+		txm.lggr.Errorw("failed to decode signed tx to rebroadcast", "id", id, "signature", sig, "error", err)
+		return
+	}
+
+	ctx, cancel := txm.chStop.CtxWithTimeout(txm.cfg.TxRetryTimeout())
+	if err = txm.txs.Rebroadcast(id, cancel); err != nil {
+		cancel()
+		txm.lggr.Debugw("tx not rebroadcast", "id", id, "signature", sig, "error", err)
+		return
+	}
+	txm.lggr.Debugw("rebroadcasting tx", "id", id, "signature", sig)
+
+	txm.done.Add(1)
+	go func() {
+		defer txm.done.Done()
+		defer cancel()
+		deltaT := 1 // ms
+		tick := time.After(0)
+		for {
+			select {
+			case <-ctx.Done():
+				txm.lggr.Debugw("stopped tx rebroadcast", "id", id, "signature", sig, "err", context.Cause(ctx))
+				return
+			case <-tick:
+				client, clientErr := txm.client.Get()
+				if clientErr != nil {
+					txm.lggr.Warnw("failed to get client to rebroadcast tx", "id", id, "error", clientErr)
+				} else if _, sendErr := txm.submit.Submit(ctx, client, tx); sendErr != nil && ctx.Err() == nil {
+					txm.lggr.Debugw("failed to rebroadcast tx", "id", id, "signature", sig, "error", sendErr)
+				}
+			}
+
+			// exponential increase in wait time, capped at 250ms
+			deltaT = min(deltaT*2, MaxRetryTimeMs)
+			tick = time.After(time.Duration(deltaT) * time.Millisecond)
+		}
+	}()
+}
+
+// getLastValidBlockHeight returns the last block height the tx blockhash can be included in (0 if unknown)
+// the latest blockhash is used if refresh is enabled, otherwise the tx blockhash is at most as recent as the latest blockhash
+// and the latest last valid block height is used as an upper bound (expiry may be detected late but never early)
+func (txm *Txm) getLastValidBlockHeight(ctx context.Context, client client.ReaderWriter, tx *solanaGo.Transaction, refresh bool) uint64 {
+	latest, err := client.LatestBlockhash(ctx)
+	if err != nil || latest == nil || latest.Value == nil {
+		txm.lggr.Warnw("failed to get latest blockhash, blockhash expiry is not tracked for tx", "error", err)
+		return 0
+	}
+	if refresh {
+		tx.Message.RecentBlockhash = latest.Value.Blockhash
+	}
+	return latest.Value.LastValidBlockHeight
+}
+
+// useDurableNonce reserves a durable nonce account of the fee payer for the tx and sets the current nonce on the tx
+// the nonce account is returned to the pool once the tx is finished
+// a replacement takes over the nonce account of the replaced tx and uses the same nonce so only one of them can be included
+func (txm *Txm) useDurableNonce(ctx context.Context, client client.ReaderWriter, id string, tx *solanaGo.Transaction, replaced *TxAttempt) (DurableNonce, error) {
+	if replaced != nil && replaced.DurableNonce != nil {
+		nonce := *replaced.DurableNonce
+		txm.nonces.reserve(nonce.Account, id)
+		if err := setDurableNonce(tx, nonce); err != nil {
+			txm.nonces.release(nonce.Account, id)
+			return DurableNonce{}, err
+		}
+		return nonce, nil
+	}
+
+	authority := tx.Message.AccountKeys[0]
+	account, err := txm.nonces.acquire(authority, id, txm.txFinished)
+	if err != nil {
+		return DurableNonce{}, err
+	}
+	nonce, err := GetDurableNonce(ctx, client, account, authority)
+	if err == nil {
+		err = setDurableNonce(tx, nonce)
+	}
+	if err != nil {
+		txm.nonces.release(account, id)
+		return DurableNonce{}, err
+	}
+	return nonce, nil
+}
+
+// txFinished returns if the tx is finished, used to release nonce accounts and inflight capacity held by the tx.
+// confirmed txs are finished unless the completion commitment is finalized.
+func (txm *Txm) txFinished(id string) bool {
+	rec, err := txm.txs.Get(id)
+	if errors.Is(err, ErrTxNotFound) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	commitment := rec.CompletionCommitment
+	if commitment == "" {
+		commitment = txm.cfg.TxCompletionCommitment()
+	}
+	return rec.State.IsTerminal() || (rec.State == TxStateConfirmed && commitment == rpc.CommitmentConfirmed)
+}
+
+// nonceAdvanced returns if the durable nonce used by the tx was advanced without the tx being included
+func (txm *Txm) nonceAdvanced(ctx context.Context, client client.ReaderWriter, id string, nonce DurableNonce) bool {
+	current, err := GetDurableNonce(ctx, client, nonce.Account, nonce.Authority)
+	if err != nil {
+		txm.lggr.Warnw("failed to get durable nonce", "error", err, "id", id, "account", nonce.Account)
+		return false
+	}
+	if current.Value == nonce.Value {
+		return false
+	}
+
+	// including the tx also advances the nonce, recheck the tx signatures now that the nonce has changed
+	rec, err := txm.txs.Get(id)
+	if err != nil || rec.State == TxStateProcessed {
+		return false
+	}
+	statuses, err := client.SignatureStatuses(ctx, rec.Signatures())
+	if err != nil {
+		txm.lggr.Warnw("failed to get signature statuses for durable nonce tx", "error", err, "id", id)
+		return false
+	}
+	for _, s := range statuses {
+		if s != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldRefreshBlockhash returns if the blockhash has expired and the tx has not been included
+func (txm *Txm) shouldRefreshBlockhash(id string, lastValid uint64) bool {
+	if lastValid == 0 || txm.blockHeight.Load() <= lastValid {
+		return false
+	}
+	// processed txs were included before the blockhash expired and may still be confirmed
+	rec, err := txm.txs.Get(id)
+	return err == nil && rec.State != TxStateProcessed
 }
 
 // goroutine that polls to confirm implementation
@@ -362,6 +889,11 @@ func (txm *Txm) confirm() {
 		case <-ctx.Done():
 			return
 		case <-tick:
+			// remove finished txs that are past retention
+			if pruned := txm.txs.PruneTerminal(txm.cfg.TxRetentionTimeout()); pruned > 0 {
+				txm.lggr.Debugw("pruned finished txs", "count", pruned)
+			}
+
 			// get list of tx signatures to confirm
 			sigs := txm.txs.ListAll()
 
@@ -377,6 +909,14 @@ func (txm *Txm) confirm() {
 				break // exit switch
 			}
 
+			// get block height for detecting expired blockhashes (previous height is used if it fails)
+			if height, heightErr := client.BlockHeight(ctx); heightErr != nil {
+				txm.lggr.Warnw("failed to get block height in soltxm.confirm", "error", heightErr)
+			} else {
+				txm.blockHeight.Store(height)
+			}
+			blockHeight := txm.blockHeight.Load()
+
 			// batch sigs no more than MaxSigsToConfirm each
 			sigsBatch, err := utils.BatchSplit(sigs, MaxSigsToConfirm)
 			if err != nil { // this should never happen
@@ -384,6 +924,9 @@ func (txm *Txm) confirm() {
 				break // exit switch
 			}
 
+			// durable nonces are checked once per tx
+			var nonceChecked sync.Map
+
 			// process signatures
 			processSigs := func(s []solanaGo.Signature, res []*rpc.SignatureStatusesResult) {
 				// sort signatures and results process successful first
@@ -401,17 +944,41 @@ func (txm *Txm) confirm() {
 							"signature", s[i],
 						)
 
+						// confirmed tx no longer found, the block was rolled back
+						// the included tx is rebroadcast and dropped if it is not included before the blockhash expires
+						if id := txm.txs.OnRollback(s[i]); id != "" {
+							txm.lggr.Warnw("confirmed transaction no longer found, block rolled back", "id", id, "signature", s[i])
+							txm.rebroadcast(id, s[i])
+							continue
+						}
+
 						// check confirm timeout exceeded
 						if txm.txs.Expired(s[i], txm.cfg.TxConfirmTimeout()) {
-							id := txm.txs.OnError(s[i], TxFailDrop)
+							id := txm.txs.OnError(s[i], TxFailDrop, errors.New("tx not found within confirm timeout"))
 							txm.lggr.Infow("failed to find transaction within confirm timeout", "id", id, "signature", s[i], "timeoutSeconds", txm.cfg.TxConfirmTimeout())
+							continue
+						}
+
+						// check if blockhash expired (tx can no longer be included)
+						if txm.txs.BlockhashExpired(s[i], blockHeight) {
+							id := txm.txs.OnError(s[i], TxFailDrop, errBlockhashExpired)
+							txm.lggr.Infow("failed to find transaction before blockhash expired", "id", id, "signature", s[i], "blockHeight", blockHeight)
+							continue
+						}
+
+						// check if durable nonce advanced (tx can no longer be included)
+						if id, nonce, ok := txm.txs.DurableNonce(s[i]); ok {
+							if _, checked := nonceChecked.LoadOrStore(id, struct{}{}); !checked && txm.nonceAdvanced(ctx, client, id, nonce) {
+								txm.txs.OnError(s[i], TxFailDrop, errNonceAdvanced)
+								txm.lggr.Infow("failed to find transaction before durable nonce advanced", "id", id, "signature", s[i], "nonceAccount", nonce.Account)
+							}
 						}
 						continue
 					}
 
 					// if signature has an error, end polling
 					if res[i].Err != nil {
-						id := txm.txs.OnError(s[i], TxFailRevert)
+						id := txm.txs.OnError(s[i], TxFailRevert, &TxError{Reason: "tx reverted", Err: res[i].Err})
 						txm.lggr.Debugw("tx state: failed",
 							"id", id,
 							"signature", s[i],
@@ -423,22 +990,35 @@ func (txm *Txm) confirm() {
 
 					// if signature is processed, keep polling
 					if res[i].ConfirmationStatus == rpc.ConfirmationStatusProcessed {
+						if id := txm.txs.OnProcessed(s[i]); id == "" {
+							continue // confirmed txs are polled until finalized, not expired
+						}
 						txm.lggr.Debugw("tx state: processed",
 							"signature", s[i],
 						)
 
 						// check confirm timeout exceeded
 						if txm.txs.Expired(s[i], txm.cfg.TxConfirmTimeout()) {
-							id := txm.txs.OnError(s[i], TxFailDrop)
+							id := txm.txs.OnError(s[i], TxFailDrop, errors.New("tx not confirmed within confirm timeout"))
 							txm.lggr.Debugw("tx failed to move beyond 'processed' within confirm timeout", "id", id, "signature", s[i], "timeoutSeconds", txm.cfg.TxConfirmTimeout())
 						}
 						continue
 					}
 
-					// if signature is confirmed/finalized, end polling
+					// if signature is confirmed, stop retrying and keep polling until finalized
 					if res[i].ConfirmationStatus == rpc.ConfirmationStatusConfirmed || res[i].ConfirmationStatus == rpc.ConfirmationStatusFinalized {
-						id := txm.txs.OnSuccess(s[i])
-						txm.lggr.Debugw(fmt.Sprintf("tx state: %s", res[i].ConfirmationStatus),
+						if id := txm.txs.OnSuccess(s[i]); id != "" {
+							txm.lggr.Debugw("tx state: confirmed",
+								"id", id,
+								"signature", s[i],
+							)
+						}
+					}
+
+					// if signature is finalized, end polling
+					if res[i].ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+						id := txm.txs.OnFinalized(s[i])
+						txm.lggr.Debugw("tx state: finalized",
 							"id", id,
 							"signature", s[i],
 						)
@@ -501,38 +1081,103 @@ func (txm *Txm) simulate() {
 }
 
 // Enqueue enqueue a msg destined for the solana chain.
-func (txm *Txm) Enqueue(ctx context.Context, accountID string, tx *solanaGo.Transaction, txCfgs ...SetTxConfig) error {
+// txID is an idempotency key used to look up the tx status, a random id is generated if nil.
+// Enqueueing a txID that is already tracked returns an error wrapping ErrTxAlreadyExists.
+func (txm *Txm) Enqueue(ctx context.Context, accountID string, tx *solanaGo.Transaction, txID *string, txCfgs ...SetTxConfig) error {
 	if err := txm.Ready(); err != nil {
 		return fmt.Errorf("error in soltxm.Enqueue: %w", err)
 	}
+	if err := txm.validateTx(ctx, tx); err != nil {
+		return fmt.Errorf("error in soltxm.Enqueue: %w", err)
+	}
+	return txm.enqueue(ctx, accountID, tx, txID, nil, txCfgs...)
+}
 
+// validateTx checks that the tx can be signed by the txm
+func (txm *Txm) validateTx(ctx context.Context, tx *solanaGo.Transaction) error {
 	// validate nil pointer
 	if tx == nil {
-		return errors.New("error in soltxm.Enqueue: tx is nil pointer")
+		return errors.New("tx is nil pointer")
 	}
 	// validate account keys slice
 	if len(tx.Message.AccountKeys) == 0 {
-		return errors.New("error in soltxm.Enqueue: not enough account keys in tx")
+		return errors.New("not enough account keys in tx")
 	}
 
 	// validate expected key exists by trying to sign with it
 	// fee payer account is index 0 account
 	// https://github.com/gagliardetto/solana-go/blob/main/transaction.go#L252
-	_, err := txm.ks.Sign(ctx, tx.Message.AccountKeys[0].String(), nil)
+	if _, err := txm.ks.Sign(ctx, tx.Message.AccountKeys[0].String(), nil); err != nil {
+		return fmt.Errorf("failed to get fee payer key: %w", err)
+	}
+	return nil
+}
+
+// enqueue tracks the validated tx and adds it to the queue of its fee payer
+// replaced is the latest attempt of the tx replaced by this tx (nil if not a replacement)
+func (txm *Txm) enqueue(ctx context.Context, accountID string, tx *solanaGo.Transaction, txID *string, replaced *TxAttempt, txCfgs ...SetTxConfig) error {
+	// the fee payer and signatures are set on a copy, the tx of the caller is unchanged if it can not be queued
+	tx = copyTx(tx)
+	cfg, err := txm.prepareTx(ctx, tx, replaced, txCfgs...)
 	if err != nil {
-		return fmt.Errorf("error in soltxm.Enqueue.GetKey: %w", err)
+		return err
 	}
 
+	id := uuid.NewString()
+	if txID != nil {
+		id = *txID
+	}
+	if err = txm.trackTx(ctx, tx, id, cfg); err != nil {
+		return err
+	}
+	return txm.queueTx(accountID, pendingTx{tx: tx, cfg: cfg, id: id})
+}
+
+// prepareTx returns the config of the tx and checks that it can be sent: its signers, estimations and fee payer balance
+func (txm *Txm) prepareTx(ctx context.Context, tx *solanaGo.Transaction, replaced *TxAttempt, txCfgs ...SetTxConfig) (TxConfig, error) {
 	// apply changes to default config
 	cfg := txm.defaultTxConfig()
+	txm.programs.apply(tx, &cfg)
+	if estimator, ok := txm.fee.(fees.TxEstimator); ok {
+		// estimate the price of the tx, falls back to the base price of the estimator
+		price, err := estimator.TxComputeUnitPrice(ctx, tx)
+		if err != nil {
+			txm.lggr.Warnw("failed to estimate compute unit price of tx, using base price", "error", err, "price", cfg.BaseComputeUnitPrice)
+		} else {
+			cfg.BaseComputeUnitPrice = price
+		}
+	}
 	for _, v := range txCfgs {
 		v(&cfg)
 	}
+	if replaced != nil {
+		// the replacement must be included within the window of the replaced tx
+		cfg.replaced = replaced
+		cfg.UseDurableNonce = replaced.DurableNonce != nil
+		cfg.RefreshBlockhash = false
+	}
+
+	presigned, err := txm.validateSigners(ctx, tx, cfg.Signers)
+	if err != nil {
+		return cfg, fmt.Errorf("error in soltxm.Enqueue: %w", err)
+	}
+	if presigned != nil {
+		// the supplied signatures are only valid for the unchanged message
+		if cfg.UseDurableNonce || replaced != nil {
+			return cfg, errors.New("error in soltxm.Enqueue: txs with supplied signatures can not use a durable nonce or replace a tx")
+		}
+		cfg.presigned = presigned
+		sealPresigned(tx, &cfg)
+	}
+
+	if cfg.UseDurableNonce && replaced == nil && !txm.nonces.has(tx.Message.AccountKeys[0]) {
+		return cfg, fmt.Errorf("error in soltxm.Enqueue: no durable nonce accounts configured for %s", tx.Message.AccountKeys[0])
+	}
 
 	if cfg.EstimateComputeUnitLimit {
 		computeUnitLimit, err := txm.EstimateComputeUnitLimit(ctx, tx)
 		if err != nil {
-			return fmt.Errorf("transaction failed simulation: %w", err)
+			return cfg, fmt.Errorf("transaction failed simulation: %w", err)
 		}
 		// If estimation returns 0 compute unit limit without error, fallback to original config
 		if computeUnitLimit != 0 {
@@ -540,20 +1185,299 @@ func (txm *Txm) Enqueue(ctx context.Context, accountID string, tx *solanaGo.Tran
 		}
 	}
 
-	msg := pendingTx{
-		tx:  tx,
-		cfg: cfg,
+	if cfg.EstimateLoadedAccountsDataSize {
+		dataSizeLimit, err := txm.EstimateLoadedAccountsDataSize(ctx, tx)
+		if err != nil {
+			return cfg, fmt.Errorf("transaction failed simulation: %w", err)
+		}
+		// If estimation returns 0 without error, fallback to original config
+		if dataSizeLimit != 0 {
+			cfg.LoadedAccountsDataSizeLimit = dataSizeLimit
+		}
 	}
 
-	select {
-	case txm.chSend <- msg:
-	default:
-		txm.lggr.Errorw("failed to enqeue tx", "queueFull", len(txm.chSend) == MaxQueueLen, "tx", msg)
-		return fmt.Errorf("failed to enqueue transaction for %s", accountID)
+	// the tip is added when the tx is sent, it is paid by the fee payer selected now
+	if cfg.presigned == nil {
+		cfg.tip = txm.submit.Tip()
+	}
+
+	// txs of fee payers with a pool are always balance checked to pick a fee payer that can pay
+	cfg.balanceCheck = txm.cfg.TxBalanceCheck() || txm.balances.hasPool(tx.Message.AccountKeys[0])
+	if cfg.balanceCheck {
+		if err := txm.selectFeePayer(ctx, tx, cfg); err != nil {
+			promSolTxmInsufficientBalance.WithLabelValues(txm.chainID, tx.Message.AccountKeys[0].String()).Add(1)
+			return cfg, fmt.Errorf("error in soltxm.Enqueue: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// trackTx tracks the prepared tx as pending before it is queued to reject duplicate ids, and reserves its fee
+func (txm *Txm) trackTx(ctx context.Context, tx *solanaGo.Transaction, id string, cfg TxConfig) error {
+	msgBytes, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("error in soltxm.Enqueue.MarshalBinary: %w", err)
+	}
+	if err := txm.txs.Pending(id, msgBytes, cfg.ComputeUnitLimit, cfg.CompletionCommitment); err != nil {
+		return fmt.Errorf("error in soltxm.Enqueue: %w", err)
+	}
+
+	// reserved once tracked, the reservation is released when the tx is finished
+	if cfg.balanceCheck {
+		if err := txm.reserveFee(ctx, tx, id, cfg); err != nil {
+			promSolTxmInsufficientBalance.WithLabelValues(txm.chainID, tx.Message.AccountKeys[0].String()).Add(1)
+			txm.txs.Delete(id)
+			return fmt.Errorf("error in soltxm.Enqueue: %w", err)
+		}
 	}
 	return nil
 }
 
+// queueTx adds the tracked tx to the queue of its fee payer, the tx is untracked if it can not be queued
+func (txm *Txm) queueTx(accountID string, msg pendingTx) error {
+	// Enqueued is published before the tx can be broadcasted
+	txm.events.register(msg.id, msg.cfg.OnEvent)
+	if err := txm.queues.push(msg, func() {
+		txm.events.publish(TxEvent{Type: TxEventEnqueued, ID: msg.id, State: TxStatePending})
+	}); err != nil {
+		txm.lggr.Errorw("failed to enqeue tx", "error", err, "tx", msg)
+		txm.untrackTx(msg.tx, msg.id)
+		return fmt.Errorf("failed to enqueue transaction for %s: %w", accountID, err)
+	}
+	return nil
+}
+
+// untrackTx deletes a tx that was never broadcasted, which allows the caller to retry with the same id
+func (txm *Txm) untrackTx(tx *solanaGo.Transaction, id string) {
+	txm.events.unregister(id)
+	txm.balances.release(tx.Message.AccountKeys[0], id)
+	txm.txs.Delete(id)
+}
+
+// selectFeePayer sets the fee payer of the tx to the first fee payer in rotation order that can pay the worst case fee
+// of the tx, returns an error wrapping ErrInsufficientBalance if none can. replacements and presigned txs keep their fee payer, the
+// durable nonce or blockhash of the replaced tx is tied to it
+func (txm *Txm) selectFeePayer(ctx context.Context, tx *solanaGo.Transaction, cfg TxConfig) error {
+	client, err := txm.client.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	feePayer := tx.Message.AccountKeys[0]
+	candidates := []solanaGo.PublicKey{feePayer}
+	if cfg.replaced == nil && cfg.presigned == nil {
+		candidates = txm.balances.candidates(feePayer)
+	}
+
+	fee := worstCaseFee(tx, cfg)
+	var errs error
+	for _, candidate := range candidates {
+		if !candidate.Equals(feePayer) {
+			if err := txm.validatePoolFeePayer(ctx, tx, candidate, cfg); err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		available, err := txm.balances.available(ctx, client, candidate, txm.txFinished)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if available < fee {
+			errs = errors.Join(errs, fmt.Errorf("%w: %s has %d unreserved lamports, worst case fee is %d", ErrInsufficientBalance, candidate, available, fee))
+			continue
+		}
+		if !candidate.Equals(feePayer) {
+			txm.lggr.Debugw("rotating tx fee payer", "feePayer", feePayer, "selected", candidate)
+			return setFeePayer(tx, candidate)
+		}
+		return nil
+	}
+	return errs
+}
+
+// validatePoolFeePayer returns an error if the fee payer from a pool cannot pay for the tx
+func (txm *Txm) validatePoolFeePayer(ctx context.Context, tx *solanaGo.Transaction, feePayer solanaGo.PublicKey, cfg TxConfig) error {
+	if err := checkFeePayer(tx, feePayer); err != nil {
+		return err
+	}
+	if cfg.UseDurableNonce && !txm.nonces.has(feePayer) {
+		return fmt.Errorf("no durable nonce accounts configured for %s", feePayer)
+	}
+	if _, err := txm.ks.Sign(ctx, feePayer.String(), nil); err != nil {
+		return fmt.Errorf("failed to get fee payer key %s: %w", feePayer, err)
+	}
+	return nil
+}
+
+// reserveFee reserves the worst case fee of the tx id with its fee payer until the tx is finished
+func (txm *Txm) reserveFee(ctx context.Context, tx *solanaGo.Transaction, id string, cfg TxConfig) error {
+	client, err := txm.client.Get()
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	feePayer := tx.Message.AccountKeys[0]
+	fee := worstCaseFee(tx, cfg)
+	available, ok, err := txm.balances.reserve(ctx, client, feePayer, id, fee, txm.txFinished)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s has %d unreserved lamports, worst case fee is %d", ErrInsufficientBalance, feePayer, available, fee)
+	}
+	return nil
+}
+
+// SubscribeEvents returns a channel receiving the lifecycle events of all txs and a func to unsubscribe.
+// Events are dropped for subscribers that do not keep up, the channel is closed when the txm is closed.
+func (txm *Txm) SubscribeEvents() (<-chan TxEvent, func()) {
+	return txm.events.subscribe()
+}
+
+// Cancel stops broadcasting the tx for the txID. A queued tx is cancelled right away, attempts that were already
+// broadcasted can still be included until their blockhash expires or their durable nonce is advanced.
+// The tx is marked as Cancelled if none of its attempts is included.
+func (txm *Txm) Cancel(ctx context.Context, txID string) error {
+	if err := txm.Ready(); err != nil {
+		return fmt.Errorf("error in soltxm.Cancel: %w", err)
+	}
+	if err := txm.cancel(txID, ErrTxCancelled); err != nil {
+		return fmt.Errorf("error in soltxm.Cancel: %w", err)
+	}
+	return nil
+}
+
+// Replace cancels the tx for the txID and enqueues tx in its place with the newTxID (random id if nil).
+// A broadcasted tx is replaced within its window: the replacement uses the same durable nonce (only one of the txs
+// can be included) or the same recent blockhash (the replacement expires with the replaced tx).
+// The replaced tx is marked as Cancelled unless one of its attempts is included first. It is only cancelled once the
+// replacement is queued, a replacement that can not be built or queued leaves the tx unchanged.
+func (txm *Txm) Replace(ctx context.Context, txID string, tx *solanaGo.Transaction, newTxID *string, txCfgs ...SetTxConfig) error {
+	if err := txm.Ready(); err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+	if err := txm.validateTx(ctx, tx); err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+
+	// held from the state check until the tx is cancelled
+	if !txm.lockReplace(txID) {
+		return fmt.Errorf("error in soltxm.Replace: tx %s is already being replaced", txID)
+	}
+	defer txm.unlockReplace(txID)
+
+	rec, err := txm.txs.Get(txID)
+	if err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+	if rec.State.IsTerminal() || rec.State == TxStateConfirmed {
+		return fmt.Errorf("error in soltxm.Replace: tx %s is already %s", txID, rec.State)
+	}
+
+	id := uuid.NewString()
+	if newTxID != nil {
+		id = *newTxID
+	}
+	if _, err = txm.txs.Get(id); err == nil {
+		return fmt.Errorf("error in soltxm.Replace: %w: %s", ErrTxAlreadyExists, id)
+	}
+
+	// queued txs have no window to reuse, neither do stored attempts without a blockhash
+	var replaced *TxAttempt
+	if len(rec.Attempts) > 0 {
+		attempt := rec.Attempts[len(rec.Attempts)-1]
+		if attempt.DurableNonce != nil && !attempt.DurableNonce.Authority.Equals(tx.Message.AccountKeys[0]) {
+			return fmt.Errorf("error in soltxm.Replace: fee payer %s is not the authority of the durable nonce used by tx %s", tx.Message.AccountKeys[0], txID)
+		}
+		if attempt.DurableNonce != nil || !attempt.Blockhash.IsZero() {
+			replaced = &attempt
+		}
+	}
+
+	// the replacement is built and queued before the tx is cancelled, a tx is never cancelled without its replacement
+	tx = copyTx(tx)
+	cfg, err := txm.prepareTx(ctx, tx, replaced, txCfgs...)
+	if err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+	if err = txm.trackTx(ctx, tx, id, cfg); err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+	if err = txm.queueTx(tx.Message.AccountKeys[0].String(), pendingTx{tx: tx, cfg: cfg, id: id}); err != nil {
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+
+	if err = txm.cancel(txID, fmt.Errorf("%w: replaced by tx %s", ErrTxCancelled, id)); err != nil {
+		// the tx was included or finished in the meantime, the replacement is withdrawn
+		if txm.queues.remove(id) {
+			txm.untrackTx(tx, id)
+		} else if cancelErr := txm.cancel(id, fmt.Errorf("%w: tx %s could not be replaced", ErrTxCancelled, txID)); cancelErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to cancel replacement tx %s: %w", id, cancelErr))
+		}
+		return fmt.Errorf("error in soltxm.Replace: %w", err)
+	}
+	return nil
+}
+
+// lockReplace marks the tx as being replaced, returns false if it already is
+func (txm *Txm) lockReplace(id string) bool {
+	txm.replacingLock.Lock()
+	defer txm.replacingLock.Unlock()
+	if _, ok := txm.replacing[id]; ok {
+		return false
+	}
+	txm.replacing[id] = struct{}{}
+	return true
+}
+
+func (txm *Txm) unlockReplace(id string) {
+	txm.replacingLock.Lock()
+	defer txm.replacingLock.Unlock()
+	delete(txm.replacing, id)
+}
+
+// cancel stops broadcasting the tx, queued txs were never broadcasted and are cancelled right away
+func (txm *Txm) cancel(id string, reason error) error {
+	if txm.queues.remove(id) {
+		txm.txs.OnPendingError(id, TxFailCancel, reason)
+		return nil
+	}
+	if err := txm.txs.Cancel(id, reason); err != nil {
+		return err
+	}
+	txm.queues.release(id)
+	return nil
+}
+
+// GetTransactionStatus returns the status of a tx enqueued with the txID.
+// Finished txs can be queried until the TxRetentionTimeout has passed.
+func (txm *Txm) GetTransactionStatus(ctx context.Context, txID string) (TxStatus, error) {
+	rec, err := txm.txs.Get(txID)
+	if err != nil {
+		return TxStatus{}, fmt.Errorf("error in soltxm.GetTransactionStatus: %w", err)
+	}
+	return TxStatus{
+		State:     rec.State,
+		Signature: rec.Signature,
+		Fee:       rec.Fee(),
+		Error:     rec.Error,
+		TxError:   rec.TxError,
+		Batch:     rec.Batch,
+	}, nil
+}
+
+// FeeEstimator returns the compute unit price estimator used for new txs. It is nil until the txm is started.
+func (txm *Txm) FeeEstimator() fees.Estimator {
+	txm.feeLock.RLock()
+	defer txm.feeLock.RUnlock()
+	return txm.fee
+}
+
+// RegisterProgramErrors adds the custom errors of the Anchor IDL of a program, used to decode the custom program errors of reverted txs.
+// errors of the AnchorIDL in ProgramConfigs are registered on start
+func (txm *Txm) RegisterProgramErrors(program solanaGo.PublicKey, errs []codec.IdlErrorCode) {
+	txm.errs.register(program, errs)
+}
+
 // EstimateComputeUnitLimit estimates the compute unit limit needed for a transaction.
 // It simulates the provided transaction to determine the used compute and applies a buffer to it.
 func (txm *Txm) EstimateComputeUnitLimit(ctx context.Context, tx *solanaGo.Transaction) (uint32, error) {
@@ -568,8 +1492,8 @@ func (txm *Txm) EstimateComputeUnitLimit(ctx context.Context, tx *solanaGo.Trans
 		if len(tx.Signatures) > 0 {
 			sig = tx.Signatures[0]
 		}
-		txm.processSimulationError(uuid.Nil, sig, res)
-		return 0, fmt.Errorf("simulated tx returned error: %v", res.Err)
+		txm.processSimulationError("", sig, res)
+		return 0, txm.simulationError(tx, res)
 	}
 
 	if res.UnitsConsumed == nil || *res.UnitsConsumed == 0 {
@@ -592,6 +1516,93 @@ func (txm *Txm) EstimateComputeUnitLimit(ctx context.Context, tx *solanaGo.Trans
 	return uint32(unitsConsumed), nil
 }
 
+// EstimateLoadedAccountsDataSize estimates the loaded accounts data size limit needed for a transaction.
+// It simulates the provided transaction returning the accounts loaded by the tx, and applies a buffer to the size of their data.
+func (txm *Txm) EstimateLoadedAccountsDataSize(ctx context.Context, tx *solanaGo.Transaction) (uint32, error) {
+	client, err := txm.client.Get()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client in EstimateLoadedAccountsDataSize: %w", err)
+	}
+
+	res, err := client.SimulateTx(ctx, tx, &rpc.SimulateTransactionOpts{
+		ReplaceRecentBlockhash: true, // the tx is not signed yet
+		Commitment:             txm.cfg.Commitment(),
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solanaGo.EncodingBase64,
+			Addresses: loadedAccounts(tx),
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate tx in EstimateLoadedAccountsDataSize: %w", err)
+	}
+
+	// Return error if response err is non-nil to avoid broadcasting a tx destined to fail
+	if res.Err != nil {
+		sig := solanaGo.Signature{}
+		if len(tx.Signatures) > 0 {
+			sig = tx.Signatures[0]
+		}
+		txm.processSimulationError("", sig, res)
+		return 0, txm.simulationError(tx, res)
+	}
+
+	var size uint64
+	for _, account := range res.Accounts {
+		if account == nil { // account does not exist
+			continue
+		}
+		size += loadedAccountBaseSize
+		if account.Data != nil {
+			size += uint64(len(account.Data.GetBinary()))
+		}
+	}
+	if size == 0 {
+		txm.lggr.Debug("failed to get loaded accounts for tx")
+		// Do not return error to allow falling back to default loaded accounts data size limit
+		return 0, nil
+	}
+
+	// Add buffer to the loaded data size estimate
+	size = bigmath.AddPercentage(new(big.Int).SetUint64(size), EstimateLoadedAccountsDataSizeBuffer).Uint64()
+	return uint32(min(size, uint64(fees.MaxLoadedAccountsDataSizeLimit))), nil //nolint:gosec // capped at MaxLoadedAccountsDataSizeLimit
+}
+
+// loadedAccounts returns the accounts loaded by the tx: the accounts of the tx, the address lookup tables,
+// and the program data accounts of the upgradeable programs invoked by the tx (non-existent accounts are not loaded)
+func loadedAccounts(tx *solanaGo.Transaction) solanaGo.PublicKeySlice {
+	keys, err := tx.Message.GetAllKeys()
+	if err != nil { // lookups not resolved, only the static keys are known
+		keys = tx.Message.AccountKeys
+	}
+	accounts := append(solanaGo.PublicKeySlice{}, keys...)
+	for _, lookup := range tx.Message.AddressTableLookups {
+		accounts.UniqueAppend(lookup.AccountKey)
+	}
+	for _, ix := range tx.Message.Instructions {
+		program, err := tx.Message.Program(ix.ProgramIDIndex)
+		if err != nil {
+			continue
+		}
+		programData, _, err := solanaGo.FindProgramAddress([][]byte{program[:]}, solanaGo.BPFLoaderUpgradeableProgramID)
+		if err != nil {
+			continue
+		}
+		accounts.UniqueAppend(programData)
+	}
+	return accounts
+}
+
+// simulationError returns the error of a reverted simulation of the tx, followed by its decoded custom program error
+func (txm *Txm) simulationError(tx *solanaGo.Transaction, res *rpc.SimulateTransactionResult) error {
+	err := &TxError{Reason: "simulated tx returned error", Err: res.Err, Logs: res.Logs}
+	decoded := decodeTxError(err)
+	txm.errs.decode(instructionPrograms(tx.Message), decoded)
+	if programErr := decoded.ProgramError(); programErr != "" {
+		return fmt.Errorf("%w (%s)", err, programErr)
+	}
+	return err
+}
+
 // simulateTx simulates transactions using the SimulateTx client method
 func (txm *Txm) simulateTx(ctx context.Context, tx *solanaGo.Transaction) (res *rpc.SimulateTransactionResult, err error) {
 	// get client
@@ -611,26 +1622,29 @@ func (txm *Txm) simulateTx(ctx context.Context, tx *solanaGo.Transaction) (res *
 }
 
 // processSimulationError parses and handles relevant errors found in simulation results
-func (txm *Txm) processSimulationError(id uuid.UUID, sig solanaGo.Signature, res *rpc.SimulateTransactionResult) {
+func (txm *Txm) processSimulationError(id string, sig solanaGo.Signature, res *rpc.SimulateTransactionResult) {
 	if res.Err != nil {
 		// handle various errors
 		// https://github.com/solana-labs/solana/blob/master/sdk/src/transaction/error.rs
 		errStr := fmt.Sprintf("%v", res.Err) // convert to string to handle various interfaces
-		switch {
+		switch code := client.ClassifyTransactionError(res.Err); {
 		// blockhash not found when simulating, occurs when network bank has not seen the given blockhash or tx is too old
 		// let confirmation process clean up
-		case strings.Contains(errStr, "BlockhashNotFound"):
+		case code == mn.TerminallyStuck:
 			txm.lggr.Debugw("simulate: BlockhashNotFound", "id", id, "signature", sig, "result", res)
 		// transaction will encounter execution error/revert, mark as reverted to remove from confirmation + retry
-		case strings.Contains(errStr, "InstructionError"):
-			txm.txs.OnError(sig, TxFailSimRevert) // cancel retry
+		case client.TransactionErrorName(res.Err) == "InstructionError":
+			txm.txs.OnError(sig, TxFailSimRevert, &TxError{Reason: "simulation reverted", Err: res.Err, Logs: res.Logs}) // cancel retry
 			txm.lggr.Debugw("simulate: InstructionError", "id", id, "signature", sig, "result", res)
 		// transaction is already processed in the chain, letting txm confirmation handle
-		case strings.Contains(errStr, "AlreadyProcessed"):
+		case code == mn.TransactionAlreadyKnown:
 			txm.lggr.Debugw("simulate: AlreadyProcessed", "id", id, "signature", sig, "result", res)
+		// temporary conditions (i.e. AccountInUse), the tx is still retried and confirmed
+		case code == mn.Retryable || code == mn.Underpriced:
+			txm.lggr.Debugw("simulate: temporary error", "id", id, "signature", sig, "result", res)
 		// unrecognized errors (indicates more concerning failures)
 		default:
-			txm.txs.OnError(sig, TxFailSimOther) // cancel retry
+			txm.txs.OnError(sig, TxFailSimOther, fmt.Errorf("simulation failed: %s", errStr)) // cancel retry
 			txm.lggr.Errorw("simulate: unrecognized error", "id", id, "signature", sig, "result", res)
 		}
 	}
@@ -645,7 +1659,11 @@ func (txm *Txm) Close() error {
 	return txm.StopOnce("Txm", func() error {
 		close(txm.chStop)
 		txm.done.Wait()
-		return txm.fee.Close()
+		var storeErr error
+		if txm.store != nil {
+			storeErr = txm.store.Close()
+		}
+		return errors.Join(txm.fee.Close(), txm.submit.Close(), storeErr)
 	})
 }
 func (txm *Txm) Name() string { return txm.lggr.Name() }
@@ -659,7 +1677,27 @@ func (txm *Txm) defaultTxConfig() TxConfig {
 		BaseComputeUnitPrice:     txm.fee.BaseComputeUnitPrice(),
 		ComputeUnitPriceMin:      txm.cfg.ComputeUnitPriceMin(),
 		ComputeUnitPriceMax:      txm.cfg.ComputeUnitPriceMax(),
+		FeeBumpStrategy:          txm.bump,
+		FeeBudget:                txm.cfg.TxFeeBudget(),
 		ComputeUnitLimit:         txm.cfg.ComputeUnitLimitDefault(),
 		EstimateComputeUnitLimit: txm.cfg.EstimateComputeUnitLimit(),
+		RefreshBlockhash:         txm.cfg.RefreshBlockhash(),
+		Batch:                    txm.cfg.TxBatching(),
+
+		EstimateLoadedAccountsDataSize: txm.cfg.EstimateLoadedAccountsDataSize(),
+	}
+}
+
+// isInvalidSendError returns true for send errors of txs that will never be included
+func isInvalidSendError(code mn.SendTxReturnCode) bool {
+	return code == mn.Fatal || code == mn.Unsupported
+}
+
+// sendErrorFailType returns the failure type of a tx rejected by the RPC
+// txs that revert in preflight simulation are failed the same as txs that revert in txm simulation
+func sendErrorFailType(err error) int {
+	if txErr, ok := client.TransactionErrorFromSendError(err); ok && client.TransactionErrorName(txErr) == "InstructionError" {
+		return TxFailSimRevert
 	}
+	return TxFailInvalid
 }
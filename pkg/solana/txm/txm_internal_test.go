@@ -6,13 +6,16 @@ import (
 	"context"
 	"errors"
 	"math/rand"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
@@ -105,6 +108,10 @@ func TestTxm(t *testing.T) {
 			cfg.Chain.FeeEstimatorMode = &estimator
 			mc := mocks.NewReaderWriter(t)
 			mc.On("GetLatestBlock", mock.Anything).Return(&rpc.GetBlockResult{}, nil).Maybe()
+			mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+				Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+			}, nil).Maybe()
+			mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
 
 			// mock solana keystore
 			mkey := keyMocks.NewSimpleKeystore(t)
@@ -160,12 +167,12 @@ func TestTxm(t *testing.T) {
 				}, nil,
 			)
 
-			// happy path (send => simulate success => tx: nil => tx: processed => tx: confirmed => done)
+			// happy path (send => simulate success => tx: nil => tx: processed => tx: confirmed => tx: finalized => done)
 			t.Run("happyPath", func(t *testing.T) {
 				sig := getSig()
 				tx, signed := getTx(t, 0, mkey, 0)
 				var wg sync.WaitGroup
-				wg.Add(3)
+				wg.Add(4)
 
 				sendCount := 0
 				var countRW sync.RWMutex
@@ -192,15 +199,25 @@ func TestTxm(t *testing.T) {
 						out.ConfirmationStatus = rpc.ConfirmationStatusConfirmed
 						return
 					}
+
+					if count == 3 {
+						out.ConfirmationStatus = rpc.ConfirmationStatusFinalized
+						return
+					}
 					return nil
 				}
 
 				// send tx
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				txID := uuid.NewString()
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID))
 				wg.Wait()
 
 				// no transactions stored inflight txs list
 				waitFor(empty)
+				status, err := txm.GetTransactionStatus(ctx, txID)
+				require.NoError(t, err)
+				assert.Equal(t, TxStateFinalized, status.State)
+				assert.Equal(t, sig, status.Signature)
 				// transaction should be sent more than twice
 				countRW.RLock()
 				t.Logf("sendTx received %d calls", sendCount)
@@ -227,7 +244,8 @@ func TestTxm(t *testing.T) {
 				}).Return(solana.Signature{}, errors.New("FAIL")).Once()
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				txID := uuid.NewString()
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID))
 				wg.Wait() // wait to be picked up and processed
 
 				// no transactions stored inflight txs list
@@ -237,6 +255,12 @@ func TestTxm(t *testing.T) {
 				prom.error++
 				prom.reject++
 				prom.assertEqual(t)
+
+				// tx marked as failed
+				status, err := txm.GetTransactionStatus(ctx, txID)
+				require.NoError(t, err)
+				assert.Equal(t, TxStateFailed, status.State)
+				assert.Contains(t, status.Error, "FAIL")
 			})
 
 			// tx fails simulation (simulation error)
@@ -255,7 +279,7 @@ func TestTxm(t *testing.T) {
 				// signature status is nil (handled automatically)
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // txs cleared quickly
 
@@ -287,7 +311,7 @@ func TestTxm(t *testing.T) {
 				// all signature statuses are nil, handled automatically
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // txs cleared after timeout
 
@@ -323,7 +347,7 @@ func TestTxm(t *testing.T) {
 				// all signature statuses are nil, handled automatically
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // txs cleared after timeout
 
@@ -359,14 +383,14 @@ func TestTxm(t *testing.T) {
 
 					out = &rpc.SignatureStatusesResult{}
 					if count == 1 {
-						out.ConfirmationStatus = rpc.ConfirmationStatusConfirmed
+						out.ConfirmationStatus = rpc.ConfirmationStatusFinalized
 						return
 					}
 					return nil
 				}
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // txs cleared after timeout
 
@@ -403,7 +427,7 @@ func TestTxm(t *testing.T) {
 				}
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // txs cleared after timeout
 
@@ -444,7 +468,7 @@ func TestTxm(t *testing.T) {
 				}
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // inflight txs cleared after timeout
 
@@ -492,7 +516,7 @@ func TestTxm(t *testing.T) {
 				}
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // inflight txs cleared after timeout
 
@@ -526,7 +550,7 @@ func TestTxm(t *testing.T) {
 				}
 
 				// tx should be able to queue
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()      // wait to be picked up and processed
 				waitFor(empty) // inflight txs cleared after timeout
 
@@ -563,12 +587,12 @@ func TestTxm(t *testing.T) {
 				statuses[retry1] = func() (out *rpc.SignatureStatusesResult) {
 					defer wg.Done()
 					return &rpc.SignatureStatusesResult{
-						ConfirmationStatus: rpc.ConfirmationStatusConfirmed,
+						ConfirmationStatus: rpc.ConfirmationStatusFinalized,
 					}
 				}
 
 				// send tx
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil))
 				wg.Wait()
 
 				// no transactions stored inflight txs list
@@ -608,7 +632,7 @@ func TestTxm(t *testing.T) {
 
 					out = &rpc.SignatureStatusesResult{}
 					if time.Since(start) > 2*defaultFeeBumpPeriod {
-						out.ConfirmationStatus = rpc.ConfirmationStatusConfirmed
+						out.ConfirmationStatus = rpc.ConfirmationStatusFinalized
 						wg.Done()
 						return
 					}
@@ -617,7 +641,7 @@ func TestTxm(t *testing.T) {
 				}
 
 				// send tx - with disabled fee bumping
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, SetFeeBumpPeriod(0)))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil, SetFeeBumpPeriod(0)))
 				wg.Wait()
 
 				// no transactions stored inflight txs list
@@ -652,12 +676,75 @@ func TestTxm(t *testing.T) {
 				statuses[sig] = func() *rpc.SignatureStatusesResult {
 					defer wg.Done()
 					return &rpc.SignatureStatusesResult{
-						ConfirmationStatus: rpc.ConfirmationStatusConfirmed,
+						ConfirmationStatus: rpc.ConfirmationStatusFinalized,
 					}
 				}
 
 				// send tx - with disabled fee bumping and disabled compute unit limit
-				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, SetFeeBumpPeriod(0), SetComputeUnitLimit(0)))
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, nil, SetFeeBumpPeriod(0), SetComputeUnitLimit(0)))
+				wg.Wait()
+
+				// no transactions stored inflight txs list
+				waitFor(empty)
+
+				// panic if sendTx called after context cancelled
+				mc.On("SendTx", mock.Anything, tx).Panic("SendTx should not be called anymore").Maybe()
+
+				// check prom metric
+				prom.success++
+				prom.assertEqual(t)
+			})
+
+			// invalid tx on initial transmit (RPC rejects the signature), tx is marked as fatal
+			t.Run("fatal_initialTx", func(t *testing.T) {
+				tx, signed := getTx(t, 13, mkey, 0)
+				var wg sync.WaitGroup
+				wg.Add(1)
+
+				// should only be called once (tx does not start retry, confirming, or simulation)
+				mc.On("SendTx", mock.Anything, signed(0, true)).Run(func(mock.Arguments) {
+					wg.Done()
+				}).Return(solana.Signature{}, &jsonrpc.RPCError{Code: -32003, Message: "Transaction signature verification failure"}).Once()
+
+				txID := uuid.NewString()
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID))
+				wg.Wait()
+
+				// no transactions stored inflight txs list
+				waitFor(empty)
+
+				// check prom metric
+				prom.error++
+				prom.reject++
+				prom.assertEqual(t)
+
+				status, err := txm.GetTransactionStatus(ctx, txID)
+				require.NoError(t, err)
+				assert.Equal(t, TxStateFatal, status.State)
+				assert.Contains(t, status.Error, "signature verification failure")
+			})
+
+			// node unhealthy on initial transmit, tx is tracked and rebroadcast
+			t.Run("retryable_initialTx", func(t *testing.T) {
+				tx, signed := getTx(t, 14, mkey, 0)
+				sig := signed(0, false).Signatures[0]
+
+				mc.On("SendTx", mock.Anything, signed(0, false)).Return(solana.Signature{}, &jsonrpc.RPCError{Code: -32005, Message: "Node is unhealthy"}).Once()
+				mc.On("SendTx", mock.Anything, signed(0, false)).Return(sig, nil)
+				mc.On("SimulateTx", mock.Anything, signed(0, false), mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Once()
+
+				// handle signature status calls
+				var wg sync.WaitGroup
+				wg.Add(1)
+				statuses[sig] = func() *rpc.SignatureStatusesResult {
+					defer wg.Done()
+					return &rpc.SignatureStatusesResult{
+						ConfirmationStatus: rpc.ConfirmationStatusFinalized,
+					}
+				}
+
+				txID := uuid.NewString()
+				assert.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID, SetFeeBumpPeriod(0), SetComputeUnitLimit(0)))
 				wg.Wait()
 
 				// no transactions stored inflight txs list
@@ -669,6 +756,10 @@ func TestTxm(t *testing.T) {
 				// check prom metric
 				prom.success++
 				prom.assertEqual(t)
+
+				status, err := txm.GetTransactionStatus(ctx, txID)
+				require.NoError(t, err)
+				assert.Equal(t, TxStateFinalized, status.State)
 			})
 		})
 	}
@@ -681,6 +772,11 @@ func TestTxm_Enqueue(t *testing.T) {
 	mc := mocks.NewReaderWriter(t)
 	mc.On("SendTx", mock.Anything, mock.Anything).Return(solana.Signature{}, nil).Maybe()
 	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
 	ctx := tests.Context(t)
 
 	// mock solana keystore
@@ -721,7 +817,7 @@ func TestTxm_Enqueue(t *testing.T) {
 		return mc, nil
 	}, cfg, mkey, lggr)
 
-	require.ErrorContains(t, txm.Enqueue(ctx, "txmUnstarted", &solana.Transaction{}), "not started")
+	require.ErrorContains(t, txm.Enqueue(ctx, "txmUnstarted", &solana.Transaction{}, nil), "not started")
 	require.NoError(t, txm.Start(ctx))
 	t.Cleanup(func() { require.NoError(t, txm.Close()) })
 
@@ -739,10 +835,357 @@ func TestTxm_Enqueue(t *testing.T) {
 	for _, run := range txs {
 		t.Run(run.name, func(t *testing.T) {
 			if !run.fail {
-				assert.NoError(t, txm.Enqueue(ctx, run.name, run.tx))
+				assert.NoError(t, txm.Enqueue(ctx, run.name, run.tx, nil))
 				return
 			}
-			assert.Error(t, txm.Enqueue(ctx, run.name, run.tx))
+			assert.Error(t, txm.Enqueue(ctx, run.name, run.tx, nil))
 		})
 	}
+
+	t.Run("duplicate_id", func(t *testing.T) {
+		txID := uuid.NewString()
+		require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID))
+		require.ErrorIs(t, txm.Enqueue(ctx, t.Name(), tx, &txID), ErrTxAlreadyExists)
+
+		_, err := txm.GetTransactionStatus(ctx, txID)
+		require.NoError(t, err)
+		_, err = txm.GetTransactionStatus(ctx, uuid.NewString())
+		require.ErrorIs(t, err, ErrTxNotFound)
+	})
+}
+
+func TestTxm_RefreshBlockhash(t *testing.T) {
+	ctx := tests.Context(t)
+	lggr := logger.Test(t)
+	cfg := config.NewDefault()
+	refresh := true
+	cfg.Chain.RefreshBlockhash = &refresh
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	// initial blockhash expires at block height 10, refreshed blockhash at 1000
+	expiredHash, freshHash := solana.Hash{1}, solana.Hash{2}
+	expiredSig, freshSig := solana.Signature{1}, solana.Signature{2}
+	var blockHeight atomic.Uint64
+	blockHeight.Store(5)
+
+	mc := mocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{Blockhash: expiredHash, LastValidBlockHeight: 10},
+	}, nil).Once()
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{Blockhash: freshHash, LastValidBlockHeight: 1000},
+	}, nil)
+	mc.On("BlockHeight", mock.Anything).Return(func(context.Context) (uint64, error) {
+		return blockHeight.Load(), nil
+	})
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		if tx.Message.RecentBlockhash == freshHash {
+			return freshSig, nil
+		}
+		return expiredSig, nil
+	})
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		out := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i := range sigs {
+			if sigs[i] == freshSig {
+				out[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+			}
+		}
+		return out, nil
+	})
+
+	txm := NewTxm("refresh_blockhash", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, lggr)
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, _ := getTx(t, 0, mkey, 0)
+	txID := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID, SetFeeBumpPeriod(0)))
+	require.Eventually(t, func() bool {
+		rec, err := txm.txs.Get(txID)
+		return err == nil && rec.State == TxStateBroadcasted
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// blockhash expires, tx is re-signed with the latest blockhash and confirmed
+	blockHeight.Store(11)
+	require.Eventually(t, func() bool {
+		status, err := txm.GetTransactionStatus(ctx, txID)
+		return err == nil && status.State == TxStateConfirmed
+	}, 5*time.Second, 50*time.Millisecond)
+
+	status, err := txm.GetTransactionStatus(ctx, txID)
+	require.NoError(t, err)
+	assert.Equal(t, freshSig, status.Signature)
+	rec, err := txm.txs.Get(txID)
+	require.NoError(t, err)
+	assert.Equal(t, []solana.Signature{expiredSig, freshSig}, rec.Signatures())
+	assert.Equal(t, uint64(1000), rec.Attempts[1].LastValidBlockHeight)
+}
+
+func TestTxm_CancelReplace(t *testing.T) {
+	ctx := tests.Context(t)
+	lggr := logger.Test(t)
+	cfg := config.NewDefault()
+	maxInflight := uint32(1)
+	cfg.Chain.TxMaxInflight = &maxInflight // keep txs queued while a tx is inflight
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	blockhash := solana.Hash{9}
+	replacementKey, revertKey := solana.PublicKey{7}, solana.PublicKey{8}
+	txSig, replacementSig := solana.Signature{1}, solana.Signature{2}
+	var blockHeight atomic.Uint64
+	blockHeight.Store(5)
+	var replacementBlockhash atomic.Value
+
+	mc := mocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{Blockhash: solana.Hash{1}, LastValidBlockHeight: 10},
+	}, nil)
+	mc.On("BlockHeight", mock.Anything).Return(func(context.Context) (uint64, error) {
+		return blockHeight.Load(), nil
+	})
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction) (solana.Signature, error) {
+		if slices.Contains(tx.Message.AccountKeys, replacementKey) {
+			replacementBlockhash.Store(tx.Message.RecentBlockhash)
+			return replacementSig, nil
+		}
+		return txSig, nil
+	})
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, tx *solana.Transaction, _ *rpc.SimulateTransactionOpts) (*rpc.SimulateTransactionResult, error) {
+		if slices.Contains(tx.Message.AccountKeys, revertKey) {
+			return &rpc.SimulateTransactionResult{Err: "InvalidAccountData"}, nil
+		}
+		return &rpc.SimulateTransactionResult{}, nil
+	}).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		out := make([]*rpc.SignatureStatusesResult, len(sigs))
+		for i := range sigs {
+			if sigs[i] == replacementSig {
+				out[i] = &rpc.SignatureStatusesResult{ConfirmationStatus: rpc.ConfirmationStatusConfirmed}
+			}
+		}
+		return out, nil
+	})
+
+	txm := NewTxm("cancel_replace", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, lggr)
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, _ := getTx(t, 0, mkey, 0)
+	tx.Message.RecentBlockhash = blockhash
+	txID := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID, SetFeeBumpPeriod(0)))
+	require.Eventually(t, func() bool {
+		rec, err := txm.txs.Get(txID)
+		return err == nil && rec.State == TxStateBroadcasted
+	}, 5*time.Second, 50*time.Millisecond)
+
+	t.Run("cancel_queued", func(t *testing.T) {
+		queuedID := uuid.NewString()
+		require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &queuedID))
+		require.NoError(t, txm.Cancel(ctx, queuedID))
+		status, err := txm.GetTransactionStatus(ctx, queuedID)
+		require.NoError(t, err)
+		assert.Equal(t, TxStateCancelled, status.State)
+		require.Error(t, txm.Cancel(ctx, queuedID))
+		require.ErrorIs(t, txm.Cancel(ctx, uuid.NewString()), ErrTxNotFound)
+	})
+
+	t.Run("replace_broadcasted", func(t *testing.T) {
+		replacement, err := solana.NewTransaction(
+			[]solana.Instruction{system.NewTransferInstruction(1, solana.PublicKey{}, replacementKey).Build()},
+			solana.Hash{},
+			solana.TransactionPayer(solana.PublicKey{}),
+		)
+		require.NoError(t, err)
+		replacementID := uuid.NewString()
+		require.ErrorIs(t, txm.Replace(ctx, txID, replacement, &txID), ErrTxAlreadyExists)
+
+		// the tx is not cancelled if the replacement can not be built
+		reverting, err := solana.NewTransaction(
+			[]solana.Instruction{system.NewTransferInstruction(1, solana.PublicKey{}, revertKey).Build()},
+			solana.Hash{},
+			solana.TransactionPayer(solana.PublicKey{}),
+		)
+		require.NoError(t, err)
+		require.ErrorContains(t, txm.Replace(ctx, txID, reverting, &replacementID, SetEstimateComputeUnitLimit(true)), "failed simulation")
+		_, err = txm.txs.Get(replacementID)
+		require.ErrorIs(t, err, ErrTxNotFound)
+
+		// a tx is replaced by one caller at a time
+		require.True(t, txm.lockReplace(txID))
+		require.ErrorContains(t, txm.Replace(ctx, txID, replacement, &replacementID), "already being replaced")
+		txm.unlockReplace(txID)
+
+		// the tx was not cancelled by the failed replacements, it can only be cancelled once
+		require.NoError(t, txm.Replace(ctx, txID, replacement, &replacementID, SetFeeBumpPeriod(0)))
+
+		// replacement is sent within the blockhash window of the replaced tx
+		require.Eventually(t, func() bool {
+			status, err := txm.GetTransactionStatus(ctx, replacementID)
+			return err == nil && status.State == TxStateConfirmed
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Equal(t, blockhash, replacementBlockhash.Load())
+		rec, err := txm.txs.Get(replacementID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(10), rec.Attempts[0].LastValidBlockHeight)
+
+		// replaced tx is cancelled once its blockhash expires
+		blockHeight.Store(11)
+		require.Eventually(t, func() bool {
+			status, err := txm.GetTransactionStatus(ctx, txID)
+			return err == nil && status.State == TxStateCancelled
+		}, 5*time.Second, 50*time.Millisecond)
+		status, err := txm.GetTransactionStatus(ctx, txID)
+		require.NoError(t, err)
+		assert.Equal(t, txSig, status.Signature)
+		assert.Contains(t, status.Error, "replaced by tx "+replacementID)
+		require.Error(t, txm.Replace(ctx, txID, replacement, nil))
+	})
+}
+
+func TestTxm_Events(t *testing.T) {
+	ctx := tests.Context(t)
+	lggr := logger.Test(t)
+	cfg := config.NewDefault()
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sig := solana.Signature{1}
+	var polls atomic.Int32
+	mc := mocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil)
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil)
+	mc.On("SendTx", mock.Anything, mock.Anything).Return(sig, nil)
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		// processed on the first poll, confirmed on the next, then finalized
+		statuses := []rpc.ConfirmationStatusType{rpc.ConfirmationStatusProcessed, rpc.ConfirmationStatusConfirmed, rpc.ConfirmationStatusFinalized}
+		status := statuses[min(int(polls.Add(1))-1, len(statuses)-1)]
+		return []*rpc.SignatureStatusesResult{{ConfirmationStatus: status}}, nil
+	})
+
+	txm := NewTxm("events", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, lggr)
+	sub, unsubscribe := txm.SubscribeEvents()
+	defer unsubscribe()
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	chCallback := make(chan TxEvent, 10)
+	tx, _ := getTx(t, 0, mkey, 0)
+	txID := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID, SetFeeBumpPeriod(0), SetEventCallback(func(ev TxEvent) {
+		chCallback <- ev
+	})))
+
+	expected := []TxEventType{TxEventEnqueued, TxEventBroadcast, TxEventProcessed, TxEventConfirmed, TxEventFinalized}
+	for _, ch := range []<-chan TxEvent{chCallback, sub} {
+		for _, eventType := range expected {
+			select {
+			case ev := <-ch:
+				assert.Equal(t, eventType, ev.Type)
+				assert.Equal(t, txID, ev.ID)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "timed out waiting for event", eventType.String())
+			}
+		}
+	}
+	status, err := txm.GetTransactionStatus(ctx, txID)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+}
+
+func TestTxm_Finality(t *testing.T) {
+	ctx := tests.Context(t)
+	lggr := logger.Test(t)
+	cfg := config.NewDefault()
+	finalized := string(rpc.CommitmentFinalized)
+	cfg.Chain.TxCompletionCommitment = &finalized
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sig := solana.Signature{1}
+	var status atomic.Value // empty status: signature not found
+	status.Store(rpc.ConfirmationStatusType(""))
+	mc := mocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil)
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil)
+	var sent atomic.Int64
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(mock.Arguments) { sent.Add(1) }).Return(sig, nil)
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	var polls atomic.Int64
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		polls.Add(1)
+		s := status.Load().(rpc.ConfirmationStatusType)
+		if s == "" {
+			return []*rpc.SignatureStatusesResult{nil}, nil
+		}
+		return []*rpc.SignatureStatusesResult{{ConfirmationStatus: s}}, nil
+	})
+
+	txm := NewTxm("finality", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, lggr)
+	sub, unsubscribe := txm.SubscribeEvents()
+	defer unsubscribe()
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, _ := getTx(t, 0, mkey, 0)
+	txID := uuid.NewString()
+	require.NoError(t, txm.Enqueue(ctx, t.Name(), tx, &txID, SetFeeBumpPeriod(0)))
+	waitForState := func(state TxState) {
+		require.Eventually(t, func() bool {
+			rec, err := txm.GetTransactionStatus(ctx, txID)
+			return err == nil && rec.State == state
+		}, 5*time.Second, 50*time.Millisecond, state.String())
+	}
+	waitForState(TxStateBroadcasted)
+
+	// confirmed txs are tracked until finalized
+	status.Store(rpc.ConfirmationStatusConfirmed)
+	waitForState(TxStateConfirmed)
+	assert.False(t, txm.txFinished(txID))
+	assert.Equal(t, 1, txm.InflightTxs())
+
+	// confirmed tx no longer found is rolled back after consecutive misses, then rebroadcast and polled again
+	status.Store(rpc.ConfirmationStatusType(""))
+	start := polls.Load()
+	waitForState(TxStateBroadcasted)
+	assert.GreaterOrEqual(t, polls.Load()-start, int64(RollbackMisses))
+	confirmedSends := sent.Load()
+	require.Eventually(t, func() bool { return sent.Load() > confirmedSends }, 5*time.Second, 10*time.Millisecond, "rolled back tx not rebroadcast")
+	status.Store(rpc.ConfirmationStatusFinalized)
+	waitForState(TxStateFinalized)
+
+	// rebroadcast stops once the tx is included again
+	included := sent.Load()
+	time.Sleep(2 * MaxRetryTimeMs * time.Millisecond)
+	assert.LessOrEqual(t, sent.Load(), included+1)
+	assert.True(t, txm.txFinished(txID))
+	assert.Equal(t, 0, txm.InflightTxs())
+
+	var events []TxEventType
+	for len(events) == 0 || events[len(events)-1] != TxEventFinalized {
+		select {
+		case ev := <-sub:
+			events = append(events, ev.Type)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for finalized event", events)
+		}
+	}
+	assert.Equal(t, []TxEventType{TxEventEnqueued, TxEventBroadcast, TxEventConfirmed, TxEventRolledBack, TxEventConfirmed, TxEventFinalized}, events)
 }
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	solanaGo "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/goplugin/plugin-common/pkg/logger"
@@ -16,6 +18,7 @@ import (
 
 	solanaClient "github.com/goplugin/plugin-solana/pkg/solana/client"
 	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	solcfg "github.com/goplugin/plugin-solana/pkg/solana/config"
 	cfgmocks "github.com/goplugin/plugin-solana/pkg/solana/config/mocks"
 	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 	feemocks "github.com/goplugin/plugin-solana/pkg/solana/fees/mocks"
@@ -54,13 +57,20 @@ func TestTxm_SendWithRetry_Race(t *testing.T) {
 	cfg.On("TxRetryTimeout").Return(txRetryDuration)
 	cfg.On("ComputeUnitLimitDefault").Return(uint32(200_000)) // default value, cannot not use 0
 	cfg.On("EstimateComputeUnitLimit").Return(false)
+	cfg.On("EstimateLoadedAccountsDataSize").Return(false)
+	cfg.On("RefreshBlockhash").Return(false)
+	cfg.On("TxBatching").Return(false)
+	cfg.On("TxFeeBudget").Return(uint64(0))
 	// keystore mock
 	ks.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
 
 	// assemble minimal tx for testing retry
 	tx := NewTestTx()
 
-	testRunner := func(t *testing.T, client solanaClient.ReaderWriter) {
+	testRunner := func(t *testing.T, client *clientmocks.ReaderWriter) {
+		client.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+			Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+		}, nil)
 		getClient := func() (solanaClient.ReaderWriter, error) {
 			return client, nil
 		}
@@ -69,8 +79,11 @@ func TestTxm_SendWithRetry_Race(t *testing.T) {
 		txm := NewTxm("retry_race", getClient, cfg, ks, lggr)
 		txm.fee = fee
 
-		_, _, _, err := txm.sendWithRetry(
+		id := uuid.NewString()
+		require.NoError(t, txm.txs.Pending(id, nil, 0, ""))
+		_, _, err := txm.sendWithRetry(
 			tests.Context(t),
+			id,
 			tx,
 			txm.defaultTxConfig(),
 		)
@@ -238,3 +251,29 @@ func TestTxm_SendWithRetry_Race(t *testing.T) {
 		testRunner(t, client)
 	})
 }
+
+func TestTxm_FeeEstimator_Race(t *testing.T) {
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	txm := NewTxm("fee_race", func() (solanaClient.ReaderWriter, error) {
+		return mc, nil
+	}, solcfg.NewDefault(), ksmocks.NewSimpleKeystore(t), logger.Test(t))
+
+	// the estimator is read by callers while the txm starts
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for txm.FeeEstimator() == nil {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	require.NoError(t, txm.Start(tests.Context(t)))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+	select {
+	case <-done:
+	case <-time.After(tests.WaitTimeout(t)):
+		t.Fatal("fee estimator not set on start")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	solanaClient "github.com/goplugin/plugin-solana/pkg/solana/client"
 	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
 	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 	solanatxm "github.com/goplugin/plugin-solana/pkg/solana/txm"
 	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
 
@@ -132,3 +133,126 @@ func createTx(t *testing.T, client solanaClient.ReaderWriter, signer solana.Publ
 	require.NoError(t, err)
 	return tx
 }
+
+func TestTxm_RecentFeesEstimator(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	feePayer, writable := solana.PublicKey{1}, solana.PublicKey{2}
+	cfg := config.NewDefault()
+	mode := "recentfees"
+	cfg.Chain.FeeEstimatorMode = &mode
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sent := make(chan *solana.Transaction, 10)
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("GetRecentPrioritizationFees", mock.Anything, solana.PublicKeySlice(nil)).Return([]rpc.PriorizationFeeResult{{PrioritizationFee: 100}}, nil).Maybe()
+	mc.On("GetRecentPrioritizationFees", mock.Anything, mock.MatchedBy(func(accounts solana.PublicKeySlice) bool {
+		return accounts.Has(writable)
+	})).Return([]rpc.PriorizationFeeResult{{PrioritizationFee: 300}, {PrioritizationFee: 700}, {PrioritizationFee: 500}}, nil).Once()
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(solana.Signature{1}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{nil}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := solanatxm.NewTxm("recent_fees", func() (solanaClient.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// tx is priced from the median fee of its writable accounts
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(writable).WRITE()}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, nil))
+	select {
+	case sentTx := <-sent:
+		var prices []fees.ComputeUnitPrice
+		for _, ix := range sentTx.Message.Instructions {
+			if price, parseErr := fees.ParseComputeUnitPrice(ix.Data); parseErr == nil {
+				prices = append(prices, price)
+			}
+		}
+		require.Equal(t, []fees.ComputeUnitPrice{500}, prices)
+	case <-ctx.Done():
+		t.Fatal("tx not sent")
+	}
+}
+
+func TestTxm_EstimateLoadedAccountsDataSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := tests.Context(t)
+	feePayer, account := solana.PublicKey{1}, solana.PublicKey{2}
+	cfg := config.NewDefault()
+	estimate := true
+	cfg.Chain.EstimateLoadedAccountsDataSize = &estimate
+	mkey := keyMocks.NewSimpleKeystore(t)
+	mkey.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil)
+
+	sent := make(chan *solana.Transaction, 10)
+	mc := clientmocks.NewReaderWriter(t)
+	// estimation requests the loaded accounts of the tx
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.MatchedBy(func(opts *rpc.SimulateTransactionOpts) bool {
+		return opts != nil && opts.Accounts != nil && solana.PublicKeySlice(opts.Accounts.Addresses).Has(account)
+	})).Return(&rpc.SimulateTransactionResult{
+		Accounts: []*rpc.Account{
+			{Data: rpc.DataBytesOrJSONFromBytes(make([]byte, 100))},
+			{Data: rpc.DataBytesOrJSONFromBytes(make([]byte, 1_000))},
+			nil, // not found
+		},
+	}, nil).Once()
+	mc.On("LatestBlockhash", mock.Anything).Return(&rpc.GetLatestBlockhashResult{
+		Value: &rpc.LatestBlockhashResult{LastValidBlockHeight: 100},
+	}, nil).Maybe()
+	mc.On("SendTx", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case sent <- args.Get(1).(*solana.Transaction):
+		default:
+		}
+	}).Return(solana.Signature{1}, nil).Maybe()
+	mc.On("SimulateTx", mock.Anything, mock.Anything, mock.Anything).Return(&rpc.SimulateTransactionResult{}, nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return([]*rpc.SignatureStatusesResult{nil}, nil).Maybe()
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+
+	txm := solanatxm.NewTxm("loaded_accounts", func() (solanaClient.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, mkey, logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(solana.MemoProgramID, solana.AccountMetaSlice{solana.Meta(account).WRITE()}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(feePayer))
+	require.NoError(t, err)
+	require.NoError(t, txm.Enqueue(ctx, "test", tx, nil, solanatxm.SetHeapFrame(64*1024)))
+	select {
+	case sentTx := <-sent:
+		var limits []fees.LoadedAccountsDataSizeLimit
+		var heapFrames []fees.HeapFrame
+		for _, ix := range sentTx.Message.Instructions {
+			if limit, parseErr := fees.ParseLoadedAccountsDataSizeLimit(ix.Data); parseErr == nil {
+				limits = append(limits, limit)
+			}
+			if heapFrame, parseErr := fees.ParseHeapFrame(ix.Data); parseErr == nil {
+				heapFrames = append(heapFrames, heapFrame)
+			}
+		}
+		// (64 + 100) + (64 + 1000) bytes with a 10% buffer
+		require.Equal(t, []fees.LoadedAccountsDataSizeLimit{1_350}, limits)
+		require.Equal(t, []fees.HeapFrame{64 * 1024}, heapFrames)
+	case <-ctx.Done():
+		t.Fatal("tx not sent")
+	}
+}
@@ -0,0 +1,620 @@
+package txm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"golang.org/x/exp/maps"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
+)
+
+// TxState tracks the lifecycle of a transaction managed by the txm
+type TxState int
+
+const (
+	TxStateNotFound    TxState = iota
+	TxStatePending             // queued in the txm, not yet broadcasted
+	TxStateBroadcasted         // broadcasted to the RPC, not yet seen onchain
+	TxStateProcessed           // included in a block
+	TxStateConfirmed           // included in a block confirmed by the cluster, polled until finalized
+	TxStateFinalized           // included in a finalized block
+	TxStateFailed              // dropped, rejected or reverted (can be resubmitted)
+	TxStateFatal               // invalid transaction that will never be included
+	TxStateCancelled           // cancelled or replaced before any broadcasted attempt was included
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxStatePending:
+		return "Pending"
+	case TxStateBroadcasted:
+		return "Broadcasted"
+	case TxStateProcessed:
+		return "Processed"
+	case TxStateConfirmed:
+		return "Confirmed"
+	case TxStateFinalized:
+		return "Finalized"
+	case TxStateFailed:
+		return "Failed"
+	case TxStateFatal:
+		return "Fatal"
+	case TxStateCancelled:
+		return "Cancelled"
+	default:
+		return "NotFound"
+	}
+}
+
+// IsTerminal returns true if the txm no longer needs to poll for the state of the transaction
+func (s TxState) IsTerminal() bool {
+	return s == TxStateFinalized || s == TxStateFailed || s == TxStateFatal || s == TxStateCancelled
+}
+
+// TxAttempt is a single signed + broadcasted version of a transaction
+type TxAttempt struct {
+	Signature            solana.Signature
+	ComputeUnitPrice     uint64
+	Blockhash            solana.Hash        // recent blockhash (or durable nonce) signed by the attempt
+	LastValidBlockHeight uint64             // last block height the attempt's blockhash can be included in (0 = unknown)
+	DurableNonce         *DurableNonce      // durable nonce used in place of a recent blockhash (nil = recent blockhash)
+	Programs             []solana.PublicKey // program of each instruction of the signed attempt, decodes the failed instruction of reverts
+	Tx                   []byte             // signed tx of the attempt, rebroadcast after a rollback or restart
+	Timestamp            time.Time
+}
+
+// TxBatchMember locates the instructions of a tx that was sent in a batch tx
+type TxBatchMember struct {
+	Batch        string // id of the batch tx
+	Instruction  int    // index of the first instruction of the tx in the batch tx, excluding compute budget instructions
+	Instructions int    // number of instructions of the tx in the batch tx
+}
+
+// TxTransition records when a transaction moved into a state
+type TxTransition struct {
+	State     TxState
+	Timestamp time.Time
+}
+
+// TxRecord is the persisted representation of a transaction
+type TxRecord struct {
+	ID               string
+	Message          []byte // serialized message of the enqueued tx (before compute budget instructions and signing)
+	ComputeUnitLimit uint32
+	// commitment at which the tx is finished (empty = TxCompletionCommitment)
+	CompletionCommitment rpc.CommitmentType
+	Attempts             []TxAttempt
+	State                TxState
+	Signature            solana.Signature     // signature that triggered the latest state transition
+	Error                string               // reason for failed + fatal txs
+	TxError              *DecodedTxError      // decoded TransactionError of reverted txs (nil if unknown)
+	Batch                *TxBatchMember       // batch tx the tx was sent in, its state follows the batch tx (nil = sent on its own)
+	Retries              map[TxRetryClass]int // times the tx was rebuilt and resent after failing, per error class
+	DeadLetter           bool                 // failed after exhausting its retries, kept until resubmitted or purged
+	Transitions          []TxTransition
+	CreatedAt            time.Time
+}
+
+// Signatures returns the signatures for all attempts of the transaction
+func (r TxRecord) Signatures() []solana.Signature {
+	sigs := make([]solana.Signature, len(r.Attempts))
+	for i := range r.Attempts {
+		sigs[i] = r.Attempts[i].Signature
+	}
+	return sigs
+}
+
+// FeeBumps returns the number of times the transaction was re-signed (bumped fee or refreshed blockhash)
+func (r TxRecord) FeeBumps() int {
+	if len(r.Attempts) == 0 {
+		return 0
+	}
+	return len(r.Attempts) - 1
+}
+
+// clone returns a deep copy of the record, records returned by the store do not share memory with the stored records
+func (r TxRecord) clone() TxRecord {
+	out := r
+	out.Message = append([]byte(nil), r.Message...)
+	if r.Attempts != nil {
+		out.Attempts = make([]TxAttempt, len(r.Attempts))
+		for i := range r.Attempts {
+			out.Attempts[i] = r.Attempts[i].clone()
+		}
+	}
+	out.Transitions = append([]TxTransition(nil), r.Transitions...)
+	out.TxError = r.TxError.clone()
+	if r.Batch != nil {
+		batch := *r.Batch
+		out.Batch = &batch
+	}
+	if r.Retries != nil {
+		out.Retries = maps.Clone(r.Retries)
+	}
+	return out
+}
+
+func (a TxAttempt) clone() TxAttempt {
+	out := a
+	if a.DurableNonce != nil {
+		nonce := *a.DurableNonce
+		out.DurableNonce = &nonce
+	}
+	out.Programs = append([]solana.PublicKey(nil), a.Programs...)
+	out.Tx = append([]byte(nil), a.Tx...)
+	return out
+}
+
+// Fee returns the fee in lamports paid by the tx signature (or expected to be paid if the tx is still inflight)
+func (r TxRecord) Fee() uint64 {
+	if len(r.Attempts) == 0 {
+		return 0
+	}
+
+	// use price of the signature that landed, fallback to the latest attempt
+	attempt := r.Attempts[len(r.Attempts)-1]
+	for _, a := range r.Attempts {
+		if a.Signature == r.Signature {
+			attempt = a
+			break
+		}
+	}
+
+	numSigs := uint64(1)
+	var msg solana.Message
+	if err := msg.UnmarshalWithDecoder(bin.NewBinDecoder(r.Message)); err == nil && msg.Header.NumRequiredSignatures > 0 {
+		numSigs = uint64(msg.Header.NumRequiredSignatures)
+	}
+	return numSigs*fees.LamportsPerSignature + fees.CalculatePriorityFee(fees.ComputeUnitPrice(attempt.ComputeUnitPrice), fees.ComputeUnitLimit(r.ComputeUnitLimit))
+}
+
+var (
+	ErrTxNotFound      = errors.New("tx not found in store")
+	ErrTxAlreadyExists = errors.New("tx already exists in store")
+)
+
+// TxStore persists transactions tracked by the txm
+type TxStore interface {
+	// Create saves a new transaction record
+	Create(rec TxRecord) error
+	// AddAttempt appends a new signed version of the transaction to the record
+	AddAttempt(id string, attempt TxAttempt) error
+	// SetState records a state transition for the transaction.
+	// sig is the signature that triggered the transition and txErr is the reason for failed + fatal txs
+	SetState(id string, state TxState, sig solana.Signature, txErr string) error
+	// SetTxError records the decoded TransactionError of a reverted transaction
+	SetTxError(id string, txErr *DecodedTxError) error
+	// SetBatch records the batch tx the transaction was sent in (nil = sent on its own)
+	SetBatch(id string, batch *TxBatchMember) error
+	// AddRetry counts a resend of the failed transaction for the error class
+	AddRetry(id string, class TxRetryClass) error
+	// SetDeadLetter marks if the failed transaction is in the dead-letter list
+	SetDeadLetter(id string, deadLetter bool) error
+	Get(id string) (TxRecord, error)
+	// ListInflight returns all transactions that have not reached a terminal state
+	ListInflight() ([]TxRecord, error)
+	// ListDeadLetters returns all transactions in the dead-letter list
+	ListDeadLetters() ([]TxRecord, error)
+	// PruneTerminal deletes transactions that reached a terminal state before the given time, dead letters are kept
+	PruneTerminal(before time.Time) (int, error)
+	Delete(id string) error
+	Close() error
+}
+
+var _ TxStore = (*memoryTxStore)(nil)
+
+// memoryTxStore keeps transactions in memory only, all records are lost on restart
+type memoryTxStore struct {
+	records map[string]TxRecord
+	lock    sync.RWMutex
+}
+
+func NewMemoryTxStore() TxStore {
+	return newMemoryTxStore()
+}
+
+func newMemoryTxStore() *memoryTxStore {
+	return &memoryTxStore{
+		records: map[string]TxRecord{},
+	}
+}
+
+func (s *memoryTxStore) Create(rec TxRecord) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.create(rec)
+}
+
+// internal function that should be called using the proper lock
+func (s *memoryTxStore) create(rec TxRecord) error {
+	if rec.ID == "" {
+		return errors.New("tx id is empty")
+	}
+	if _, exists := s.records[rec.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrTxAlreadyExists, rec.ID)
+	}
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+	if len(rec.Transitions) == 0 {
+		rec.Transitions = []TxTransition{{State: rec.State, Timestamp: rec.CreatedAt}}
+	}
+	s.records[rec.ID] = rec.clone()
+	return nil
+}
+
+func (s *memoryTxStore) AddAttempt(id string, attempt TxAttempt) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.addAttempt(id, attempt)
+}
+
+func (s *memoryTxStore) addAttempt(id string, attempt TxAttempt) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	if attempt.Timestamp.IsZero() {
+		attempt.Timestamp = time.Now()
+	}
+	rec.Attempts = append(rec.Attempts, attempt.clone())
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) SetState(id string, state TxState, sig solana.Signature, txErr string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setState(id, state, sig, txErr)
+}
+
+func (s *memoryTxStore) setState(id string, state TxState, sig solana.Signature, txErr string) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	if rec.State == state {
+		return nil // no transition
+	}
+	rec.State = state
+	rec.Signature = sig
+	rec.Error = txErr
+	rec.Transitions = append(rec.Transitions, TxTransition{State: state, Timestamp: time.Now()})
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) SetTxError(id string, txErr *DecodedTxError) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setTxError(id, txErr)
+}
+
+func (s *memoryTxStore) setTxError(id string, txErr *DecodedTxError) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	rec.TxError = txErr.clone()
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) SetBatch(id string, batch *TxBatchMember) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setBatch(id, batch)
+}
+
+func (s *memoryTxStore) setBatch(id string, batch *TxBatchMember) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	rec.Batch = nil
+	if batch != nil {
+		loc := *batch
+		rec.Batch = &loc
+	}
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) AddRetry(id string, class TxRetryClass) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.addRetry(id, class)
+}
+
+func (s *memoryTxStore) addRetry(id string, class TxRetryClass) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	if rec.Retries == nil {
+		rec.Retries = map[TxRetryClass]int{}
+	}
+	rec.Retries[class]++
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) SetDeadLetter(id string, deadLetter bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.setDeadLetter(id, deadLetter)
+}
+
+func (s *memoryTxStore) setDeadLetter(id string, deadLetter bool) error {
+	rec, exists := s.records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	rec.DeadLetter = deadLetter
+	s.records[id] = rec
+	return nil
+}
+
+func (s *memoryTxStore) Get(id string) (TxRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	rec, exists := s.records[id]
+	if !exists {
+		return TxRecord{}, fmt.Errorf("%w: %s", ErrTxNotFound, id)
+	}
+	return rec.clone(), nil
+}
+
+func (s *memoryTxStore) ListInflight() ([]TxRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var out []TxRecord
+	for _, rec := range s.records {
+		if !rec.State.IsTerminal() {
+			out = append(out, rec.clone())
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryTxStore) ListDeadLetters() ([]TxRecord, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var out []TxRecord
+	for _, rec := range s.records {
+		if rec.DeadLetter {
+			out = append(out, rec.clone())
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryTxStore) PruneTerminal(before time.Time) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pruneTerminal(before), nil
+}
+
+func (s *memoryTxStore) pruneTerminal(before time.Time) int {
+	var count int
+	for id, rec := range s.records {
+		if !rec.State.IsTerminal() || rec.DeadLetter || len(rec.Transitions) == 0 {
+			continue
+		}
+		if rec.Transitions[len(rec.Transitions)-1].Timestamp.Before(before) {
+			delete(s.records, id)
+			count++
+		}
+	}
+	return count
+}
+
+func (s *memoryTxStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryTxStore) Close() error {
+	return nil
+}
+
+var _ TxStore = (*fileTxStore)(nil)
+
+// fileTxStore keeps transactions in memory and writes every change through to a file on disk.
+// every change rewrites the whole file (O(n) in the stored txs, PruneTerminal keeps n bounded) to a synced temp file
+// that replaces the store file, so a crash leaves either the previous or the new records on disk
+type fileTxStore struct {
+	mem  *memoryTxStore
+	path string
+}
+
+// NewFileTxStore opens (or creates) a file backed tx store at the given path and loads any previously stored txs
+func NewFileTxStore(path string) (TxStore, error) {
+	if path == "" {
+		return nil, errors.New("tx store path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create tx store directory: %w", err)
+	}
+
+	s := &fileTxStore{
+		mem:  newMemoryTxStore(),
+		path: path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tx store: %w", err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+
+	var records []TxRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode tx store (%s): %w", path, err)
+	}
+	for _, rec := range records {
+		s.mem.records[rec.ID] = rec
+	}
+	return s, nil
+}
+
+func (s *fileTxStore) Create(rec TxRecord) error {
+	return s.update(rec.ID, func() error { return s.mem.create(rec) })
+}
+
+func (s *fileTxStore) AddAttempt(id string, attempt TxAttempt) error {
+	return s.update(id, func() error { return s.mem.addAttempt(id, attempt) })
+}
+
+func (s *fileTxStore) SetState(id string, state TxState, sig solana.Signature, txErr string) error {
+	return s.update(id, func() error { return s.mem.setState(id, state, sig, txErr) })
+}
+
+func (s *fileTxStore) SetTxError(id string, txErr *DecodedTxError) error {
+	return s.update(id, func() error { return s.mem.setTxError(id, txErr) })
+}
+
+func (s *fileTxStore) SetBatch(id string, batch *TxBatchMember) error {
+	return s.update(id, func() error { return s.mem.setBatch(id, batch) })
+}
+
+func (s *fileTxStore) AddRetry(id string, class TxRetryClass) error {
+	return s.update(id, func() error { return s.mem.addRetry(id, class) })
+}
+
+func (s *fileTxStore) SetDeadLetter(id string, deadLetter bool) error {
+	return s.update(id, func() error { return s.mem.setDeadLetter(id, deadLetter) })
+}
+
+func (s *fileTxStore) Get(id string) (TxRecord, error) {
+	return s.mem.Get(id)
+}
+
+func (s *fileTxStore) ListInflight() ([]TxRecord, error) {
+	return s.mem.ListInflight()
+}
+
+func (s *fileTxStore) ListDeadLetters() ([]TxRecord, error) {
+	return s.mem.ListDeadLetters()
+}
+
+func (s *fileTxStore) PruneTerminal(before time.Time) (int, error) {
+	s.mem.lock.Lock()
+	defer s.mem.lock.Unlock()
+	prev := maps.Clone(s.mem.records)
+	count := s.mem.pruneTerminal(before)
+	if count == 0 {
+		return 0, nil
+	}
+	if err := s.flush(); err != nil {
+		s.mem.records = prev // pruning only removes records, the remaining records are unchanged
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *fileTxStore) Delete(id string) error {
+	s.mem.lock.Lock()
+	defer s.mem.lock.Unlock()
+	rec, exists := s.mem.records[id]
+	if !exists {
+		return nil
+	}
+	delete(s.mem.records, id)
+	if err := s.flush(); err != nil {
+		s.mem.records[id] = rec
+		return err
+	}
+	return nil
+}
+
+func (s *fileTxStore) Close() error {
+	s.mem.lock.Lock()
+	defer s.mem.lock.Unlock()
+	return s.flush()
+}
+
+// update applies a change to the in-memory record with the id and writes the store to disk.
+// the change is rolled back if the write fails, the in-memory records always match the file
+func (s *fileTxStore) update(id string, change func() error) error {
+	s.mem.lock.Lock()
+	defer s.mem.lock.Unlock()
+	prev, existed := s.mem.records[id]
+	if existed {
+		prev = prev.clone()
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	if err := s.flush(); err != nil {
+		if existed {
+			s.mem.records[id] = prev
+		} else {
+			delete(s.mem.records, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// flush writes all records to disk, must be called while holding the write lock.
+// the records are written and synced to a temp file that replaces the store file, the directory is synced
+// after the rename so the replaced file survives a crash or power loss
+func (s *fileTxStore) flush() error {
+	data, err := json.Marshal(maps.Values(s.mem.records))
+	if err != nil {
+		return fmt.Errorf("failed to encode tx store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return fmt.Errorf("failed to write tx store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace tx store: %w", err)
+	}
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("failed to sync tx store directory: %w", err)
+	}
+	return nil
+}
+
+// writeFileSync writes data to the file at path and syncs it to disk before returning
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err = f.Sync(); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	return f.Close()
+}
+
+// syncDir syncs the directory entries of dir, persisting files renamed into it
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	return errors.Join(d.Sync(), d.Close())
+}
@@ -0,0 +1,316 @@
+package txm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goplugin/plugin-common/pkg/logger"
+	"github.com/goplugin/plugin-common/pkg/utils/tests"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/client"
+	clientmocks "github.com/goplugin/plugin-solana/pkg/solana/client/mocks"
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
+	keyMocks "github.com/goplugin/plugin-solana/pkg/solana/txm/mocks"
+)
+
+func TestTxStore(t *testing.T) {
+	fileStore, err := NewFileTxStore(filepath.Join(t.TempDir(), "txs.json"))
+	require.NoError(t, err)
+
+	for name, store := range map[string]TxStore{
+		"memory": NewMemoryTxStore(),
+		"file":   fileStore,
+	} {
+		t.Run(name, func(t *testing.T) {
+			id := uuid.NewString()
+			require.Error(t, store.Create(TxRecord{}), "empty id")
+			require.NoError(t, store.Create(TxRecord{
+				ID:       id,
+				Message:  []byte{1, 2, 3},
+				Attempts: []TxAttempt{{Signature: solana.Signature{1}}},
+				State:    TxStateBroadcasted,
+			}))
+			require.Error(t, store.Create(TxRecord{ID: id}), "duplicate id")
+
+			require.NoError(t, store.AddAttempt(id, TxAttempt{Signature: solana.Signature{2}, ComputeUnitPrice: 1}))
+			require.ErrorIs(t, store.AddAttempt("missing", TxAttempt{}), ErrTxNotFound)
+			require.NoError(t, store.SetState(id, TxStateProcessed, solana.Signature{2}, ""))
+			txErr := &DecodedTxError{Name: "InstructionError", ErrorName: "StaleReport"}
+			require.NoError(t, store.SetTxError(id, txErr))
+			require.ErrorIs(t, store.SetTxError("missing", txErr), ErrTxNotFound)
+			require.NoError(t, store.AddRetry(id, TxRetryDropped))
+			require.NoError(t, store.AddRetry(id, TxRetryDropped))
+			require.ErrorIs(t, store.AddRetry("missing", TxRetryDropped), ErrTxNotFound)
+
+			rec, err := store.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, []byte{1, 2, 3}, rec.Message)
+			assert.Equal(t, []solana.Signature{{1}, {2}}, rec.Signatures())
+			assert.Equal(t, 1, rec.FeeBumps())
+			assert.Equal(t, TxStateProcessed, rec.State)
+			assert.Equal(t, solana.Signature{2}, rec.Signature)
+			assert.Equal(t, txErr, rec.TxError)
+			assert.Equal(t, map[TxRetryClass]int{TxRetryDropped: 2}, rec.Retries)
+			require.Len(t, rec.Transitions, 2)
+			assert.Equal(t, TxStateBroadcasted, rec.Transitions[0].State)
+			assert.Equal(t, TxStateProcessed, rec.Transitions[1].State)
+
+			inflight, err := store.ListInflight()
+			require.NoError(t, err)
+			assert.Len(t, inflight, 1)
+
+			// confirmed txs are inflight until finalized
+			require.NoError(t, store.SetState(id, TxStateConfirmed, solana.Signature{2}, ""))
+			inflight, err = store.ListInflight()
+			require.NoError(t, err)
+			assert.Len(t, inflight, 1)
+
+			// terminal txs are not inflight
+			require.NoError(t, store.SetState(id, TxStateFinalized, solana.Signature{2}, ""))
+			inflight, err = store.ListInflight()
+			require.NoError(t, err)
+			assert.Len(t, inflight, 0)
+
+			// terminal txs are only pruned after the retention period
+			pruned, err := store.PruneTerminal(time.Now().Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 0, pruned)
+			pruned, err = store.PruneTerminal(time.Now().Add(time.Second))
+			require.NoError(t, err)
+			assert.Equal(t, 1, pruned)
+			_, err = store.Get(id)
+			require.ErrorIs(t, err, ErrTxNotFound)
+
+			// dead letters are not pruned
+			require.NoError(t, store.Create(TxRecord{ID: id, State: TxStateFailed}))
+			require.NoError(t, store.SetDeadLetter(id, true))
+			require.ErrorIs(t, store.SetDeadLetter("missing", true), ErrTxNotFound)
+			deadLetters, err := store.ListDeadLetters()
+			require.NoError(t, err)
+			require.Len(t, deadLetters, 1)
+			assert.Equal(t, id, deadLetters[0].ID)
+			pruned, err = store.PruneTerminal(time.Now().Add(time.Second))
+			require.NoError(t, err)
+			assert.Equal(t, 0, pruned)
+
+			require.NoError(t, store.Delete(id))
+			_, err = store.Get(id)
+			require.ErrorIs(t, err, ErrTxNotFound)
+		})
+	}
+}
+
+func TestTxStore_Copies(t *testing.T) {
+	store := NewMemoryTxStore()
+	id := uuid.NewString()
+	code := uint32(6000)
+	require.NoError(t, store.Create(TxRecord{ID: id, State: TxStateBroadcasted}))
+	attempt := TxAttempt{
+		Signature:    solana.Signature{1},
+		DurableNonce: &DurableNonce{Value: solana.Hash{1}},
+		Programs:     []solana.PublicKey{{1}},
+		Tx:           []byte{1},
+	}
+	require.NoError(t, store.AddAttempt(id, attempt))
+	require.NoError(t, store.SetTxError(id, &DecodedTxError{CustomCode: &code, Logs: []string{"log"}}))
+	require.NoError(t, store.SetBatch(id, &TxBatchMember{Batch: "batch"}))
+	expected, err := store.Get(id)
+	require.NoError(t, err)
+
+	// changing the records passed to or returned by the store does not change the stored record
+	attempt.DurableNonce.Value, attempt.Programs[0], attempt.Tx[0] = solana.Hash{2}, solana.PublicKey{2}, 2
+	rec, err := store.Get(id)
+	require.NoError(t, err)
+	rec.Attempts[0].DurableNonce.Value, rec.Attempts[0].Programs[0], rec.Attempts[0].Tx[0] = solana.Hash{2}, solana.PublicKey{2}, 2
+	*rec.TxError.CustomCode, rec.TxError.Logs[0] = 0, ""
+	rec.Batch.Batch = ""
+	rec, err = store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, expected, rec)
+	assert.Equal(t, solana.Hash{1}, rec.Attempts[0].DurableNonce.Value)
+	assert.Equal(t, []byte{1}, rec.Attempts[0].Tx)
+}
+
+func TestFileTxStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "txs.json")
+	store, err := NewFileTxStore(path)
+	require.NoError(t, err)
+
+	id := uuid.NewString()
+	require.NoError(t, store.Create(TxRecord{
+		ID:       id,
+		Message:  []byte{1},
+		Attempts: []TxAttempt{{Signature: solana.Signature{1}, ComputeUnitPrice: 10}},
+		State:    TxStateBroadcasted,
+	}))
+	require.NoError(t, store.AddAttempt(id, TxAttempt{Signature: solana.Signature{2}, ComputeUnitPrice: 20, Programs: []solana.PublicKey{{3}}}))
+	code := uint32(6000)
+	txErr := &DecodedTxError{Name: "InstructionError", CustomCode: &code, Program: solana.PublicKey{3}, ErrorName: "StaleReport"}
+	require.NoError(t, store.SetTxError(id, txErr))
+	require.NoError(t, store.AddRetry(id, TxRetryExpired))
+	require.NoError(t, store.SetDeadLetter(id, true))
+	require.NoError(t, store.Close())
+
+	// reopen store and validate persisted data
+	reopened, err := NewFileTxStore(path)
+	require.NoError(t, err)
+	rec, err := reopened.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, []solana.Signature{{1}, {2}}, rec.Signatures())
+	assert.Equal(t, uint64(20), rec.Attempts[1].ComputeUnitPrice)
+	assert.Equal(t, []solana.PublicKey{{3}}, rec.Attempts[1].Programs)
+	assert.Equal(t, txErr, rec.TxError)
+	assert.Equal(t, TxStateBroadcasted, rec.State)
+	assert.Equal(t, map[TxRetryClass]int{TxRetryExpired: 1}, rec.Retries)
+	assert.True(t, rec.DeadLetter)
+}
+
+func TestTxm_Reconcile(t *testing.T) {
+	ctx := tests.Context(t)
+	path := filepath.Join(t.TempDir(), "txs.json")
+
+	// tx stored by a previous txm instance
+	id := uuid.New()
+	sig := solana.Signature{1}
+	store, err := NewFileTxStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(TxRecord{
+		ID:        id.String(),
+		Attempts:  []TxAttempt{{Signature: sig}},
+		State:     TxStateBroadcasted,
+		CreatedAt: time.Now(),
+	}))
+	pendingID := uuid.NewString()
+	require.NoError(t, store.Create(TxRecord{ID: pendingID, State: TxStatePending}))
+	require.NoError(t, store.Close())
+
+	cfg := config.NewDefault()
+	cfg.Chain.TxStorePath = &path
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("BlockHeight", mock.Anything).Return(uint64(1), nil).Maybe()
+	mc.On("SignatureStatuses", mock.Anything, []solana.Signature{sig}).Return([]*rpc.SignatureStatusesResult{
+		{ConfirmationStatus: rpc.ConfirmationStatusConfirmed},
+	}, nil).Once()
+	mc.On("SignatureStatuses", mock.Anything, []solana.Signature{sig}).Return([]*rpc.SignatureStatusesResult{
+		{ConfirmationStatus: rpc.ConfirmationStatusFinalized},
+	}, nil)
+
+	txm := NewTxm("reconcile", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keyMocks.NewSimpleKeystore(t), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	// restored tx is finalized
+	require.Eventually(t, func() bool {
+		return txm.InflightTxs() == 0
+	}, 5*time.Second, 100*time.Millisecond)
+	status, err := txm.GetTransactionStatus(ctx, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFinalized, status.State)
+	assert.Equal(t, sig, status.Signature)
+
+	// tx that was never broadcasted is marked as failed
+	status, err = txm.GetTransactionStatus(ctx, pendingID)
+	require.NoError(t, err)
+	assert.Equal(t, TxStateFailed, status.State)
+}
+
+func TestFileTxStore_FlushError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.json")
+	store, err := NewFileTxStore(path)
+	require.NoError(t, err)
+	id := uuid.NewString()
+	require.NoError(t, store.Create(TxRecord{ID: id, Attempts: []TxAttempt{{Signature: solana.Signature{1}}}, State: TxStateBroadcasted}))
+
+	// the temp file can not be written while a directory is in its place
+	require.NoError(t, os.Mkdir(path+".tmp", 0o700))
+
+	// failed changes are rolled back in memory
+	otherID := uuid.NewString()
+	require.Error(t, store.Create(TxRecord{ID: otherID, State: TxStatePending}))
+	_, err = store.Get(otherID)
+	require.ErrorIs(t, err, ErrTxNotFound)
+	require.Error(t, store.AddAttempt(id, TxAttempt{Signature: solana.Signature{2}}))
+	require.Error(t, store.SetState(id, TxStateFinalized, solana.Signature{2}, ""))
+	require.Error(t, store.Delete(id))
+	rec, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, []solana.Signature{{1}}, rec.Signatures())
+	assert.Equal(t, TxStateBroadcasted, rec.State)
+
+	// memory and disk match once the store can be written again
+	require.NoError(t, os.Remove(path+".tmp"))
+	require.NoError(t, store.AddAttempt(id, TxAttempt{Signature: solana.Signature{3}}))
+	reopened, err := NewFileTxStore(path)
+	require.NoError(t, err)
+	rec, err = reopened.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, []solana.Signature{{1}, {3}}, rec.Signatures())
+	_, err = reopened.Get(otherID)
+	require.ErrorIs(t, err, ErrTxNotFound)
+}
+
+func TestTxm_Reconcile_Resend(t *testing.T) {
+	ctx := tests.Context(t)
+	path := filepath.Join(t.TempDir(), "txs.json")
+
+	// broadcasted txs stored by a previous txm instance, one can still be included and one has expired
+	signedTx := func(sig solana.Signature) []byte {
+		tx := payerTx(t, solana.PublicKey{1})
+		tx.Signatures = []solana.Signature{sig}
+		b, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		return b
+	}
+	validSig, expiredSig := solana.Signature{1}, solana.Signature{2}
+	store, err := NewFileTxStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(TxRecord{
+		ID:        uuid.NewString(),
+		Attempts:  []TxAttempt{{Signature: validSig, LastValidBlockHeight: 100, Tx: signedTx(validSig)}},
+		State:     TxStateBroadcasted,
+		CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.Create(TxRecord{
+		ID:        uuid.NewString(),
+		Attempts:  []TxAttempt{{Signature: expiredSig, LastValidBlockHeight: 5, Tx: signedTx(expiredSig)}},
+		State:     TxStateBroadcasted,
+		CreatedAt: time.Now(),
+	}))
+	require.NoError(t, store.Close())
+
+	cfg := config.NewDefault()
+	cfg.Chain.TxStorePath = &path
+	mc := clientmocks.NewReaderWriter(t)
+	mc.On("BlockHeight", mock.Anything).Return(uint64(10), nil)
+	mc.On("SignatureStatuses", mock.Anything, mock.Anything).Return(func(_ context.Context, sigs []solana.Signature) ([]*rpc.SignatureStatusesResult, error) {
+		return make([]*rpc.SignatureStatusesResult, len(sigs)), nil
+	}).Maybe()
+	// only the tx that can still be included is resent, unchanged
+	var resent atomic.Int64
+	mc.On("SendTx", mock.Anything, mock.MatchedBy(func(tx *solana.Transaction) bool {
+		return len(tx.Signatures) == 1 && tx.Signatures[0] == validSig
+	})).Run(func(mock.Arguments) { resent.Add(1) }).Return(validSig, nil)
+
+	txm := NewTxm("reconcile", func() (client.ReaderWriter, error) {
+		return mc, nil
+	}, cfg, keyMocks.NewSimpleKeystore(t), logger.Test(t))
+	require.NoError(t, txm.Start(ctx))
+	t.Cleanup(func() { require.NoError(t, txm.Close()) })
+
+	require.Eventually(t, func() bool {
+		return resent.Load() > 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
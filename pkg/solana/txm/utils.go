@@ -9,6 +9,8 @@ import (
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/fees"
 )
 
 // tx not found
@@ -174,6 +176,16 @@ func SetComputeUnitPriceMax(v uint64) SetTxConfig {
 		cfg.ComputeUnitPriceMax = v
 	}
 }
+func SetFeeBumpStrategy(s fees.BumpStrategy) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.FeeBumpStrategy = s
+	}
+}
+func SetFeeBudget(v uint64) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.FeeBudget = v
+	}
+}
 func SetComputeUnitLimit(v uint32) SetTxConfig {
 	return func(cfg *TxConfig) {
 		cfg.ComputeUnitLimit = v
@@ -184,3 +196,48 @@ func SetEstimateComputeUnitLimit(v bool) SetTxConfig {
 		cfg.EstimateComputeUnitLimit = v
 	}
 }
+func SetEstimateLoadedAccountsDataSize(v bool) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.EstimateLoadedAccountsDataSize = v
+	}
+}
+func SetLoadedAccountsDataSizeLimit(v uint32) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.LoadedAccountsDataSizeLimit = v
+	}
+}
+func SetHeapFrame(v uint32) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.HeapFrame = v
+	}
+}
+func SetRefreshBlockhash(v bool) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.RefreshBlockhash = v
+	}
+}
+func SetCompletionCommitment(v rpc.CommitmentType) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.CompletionCommitment = v
+	}
+}
+func SetUseDurableNonce(v bool) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.UseDurableNonce = v
+	}
+}
+func SetSigners(keys ...solana.PrivateKey) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.Signers = keys
+	}
+}
+func SetBatch(v bool) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.Batch = v
+	}
+}
+func SetEventCallback(fn func(TxEvent)) SetTxConfig {
+	return func(cfg *TxConfig) {
+		cfg.OnEvent = fn
+	}
+}
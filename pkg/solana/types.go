@@ -6,6 +6,8 @@ import (
 
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
+
+	"github.com/goplugin/plugin-solana/pkg/solana/config"
 )
 
 const (
@@ -176,4 +178,7 @@ type RelayConfig struct {
 	OCR2ProgramID   string `json:"ocr2ProgramID"`
 	TransmissionsID string `json:"transmissionsID"`
 	StoreProgramID  string `json:"storeProgramID"`
+
+	// optional contract reader config, exposed to the plugin by the median provider
+	ChainReader *config.ChainReader `json:"chainReader,omitempty"`
 }